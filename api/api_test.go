@@ -355,6 +355,29 @@ func TestRobotConnection(t *testing.T) {
 	var body map[string]interface{}
 	json.NewDecoder(response.Body).Decode(&body)
 	gobottest.Assert(t, body["connection"].(map[string]interface{})["name"].(string), "Connection1")
+	gobottest.Assert(t, body["connection"].(map[string]interface{})["pins"], nil)
+	gobottest.Assert(t, body["connection"].(map[string]interface{})["capabilities"], map[string]interface{}{
+		"interfaces": []interface{}{},
+		"pins": []interface{}{
+			map[string]interface{}{"pin": "13", "modes": []interface{}{"in", "out"}},
+		},
+		"port":     "/dev/null",
+		"firmware": "test 1.0",
+	})
+
+	// allocated pins
+	a.gobot.Robot("Robot1").Connection("Connection1").(gobot.PinAllocator).AllocatePin("13", "out", "Device1")
+	request, _ = http.NewRequest("GET",
+		"/api/robots/Robot1/connections/Connection1",
+		nil,
+	)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = map[string]interface{}{}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["connection"].(map[string]interface{})["pins"], []interface{}{
+		map[string]interface{}{"pin": "13", "mode": "out", "owner": "Device1"},
+	})
 
 	// unknown connection
 	request, _ = http.NewRequest("GET",
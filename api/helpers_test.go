@@ -59,6 +59,7 @@ func newTestDriver(adaptor *testAdaptor, name string, pin string) *testDriver {
 }
 
 type testAdaptor struct {
+	gobot.PinTable
 	name string
 	port string
 }
@@ -70,6 +71,12 @@ func (t *testAdaptor) Finalize() (errs []error) { return testAdaptorFinalize() }
 func (t *testAdaptor) Connect() (errs []error)  { return testAdaptorConnect() }
 func (t *testAdaptor) Name() string             { return t.name }
 func (t *testAdaptor) Port() string             { return t.port }
+func (t *testAdaptor) Capabilities() gobot.Capabilities {
+	return gobot.Capabilities{
+		Pins:     []gobot.PinCapability{{Pin: "13", Modes: []string{"in", "out"}}},
+		Firmware: "test 1.0",
+	}
+}
 
 func newTestAdaptor(name string, port string) *testAdaptor {
 	return &testAdaptor{
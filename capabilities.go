@@ -0,0 +1,91 @@
+package gobot
+
+import (
+	"sort"
+	"sync"
+)
+
+// Capabilities describes what an adaptor supports, so that clients only offer
+// the operations a connection can perform
+type Capabilities struct {
+	// Interfaces names the io interfaces the adaptor implements, eg.
+	// "DigitalReader", "PwmWriter" or "I2c", sorted by name
+	Interfaces []string `json:"interfaces"`
+	// Pins lists the pins of the adaptor and the modes each supports
+	Pins []PinCapability `json:"pins,omitempty"`
+	// Port is the port the adaptor connects to
+	Port string `json:"port,omitempty"`
+	// Firmware is the name and version of the firmware of the board, eg.
+	// "StandardFirmata.ino 2.5"
+	Firmware string `json:"firmware,omitempty"`
+	// Metadata holds further facts about the board, such as its model
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// PinCapability describes a pin and the modes it supports, eg. "in", "out",
+// "pwm", "servo" or "analog". Analog inputs are listed apart from the digital
+// pins, with the "analog" mode only, as they are named apart.
+type PinCapability struct {
+	Pin   string   `json:"pin"`
+	Modes []string `json:"modes"`
+}
+
+// Capabler is the interface that describes an adaptor reporting its pins,
+// firmware and metadata. The interfaces and port of the adaptor are found
+// by NewCapabilities, so adaptors need not report them.
+type Capabler interface {
+	Capabilities() Capabilities
+}
+
+type ioInterface struct {
+	name       string
+	implements func(Adaptor) bool
+}
+
+var (
+	ioInterfacesMutex sync.Mutex
+	ioInterfaces      []ioInterface
+)
+
+// RegisterInterface registers the io interface name, which an adaptor
+// implements when implements returns true. The packages defining io
+// interfaces, such as gpio and i2c, register them when they are imported.
+func RegisterInterface(name string, implements func(Adaptor) bool) {
+	ioInterfacesMutex.Lock()
+	defer ioInterfacesMutex.Unlock()
+	ioInterfaces = append(ioInterfaces, ioInterface{name: name, implements: implements})
+}
+
+// NewCapabilities returns the capabilities of connection: the registered
+// interfaces it implements, its port when it is a Porter, and the pins,
+// firmware and metadata it reports when it is a Capabler.
+func NewCapabilities(connection Connection) Capabilities {
+	c := Capabilities{}
+	if capabler, ok := connection.(Capabler); ok {
+		c = capabler.Capabilities()
+	}
+	if c.Port == "" {
+		if porter, ok := connection.(Porter); ok {
+			c.Port = porter.Port()
+		}
+	}
+
+	names := map[string]bool{}
+	for _, name := range c.Interfaces {
+		names[name] = true
+	}
+	ioInterfacesMutex.Lock()
+	for _, i := range ioInterfaces {
+		if !names[i.name] && i.implements(connection) {
+			names[i.name] = true
+		}
+	}
+	ioInterfacesMutex.Unlock()
+
+	c.Interfaces = []string{}
+	for name := range names {
+		c.Interfaces = append(c.Interfaces, name)
+	}
+	sort.Strings(c.Interfaces)
+	return c
+}
@@ -0,0 +1,40 @@
+package gobot
+
+import (
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+type testCapablerAdaptor struct {
+	testAdaptor
+}
+
+func (t *testCapablerAdaptor) Capabilities() Capabilities {
+	return Capabilities{
+		Interfaces: []string{"Serial"},
+		Pins:       []PinCapability{{Pin: "1", Modes: []string{"in"}}},
+		Firmware:   "test 1.0",
+	}
+}
+
+func TestNewCapabilities(t *testing.T) {
+	RegisterInterface("Porter", func(a Adaptor) bool { _, ok := a.(Porter); return ok })
+	RegisterInterface("Capabler", func(a Adaptor) bool { _, ok := a.(Capabler); return ok })
+
+	c := NewCapabilities(newTestAdaptor("Connection1", "/dev/null"))
+	gobottest.Assert(t, c, Capabilities{
+		Interfaces: []string{"Porter"},
+		Port:       "/dev/null",
+	})
+
+	c = NewCapabilities(&testCapablerAdaptor{testAdaptor: *newTestAdaptor("Connection2", "/dev/ttyACM0")})
+	gobottest.Assert(t, c, Capabilities{
+		Interfaces: []string{"Capabler", "Porter", "Serial"},
+		Pins:       []PinCapability{{Pin: "1", Modes: []string{"in"}}},
+		Port:       "/dev/ttyACM0",
+		Firmware:   "test 1.0",
+	})
+
+	gobottest.Assert(t, NewJSONConnection(newTestAdaptor("Connection3", "")).Capabilities.Port, "")
+}
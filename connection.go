@@ -8,16 +8,24 @@ import (
 
 // JSONConnection is a JSON representation of a Connection.
 type JSONConnection struct {
-	Name    string `json:"name"`
-	Adaptor string `json:"adaptor"`
+	Name         string          `json:"name"`
+	Adaptor      string          `json:"adaptor"`
+	Pins         []PinAllocation `json:"pins,omitempty"`
+	Capabilities Capabilities    `json:"capabilities"`
 }
 
-// NewJSONConnection returns a JSONConnection given a Connection.
+// NewJSONConnection returns a JSONConnection given a Connection. The pins of
+// connections which are a PinAllocator list their allocated pins.
 func NewJSONConnection(connection Connection) *JSONConnection {
-	return &JSONConnection{
-		Name:    connection.Name(),
-		Adaptor: reflect.TypeOf(connection).String(),
+	jsonConnection := &JSONConnection{
+		Name:         connection.Name(),
+		Adaptor:      reflect.TypeOf(connection).String(),
+		Capabilities: NewCapabilities(connection),
 	}
+	if allocator, ok := connection.(PinAllocator); ok {
+		jsonConnection.Pins = allocator.PinAllocations()
+	}
+	return jsonConnection
 }
 
 // A Connection is an instance of an Adaptor
@@ -51,9 +51,13 @@ func (d *Devices) Each(f func(Device)) {
 	}
 }
 
-// Start calls Start on each Device in d
+// Start allocates the pins of each Device in d on its connection, then calls
+// Start on each Device. No Device is started when two claim the same pin.
 func (d *Devices) Start() (errs []error) {
 	log.Println("Starting devices...")
+	if err := d.allocatePins(); err != nil {
+		return []error{err}
+	}
 	for _, device := range *d {
 		info := "Starting device " + device.Name()
 
@@ -72,7 +76,7 @@ func (d *Devices) Start() (errs []error) {
 	return
 }
 
-// Halt calls Halt on each Device in d
+// Halt calls Halt on each Device in d, and releases their pins
 func (d *Devices) Halt() (errs []error) {
 	for _, device := range *d {
 		if derrs := device.Halt(); len(derrs) > 0 {
@@ -82,5 +86,6 @@ func (d *Devices) Halt() (errs []error) {
 			errs = append(errs, derrs...)
 		}
 	}
+	d.releasePins()
 	return
 }
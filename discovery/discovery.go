@@ -0,0 +1,205 @@
+package discovery
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/firmata"
+	"github.com/potix/gobot/platforms/mavlink"
+	"github.com/potix/gobot/platforms/neurosky"
+	"github.com/potix/gobot/platforms/sphero"
+	"github.com/potix/gobot/sysfs"
+)
+
+const (
+	// Firmata is the protocol of boards running Firmata, such as Arduinos
+	Firmata = "firmata"
+	// Sphero is the protocol of Sphero robots
+	Sphero = "sphero"
+	// Mavlink is the protocol of MAVLink autopilots and telemetry radios
+	Mavlink = "mavlink"
+	// Neurosky is the ThinkGear protocol of NeuroSky headsets
+	Neurosky = "neurosky"
+)
+
+// Protocols holds the protocols the discovery package probes for, in the
+// order they are probed when a device matches no known usb id
+var Protocols = []string{Firmata, Mavlink, Neurosky, Sphero}
+
+// usbID maps usb vendor ids, and optionally product ids, to the protocols
+// the devices are expected to speak
+type usbID struct {
+	vendor    string
+	product   string
+	protocols []string
+}
+
+// usbIDs holds the usb ids of known boards. Devices with a generic usb
+// serial converter, such as the FTDI and Silicon Labs ones, could speak any
+// of several protocols.
+var usbIDs = []usbID{
+	// Arduino, Arduino.org, SparkFun and Adafruit boards, and CH340 clones
+	{vendor: "2341", protocols: []string{Firmata}},
+	{vendor: "2a03", protocols: []string{Firmata}},
+	{vendor: "1b4f", protocols: []string{Firmata}},
+	{vendor: "239a", protocols: []string{Firmata}},
+	{vendor: "1a86", product: "7523", protocols: []string{Firmata}},
+	// 3D Robotics Pixhawk and Hex Cube autopilots
+	{vendor: "26ac", protocols: []string{Mavlink}},
+	{vendor: "2dae", protocols: []string{Mavlink}},
+	// FTDI and Silicon Labs CP210x converters
+	{vendor: "0403", protocols: []string{Firmata, Mavlink}},
+	{vendor: "10c4", product: "ea60", protocols: []string{Mavlink, Neurosky, Firmata}},
+}
+
+// Device is a serial device found in sysfs
+type Device struct {
+	// Port is the device file of the serial port, eg. "/dev/ttyACM0"
+	Port string `json:"port"`
+	// VendorID and ProductID are the hexadecimal usb ids of a usb device,
+	// eg. "2341" and "0043" for an Arduino Uno
+	VendorID  string `json:"vendor_id,omitempty"`
+	ProductID string `json:"product_id,omitempty"`
+	// Manufacturer, Product and Serial are the usb strings of the device
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	// Bluetooth is true for a bluetooth rfcomm port
+	Bluetooth bool `json:"bluetooth,omitempty"`
+	// Protocols are the protocols the device is expected to speak, most
+	// likely first
+	Protocols []string `json:"protocols"`
+}
+
+// Candidate is a device with the protocol it speaks, and an adaptor for it
+type Candidate struct {
+	Device
+	// Protocol is the protocol the device speaks, or the most likely one
+	// when the device was not probed. It is empty for an unknown device.
+	Protocol string `json:"protocol"`
+	// Probed is true when the device answered the protocol
+	Probed bool `json:"probed"`
+	// Adaptor is an adaptor for the device, or nil for an unknown device
+	Adaptor gobot.Adaptor `json:"-"`
+}
+
+var glob = func(pattern string) (matches []string, err error) {
+	return filepath.Glob(pattern)
+}
+
+var evalSymlinks = func(path string) (string, error) {
+	return filepath.EvalSymlinks(path)
+}
+
+// Devices returns the usb serial ports and bluetooth rfcomm ports found in
+// /sys/class/tty, sorted by port
+func Devices() (devices []Device, err error) {
+	ttys, err := glob("/sys/class/tty/*")
+	if err != nil {
+		return
+	}
+	sort.Strings(ttys)
+	for _, tty := range ttys {
+		name := path.Base(tty)
+		if strings.HasPrefix(name, "rfcomm") {
+			devices = append(devices, Device{
+				Port:      "/dev/" + name,
+				Bluetooth: true,
+				Protocols: []string{Sphero, Neurosky},
+			})
+			continue
+		}
+		dir, err := evalSymlinks(tty + "/device")
+		if err != nil {
+			continue
+		}
+		if usb, ok := usbDevice(dir); ok {
+			d := Device{
+				Port:         "/dev/" + name,
+				VendorID:     readAttribute(usb, "idVendor"),
+				ProductID:    readAttribute(usb, "idProduct"),
+				Manufacturer: readAttribute(usb, "manufacturer"),
+				Product:      readAttribute(usb, "product"),
+				Serial:       readAttribute(usb, "serial"),
+			}
+			d.Protocols = protocols(d.VendorID, d.ProductID)
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+// usbDevice returns the usb device directory above the tty device directory
+// dir, which is the directory holding its idVendor attribute
+func usbDevice(dir string) (string, bool) {
+	for ; dir != "/" && dir != "."; dir = path.Dir(dir) {
+		if readAttribute(dir, "idVendor") != "" {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// protocols returns the protocols of the devices with a usb id, or every
+// protocol for an unknown device
+func protocols(vendor string, product string) []string {
+	for _, id := range usbIDs {
+		if id.vendor == vendor && (id.product == "" || id.product == product) {
+			return id.protocols
+		}
+	}
+	return Protocols
+}
+
+// readAttribute returns the sysfs attribute name of dir, or an empty string
+// if it can not be read
+func readAttribute(dir string, name string) string {
+	buf, _ := sysfs.ReadAttribute(dir + "/" + name)
+	return buf
+}
+
+// Scan returns a candidate for each serial device. When probe is set, each
+// device is probed for its protocols within timeout each, and the candidates
+// of devices which answer none have no protocol and no adaptor.
+func Scan(probe bool, timeout time.Duration) (candidates []Candidate, err error) {
+	devices, err := Devices()
+	if err != nil {
+		return
+	}
+	for _, d := range devices {
+		c := Candidate{Device: d}
+		if probe {
+			if c.Protocol, err = Probe(d.Port, d.Protocols, timeout); err == nil {
+				c.Probed = true
+			}
+			err = nil
+		} else if len(d.Protocols) > 0 {
+			c.Protocol = d.Protocols[0]
+		}
+		c.Adaptor = NewAdaptor(c.Protocol, d.Port)
+		candidates = append(candidates, c)
+	}
+	return
+}
+
+// NewAdaptor returns an adaptor for a device speaking protocol on port, named
+// after them, eg. "firmata-ttyACM0", or nil for an unknown protocol
+func NewAdaptor(protocol string, port string) gobot.Adaptor {
+	name := fmt.Sprintf("%v-%v", protocol, path.Base(port))
+	switch protocol {
+	case Firmata:
+		return firmata.NewFirmataAdaptor(name, port)
+	case Sphero:
+		return sphero.NewSpheroAdaptor(name, port)
+	case Mavlink:
+		return mavlink.NewMavlinkAdaptor(name, port)
+	case Neurosky:
+		return neurosky.NewNeuroskyAdaptor(name, port)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/platforms/firmata"
+	"github.com/potix/gobot/platforms/mavlink"
+	"github.com/potix/gobot/platforms/neurosky"
+	"github.com/potix/gobot/platforms/sphero"
+	"github.com/potix/gobot/sysfs"
+)
+
+// initTestSysfs adds an Arduino Uno on ttyACM0, an FTDI converter on
+// ttyUSB0, a bluetooth port and an on board uart to a simulated sysfs
+func initTestSysfs() {
+	usb := "/sys/devices/pci0000:00/usb1/1-1"
+	ftdi := "/sys/devices/pci0000:00/usb1/1-2"
+
+	sim := sysfs.NewSimulator()
+	sim.AddFile(usb+"/idVendor", "2341\n")
+	sim.AddFile(usb+"/idProduct", "0043\n")
+	sim.AddFile(usb+"/manufacturer", "Arduino (www.arduino.cc)\n")
+	sim.AddFile(usb+"/product", "Arduino Uno\n")
+	sim.AddFile(usb+"/serial", "75237333536351F0E0C1\n")
+	sim.AddFile(ftdi+"/idVendor", "0403\n")
+	sim.AddFile(ftdi+"/idProduct", "6001\n")
+	sim.AddFile(ftdi+"/product", "FT232R USB UART\n")
+	sysfs.SetFilesystem(sim)
+
+	devices := map[string]string{
+		"/sys/class/tty/ttyUSB0/device": ftdi + "/1-2:1.0/ttyUSB0",
+		"/sys/class/tty/ttyACM0/device": usb + "/1-1:1.0",
+		"/sys/class/tty/ttyS0/device":   "/sys/devices/platform/serial8250",
+	}
+	glob = func(pattern string) ([]string, error) {
+		return []string{
+			"/sys/class/tty/ttyUSB0",
+			"/sys/class/tty/ttyACM0",
+			"/sys/class/tty/rfcomm0",
+			"/sys/class/tty/ttyS0",
+			"/sys/class/tty/tty1",
+		}, nil
+	}
+	evalSymlinks = func(path string) (string, error) {
+		if dir, ok := devices[path]; ok {
+			return dir, nil
+		}
+		return "", errors.New("no such file or directory")
+	}
+}
+
+func restoreSysfs() {
+	sysfs.SetFilesystem(&sysfs.NativeFilesystem{})
+	glob = filepath.Glob
+	evalSymlinks = filepath.EvalSymlinks
+}
+
+func TestDevices(t *testing.T) {
+	initTestSysfs()
+	defer restoreSysfs()
+
+	devices, err := Devices()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, len(devices), 3)
+
+	gobottest.Assert(t, devices[0], Device{
+		Port:      "/dev/rfcomm0",
+		Bluetooth: true,
+		Protocols: []string{Sphero, Neurosky},
+	})
+	gobottest.Assert(t, devices[1], Device{
+		Port:         "/dev/ttyACM0",
+		VendorID:     "2341",
+		ProductID:    "0043",
+		Manufacturer: "Arduino (www.arduino.cc)",
+		Product:      "Arduino Uno",
+		Serial:       "75237333536351F0E0C1",
+		Protocols:    []string{Firmata},
+	})
+	gobottest.Assert(t, devices[2], Device{
+		Port:      "/dev/ttyUSB0",
+		VendorID:  "0403",
+		ProductID: "6001",
+		Product:   "FT232R USB UART",
+		Protocols: []string{Firmata, Mavlink},
+	})
+}
+
+func TestDevicesGlobError(t *testing.T) {
+	initTestSysfs()
+	defer restoreSysfs()
+
+	glob = func(pattern string) ([]string, error) {
+		return nil, errors.New("glob error")
+	}
+	_, err := Devices()
+	gobottest.Assert(t, err, errors.New("glob error"))
+}
+
+func TestProtocols(t *testing.T) {
+	gobottest.Assert(t, protocols("2341", "0001"), []string{Firmata})
+	gobottest.Assert(t, protocols("26ac", "0011"), []string{Mavlink})
+	gobottest.Assert(t, protocols("1a86", "7523"), []string{Firmata})
+	gobottest.Assert(t, protocols("1a86", "5523"), Protocols)
+	gobottest.Assert(t, protocols("1234", "5678"), Protocols)
+}
+
+func TestScan(t *testing.T) {
+	initTestSysfs()
+	defer restoreSysfs()
+
+	candidates, err := Scan(false, time.Second)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, len(candidates), 3)
+
+	gobottest.Assert(t, candidates[0].Protocol, Sphero)
+	gobottest.Assert(t, candidates[0].Probed, false)
+	gobottest.Assert(t, candidates[0].Adaptor.Name(), "sphero-rfcomm0")
+	gobottest.Assert(t, candidates[1].Protocol, Firmata)
+	gobottest.Assert(t, candidates[1].Adaptor.Name(), "firmata-ttyACM0")
+	gobottest.Assert(t, candidates[2].Protocol, Firmata)
+	gobottest.Assert(t, candidates[2].Adaptor.(*firmata.FirmataAdaptor).Port(), "/dev/ttyUSB0")
+}
+
+func TestScanProbe(t *testing.T) {
+	initTestSysfs()
+	defer restoreSysfs()
+	defer restoreOpenPort()
+
+	answers := map[string]map[int][]byte{
+		"/dev/ttyUSB0": {57600: mavlinkHeartbeat},
+		"/dev/ttyACM0": {57600: firmataVersion},
+	}
+	openPort = func(port string, baud int) (io.ReadWriteCloser, error) {
+		return newTestPort(answers[port][baud]), nil
+	}
+
+	candidates, err := Scan(true, 50*time.Millisecond)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, len(candidates), 3)
+
+	gobottest.Assert(t, candidates[0].Protocol, "")
+	gobottest.Assert(t, candidates[0].Probed, false)
+	gobottest.Assert(t, candidates[0].Adaptor, nil)
+	gobottest.Assert(t, candidates[1].Protocol, Firmata)
+	gobottest.Assert(t, candidates[1].Probed, true)
+	gobottest.Assert(t, candidates[2].Protocol, Mavlink)
+	gobottest.Assert(t, candidates[2].Probed, true)
+	gobottest.Assert(t, candidates[2].Adaptor.Name(), "mavlink-ttyUSB0")
+}
+
+func TestNewAdaptor(t *testing.T) {
+	_, ok := NewAdaptor(Firmata, "/dev/ttyACM0").(*firmata.FirmataAdaptor)
+	gobottest.Assert(t, ok, true)
+	_, ok = NewAdaptor(Sphero, "/dev/rfcomm0").(*sphero.SpheroAdaptor)
+	gobottest.Assert(t, ok, true)
+	_, ok = NewAdaptor(Mavlink, "/dev/ttyUSB0").(*mavlink.MavlinkAdaptor)
+	gobottest.Assert(t, ok, true)
+	_, ok = NewAdaptor(Neurosky, "/dev/rfcomm1").(*neurosky.NeuroskyAdaptor)
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, NewAdaptor("", "/dev/ttyS0"), nil)
+}
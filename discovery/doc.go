@@ -0,0 +1,26 @@
+/*
+Package discovery finds the boards and robots connected to serial ports, so
+that the port of a FirmataAdaptor, SpheroAdaptor, MavlinkAdaptor or
+NeuroskyAdaptor does not have to be known in advance.
+
+Devices lists the usb serial ports found in /sys/class/tty, with the usb
+vendor and product ids the protocol a device speaks is guessed from, and the
+bluetooth rfcomm ports. Probe confirms the protocol by talking to the device:
+
+	firmata    asks for the Firmata protocol version
+	sphero     pings the Sphero
+	mavlink    listens for a MAVLink heartbeat
+	neurosky   listens for a ThinkGear packet
+
+Scan combines both, and returns candidates holding an adaptor for each device:
+
+	candidates, err := discovery.Scan(true, 3*time.Second)
+	for _, c := range candidates {
+		if c.Probed {
+			fmt.Println(c.Port, c.Protocol)
+		}
+	}
+
+The gobot command line tool lists them with "gobot scan".
+*/
+package discovery
@@ -0,0 +1,178 @@
+package discovery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/potix/gobot/transport"
+)
+
+// ErrNoAnswer is the error resulting of probing a device which answers none
+// of the probed protocols
+var ErrNoAnswer = errors.New("discovery: the device answered none of the protocols")
+
+// openPort opens a serial port, it is replaced in tests
+var openPort = func(port string, baud int) (io.ReadWriteCloser, error) {
+	return transport.Open(port, baud)
+}
+
+// prober describes how a protocol is recognised: query is written to the
+// port, which is then read until match finds an answer in what was read
+type prober struct {
+	baud  int
+	query []byte
+	match func(buf []byte) bool
+}
+
+// spheroPingSeq is the sequence number of the ping sent to a Sphero
+const spheroPingSeq = 0x42
+
+var probers = map[string]prober{
+	// a Firmata board reports its protocol version when asked, and when it
+	// boots, which opening the port of an Arduino triggers
+	Firmata: {baud: 57600, query: []byte{0xF9}, match: matchFirmata},
+	// a Sphero answers a ping with a simple response
+	Sphero: {baud: 115200, query: spheroPing(), match: matchSphero},
+	// an autopilot sends a heartbeat every second
+	Mavlink: {baud: 57600, match: matchMavlink},
+	// a headset streams ThinkGear packets
+	Neurosky: {baud: 57600, match: matchNeurosky},
+}
+
+// Probe opens port for each of protocols in turn, and returns the first
+// protocol the device answers within timeout
+func Probe(port string, protocols []string, timeout time.Duration) (string, error) {
+	for _, protocol := range protocols {
+		p, ok := probers[protocol]
+		if !ok {
+			return "", fmt.Errorf("discovery: unknown protocol %q", protocol)
+		}
+		ok, err := p.probe(port, timeout)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return protocol, nil
+		}
+	}
+	return "", ErrNoAnswer
+}
+
+// probe returns true if the device on port answers within timeout. The port
+// is closed on timeout, which ends the pending read.
+func (p prober) probe(port string, timeout time.Duration) (bool, error) {
+	conn, err := openPort(port, p.baud)
+	if err != nil {
+		return false, err
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		if len(p.query) > 0 {
+			if _, err := conn.Write(p.query); err != nil {
+				done <- false
+				return
+			}
+		}
+		buf := []byte{}
+		read := make([]byte, 64)
+		for {
+			n, err := conn.Read(read)
+			buf = append(buf, read[:n]...)
+			if p.match(buf) {
+				done <- true
+				return
+			}
+			if err != nil {
+				done <- false
+				return
+			}
+		}
+	}()
+
+	select {
+	case ok := <-done:
+		conn.Close()
+		return ok, nil
+	case <-time.After(timeout):
+		conn.Close()
+		return false, nil
+	}
+}
+
+// matchFirmata finds a protocol version report, 0xF9 followed by the major
+// and minor version
+func matchFirmata(buf []byte) bool {
+	for i := 0; i+2 < len(buf); i++ {
+		if buf[i] == 0xF9 && buf[i+1] < 0x80 && buf[i+2] < 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// spheroPing returns the ping command packet of the Sphero api
+func spheroPing() []byte {
+	packet := []byte{0xFF, 0xFF, 0x00, 0x01, spheroPingSeq, 0x01}
+	return append(packet, checksum(packet[2:]))
+}
+
+// matchSphero finds the simple response to the ping
+func matchSphero(buf []byte) bool {
+	response := []byte{0xFF, 0xFF, 0x00, spheroPingSeq, 0x01}
+	i := bytes.Index(buf, response)
+	return i != -1 && i+len(response) < len(buf) &&
+		buf[i+len(response)] == checksum(response[2:])
+}
+
+// checksum is the inverted modulo 256 sum of the Sphero packets
+func checksum(b []byte) byte {
+	var sum byte
+	for _, c := range b {
+		sum += c
+	}
+	return ^sum
+}
+
+// matchMavlink finds the header of a MAVLink 1 or 2 heartbeat, whose
+// message id is 0 and whose payload is 9 bytes long
+func matchMavlink(buf []byte) bool {
+	for i := 0; i < len(buf); i++ {
+		switch {
+		case buf[i] == 0xFE && i+5 < len(buf):
+			if buf[i+1] == 9 && buf[i+5] == 0 {
+				return true
+			}
+		case buf[i] == 0xFD && i+9 < len(buf):
+			if buf[i+1] == 9 && buf[i+7] == 0 && buf[i+8] == 0 && buf[i+9] == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchNeurosky finds a ThinkGear packet with a valid checksum: two 0xAA
+// sync bytes, the payload length, the payload and its inverted sum
+func matchNeurosky(buf []byte) bool {
+	for i := 0; i+3 < len(buf); i++ {
+		if buf[i] != 0xAA || buf[i+1] != 0xAA {
+			continue
+		}
+		length := int(buf[i+2])
+		if length >= 170 || i+3+length >= len(buf) {
+			continue
+		}
+		var sum byte
+		for _, c := range buf[i+3 : i+3+length] {
+			sum += c
+		}
+		if ^sum == buf[i+3+length] {
+			return true
+		}
+	}
+	return false
+}
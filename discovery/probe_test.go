@@ -0,0 +1,143 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/transport"
+)
+
+var (
+	firmataVersion   = []byte{0xF9, 0x02, 0x05}
+	spheroResponse   = []byte{0xFF, 0xFF, 0x00, spheroPingSeq, 0x01, 0xBC}
+	mavlinkHeartbeat = []byte{0xFE, 0x09, 0x4E, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x51, 0x04, 0x03, 0x1C, 0x7F}
+	thinkgearPacket  = []byte{0xAA, 0xAA, 0x04, 0x80, 0x02, 0x00, 0x10, 0x6D}
+)
+
+// testPort answers the first write, or the open when nothing is written,
+// with answer, and blocks reads until it is closed
+type testPort struct {
+	answer  []byte
+	written []byte
+	closed  chan bool
+	once    sync.Once
+	mutex   sync.Mutex
+}
+
+func newTestPort(answer []byte) *testPort {
+	return &testPort{answer: answer, closed: make(chan bool)}
+}
+
+func (p *testPort) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.written = append(p.written, b...)
+	return len(b), nil
+}
+
+func (p *testPort) Read(b []byte) (int, error) {
+	p.mutex.Lock()
+	n := copy(b, p.answer)
+	p.answer = p.answer[n:]
+	p.mutex.Unlock()
+	if n > 0 {
+		return n, nil
+	}
+	<-p.closed
+	return 0, io.EOF
+}
+
+func (p *testPort) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return nil
+}
+
+func restoreOpenPort() {
+	openPort = func(port string, baud int) (io.ReadWriteCloser, error) {
+		return transport.Open(port, baud)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	defer restoreOpenPort()
+
+	var last *testPort
+	answer := func(baud int, b []byte) {
+		openPort = func(port string, rate int) (io.ReadWriteCloser, error) {
+			last = newTestPort(nil)
+			if rate == baud {
+				last.answer = b
+			}
+			return last, nil
+		}
+	}
+
+	answer(57600, firmataVersion)
+	protocol, err := Probe("/dev/ttyACM0", []string{Firmata}, time.Second)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, protocol, Firmata)
+	gobottest.Assert(t, last.written, []byte{0xF9})
+
+	answer(115200, spheroResponse)
+	protocol, err = Probe("/dev/rfcomm0", []string{Neurosky, Sphero}, 50*time.Millisecond)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, protocol, Sphero)
+	gobottest.Assert(t, last.written, spheroPing())
+
+	answer(57600, mavlinkHeartbeat)
+	protocol, err = Probe("/dev/ttyUSB0", []string{Mavlink}, time.Second)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, protocol, Mavlink)
+	gobottest.Assert(t, len(last.written), 0)
+
+	answer(57600, thinkgearPacket)
+	protocol, err = Probe("/dev/rfcomm1", []string{Neurosky}, time.Second)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, protocol, Neurosky)
+}
+
+func TestProbeNoAnswer(t *testing.T) {
+	defer restoreOpenPort()
+
+	openPort = func(port string, baud int) (io.ReadWriteCloser, error) {
+		return newTestPort([]byte{0x00, 0x01, 0x02}), nil
+	}
+	_, err := Probe("/dev/ttyUSB0", Protocols, 10*time.Millisecond)
+	gobottest.Assert(t, err, ErrNoAnswer)
+}
+
+func TestProbeErrors(t *testing.T) {
+	defer restoreOpenPort()
+
+	_, err := Probe("/dev/ttyUSB0", []string{"xbee"}, time.Second)
+	gobottest.Assert(t, err.Error(), `discovery: unknown protocol "xbee"`)
+
+	openPort = func(port string, baud int) (io.ReadWriteCloser, error) {
+		return nil, errors.New("permission denied")
+	}
+	_, err = Probe("/dev/ttyUSB0", []string{Firmata}, time.Second)
+	gobottest.Assert(t, err, errors.New("permission denied"))
+}
+
+func TestMatchers(t *testing.T) {
+	gobottest.Assert(t, matchFirmata(append([]byte{0x90, 0x01}, firmataVersion...)), true)
+	gobottest.Assert(t, matchFirmata(firmataVersion[:2]), false)
+	gobottest.Assert(t, matchFirmata([]byte{0xF9, 0x02, 0xF7}), false)
+
+	gobottest.Assert(t, spheroPing(), []byte{0xFF, 0xFF, 0x00, 0x01, spheroPingSeq, 0x01, 0xBB})
+	gobottest.Assert(t, matchSphero(spheroResponse), true)
+	gobottest.Assert(t, matchSphero(spheroResponse[:5]), false)
+	gobottest.Assert(t, matchSphero([]byte{0xFF, 0xFF, 0x00, spheroPingSeq, 0x01, 0x00}), false)
+
+	gobottest.Assert(t, matchMavlink(mavlinkHeartbeat[:6]), true)
+	gobottest.Assert(t, matchMavlink([]byte{0xFD, 0x09, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00}), true)
+	gobottest.Assert(t, matchMavlink([]byte{0xFE, 0x1C, 0x00, 0x01, 0x01, 0x21}), false)
+
+	gobottest.Assert(t, matchNeurosky(append([]byte{0x00, 0xAA}, thinkgearPacket...)), true)
+	gobottest.Assert(t, matchNeurosky(thinkgearPacket[:7]), false)
+	gobottest.Assert(t, matchNeurosky([]byte{0xAA, 0xAA, 0x01, 0x02, 0x00}), false)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/board"
+	"github.com/potix/gobot/platforms/gpio"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+
+	d, err := board.LoadDescription(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	r := board.NewBoardAdaptor("board", d)
+	led := gpio.NewLedDriver(r, "led", "7")
+
+	work := func() {
+		gobot.Every(1*time.Second, func() {
+			led.Toggle()
+		})
+	}
+
+	robot := gobot.NewRobot("blinkBot",
+		[]gobot.Connection{r},
+		[]gobot.Device{led},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
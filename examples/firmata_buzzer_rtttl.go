@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/firmata"
+	"github.com/potix/gobot/platforms/gpio"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+
+	firmataAdaptor := firmata.NewFirmataAdaptor("arduino", "/dev/ttyACM0")
+	buzzer := gpio.NewBuzzerDriver(firmataAdaptor, "buzzer", "3")
+
+	work := func() {
+		gobot.On(buzzer.Event(gpio.Done), func(data interface{}) {
+			fmt.Println("the melody is over")
+		})
+
+		buzzer.PlayRTTTL("Twinkle:d=4,o=5,b=120:c,c,g,g,a,a,2g,f,f,e,e,d,d,2c")
+	}
+
+	robot := gobot.NewRobot("bot",
+		[]gobot.Connection{firmataAdaptor},
+		[]gobot.Device{buzzer},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
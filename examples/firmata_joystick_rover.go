@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/firmata"
+	"github.com/potix/gobot/platforms/gpio"
+	"github.com/potix/gobot/platforms/joystick"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+
+	firmataAdaptor := firmata.NewFirmataAdaptor("firmata", "/dev/ttyACM0")
+	left := gpio.NewHBridgeDriver(firmataAdaptor, "left", "7", "8", "5")
+	right := gpio.NewHBridgeDriver(firmataAdaptor, "right", "9", "10", "6")
+	rover := gpio.NewDifferentialDrive("rover", left, right)
+	rover.SetRamp(2)
+
+	joystickAdaptor := joystick.NewJoystickAdaptor("xbox360")
+	stick := joystick.NewJoystickDriver(joystickAdaptor,
+		"xbox360",
+		"./platforms/joystick/configs/xbox360_power_a_mini_proex.json",
+	)
+
+	work := func() {
+		x, y := int16(0), int16(0)
+
+		gobot.On(stick.Event("left_x"), func(data interface{}) {
+			x = data.(int16)
+			rover.DriveJoystick(x, y)
+		})
+		gobot.On(stick.Event("left_y"), func(data interface{}) {
+			y = data.(int16)
+			rover.DriveJoystick(x, y)
+		})
+		gobot.On(stick.Event("a_press"), func(data interface{}) {
+			rover.Brake()
+		})
+	}
+
+	robot := gobot.NewRobot("rover",
+		[]gobot.Connection{firmataAdaptor, joystickAdaptor},
+		[]gobot.Device{rover, stick},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
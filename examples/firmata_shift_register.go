@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/firmata"
+	"github.com/potix/gobot/platforms/gpio"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+
+	firmataAdaptor := firmata.NewFirmataAdaptor("arduino", "/dev/ttyACM0")
+	outputs := gpio.NewShiftRegister595Driver(firmataAdaptor, "outputs", "2", "3", "4", 2)
+	inputs := gpio.NewShiftRegister165Driver(firmataAdaptor, "inputs", "5", "6", "7", 1)
+	led := gpio.NewLedDriver(outputs, "led", "sr1.5")
+	button := gpio.NewButtonDriver(inputs, "button", "sr0.0")
+
+	work := func() {
+		gobot.On(button.Event(gpio.Click), func(data interface{}) {
+			led.Toggle()
+		})
+
+		// light every other output at once
+		gobot.Every(time.Second, func() {
+			outputs.Batch(func() error {
+				for pin := 0; pin < 8; pin += 2 {
+					if err := outputs.DigitalWrite(fmt.Sprintf("sr0.%v", pin), 1); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}
+
+	robot := gobot.NewRobot("bot",
+		[]gobot.Connection{firmataAdaptor},
+		[]gobot.Device{outputs, inputs, led, button},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
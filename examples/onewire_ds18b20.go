@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/onewire"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+
+	w1 := onewire.NewOneWireAdaptor("w1")
+	sensor := onewire.NewDS18B20Driver(w1, "sensor", "", 2*time.Second)
+
+	work := func() {
+		gobot.On(sensor.Event(onewire.Temperature), func(data interface{}) {
+			fmt.Println("temperature", data)
+		})
+	}
+
+	robot := gobot.NewRobot("thermometerBot",
+		[]gobot.Connection{w1},
+		[]gobot.Device{sensor},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
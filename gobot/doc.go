@@ -1,5 +1,6 @@
 /*
-CLI tool for generating new Gobot projects.
+CLI tool for generating new Gobot projects, and for finding the serial
+devices to use with them.
 
 	NAME:
 		 gobot - Command Line Utility for Gobot
@@ -12,11 +13,21 @@ CLI tool for generating new Gobot projects.
 
 	COMMANDS:
 		 generate     Generate new Gobot skeleton project
+		 scan         Scan for serial devices and the Gobot adaptors to use with them
 		 help, h      Shows a list of commands or help for one command
 
 	GLOBAL OPTIONS:
 		 --help, -h           show help
 		 --version, -v        print the version
 
+Scan lists the usb serial and bluetooth ports, and the protocol each device
+most likely speaks. With --probe each device is opened and asked for its
+protocol, within --timeout, which defaults to 3s:
+
+	$ gobot scan --probe
+	PORT          USB ID     PRODUCT      PROTOCOL  ADAPTOR
+	/dev/rfcomm0  -          bluetooth    sphero    *sphero.SpheroAdaptor
+	/dev/ttyACM0  2341:0043  Arduino Uno  firmata   *firmata.FirmataAdaptor
+
 */
 package main
@@ -16,6 +16,7 @@ func main() {
 	app.Usage = "Command Line Utility for Gobot"
 	app.Commands = []cli.Command{
 		Generate(),
+		Scan(),
 	}
 	app.Run(os.Args)
 }
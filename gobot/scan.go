@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/potix/gobot/discovery"
+)
+
+func Scan() cli.Command {
+	return cli.Command{
+		Name:  "scan",
+		Usage: "Scan for serial devices and the Gobot adaptors to use with them",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "probe",
+				Usage: "probe each device for the protocols it speaks",
+			},
+			cli.DurationFlag{
+				Name:  "timeout",
+				Value: 3 * time.Second,
+				Usage: "time each device has to answer a probe",
+			},
+		},
+		Action: func(c *cli.Context) {
+			candidates, err := discovery.Scan(c.Bool("probe"), c.Duration("timeout"))
+			if err != nil {
+				fmt.Println("Scanning failed:", err)
+				return
+			}
+			if len(candidates) == 0 {
+				fmt.Println("No serial devices found.")
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "PORT\tUSB ID\tPRODUCT\tPROTOCOL\tADAPTOR")
+			for _, c := range candidates {
+				id, product := "-", c.Product
+				if c.VendorID != "" {
+					id = c.VendorID + ":" + c.ProductID
+				}
+				if c.Bluetooth {
+					product = "bluetooth"
+				}
+				protocol, adaptor := "unknown", "-"
+				if c.Adaptor != nil {
+					protocol, adaptor = c.Protocol, fmt.Sprintf("%T", c.Adaptor)
+					if !c.Probed {
+						protocol += "?"
+					}
+				}
+				if product == "" {
+					product = "-"
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", c.Port, id, product, protocol, adaptor)
+			}
+			w.Flush()
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -33,3 +34,26 @@ func Refute(t *testing.T, a interface{}, b interface{}) {
 			a, reflect.TypeOf(a), b, reflect.TypeOf(b)))
 	}
 }
+
+// Concurrently runs f in n goroutines at once, passing each its index, and
+// emits a t.Errorf for each error returned. Run with the race detector, it
+// checks that f is safe for concurrent use.
+func Concurrently(t *testing.T, n int, f func(i int) error) {
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- f(i)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			logFailure(t, fmt.Sprintf("%v - \"%v\", should be nil", err, reflect.TypeOf(err)))
+		}
+	}
+}
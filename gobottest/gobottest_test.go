@@ -35,3 +35,33 @@ func TestRefute(t *testing.T) {
 		t.Errorf("Refute failed: 1 should not be 1")
 	}
 }
+
+type testError string
+
+func (e testError) Error() string { return string(e) }
+
+func TestConcurrently(t *testing.T) {
+	err := ""
+	errFunc = func(t *testing.T, message string) {
+		err = message
+	}
+
+	ran := make(chan int, 4)
+	Concurrently(t, 4, func(i int) error {
+		ran <- i
+		return nil
+	})
+	if err != "" || len(ran) != 4 {
+		t.Errorf("Concurrently failed: f should run 4 times without errors")
+	}
+
+	Concurrently(t, 2, func(i int) error {
+		if i == 1 {
+			return testError("failed")
+		}
+		return nil
+	})
+	if err != `gobottest_test.go:58: failed - "gobottest.testError", should be nil` {
+		t.Errorf("Concurrently failed: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package gobot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PinModer is the interface that describes a driver using pins, mapping each
+// pin to the mode the driver uses it in, eg. "in", "out", "pwm" or "analog"
+type PinModer interface {
+	PinModes() map[string]string
+}
+
+// PinAllocation records the owner of a pin of a connection and the mode the
+// owner uses it in
+type PinAllocation struct {
+	Pin   string `json:"pin"`
+	Mode  string `json:"mode"`
+	Owner string `json:"owner"`
+}
+
+// PinAllocator is the interface that describes an adaptor recording which
+// device owns each of its pins
+type PinAllocator interface {
+	// AllocatePin records that owner uses pin in mode, returning a
+	// *PinConflictError when another owner already uses the pin
+	AllocatePin(pin string, mode string, owner string) error
+	// ReleasePins releases the pins of owner
+	ReleasePins(owner string)
+	// PinAllocations returns the allocated pins, sorted by pin
+	PinAllocations() []PinAllocation
+}
+
+// PinConflictError is the error resulting of two owners using the same pin
+type PinConflictError struct {
+	// Pin is the pin which could not be allocated
+	Pin string
+	// Mode is the mode the pin could not be allocated in
+	Mode string
+	// Owner is the current allocation of the pin, whose Pin differs from Pin
+	// when both name the same pin
+	Owner PinAllocation
+}
+
+func (e *PinConflictError) Error() string {
+	if e.Owner.Pin != e.Pin {
+		return fmt.Sprintf("Pin %q is already owned by %q as pin %q in mode %q",
+			e.Pin, e.Owner.Owner, e.Owner.Pin, e.Owner.Mode)
+	}
+	return fmt.Sprintf("Pin %q is already owned by %q in mode %q",
+		e.Pin, e.Owner.Owner, e.Owner.Mode)
+}
+
+// PinTable is a PinAllocator for adaptors to embed. Its zero value is an
+// empty table. Most boards name their analog inputs apart from their digital
+// pins, even when the names look alike, such as the analog and digital pins
+// "0" of an Arduino, so pins allocated in "analog" mode only conflict with
+// other analog pins.
+type PinTable struct {
+	mutex sync.Mutex
+	pins  map[string]PinAllocation
+}
+
+// pinKey returns the key of pin in the table
+func pinKey(pin string, mode string) string {
+	if mode == "analog" {
+		return "analog " + pin
+	}
+	return pin
+}
+
+// AllocatePin records that owner uses pin in mode. An owner may allocate its
+// own pins again, eg. to change their mode.
+func (t *PinTable) AllocatePin(pin string, mode string, owner string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := pinKey(pin, mode)
+	if a, ok := t.pins[key]; ok && a.Owner != owner {
+		return &PinConflictError{Pin: pin, Mode: mode, Owner: a}
+	}
+	if t.pins == nil {
+		t.pins = make(map[string]PinAllocation)
+	}
+	t.pins[key] = PinAllocation{Pin: pin, Mode: mode, Owner: owner}
+	return nil
+}
+
+// ReleasePins releases the pins of owner
+func (t *PinTable) ReleasePins(owner string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for pin, a := range t.pins {
+		if a.Owner == owner {
+			delete(t.pins, pin)
+		}
+	}
+}
+
+// PinAllocations returns the allocated pins, sorted by pin and mode
+func (t *PinTable) PinAllocations() []PinAllocation {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	allocations := []PinAllocation{}
+	for _, a := range t.pins {
+		allocations = append(allocations, a)
+	}
+	sort.Sort(byPin(allocations))
+	return allocations
+}
+
+type byPin []PinAllocation
+
+func (p byPin) Len() int { return len(p) }
+func (p byPin) Less(i, j int) bool {
+	if p[i].Pin == p[j].Pin {
+		return p[i].Mode < p[j].Mode
+	}
+	return p[i].Pin < p[j].Pin
+}
+func (p byPin) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// devicePins returns the pins of device mapped to their modes. Devices which
+// only report their pin with Pin use it in an unknown mode.
+func devicePins(device Device) map[string]string {
+	if moder, ok := device.(PinModer); ok {
+		return moder.PinModes()
+	}
+	if pinner, ok := device.(Pinner); ok && pinner.Pin() != "" {
+		return map[string]string{pinner.Pin(): ""}
+	}
+	return nil
+}
+
+// allocatePins allocates the pins of each device on its connection, releasing
+// them all again on the first conflict
+func (d *Devices) allocatePins() error {
+	for _, device := range *d {
+		allocator, ok := device.Connection().(PinAllocator)
+		if !ok {
+			continue
+		}
+		pins := devicePins(device)
+		names := []string{}
+		for pin := range pins {
+			names = append(names, pin)
+		}
+		sort.Strings(names)
+		for _, pin := range names {
+			if err := allocator.AllocatePin(pin, pins[pin], device.Name()); err != nil {
+				d.releasePins()
+				return fmt.Errorf("Device %q: %v", device.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// releasePins releases the pins of each device
+func (d *Devices) releasePins() {
+	for _, device := range *d {
+		if allocator, ok := device.Connection().(PinAllocator); ok {
+			allocator.ReleasePins(device.Name())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package gobot
+
+import (
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+type testPinAdaptor struct {
+	testAdaptor
+	PinTable
+}
+
+type testPinDriver struct {
+	*testDriver
+	modes map[string]string
+}
+
+func (t *testPinDriver) PinModes() map[string]string { return t.modes }
+
+func TestPinTable(t *testing.T) {
+	var p PinTable
+	gobottest.Assert(t, p.PinAllocations(), []PinAllocation{})
+
+	gobottest.Assert(t, p.AllocatePin("13", "out", "led"), nil)
+	gobottest.Assert(t, p.AllocatePin("2", "in", "button"), nil)
+	// an owner may allocate its own pin again
+	gobottest.Assert(t, p.AllocatePin("13", "pwm", "led"), nil)
+	gobottest.Assert(t, p.PinAllocations(), []PinAllocation{
+		{Pin: "13", Mode: "pwm", Owner: "led"},
+		{Pin: "2", Mode: "in", Owner: "button"},
+	})
+
+	err := p.AllocatePin("13", "in", "button")
+	gobottest.Assert(t, err.Error(), `Pin "13" is already owned by "led" in mode "pwm"`)
+	gobottest.Assert(t, err.(*PinConflictError).Owner.Owner, "led")
+
+	p.ReleasePins("led")
+	gobottest.Assert(t, p.AllocatePin("13", "in", "button"), nil)
+	gobottest.Assert(t, len(p.PinAllocations()), 2)
+
+	// analog pins are named apart from digital pins
+	gobottest.Assert(t, p.AllocatePin("13", "analog", "sensor"), nil)
+	gobottest.Assert(t, p.PinAllocations()[0], PinAllocation{Pin: "13", Mode: "analog", Owner: "sensor"})
+	gobottest.Refute(t, p.AllocatePin("13", "analog", "other"), nil)
+
+	err = &PinConflictError{Pin: "GPIO17", Mode: "out", Owner: PinAllocation{Pin: "11", Mode: "in", Owner: "button"}}
+	gobottest.Assert(t, err.Error(), `Pin "GPIO17" is already owned by "button" as pin "11" in mode "in"`)
+}
+
+func TestRobotPinConflict(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	adaptor := &testPinAdaptor{testAdaptor: testAdaptor{name: "Connection1"}}
+	button := &testPinDriver{newTestDriver(&adaptor.testAdaptor, "button", "13"), map[string]string{"13": "in"}}
+	button.connection = adaptor
+	led := &testPinDriver{newTestDriver(&adaptor.testAdaptor, "led", "13"), map[string]string{"13": "out"}}
+	led.connection = adaptor
+
+	started := false
+	testDriverStart = func() (errs []error) {
+		started = true
+		return
+	}
+	defer func() { testDriverStart = func() (errs []error) { return } }()
+	testDriverHalt = func() (errs []error) { return }
+	testAdaptorFinalize = func() (errs []error) { return }
+
+	r := NewRobot("Robot1", []Connection{adaptor}, []Device{button, led})
+	errs := r.Start()
+	gobottest.Assert(t, errs[0], errors.New(`Device "led": Pin "13" is already owned by "button" in mode "in"`))
+	gobottest.Assert(t, started, false)
+	gobottest.Assert(t, adaptor.PinAllocations(), []PinAllocation{})
+
+	// a device without PinModes allocates its pin in an unknown mode
+	r = NewRobot("Robot1", []Connection{adaptor}, []Device{button, newTestDriver(&adaptor.testAdaptor, "other", "2")})
+	r.Devices().Each(func(d Device) {
+		if td, ok := d.(*testDriver); ok {
+			td.connection = adaptor
+		}
+	})
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, started, true)
+	gobottest.Assert(t, NewJSONConnection(adaptor).Pins, []PinAllocation{
+		{Pin: "13", Mode: "in", Owner: "button"},
+		{Pin: "2", Mode: "", Owner: "other"},
+	})
+
+	gobottest.Assert(t, len(r.Stop()), 0)
+	gobottest.Assert(t, adaptor.PinAllocations(), []PinAllocation{})
+	gobottest.Assert(t, len(NewJSONConnection(newTestAdaptor("Connection2", "")).Pins), 0)
+}
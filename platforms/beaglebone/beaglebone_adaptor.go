@@ -8,8 +8,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/board"
 	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/platforms/i2c"
 	"github.com/potix/gobot/sysfs"
@@ -33,72 +35,94 @@ var glob = func(pattern string) (matches []string, err error) {
 	return filepath.Glob(pattern)
 }
 
-var pins = map[string]int{
-	"P8_3":  38,
-	"P8_4":  39,
-	"P8_5":  34,
-	"P8_6":  35,
-	"P8_7":  66,
-	"P8_8":  67,
-	"P8_9":  69,
-	"P8_10": 68,
-	"P8_11": 45,
-	"P8_12": 44,
-	"P8_13": 23,
-	"P8_14": 26,
-	"P8_15": 47,
-	"P8_16": 46,
-	"P8_17": 27,
-	"P8_18": 65,
-	"P8_19": 22,
-	"P8_20": 63,
-	"P8_21": 62,
-	"P8_22": 37,
-	"P8_23": 36,
-	"P8_24": 33,
-	"P8_25": 32,
-	"P8_26": 61,
-	"P8_27": 86,
-	"P8_28": 88,
-	"P8_29": 87,
-	"P8_30": 89,
-	"P8_31": 10,
-	"P8_32": 11,
-	"P8_33": 9,
-	"P8_34": 81,
-	"P8_35": 8,
-	"P8_36": 80,
-	"P8_37": 78,
-	"P8_38": 79,
-	"P8_39": 76,
-	"P8_40": 77,
-	"P8_41": 74,
-	"P8_42": 75,
-	"P8_43": 72,
-	"P8_44": 73,
-	"P8_45": 70,
-	"P8_46": 71,
-	"P9_11": 30,
-	"P9_12": 60,
-	"P9_13": 31,
-	"P9_14": 50,
-	"P9_15": 48,
-	"P9_16": 51,
-	"P9_17": 5,
-	"P9_18": 4,
-	"P9_19": 13,
-	"P9_20": 12,
-	"P9_21": 3,
-	"P9_22": 2,
-	"P9_23": 49,
-	"P9_24": 15,
-	"P9_25": 117,
-	"P9_26": 14,
-	"P9_27": 115,
-	"P9_28": 113,
-	"P9_29": 111,
-	"P9_30": 112,
-	"P9_31": 110,
+// description returns the board description of the Beaglebone Black. Devices
+// use the i2c bus /dev/i2c-1, and the usr0 through usr3 pins drive the user
+// leds.
+func description() *board.Description {
+	return &board.Description{
+		Name:   "Beaglebone Black",
+		I2cBus: 1,
+		Pins: map[string]*board.Pin{
+			"P8_3":  &board.Pin{Gpio: 38},
+			"P8_4":  &board.Pin{Gpio: 39},
+			"P8_5":  &board.Pin{Gpio: 34},
+			"P8_6":  &board.Pin{Gpio: 35},
+			"P8_7":  &board.Pin{Gpio: 66},
+			"P8_8":  &board.Pin{Gpio: 67},
+			"P8_9":  &board.Pin{Gpio: 69},
+			"P8_10": &board.Pin{Gpio: 68},
+			"P8_11": &board.Pin{Gpio: 45},
+			"P8_12": &board.Pin{Gpio: 44},
+			"P8_13": &board.Pin{Gpio: 23},
+			"P8_14": &board.Pin{Gpio: 26},
+			"P8_15": &board.Pin{Gpio: 47},
+			"P8_16": &board.Pin{Gpio: 46},
+			"P8_17": &board.Pin{Gpio: 27},
+			"P8_18": &board.Pin{Gpio: 65},
+			"P8_19": &board.Pin{Gpio: 22},
+			"P8_20": &board.Pin{Gpio: 63},
+			"P8_21": &board.Pin{Gpio: 62},
+			"P8_22": &board.Pin{Gpio: 37},
+			"P8_23": &board.Pin{Gpio: 36},
+			"P8_24": &board.Pin{Gpio: 33},
+			"P8_25": &board.Pin{Gpio: 32},
+			"P8_26": &board.Pin{Gpio: 61},
+			"P8_27": &board.Pin{Gpio: 86},
+			"P8_28": &board.Pin{Gpio: 88},
+			"P8_29": &board.Pin{Gpio: 87},
+			"P8_30": &board.Pin{Gpio: 89},
+			"P8_31": &board.Pin{Gpio: 10},
+			"P8_32": &board.Pin{Gpio: 11},
+			"P8_33": &board.Pin{Gpio: 9},
+			"P8_34": &board.Pin{Gpio: 81},
+			"P8_35": &board.Pin{Gpio: 8},
+			"P8_36": &board.Pin{Gpio: 80},
+			"P8_37": &board.Pin{Gpio: 78},
+			"P8_38": &board.Pin{Gpio: 79},
+			"P8_39": &board.Pin{Gpio: 76},
+			"P8_40": &board.Pin{Gpio: 77},
+			"P8_41": &board.Pin{Gpio: 74},
+			"P8_42": &board.Pin{Gpio: 75},
+			"P8_43": &board.Pin{Gpio: 72},
+			"P8_44": &board.Pin{Gpio: 73},
+			"P8_45": &board.Pin{Gpio: 70},
+			"P8_46": &board.Pin{Gpio: 71},
+			"P9_11": &board.Pin{Gpio: 30},
+			"P9_12": &board.Pin{Gpio: 60},
+			"P9_13": &board.Pin{Gpio: 31},
+			"P9_14": &board.Pin{Gpio: 50},
+			"P9_15": &board.Pin{Gpio: 48},
+			"P9_16": &board.Pin{Gpio: 51},
+			"P9_17": &board.Pin{Gpio: 5},
+			"P9_18": &board.Pin{Gpio: 4},
+			"P9_19": &board.Pin{Gpio: 13},
+			"P9_20": &board.Pin{Gpio: 12},
+			"P9_21": &board.Pin{Gpio: 3},
+			"P9_22": &board.Pin{Gpio: 2},
+			"P9_23": &board.Pin{Gpio: 49},
+			"P9_24": &board.Pin{Gpio: 15},
+			"P9_25": &board.Pin{Gpio: 117},
+			"P9_26": &board.Pin{Gpio: 14},
+			"P9_27": &board.Pin{Gpio: 115},
+			"P9_28": &board.Pin{Gpio: 113},
+			"P9_29": &board.Pin{Gpio: 111},
+			"P9_30": &board.Pin{Gpio: 112},
+			"P9_31": &board.Pin{Gpio: 110},
+			"usr0":  &board.Pin{Gpio: board.NoGpio, Led: usrLed + "usr0"},
+			"usr1":  &board.Pin{Gpio: board.NoGpio, Led: usrLed + "usr1"},
+			"usr2":  &board.Pin{Gpio: board.NoGpio, Led: usrLed + "usr2"},
+			"usr3":  &board.Pin{Gpio: board.NoGpio, Led: usrLed + "usr3"},
+		},
+		Analog: map[string]*board.Analog{
+			"P9_39": &board.Analog{Device: 0, Channel: 0, Bits: 12},
+			"P9_40": &board.Analog{Device: 0, Channel: 1, Bits: 12},
+			"P9_37": &board.Analog{Device: 0, Channel: 2, Bits: 12},
+			"P9_38": &board.Analog{Device: 0, Channel: 3, Bits: 12},
+			"P9_33": &board.Analog{Device: 0, Channel: 4, Bits: 12},
+			"P9_36": &board.Analog{Device: 0, Channel: 5, Bits: 12},
+			"P9_35": &board.Analog{Device: 0, Channel: 6, Bits: 12},
+		},
+	}
 }
 
 var pwmPins = map[string]string{
@@ -113,33 +137,21 @@ var pwmPins = map[string]string{
 	"P8_46": "P8_46",
 }
 
-var analogPins = map[string]string{
-	"P9_39": "AIN0",
-	"P9_40": "AIN1",
-	"P9_37": "AIN2",
-	"P9_38": "AIN3",
-	"P9_33": "AIN4",
-	"P9_36": "AIN5",
-	"P9_35": "AIN6",
-}
-
 // BeagleboneAdaptor is the gobot.Adaptor representation for the Beaglebone
 type BeagleboneAdaptor struct {
-	name        string
-	digitalPins []sysfs.DigitalPin
-	pwmPins     map[string]*pwmPin
-	i2cDevice   sysfs.I2cDevice
-	ocp         string
-	helper      string
-	slots       string
+	*board.BoardAdaptor
+	mutex   sync.Mutex
+	pwmPins map[string]*pwmPin
+	ocp     string
+	helper  string
+	slots   string
 }
 
 // NewBeagleboneAdaptor returns a new BeagleboneAdaptor with specified name
 func NewBeagleboneAdaptor(name string) *BeagleboneAdaptor {
 	b := &BeagleboneAdaptor{
-		name:        name,
-		digitalPins: make([]sysfs.DigitalPin, 120),
-		pwmPins:     make(map[string]*pwmPin),
+		BoardAdaptor: board.NewBoardAdaptor(name, description()),
+		pwmPins:      make(map[string]*pwmPin),
 	}
 
 	g, _ := glob(ocp)
@@ -150,9 +162,6 @@ func NewBeagleboneAdaptor(name string) *BeagleboneAdaptor {
 	return b
 }
 
-// Name returns the BeagleboneAdaptors name
-func (b *BeagleboneAdaptor) Name() string { return b.name }
-
 // Connect initializes the pwm and analog dts.
 func (b *BeagleboneAdaptor) Connect() (errs []error) {
 	if err := ensureSlot(b.slots, "cape-bone-iio"); err != nil {
@@ -167,13 +176,16 @@ func (b *BeagleboneAdaptor) Connect() (errs []error) {
 	if err != nil {
 		return []error{err}
 	}
-	b.helper = g[0]
+	if len(g) > 0 {
+		b.helper = g[0]
+	}
 
-	return
+	return b.BoardAdaptor.Connect()
 }
 
 // Finalize releases all i2c devices and exported analog, digital, pwm pins.
 func (b *BeagleboneAdaptor) Finalize() (errs []error) {
+	b.mutex.Lock()
 	for _, pin := range b.pwmPins {
 		if pin != nil {
 			if err := pin.release(); err != nil {
@@ -181,19 +193,8 @@ func (b *BeagleboneAdaptor) Finalize() (errs []error) {
 			}
 		}
 	}
-	for _, pin := range b.digitalPins {
-		if pin != nil {
-			if err := pin.Unexport(); err != nil {
-				errs = append(errs, err)
-			}
-		}
-	}
-	if b.i2cDevice != nil {
-		if err := b.i2cDevice.Close(); err != nil {
-			errs = append(errs, err)
-		}
-	}
-	return
+	b.mutex.Unlock()
+	return append(errs, b.BoardAdaptor.Finalize()...)
 }
 
 // PwmWrite writes the 0-254 value to the specified pin
@@ -203,50 +204,40 @@ func (b *BeagleboneAdaptor) PwmWrite(pin string, val byte) (err error) {
 
 // ServoWrite writes the 0-180 degree val to the specified pin.
 func (b *BeagleboneAdaptor) ServoWrite(pin string, val byte) (err error) {
-	i, err := b.pwmPin(pin)
+	p, err := b.pwmPin(pin)
 	if err != nil {
 		return err
 	}
 	period := 16666666.0
 	duty := (gobot.FromScale(float64(val), 0, 180.0) * 0.115) + 0.05
-	return b.pwmPins[i].pwmWrite(strconv.Itoa(int(period)), strconv.Itoa(int(period*duty)))
+	return p.pwmWrite(strconv.Itoa(int(period)), strconv.Itoa(int(period*duty)))
 }
 
-// DigitalRead returns a digital value from specified pin
-func (b *BeagleboneAdaptor) DigitalRead(pin string) (val int, err error) {
-	sysfsPin, err := b.digitalPin(pin, sysfs.IN)
-	if err != nil {
-		return
-	}
-	return sysfsPin.Read()
+// AnalogRead returns the voltage on the specified pin in millivolts (0-1800).
+// It reads from the cape-bone-iio helper when the kernel provides one, and from
+// the industrial i/o ADC device otherwise.
+func (b *BeagleboneAdaptor) AnalogRead(pin string) (val int, err error) {
+	mv, err := b.AnalogReadMillivolts(pin)
+	return int(mv), err
 }
 
-// DigitalWrite writes a digital value to specified pin.
-// valid usr pin values are usr0, usr1, usr2 and usr3
-func (b *BeagleboneAdaptor) DigitalWrite(pin string, val byte) (err error) {
-	if strings.Contains(pin, "usr") {
-		fi, err := sysfs.OpenFile(usrLed+pin+"/brightness", os.O_WRONLY|os.O_APPEND, 0666)
-		defer fi.Close()
+// AnalogReadMillivolts returns the voltage on the specified pin in millivolts
+func (b *BeagleboneAdaptor) AnalogReadMillivolts(pin string) (mv float64, err error) {
+	analogPin, ok := b.Description().Analog[pin]
+	if !ok {
+		err = errors.New("Not a valid pin")
+		return
+	}
+	if b.helper == "" {
+		raw, err := sysfs.NewAnalogPin(analogPin.Device, analogPin.Channel).Read()
 		if err != nil {
-			return err
+			return 0, err
 		}
-		_, err = fi.WriteString(strconv.Itoa(int(val)))
-		return err
+		// the ADC has 12 bits over a 1.8V reference and reports no scale
+		return float64(raw) * 1800 / 4095, nil
 	}
-	sysfsPin, err := b.digitalPin(pin, sysfs.OUT)
-	if err != nil {
-		return err
-	}
-	return sysfsPin.Write(int(val))
-}
 
-// AnalogRead returns an analog value from specified pin
-func (b *BeagleboneAdaptor) AnalogRead(pin string) (val int, err error) {
-	analogPin, err := b.translateAnalogPin(pin)
-	if err != nil {
-		return
-	}
-	fi, err := sysfs.OpenFile(fmt.Sprintf("%v/%v", b.helper, analogPin), os.O_RDONLY, 0644)
+	fi, err := sysfs.OpenFile(fmt.Sprintf("%v/AIN%v", b.helper, analogPin.Channel), os.O_RDONLY, 0644)
 	defer fi.Close()
 
 	if err != nil {
@@ -259,46 +250,8 @@ func (b *BeagleboneAdaptor) AnalogRead(pin string) (val int, err error) {
 		return
 	}
 
-	val, _ = strconv.Atoi(strings.Split(string(buf), "\n")[0])
-	return
-}
-
-// I2cStart starts a i2c device in specified address on i2c bus /dev/i2c-1
-func (b *BeagleboneAdaptor) I2cStart(address int) (err error) {
-	if b.i2cDevice == nil {
-		b.i2cDevice, err = sysfs.NewI2cDevice("/dev/i2c-1", address)
-	}
-	return
-}
-
-// I2cWrite writes data to i2c device
-func (b *BeagleboneAdaptor) I2cWrite(address int, data []byte) (err error) {
-	if err = b.i2cDevice.SetAddress(address); err != nil {
-		return
-	}
-	_, err = b.i2cDevice.Write(data)
-	return
-}
-
-// I2cRead returns size bytes from the i2c device
-func (b *BeagleboneAdaptor) I2cRead(address int, size int) (data []byte, err error) {
-	if err = b.i2cDevice.SetAddress(address); err != nil {
-		return
-	}
-	data = make([]byte, size)
-	_, err = b.i2cDevice.Read(data)
-	return
-}
-
-// translatePin converts digital pin name to pin position
-func (b *BeagleboneAdaptor) translatePin(pin string) (value int, err error) {
-	for key, value := range pins {
-		if key == pin {
-			return value, nil
-		}
-	}
-	err = errors.New("Not a valid pin")
-	return
+	val, _ := strconv.Atoi(strings.Split(string(buf), "\n")[0])
+	return float64(val), nil
 }
 
 // translatePwmPin converts pwm pin name to pin position
@@ -312,42 +265,17 @@ func (b *BeagleboneAdaptor) translatePwmPin(pin string) (value string, err error
 	return
 }
 
-// translateAnalogPin converts analog pin name to pin position
-func (b *BeagleboneAdaptor) translateAnalogPin(pin string) (value string, err error) {
-	for key, value := range analogPins {
-		if key == pin {
-			return value, nil
-		}
-	}
-	err = errors.New("Not a valid pin")
-	return
-}
-
-// digitalPin retrieves digital pin value by name
-func (b *BeagleboneAdaptor) digitalPin(pin string, dir string) (sysfsPin sysfs.DigitalPin, err error) {
-	i, err := b.translatePin(pin)
+// pwmPin returns the pwm pin of the specified pin, loading its slot on first
+// use. The slots are loaded one at a time, as the cape manager fails to load
+// a slot while loading another.
+func (b *BeagleboneAdaptor) pwmPin(pin string) (p *pwmPin, err error) {
+	i, err := b.translatePwmPin(pin)
 	if err != nil {
 		return
 	}
-	if b.digitalPins[i] == nil {
-		b.digitalPins[i] = sysfs.NewDigitalPin(i)
-		err := b.digitalPins[i].Export()
-		if err != nil {
-			return nil, err
-		}
-	}
-	if err = b.digitalPins[i].Direction(dir); err != nil {
-		return
-	}
-	return b.digitalPins[i], nil
-}
 
-// pwPin retrieves pwm pin value by name
-func (b *BeagleboneAdaptor) pwmPin(pin string) (i string, err error) {
-	i, err = b.translatePwmPin(pin)
-	if err != nil {
-		return
-	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	if b.pwmPins[i] == nil {
 		err = ensureSlot(b.slots, fmt.Sprintf("bone_pwm_%v", pin))
 		if err != nil {
@@ -358,18 +286,18 @@ func (b *BeagleboneAdaptor) pwmPin(pin string) (i string, err error) {
 			return
 		}
 	}
-	return
+	return b.pwmPins[i], nil
 }
 
 // pwmWrite writes pwm value to specified pin
 func (b *BeagleboneAdaptor) pwmWrite(pin string, val byte) (err error) {
-	i, err := b.pwmPin(pin)
+	p, err := b.pwmPin(pin)
 	if err != nil {
 		return
 	}
 	period := 500000.0
 	duty := gobot.FromScale(float64(val), 0, 255.0)
-	return b.pwmPins[i].pwmWrite(strconv.Itoa(int(period)), strconv.Itoa(int(period*duty)))
+	return p.pwmWrite(strconv.Itoa(int(period)), strconv.Itoa(int(period*duty)))
 }
 
 func ensureSlot(slots, item string) (err error) {
@@ -2,39 +2,17 @@ package beaglebone
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 
 	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/platforms/board"
 	"github.com/potix/gobot/sysfs"
 )
 
-type NullReadWriteCloser struct {
-	contents []byte
-}
-
-func (n *NullReadWriteCloser) SetAddress(int) error {
-	return nil
-}
-
-func (n *NullReadWriteCloser) Write(b []byte) (int, error) {
-	n.contents = make([]byte, len(b))
-	copy(n.contents[:], b[:])
-
-	return len(b), nil
-}
-
-func (n *NullReadWriteCloser) Read(b []byte) (int, error) {
-	copy(b, n.contents)
-	return len(b), nil
-}
-
-var closeErr error = nil
-
-func (n *NullReadWriteCloser) Close() error {
-	return closeErr
-}
-
 func TestBeagleboneAdaptor(t *testing.T) {
 	glob = func(pattern string) (matches []string, err error) {
 		return make([]string, 2), nil
@@ -68,6 +46,10 @@ func TestBeagleboneAdaptor(t *testing.T) {
 
 	a.helper = "/sys/devices/ocp.3/helper.5"
 
+	// each adaptor owns its description
+	a.Description().Pins["usr0"].Gpio = 1
+	gobottest.Assert(t, NewBeagleboneAdaptor("other").Description().Pins["usr0"].Gpio, board.NoGpio)
+
 	// PWM
 	glob = func(pattern string) (matches []string, err error) {
 		pattern = strings.TrimSuffix(pattern, "*")
@@ -107,6 +89,13 @@ func TestBeagleboneAdaptor(t *testing.T) {
 	i, err := a.AnalogRead("P9_99")
 	gobottest.Assert(t, err, errors.New("Not a valid pin"))
 
+	// industrial i/o ADC on kernels without the cape-bone-iio helper
+	a.helper = ""
+	fs.Add("/sys/bus/iio/devices/iio:device0/in_voltage1_raw").Contents = "4095\n"
+	i, _ = a.AnalogRead("P9_40")
+	gobottest.Assert(t, i, 1800)
+	a.helper = "/sys/devices/ocp.3/helper.5"
+
 	// DigitalIO
 	a.DigitalWrite("usr1", 1)
 	gobottest.Assert(t,
@@ -127,11 +116,103 @@ func TestBeagleboneAdaptor(t *testing.T) {
 	sysfs.SetSyscall(&sysfs.MockSyscall{})
 	a.I2cStart(0xff)
 
-	a.i2cDevice = &NullReadWriteCloser{}
-
 	a.I2cWrite(0xff, []byte{0x00, 0x01})
 	data, _ := a.I2cRead(0xff, 2)
 	gobottest.Assert(t, data, []byte{0x00, 0x01})
 
 	gobottest.Assert(t, len(a.Finalize()), 0)
 }
+
+func TestBeagleboneAdaptorSimulator(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	glob = sim.Glob
+	defer func() { glob = filepath.Glob }()
+
+	sim.AddFile("/sys/devices/bone_capemgr.9/slots", "")
+	sim.AddFile("/sys/devices/ocp.3/gpio-leds.8/leds/beaglebone:green:usr1/brightness", "0")
+	for _, attr := range []string{"run", "period", "polarity", "duty"} {
+		sim.AddFile("/sys/devices/ocp.3/pwm_test_P9_14.15/"+attr, "0")
+	}
+	sim.AddGpio(60)
+	sim.AddGpio(10)
+	sim.AddIioDevice(0, 7)
+	sim.SetAnalog(0, 1, 4095)
+	sim.AddI2cBus(1, 0)
+	sim.AddI2cDevice(1, 0x40)
+
+	a := NewBeagleboneAdaptor("myAdaptor")
+	gobottest.Assert(t, a.ocp, "/sys/devices/ocp.3")
+	gobottest.Assert(t, a.slots, "/sys/devices/bone_capemgr.9/slots")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	gobottest.Assert(t, a.PwmWrite("P9_14", 175), nil)
+	gobottest.Assert(t, sim.Contents("/sys/devices/ocp.3/pwm_test_P9_14.15/run"), "1")
+	gobottest.Assert(t, sim.Contents("/sys/devices/ocp.3/pwm_test_P9_14.15/duty"), "343137")
+
+	// industrial i/o ADC, as there is no cape-bone-iio helper
+	i, err := a.AnalogRead("P9_40")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1800)
+
+	gobottest.Assert(t, a.DigitalWrite("usr1", 1), nil)
+	gobottest.Assert(t, sim.Contents("/sys/devices/ocp.3/gpio-leds.8/leds/beaglebone:green:usr1/brightness"), "1")
+
+	gobottest.Assert(t, a.DigitalWrite("P9_12", 1), nil)
+	g, _ := sim.Gpio(60)
+	gobottest.Assert(t, g.Level, 1)
+
+	sim.SetGpioLevel(10, 1)
+	i, _ = a.DigitalRead("P8_31")
+	gobottest.Assert(t, i, 1)
+
+	gobottest.Assert(t, a.I2cStart(0x40), nil)
+	gobottest.Assert(t, a.I2cWrite(0x40, []byte{0x00, 0x10}), nil)
+	gobottest.Assert(t, sim.I2cRegisters(1, 0x40)[0], byte(0x10))
+	sim.SetFault("write", "/dev/i2c-1", syscall.EIO)
+	err = a.I2cWrite(0x40, []byte{0x00, 0x20})
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EIO)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(60)
+	gobottest.Assert(t, g.Exported, false)
+}
+
+func TestBeagleboneAdaptorConcurrency(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	glob = sim.Glob
+	defer func() { glob = filepath.Glob }()
+
+	sim.AddFile("/sys/devices/bone_capemgr.9/slots", "")
+	for _, attr := range []string{"run", "period", "polarity", "duty"} {
+		sim.AddFile("/sys/devices/ocp.3/pwm_test_P9_14.15/"+attr, "0")
+	}
+	sim.AddGpio(60)
+	sim.AddIioDevice(0, 7)
+
+	a := NewBeagleboneAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	gobottest.Concurrently(t, 16, func(i int) (err error) {
+		for j := 0; j < 20 && err == nil; j++ {
+			switch (i + j) % 4 {
+			case 0:
+				err = a.PwmWrite("P9_14", byte(j))
+			case 1:
+				err = a.ServoWrite("P9_14", byte(j))
+			case 2:
+				err = a.DigitalWrite("P9_12", byte(j%2))
+			case 3:
+				_, err = a.AnalogRead("P9_40")
+			}
+		}
+		return
+	})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
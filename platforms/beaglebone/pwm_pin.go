@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/potix/gobot/sysfs"
 )
 
+// pwmPin is a pwm_test device. Its mutex keeps the period and duty of a
+// write together.
 type pwmPin struct {
+	mutex     sync.Mutex
 	pinNum    string
 	pwmDevice string
 }
@@ -78,6 +82,9 @@ func newPwmPin(pinNum string, ocp string) (p *pwmPin, err error) {
 
 // pwmWrite writes to a pwm pin with specified period and duty
 func (p *pwmPin) pwmWrite(period string, duty string) (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	f1, err := sysfs.OpenFile(fmt.Sprintf("%v/period", p.pwmDevice), os.O_WRONLY|os.O_APPEND, 0666)
 	defer f1.Close()
 	if err != nil {
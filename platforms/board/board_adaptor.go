@@ -0,0 +1,899 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/gpio"
+	"github.com/potix/gobot/platforms/i2c"
+	"github.com/potix/gobot/platforms/spi"
+	"github.com/potix/gobot/sysfs"
+)
+
+var _ gobot.Adaptor = (*BoardAdaptor)(nil)
+
+var _ gpio.DigitalReader = (*BoardAdaptor)(nil)
+var _ gpio.DigitalWriter = (*BoardAdaptor)(nil)
+var _ gpio.AnalogReader = (*BoardAdaptor)(nil)
+var _ gpio.PwmWriter = (*BoardAdaptor)(nil)
+var _ gpio.ServoWriter = (*BoardAdaptor)(nil)
+var _ gpio.PwmPulseWriter = (*BoardAdaptor)(nil)
+var _ gpio.PulseReader = (*BoardAdaptor)(nil)
+var _ gpio.PinConfigurer = (*BoardAdaptor)(nil)
+
+var _ i2c.I2c = (*BoardAdaptor)(nil)
+var _ i2c.I2c = (*I2cBusConnection)(nil)
+
+var _ spi.Spi = (*BoardAdaptor)(nil)
+
+var _ gobot.PinAllocator = (*BoardAdaptor)(nil)
+
+var _ gobot.Capabler = (*BoardAdaptor)(nil)
+
+const (
+	// DefaultPwmPeriod is the period in nanoseconds set on pwm channels which
+	// the kernel initializes without one
+	DefaultPwmPeriod = 500000
+	// ServoPeriod is the period in nanoseconds of the signal sent to servos
+	ServoPeriod = 20000000
+)
+
+// pwmChannel is an exported pwm channel. Its mutex serializes the writes of
+// the pins sharing the channel.
+type pwmChannel struct {
+	mutex  sync.Mutex
+	pin    sysfs.PwmPin
+	period int
+}
+
+// BoardAdaptor is the gobot.Adaptor for single board linux computers which
+// are driven through sysfs, as described by a Description. The adaptors of
+// boards such as the Raspberry Pi embed it.
+//
+// A BoardAdaptor is safe for concurrent use. Each header pin has its own
+// lock, held while the pin is muxed and read or written, so a pin is never
+// switched to another mode in the middle of a read, while drivers using
+// different pins do not wait for each other.
+type BoardAdaptor struct {
+	gobot.PinTable
+	name         string
+	description  *Description
+	pinMutex     sync.Mutex
+	locks        map[*Pin]*sync.Mutex
+	gpios        map[int]sysfs.DigitalPin
+	modes        map[*Pin]string
+	owners       map[*Pin]string
+	configs      map[*Pin]gpio.PinConfig
+	pwmPins      map[Pwm]*pwmChannel
+	i2cBuses     map[int]*sysfs.I2cBus
+	i2cAddresses map[int]int
+	i2cReady     map[int]bool
+	i2cMutex     sync.Mutex
+	spiDevices   map[int]sysfs.SpiDevice
+	spiChips     map[int]int
+	spiReady     map[int]bool
+	spiMutex     sync.Mutex
+	uartReady    map[int]bool
+	uartMutex    sync.Mutex
+}
+
+// NewBoardAdaptor returns a new BoardAdaptor with specified name, for the
+// board described by d
+func NewBoardAdaptor(name string, d *Description) *BoardAdaptor {
+	return &BoardAdaptor{
+		name:         name,
+		description:  d,
+		locks:        make(map[*Pin]*sync.Mutex),
+		gpios:        make(map[int]sysfs.DigitalPin),
+		modes:        make(map[*Pin]string),
+		owners:       make(map[*Pin]string),
+		configs:      make(map[*Pin]gpio.PinConfig),
+		pwmPins:      make(map[Pwm]*pwmChannel),
+		i2cBuses:     make(map[int]*sysfs.I2cBus),
+		i2cAddresses: make(map[int]int),
+		i2cReady:     make(map[int]bool),
+		spiDevices:   make(map[int]sysfs.SpiDevice),
+		spiChips:     make(map[int]int),
+		spiReady:     make(map[int]bool),
+		uartReady:    make(map[int]bool),
+	}
+}
+
+// Name returns the BoardAdaptors name
+func (b *BoardAdaptor) Name() string { return b.name }
+
+// Description returns the description of the board
+func (b *BoardAdaptor) Description() *Description { return b.description }
+
+// Capabilities returns the header pins of the board with the modes they
+// support, its analog inputs and its name
+func (b *BoardAdaptor) Capabilities() gobot.Capabilities {
+	d := b.description
+	c := gobot.Capabilities{Pins: []gobot.PinCapability{}}
+	if d.Name != "" {
+		c.Metadata = map[string]string{"board": d.Name}
+	}
+
+	names := []string{}
+	for name := range d.Pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := d.Pins[name]
+		modes := []string{}
+		if p.Gpio != NoGpio || p.Led != "" {
+			modes = append(modes, ModeIn, ModeOut)
+		}
+		if p.Pwm != nil {
+			modes = append(modes, ModePwm, "servo")
+		}
+		for mode := range p.Modes {
+			if !contains(modes, mode) {
+				modes = append(modes, mode)
+			}
+		}
+		if len(modes) > 0 {
+			sort.Strings(modes)
+			c.Pins = append(c.Pins, gobot.PinCapability{Pin: name, Modes: modes})
+		}
+	}
+
+	names = []string{}
+	for name := range d.Analog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.Pins = append(c.Pins, gobot.PinCapability{Pin: name, Modes: []string{"analog"}})
+	}
+	return c
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect runs the setup steps of the board
+func (b *BoardAdaptor) Connect() (errs []error) {
+	if err := b.runSteps(b.description.Setup); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// Finalize disables the pwm channels, and releases all i2c buses, spi
+// devices and exported gpios
+func (b *BoardAdaptor) Finalize() (errs []error) {
+	b.pinMutex.Lock()
+	defer b.pinMutex.Unlock()
+
+	for _, pwm := range b.pwmPins {
+		if err := pwm.pin.Enable(false); err != nil {
+			errs = append(errs, err)
+		}
+		if err := pwm.pin.Unexport(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, pin := range b.gpios {
+		if err := pin.Unexport(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, bus := range b.i2cBuses {
+		if err := bus.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, device := range b.spiDevices {
+		if err := device.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// DigitalRead reads digital value from pin
+func (b *BoardAdaptor) DigitalRead(pin string) (val int, err error) {
+	p, err := b.pin(pin)
+	if err != nil {
+		return
+	}
+	defer b.lock(p)()
+
+	if p.Led != "" {
+		buf, err := sysfs.ReadAttribute(p.Led + "/brightness")
+		if err != nil {
+			return 0, err
+		}
+		if buf == "0" {
+			return 0, nil
+		}
+		return 1, nil
+	}
+	sysfsPin, err := b.digitalPin(pin, p, ModeIn)
+	if err != nil {
+		return
+	}
+	return sysfsPin.Read()
+}
+
+// DigitalWrite writes digital value to specified pin
+func (b *BoardAdaptor) DigitalWrite(pin string, val byte) (err error) {
+	p, err := b.pin(pin)
+	if err != nil {
+		return
+	}
+	defer b.lock(p)()
+
+	if p.Led != "" {
+		return writeFile(p.Led+"/brightness", strconv.Itoa(int(val)))
+	}
+	sysfsPin, err := b.digitalPin(pin, p, ModeOut)
+	if err != nil {
+		return
+	}
+	return sysfsPin.Write(int(val))
+}
+
+// PulseRead returns the width of the next pulse at level on the specified
+// pin, or 0 when the pulse does not end within timeout. A pulse already under
+// way is skipped, as its start was missed. The gpio is read in a tight loop
+// while the pin is held, which times the edges more precisely than polling
+// DigitalRead.
+func (b *BoardAdaptor) PulseRead(pin string, level int, timeout time.Duration) (width time.Duration, err error) {
+	p, err := b.pin(pin)
+	if err != nil {
+		return
+	}
+	defer b.lock(p)()
+
+	sysfsPin, err := b.digitalPin(pin, p, ModeIn)
+	if err != nil {
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	waitFor := func(level int) (at time.Time, err error) {
+		for {
+			val, err := sysfsPin.Read()
+			at = time.Now()
+			if err != nil || val == level || at.After(deadline) {
+				return at, err
+			}
+		}
+	}
+	idle, err := waitFor(level ^ 1)
+	if err != nil || idle.After(deadline) {
+		return
+	}
+	start, err := waitFor(level)
+	if err != nil || start.After(deadline) {
+		return
+	}
+	end, err := waitFor(level ^ 1)
+	if err != nil || end.After(deadline) {
+		return
+	}
+	return end.Sub(start), nil
+}
+
+// ConfigurePin sets the pull resistor, active level, drive and debounce
+// period of the specified pin. The lines of a gpio chip support each setting.
+// Gpios exported through sysfs are only set active low, and pull resistors
+// are set by the pull steps of the pin, run again whenever it is switched to
+// ModeIn.
+func (b *BoardAdaptor) ConfigurePin(pin string, config gpio.PinConfig) (err error) {
+	if err = gpio.ValidPull(config.Pull); err != nil {
+		return
+	}
+	p, err := b.pin(pin)
+	if err != nil {
+		return
+	}
+	if p.Gpio == NoGpio {
+		return errors.New("Not a valid pin")
+	}
+	defer b.lock(p)()
+
+	sysfsPin, err := b.gpio(p.Gpio, p.Label)
+	if err != nil {
+		return
+	}
+	if line, ok := sysfsPin.(sysfs.ConfigurablePin); ok {
+		err = line.Configure(sysfs.LineConfig{
+			Pull:      config.Pull,
+			ActiveLow: config.ActiveLow,
+			OpenDrain: config.OpenDrain,
+			Debounce:  config.Debounce,
+		})
+	} else {
+		err = b.configureSysfsPin(p, config)
+	}
+	if err != nil {
+		return
+	}
+
+	b.pinMutex.Lock()
+	defer b.pinMutex.Unlock()
+	b.configs[p] = config
+	return
+}
+
+// configureSysfsPin sets the active level and pull resistor of a gpio
+// exported through sysfs. The caller holds the lock of p.
+func (b *BoardAdaptor) configureSysfsPin(p *Pin, config gpio.PinConfig) error {
+	if config.OpenDrain {
+		return gpio.ErrOpenDrainUnsupported
+	}
+	if config.Debounce > 0 {
+		return gpio.ErrDebounceUnsupported
+	}
+	if config.Pull != "" && p.Pulls[config.Pull] == nil {
+		return gpio.ErrPullUnsupported
+	}
+
+	label := p.Label
+	if label == "" {
+		label = fmt.Sprintf("gpio%v", p.Gpio)
+	}
+	activeLow := "0"
+	if config.ActiveLow {
+		activeLow = "1"
+	}
+	if err := writeFile(sysfs.GPIOPATH+"/"+label+"/active_low", activeLow); err != nil {
+		return err
+	}
+	if mode, _ := b.state(p); mode == ModeIn {
+		return b.runSteps(p.Pulls[config.Pull])
+	}
+	return nil
+}
+
+// PwmWrite writes the 0-255 value to the specified pin, as a duty cycle of
+// the period of its pwm channel
+func (b *BoardAdaptor) PwmWrite(pin string, val byte) (err error) {
+	p, err := b.pin(pin)
+	if err != nil {
+		return
+	}
+	defer b.lock(p)()
+
+	pwm, err := b.pwmPin(pin, p)
+	if err != nil {
+		return
+	}
+	pwm.mutex.Lock()
+	defer pwm.mutex.Unlock()
+
+	duty := gobot.FromScale(float64(val), 0, 255.0)
+	return pwm.pin.SetDutyCycle(int(float64(pwm.period) * duty))
+}
+
+// ServoWrite writes the 0-180 degree angle to the specified pin, as a pulse
+// of 0.5 to 2.5 milliseconds every 20 milliseconds
+func (b *BoardAdaptor) ServoWrite(pin string, angle byte) (err error) {
+	width := 500000 + gobot.FromScale(float64(angle), 0, 180.0)*2000000
+	return b.PwmPulseWrite(pin, ServoPeriod, time.Duration(width))
+}
+
+// PwmPulseWrite writes pulses of width every period to the specified pin,
+// setting the period of its pwm channel
+func (b *BoardAdaptor) PwmPulseWrite(pin string, period time.Duration, width time.Duration) (err error) {
+	p, err := b.pin(pin)
+	if err != nil {
+		return
+	}
+	defer b.lock(p)()
+
+	pwm, err := b.pwmPin(pin, p)
+	if err != nil {
+		return
+	}
+	pwm.mutex.Lock()
+	defer pwm.mutex.Unlock()
+
+	if pwm.period != int(period) {
+		// the duty cycle can not exceed the period, so it is lowered first
+		// when shortening the period
+		if int(period) < pwm.period {
+			if err = pwm.pin.SetDutyCycle(int(width)); err != nil {
+				return
+			}
+		}
+		if err = pwm.pin.SetPeriod(int(period)); err != nil {
+			return
+		}
+		pwm.period = int(period)
+	}
+	return pwm.pin.SetDutyCycle(int(width))
+}
+
+// AnalogRead returns the value of the specified analog pin, scaled to the
+// 0-1023 range
+func (b *BoardAdaptor) AnalogRead(pin string) (val int, err error) {
+	analogPin, a, err := b.analogPin(pin)
+	if err != nil {
+		return
+	}
+	val, err = analogPin.Read()
+	if a.Bits > 10 {
+		val = val >> uint(a.Bits-10)
+	} else if a.Bits > 0 {
+		val = val << uint(10-a.Bits)
+	}
+	return
+}
+
+// AnalogReadMillivolts returns the voltage of the specified analog pin in millivolts
+func (b *BoardAdaptor) AnalogReadMillivolts(pin string) (mv float64, err error) {
+	analogPin, _, err := b.analogPin(pin)
+	if err != nil {
+		return
+	}
+	return analogPin.ReadMillivolts()
+}
+
+// SetI2cBus selects the i2c bus used by the device at address. Devices
+// default to the i2c bus of the board description. Devices sharing an address
+// on different buses are reached through I2cBus instead.
+func (b *BoardAdaptor) SetI2cBus(address int, bus int) {
+	b.i2cMutex.Lock()
+	defer b.i2cMutex.Unlock()
+	b.i2cAddresses[address] = bus
+}
+
+// I2cBus returns a connection to the devices on the i2c bus, whichever bus is
+// selected for their address, so that the drivers of identical devices at the
+// same address on different buses can share the BoardAdaptor
+func (b *BoardAdaptor) I2cBus(bus int) *I2cBusConnection {
+	return &I2cBusConnection{board: b, bus: bus}
+}
+
+// i2cBusNumber returns the number of the i2c bus used by the device at
+// address
+func (b *BoardAdaptor) i2cBusNumber(address int) int {
+	b.i2cMutex.Lock()
+	defer b.i2cMutex.Unlock()
+
+	bus, ok := b.i2cAddresses[address]
+	if !ok {
+		bus = b.description.I2cBus
+	}
+	return bus
+}
+
+// i2cBus returns the i2c bus numbered n
+func (b *BoardAdaptor) i2cBus(n int) *sysfs.I2cBus {
+	b.i2cMutex.Lock()
+	defer b.i2cMutex.Unlock()
+
+	if b.i2cBuses[n] == nil {
+		b.i2cBuses[n] = sysfs.NewI2cBus(fmt.Sprintf("/dev/i2c-%v", n))
+	}
+	return b.i2cBuses[n]
+}
+
+// I2cStart starts an i2c device in specified address, running the i2c
+// setup steps of its bus and muxing its pins the first time the bus is used
+func (b *BoardAdaptor) I2cStart(address int) (err error) {
+	return b.i2cStart(b.i2cBusNumber(address), address)
+}
+
+// I2cWrite writes data to i2c device
+func (b *BoardAdaptor) I2cWrite(address int, data []byte) (err error) {
+	return b.i2cBus(b.i2cBusNumber(address)).Write(address, data)
+}
+
+// I2cRead returns size bytes from the i2c device
+func (b *BoardAdaptor) I2cRead(address int, size int) (data []byte, err error) {
+	return b.i2cBus(b.i2cBusNumber(address)).Read(address, size)
+}
+
+// i2cStart starts the i2c device at address on the i2c bus numbered n
+func (b *BoardAdaptor) i2cStart(n int, address int) (err error) {
+	bus := b.i2cBus(n)
+
+	b.i2cMutex.Lock()
+	if !b.i2cReady[n] {
+		if err = b.runSteps(b.description.I2cSetup[n]); err != nil {
+			b.i2cMutex.Unlock()
+			return
+		}
+		owner := fmt.Sprintf("i2c bus %v", n)
+		if err = b.busPins(ModeI2c, b.description.I2cPins[n], owner); err != nil {
+			b.i2cMutex.Unlock()
+			return
+		}
+		b.i2cReady[n] = true
+	}
+	b.i2cMutex.Unlock()
+
+	return bus.Start(address)
+}
+
+// I2cBusConnection is a connection to the devices on one i2c bus of a
+// BoardAdaptor, returned by BoardAdaptor.I2cBus. The BoardAdaptor connects
+// and closes the bus.
+type I2cBusConnection struct {
+	board *BoardAdaptor
+	bus   int
+}
+
+// Name returns the name of the BoardAdaptor followed by the i2c bus
+func (c *I2cBusConnection) Name() string { return fmt.Sprintf("%v i2c-%v", c.board.Name(), c.bus) }
+
+// Connect implements the Adaptor interface
+func (c *I2cBusConnection) Connect() (errs []error) { return }
+
+// Finalize implements the Adaptor interface
+func (c *I2cBusConnection) Finalize() (errs []error) { return }
+
+// I2cStart starts the i2c device at address on the bus
+func (c *I2cBusConnection) I2cStart(address int) (err error) {
+	return c.board.i2cStart(c.bus, address)
+}
+
+// I2cWrite writes data to the i2c device at address on the bus
+func (c *I2cBusConnection) I2cWrite(address int, data []byte) (err error) {
+	return c.board.i2cBus(c.bus).Write(address, data)
+}
+
+// I2cRead returns size bytes from the i2c device at address on the bus
+func (c *I2cBusConnection) I2cRead(address int, size int) (data []byte, err error) {
+	return c.board.i2cBus(c.bus).Read(address, size)
+}
+
+// SetSpiBus selects the spi bus used by the device on chip select chip.
+// Devices default to the spi bus of the board description.
+func (b *BoardAdaptor) SetSpiBus(chip int, bus int) {
+	b.spiMutex.Lock()
+	defer b.spiMutex.Unlock()
+	b.spiChips[chip] = bus
+}
+
+// SpiStart opens the device on chip select chip with the spi mode and clock
+// speed in Hz, running the spi setup steps of its bus and muxing its pins the
+// first time the bus is used
+func (b *BoardAdaptor) SpiStart(chip int, mode int, speed int) (err error) {
+	b.spiMutex.Lock()
+	defer b.spiMutex.Unlock()
+
+	bus, ok := b.spiChips[chip]
+	if !ok {
+		bus = b.description.SpiBus
+	}
+	if !b.spiReady[bus] {
+		if err = b.runSteps(b.description.SpiSetup[bus]); err != nil {
+			return
+		}
+		owner := fmt.Sprintf("spi bus %v", bus)
+		if err = b.busPins(ModeSpi, b.description.SpiPins[bus], owner); err != nil {
+			return
+		}
+		b.spiReady[bus] = true
+	}
+
+	device, err := sysfs.NewSpiDevice(fmt.Sprintf("/dev/spidev%v.%v", bus, chip), mode, 8, speed)
+	if err != nil {
+		return
+	}
+	if b.spiDevices[chip] != nil {
+		b.spiDevices[chip].Close()
+	}
+	b.spiDevices[chip] = device
+	return
+}
+
+// SpiTransfer writes data to the device on chip select chip, returning the
+// bytes read from it meanwhile
+func (b *BoardAdaptor) SpiTransfer(chip int, data []byte) (rx []byte, err error) {
+	b.spiMutex.Lock()
+	defer b.spiMutex.Unlock()
+
+	device, ok := b.spiDevices[chip]
+	if !ok {
+		return nil, fmt.Errorf("spi chip select %v has not been started", chip)
+	}
+	return device.Transfer(data)
+}
+
+// UartStart routes the specified uart to the header, running its setup steps
+// and muxing its pins the first time it is started, and returns the path of
+// its tty device
+func (b *BoardAdaptor) UartStart(uart int) (device string, err error) {
+	b.uartMutex.Lock()
+	defer b.uartMutex.Unlock()
+
+	device, ok := b.description.Uarts[uart]
+	if !ok {
+		return "", fmt.Errorf("%v has no uart %v", b.description.Name, uart)
+	}
+	if !b.uartReady[uart] {
+		if err = b.runSteps(b.description.UartSetup[uart]); err != nil {
+			return "", err
+		}
+		owner := fmt.Sprintf("uart %v", uart)
+		if err = b.busPins(ModeUart, b.description.UartPins[uart], owner); err != nil {
+			return "", err
+		}
+		b.uartReady[uart] = true
+	}
+	return device, nil
+}
+
+// busPins muxes the header pins of a bus for mode, reserving them for the
+// bus so that they are not used as gpios or pwm outputs meanwhile
+func (b *BoardAdaptor) busPins(mode string, pins []string, owner string) error {
+	for _, name := range pins {
+		p, ok := b.description.Pins[name]
+		if !ok {
+			return fmt.Errorf("%v is routed to pin %v, which is not a valid pin", owner, name)
+		}
+		if err := b.busPin(name, p, mode, owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// busPin muxes a header pin of a bus for mode and reserves it for the bus
+func (b *BoardAdaptor) busPin(pin string, p *Pin, mode string, owner string) error {
+	defer b.lock(p)()
+
+	if _, other := b.state(p); other != "" && other != owner {
+		return fmt.Errorf("Pin %v is in use by %v", pin, other)
+	}
+	if err := b.AllocatePin(pin, mode, owner); err != nil {
+		return err
+	}
+	if err := b.runSteps(p.Modes[mode]); err != nil {
+		return err
+	}
+	b.setState(p, mode, owner)
+	return nil
+}
+
+// AllocatePin records that owner uses pin in mode. Pin names which name the
+// same header pin, such as the physical and BCM names of a Raspberry Pi pin,
+// conflict with each other.
+func (b *BoardAdaptor) AllocatePin(pin string, mode string, owner string) error {
+	if p, ok := b.description.Pins[pin]; ok && mode != "analog" {
+		for _, a := range b.PinAllocations() {
+			if a.Pin != pin && a.Owner != owner && a.Mode != "analog" && b.description.Pins[a.Pin] == p {
+				return &gobot.PinConflictError{Pin: pin, Mode: mode, Owner: a}
+			}
+		}
+	}
+	return b.PinTable.AllocatePin(pin, mode, owner)
+}
+
+// pin returns the description of the specified header pin
+func (b *BoardAdaptor) pin(pin string) (*Pin, error) {
+	p, ok := b.description.Pins[pin]
+	if !ok {
+		return nil, errors.New("Not a valid pin")
+	}
+	return p, nil
+}
+
+// lock locks the header pin p, returning the function unlocking it
+func (b *BoardAdaptor) lock(p *Pin) func() {
+	b.pinMutex.Lock()
+	l, ok := b.locks[p]
+	if !ok {
+		l = &sync.Mutex{}
+		b.locks[p] = l
+	}
+	b.pinMutex.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// state returns the mode p was last muxed for, and the bus owning it if any
+func (b *BoardAdaptor) state(p *Pin) (mode string, owner string) {
+	b.pinMutex.Lock()
+	defer b.pinMutex.Unlock()
+	return b.modes[p], b.owners[p]
+}
+
+// setState records the mode p is muxed for, and the bus owning it if any
+func (b *BoardAdaptor) setState(p *Pin, mode string, owner string) {
+	b.pinMutex.Lock()
+	defer b.pinMutex.Unlock()
+	b.modes[p] = mode
+	if owner != "" {
+		b.owners[p] = owner
+	}
+}
+
+// gpio returns the exported gpio, exporting it on first use. Gpios are lines
+// of the gpio chip of the description when it has one.
+func (b *BoardAdaptor) gpio(i int, label string) (sysfs.DigitalPin, error) {
+	b.pinMutex.Lock()
+	defer b.pinMutex.Unlock()
+
+	if b.gpios[i] == nil {
+		var p sysfs.DigitalPin
+		if b.description.GpioChip != "" {
+			p = sysfs.NewChardevPin(b.description.GpioChip, i)
+		} else if label != "" {
+			p = sysfs.NewDigitalPin(i, label)
+		} else {
+			p = sysfs.NewDigitalPin(i)
+		}
+		if err := p.Export(); err != nil {
+			return nil, err
+		}
+		b.gpios[i] = p
+	}
+	return b.gpios[i], nil
+}
+
+// digitalPin returns the gpio of a pin, muxing it for mode when it was last
+// used in another mode, and setting the pull resistor the pin is configured
+// with when it is switched to ModeIn. Pin names sharing a Pin share its mode.
+// The caller holds the lock of p.
+func (b *BoardAdaptor) digitalPin(pin string, p *Pin, mode string) (sysfs.DigitalPin, error) {
+	if p.Gpio == NoGpio {
+		return nil, errors.New("Not a valid pin")
+	}
+	current, owner := b.state(p)
+	if owner != "" {
+		return nil, fmt.Errorf("Pin %v is in use by %v", pin, owner)
+	}
+	sysfsPin, err := b.gpio(p.Gpio, p.Label)
+	if err != nil {
+		return nil, err
+	}
+	if current == mode {
+		return sysfsPin, nil
+	}
+	if err = b.runSteps(p.Modes[mode]); err != nil {
+		return nil, err
+	}
+	if err = sysfsPin.Direction(mode); err != nil {
+		return nil, err
+	}
+	if mode == ModeIn {
+		b.pinMutex.Lock()
+		pull := b.configs[p].Pull
+		b.pinMutex.Unlock()
+		if err = b.runSteps(p.Pulls[pull]); err != nil {
+			return nil, err
+		}
+	}
+	b.setState(p, mode, "")
+	return sysfsPin, nil
+}
+
+// pwmPin returns the pwm channel of a pin, exporting and enabling it on
+// first use, and muxing the pin when it was last used in another mode. Pins
+// sharing a channel share its period and duty cycle. The caller holds the
+// lock of p.
+func (b *BoardAdaptor) pwmPin(pin string, p *Pin) (*pwmChannel, error) {
+	if p.Pwm == nil {
+		return nil, errors.New("Not a PWM pin")
+	}
+	current, owner := b.state(p)
+	if owner != "" {
+		return nil, fmt.Errorf("Pin %v is in use by %v", pin, owner)
+	}
+	if current != ModePwm {
+		if err := b.runSteps(p.Modes[ModePwm]); err != nil {
+			return nil, err
+		}
+	}
+
+	b.pinMutex.Lock()
+	defer b.pinMutex.Unlock()
+	pwm, ok := b.pwmPins[*p.Pwm]
+	if !ok {
+		var err error
+		if pwm, err = newPwmChannel(p.Pwm); err != nil {
+			return nil, err
+		}
+		b.pwmPins[*p.Pwm] = pwm
+	}
+	b.modes[p] = ModePwm
+	return pwm, nil
+}
+
+// newPwmChannel exports and enables a pwm channel, setting DefaultPwmPeriod
+// when the kernel initializes it without a period
+func newPwmChannel(p *Pwm) (pwm *pwmChannel, err error) {
+	pwm = &pwmChannel{pin: sysfs.NewPwmPin(p.Chip, p.Channel)}
+	if err = pwm.pin.Export(); err != nil {
+		return
+	}
+	if pwm.period, err = pwm.pin.Period(); err != nil {
+		return
+	}
+	if pwm.period == 0 {
+		if err = pwm.pin.SetPeriod(DefaultPwmPeriod); err != nil {
+			return
+		}
+		pwm.period = DefaultPwmPeriod
+	}
+	err = pwm.pin.Enable(true)
+	return
+}
+
+// analogPin returns the industrial i/o channel of the specified analog pin
+func (b *BoardAdaptor) analogPin(pin string) (sysfs.AnalogPin, *Analog, error) {
+	a, ok := b.description.Analog[pin]
+	if !ok {
+		return nil, nil, errors.New("Not a valid analog pin")
+	}
+	return sysfs.NewAnalogPin(a.Device, a.Channel), a, nil
+}
+
+// runSteps runs muxing steps in order, stopping at the first failing step
+func (b *BoardAdaptor) runSteps(steps []Step) (err error) {
+	for _, s := range steps {
+		if err = b.runStep(s); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (b *BoardAdaptor) runStep(s Step) (err error) {
+	if s.File != "" {
+		return writeFile(s.File, s.Value)
+	}
+
+	pin, err := b.gpio(s.Gpio, "")
+	if err != nil {
+		return
+	}
+	switch s.Direction {
+	case sysfs.IN:
+		err = pin.Direction(sysfs.IN)
+	case "high", "low":
+		if err = pin.Direction(sysfs.OUT); err != nil {
+			return
+		}
+		if s.Direction == "high" {
+			err = pin.Write(sysfs.HIGH)
+		} else {
+			err = pin.Write(sysfs.LOW)
+		}
+	default:
+		err = fmt.Errorf("Invalid direction %q for gpio %v", s.Direction, s.Gpio)
+	}
+	if err != nil {
+		return
+	}
+
+	if s.Unexport {
+		b.pinMutex.Lock()
+		delete(b.gpios, s.Gpio)
+		b.pinMutex.Unlock()
+		return pin.Unexport()
+	}
+	return
+}
+
+// writeFile writes value to the file at path
+func writeFile(path string, value string) (err error) {
+	fi, err := sysfs.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	defer fi.Close()
+	if err != nil {
+		return
+	}
+	_, err = fi.WriteString(value)
+	return
+}
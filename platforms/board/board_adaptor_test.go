@@ -0,0 +1,541 @@
+package board
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/platforms/gpio"
+	"github.com/potix/gobot/sysfs"
+)
+
+const pinmux = "/sys/kernel/debug/gpio_debug/gpio13/current_pinmux"
+
+func testDescription() *Description {
+	return &Description{
+		Name:   "Test Board",
+		I2cBus: 1,
+		Pins: map[string]*Pin{
+			"1": &Pin{Gpio: 4},
+			"2": &Pin{
+				Gpio: 13,
+				Pwm:  &Pwm{Chip: 0, Channel: 1},
+				Modes: map[string][]Step{
+					ModeIn:  {{Gpio: 221, Direction: "low"}, {File: pinmux, Value: "mode0"}},
+					ModeOut: {{Gpio: 221, Direction: "in"}, {File: pinmux, Value: "mode0"}},
+					ModePwm: {{Gpio: 221, Direction: "in"}, {File: pinmux, Value: "mode1"}},
+					ModeSpi: {{Gpio: 221, Direction: "in"}, {File: pinmux, Value: "mode2"}},
+				},
+				Pulls: map[string][]Step{
+					"none": {{Gpio: 221, Direction: "in"}},
+					"up":   {{Gpio: 221, Direction: "high"}},
+					"down": {{Gpio: 221, Direction: "low"}},
+				},
+			},
+			"led": &Pin{Gpio: NoGpio, Led: "/sys/class/leds/test:green:usr0"},
+			"A":   &Pin{Gpio: NoGpio},
+		},
+		Analog: map[string]*Analog{
+			"A0": &Analog{Device: 0, Channel: 1, Bits: 12},
+		},
+		I2cSetup: map[int][]Step{
+			1: {{Gpio: 28, Direction: "in", Unexport: true}},
+		},
+		Setup: []Step{{Gpio: 214, Direction: "high"}},
+	}
+}
+
+func initTestBoardAdaptor() (*BoardAdaptor, *sysfs.Simulator) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	for _, i := range []int{4, 13, 28, 214, 221} {
+		sim.AddGpio(i)
+	}
+	sim.AddFile(pinmux, "mode0")
+	sim.AddFile("/sys/class/leds/test:green:usr0/brightness", "0")
+	sim.AddPwmChip(0, 2)
+	sim.AddIioDevice(0, 2)
+	sim.AddI2cBus(1, 0)
+	sim.AddI2cBus(2, 0)
+	sim.AddI2cDevice(1, 0x40)
+	sim.AddI2cDevice(2, 0x40)
+
+	a := NewBoardAdaptor("myAdaptor", testDescription())
+	a.Connect()
+	return a, sim
+}
+
+func TestBoardAdaptor(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+	gobottest.Assert(t, a.Name(), "myAdaptor")
+	gobottest.Assert(t, a.Description().Name, "Test Board")
+
+	g, _ := sim.Gpio(214)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 1)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(214)
+	gobottest.Assert(t, g.Exported, false)
+}
+
+func TestBoardAdaptorCapabilities(t *testing.T) {
+	a := NewBoardAdaptor("myAdaptor", testDescription())
+	gobottest.Assert(t, a.Capabilities(), gobot.Capabilities{
+		Pins: []gobot.PinCapability{
+			{Pin: "1", Modes: []string{"in", "out"}},
+			{Pin: "2", Modes: []string{"in", "out", "pwm", "servo", "spi"}},
+			{Pin: "led", Modes: []string{"in", "out"}},
+			{Pin: "A0", Modes: []string{"analog"}},
+		},
+		Metadata: map[string]string{"board": "Test Board"},
+	})
+
+	c := gobot.NewCapabilities(a)
+	gobottest.Assert(t, c.Interfaces, []string{
+		"AnalogReader", "DigitalReader", "DigitalWriter", "I2c",
+		"PinConfigurer", "PulseReader", "PwmPulseWriter", "PwmWriter", "ServoWriter", "Spi",
+	})
+	gobottest.Assert(t, len(c.Pins), 4)
+}
+
+func TestBoardAdaptorConnect(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	sysfs.SetFilesystem(sysfs.NewSimulator())
+	a := NewBoardAdaptor("myAdaptor", testDescription())
+	gobottest.Refute(t, len(a.Connect()), 0)
+
+	initTestBoardAdaptor()
+	d := testDescription()
+	d.Setup = []Step{{Gpio: 4, Direction: "up"}}
+	a = NewBoardAdaptor("myAdaptor", d)
+	gobottest.Assert(t, a.Connect()[0], errors.New(`Invalid direction "up" for gpio 4`))
+}
+
+func TestBoardAdaptorDigitalIO(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+
+	gobottest.Assert(t, a.DigitalWrite("1", 1), nil)
+	g, _ := sim.Gpio(4)
+	gobottest.Assert(t, g.Level, 1)
+
+	sim.SetGpioLevel(13, 1)
+	i, err := a.DigitalRead("2")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1)
+	g, _ = sim.Gpio(221)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 0)
+
+	// the mode steps run again when the pin changes mode
+	gobottest.Assert(t, a.DigitalWrite("2", 0), nil)
+	g, _ = sim.Gpio(221)
+	gobottest.Assert(t, g.Direction, sysfs.IN)
+
+	gobottest.Assert(t, a.DigitalWrite("led", 1), nil)
+	gobottest.Assert(t, sim.Contents("/sys/class/leds/test:green:usr0/brightness"), "1")
+	i, _ = a.DigitalRead("led")
+	gobottest.Assert(t, i, 1)
+
+	gobottest.Assert(t, a.DigitalWrite("99", 1), errors.New("Not a valid pin"))
+	gobottest.Assert(t, a.DigitalWrite("A", 1), errors.New("Not a valid pin"))
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(221)
+	gobottest.Assert(t, g.Exported, false)
+}
+
+// pulseFilesystem scripts the levels read from the value file at path, one
+// level per read, and reads the simulated gpio once they run out
+type pulseFilesystem struct {
+	sysfs.Filesystem
+	path   string
+	levels string
+}
+
+func (p *pulseFilesystem) OpenFile(name string, flag int, perm os.FileMode) (sysfs.File, error) {
+	f, err := p.Filesystem.OpenFile(name, flag, perm)
+	if err != nil || name != p.path {
+		return f, err
+	}
+	return &pulseFile{File: f, fs: p}, nil
+}
+
+type pulseFile struct {
+	sysfs.File
+	fs *pulseFilesystem
+}
+
+func (f *pulseFile) Read(b []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	if len(f.fs.levels) == 0 {
+		return f.File.Read(b)
+	}
+	n := copy(b, f.fs.levels[:1])
+	f.fs.levels = f.fs.levels[1:]
+	return n, nil
+}
+
+func TestBoardAdaptorPulseRead(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	_, sim := initTestBoardAdaptor()
+	// each read of the scripted levels takes a millisecond
+	fs := &pulseFilesystem{Filesystem: sim, path: "/sys/class/gpio/gpio4/value"}
+	sysfs.SetFilesystem(fs)
+	a := NewBoardAdaptor("myAdaptor", testDescription())
+
+	fs.levels = "00" + strings.Repeat("1", 10) + "0"
+	width, err := a.PulseRead("1", 1, time.Second)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, width >= 10*time.Millisecond && width < time.Second, true)
+
+	// the pulse under way is skipped, and the next one is timed
+	fs.levels = "1100" + strings.Repeat("1", 20) + "0"
+	width, err = a.PulseRead("1", 1, time.Second)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, width >= 20*time.Millisecond && width < time.Second, true)
+
+	// the pulse does not end within the timeout
+	fs.levels = "0" + strings.Repeat("1", 20)
+	width, err = a.PulseRead("1", 1, 10*time.Millisecond)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, width, time.Duration(0))
+
+	_, err = a.PulseRead("led", 1, time.Millisecond)
+	gobottest.Assert(t, err, errors.New("Not a valid pin"))
+	_, err = a.PulseRead("99", 1, time.Millisecond)
+	gobottest.Assert(t, err, errors.New("Not a valid pin"))
+}
+
+func TestBoardAdaptorConfigurePin(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+
+	// the pull steps run once the pin is an input
+	gobottest.Assert(t, a.ConfigurePin("2", gpio.PinConfig{Pull: gpio.PullUp, ActiveLow: true}), nil)
+	g, _ := sim.Gpio(221)
+	gobottest.Assert(t, g.Exported, false)
+	sim.SetGpioLevel(13, 0)
+	i, err := a.DigitalRead("2")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1)
+	g, _ = sim.Gpio(221)
+	gobottest.Assert(t, g.Level, 1)
+
+	gobottest.Assert(t, a.ConfigurePin("2", gpio.PinConfig{Pull: gpio.PullDown}), nil)
+	g, _ = sim.Gpio(221)
+	gobottest.Assert(t, g.Level, 0)
+	i, _ = a.DigitalRead("2")
+	gobottest.Assert(t, i, 0)
+
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{ActiveLow: true}), nil)
+	g, _ = sim.Gpio(4)
+	gobottest.Assert(t, g.ActiveLow, true)
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{Pull: gpio.PullUp}), gpio.ErrPullUnsupported)
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{OpenDrain: true}), gpio.ErrOpenDrainUnsupported)
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{Debounce: time.Millisecond}), gpio.ErrDebounceUnsupported)
+	gobottest.Refute(t, a.ConfigurePin("1", gpio.PinConfig{Pull: "sideways"}), nil)
+	gobottest.Assert(t, a.ConfigurePin("led", gpio.PinConfig{}), errors.New("Not a valid pin"))
+	gobottest.Assert(t, a.ConfigurePin("99", gpio.PinConfig{}), errors.New("Not a valid pin"))
+}
+
+func TestBoardAdaptorGpioChip(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	_, sim := initTestBoardAdaptor()
+	sim.AddGpioChip(0, 0, 32)
+	d := testDescription()
+	d.GpioChip = "/dev/gpiochip0"
+	d.Setup = nil
+	a := NewBoardAdaptor("myAdaptor", d)
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	config := gpio.PinConfig{Pull: gpio.PullUp, ActiveLow: true, Debounce: 10 * time.Millisecond}
+	gobottest.Assert(t, a.ConfigurePin("1", config), nil)
+	g, _ := sim.Gpio(4)
+	gobottest.Assert(t, g.Requested, true)
+	gobottest.Assert(t, g.Exported, false)
+	gobottest.Assert(t, g.Debounce, 10*time.Millisecond)
+
+	i, err := a.DigitalRead("1")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 0)
+	sim.SetGpioLevel(4, 0)
+	i, _ = a.DigitalRead("1")
+	gobottest.Assert(t, i, 1)
+
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{OpenDrain: true}), nil)
+	gobottest.Assert(t, a.DigitalWrite("1", 0), nil)
+	g, _ = sim.Gpio(4)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.OpenDrain, true)
+	gobottest.Assert(t, g.Level, 0)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(4)
+	gobottest.Assert(t, g.Requested, false)
+}
+
+func TestBoardAdaptorPwm(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+
+	gobottest.Assert(t, a.PwmWrite("2", 100), nil)
+	gobottest.Assert(t, sim.Contents(pinmux), "mode1")
+	p, _ := sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Enabled, true)
+	gobottest.Assert(t, p.Period, DefaultPwmPeriod)
+	gobottest.Assert(t, p.DutyCycle, 196078)
+
+	gobottest.Assert(t, a.ServoWrite("2", 90), nil)
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Period, ServoPeriod)
+	gobottest.Assert(t, p.DutyCycle, 1500000)
+
+	// the pin is muxed again after being used as a gpio
+	gobottest.Assert(t, a.DigitalWrite("2", 1), nil)
+	gobottest.Assert(t, sim.Contents(pinmux), "mode0")
+	gobottest.Assert(t, a.PwmWrite("2", 255), nil)
+	gobottest.Assert(t, sim.Contents(pinmux), "mode1")
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.DutyCycle, ServoPeriod)
+
+	gobottest.Assert(t, a.PwmPulseWrite("2", 10*time.Millisecond, 1200*time.Microsecond), nil)
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Period, 10000000)
+	gobottest.Assert(t, p.DutyCycle, 1200000)
+
+	gobottest.Assert(t, a.PwmWrite("1", 100), errors.New("Not a PWM pin"))
+	gobottest.Assert(t, a.ServoWrite("99", 100), errors.New("Not a valid pin"))
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Exported, false)
+}
+
+func TestBoardAdaptorBuzzer(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+
+	// a buzzer on a pwm pin is driven at the frequency of the tone
+	l := gpio.NewBuzzerDriver(a, "buzzer", "2")
+	l.BPM = 6000
+	gobottest.Assert(t, l.Tone(gpio.A4, 1), nil)
+	p, _ := sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Period, int(time.Second/440))
+	gobottest.Assert(t, p.DutyCycle, 0)
+
+	// and toggled on a plain gpio pin
+	l = gpio.NewBuzzerDriver(a, "buzzer", "1")
+	l.BPM = 6000
+	gobottest.Assert(t, l.Tone(gpio.A4, 1), nil)
+	g, _ := sim.Gpio(4)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 0)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestBoardAdaptorAnalog(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+	sim.AddFile("/sys/bus/iio/devices/iio:device0/in_voltage_scale", "0.439453125\n")
+
+	sim.SetAnalog(0, 1, 4095)
+	i, err := a.AnalogRead("A0")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1023)
+	mv, _ := a.AnalogReadMillivolts("A0")
+	gobottest.Assert(t, mv, 1799.560546875)
+
+	_, err = a.AnalogRead("A1")
+	gobottest.Assert(t, err, errors.New("Not a valid analog pin"))
+}
+
+func TestBoardAdaptorI2c(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+
+	// the i2c setup fails, and runs again on the next start
+	sim.SetFault("write", "/sys/class/gpio/gpio28/direction", syscall.EIO)
+	err := a.I2cStart(0x40)
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EIO)
+	sim.ClearFault("write", "/sys/class/gpio/gpio28/direction")
+	gobottest.Assert(t, a.I2cStart(0x40), nil)
+	g, _ := sim.Gpio(28)
+	gobottest.Assert(t, g.Exported, false)
+
+	gobottest.Assert(t, a.I2cWrite(0x40, []byte{0x00, 0x01}), nil)
+	gobottest.Assert(t, sim.I2cWrites(1, 0x40), [][]byte{{0x00, 0x01}})
+
+	a.SetI2cBus(0x40, 2)
+	_, err = a.I2cRead(0x40, 1)
+	gobottest.Assert(t, err.Error(), "i2c bus /dev/i2c-2 has not been started")
+	gobottest.Assert(t, a.I2cStart(0x40), nil)
+	gobottest.Assert(t, a.I2cWrite(0x40, []byte{0x02}), nil)
+	gobottest.Assert(t, sim.I2cWrites(2, 0x40), [][]byte{{0x02}})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestBoardAdaptorI2cBusConnection(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+
+	// identical devices at the same address on both buses
+	bus1, bus2 := a.I2cBus(1), a.I2cBus(2)
+	gobottest.Assert(t, bus1.Name(), "myAdaptor i2c-1")
+	gobottest.Assert(t, bus1.I2cStart(0x40), nil)
+	gobottest.Assert(t, bus2.I2cStart(0x40), nil)
+	g, _ := sim.Gpio(28)
+	gobottest.Assert(t, g.Exported, false)
+
+	gobottest.Assert(t, bus1.I2cWrite(0x40, []byte{0x01}), nil)
+	gobottest.Assert(t, bus2.I2cWrite(0x40, []byte{0x02}), nil)
+	gobottest.Assert(t, sim.I2cWrites(1, 0x40), [][]byte{{0x01}})
+	gobottest.Assert(t, sim.I2cWrites(2, 0x40), [][]byte{{0x02}})
+	_, err := bus2.I2cRead(0x40, 1)
+	gobottest.Assert(t, err, nil)
+
+	// the bus selected for the address does not matter
+	a.SetI2cBus(0x40, 2)
+	gobottest.Assert(t, bus1.I2cWrite(0x40, []byte{0x03}), nil)
+	gobottest.Assert(t, sim.I2cWrites(1, 0x40), [][]byte{{0x01}, {0x03}})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestBoardAdaptorSpi(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+	a.Description().SpiSetup = map[int][]Step{1: {{Gpio: 4, Direction: "low"}}}
+	a.Description().SpiPins = map[int][]string{1: {"2"}, 2: {"99"}}
+	sim.AddSpiDevice(0, 0, nil)
+	sim.AddSpiDevice(1, 0, func(tx []byte) []byte { return []byte{0x00, 0x2a} })
+
+	_, err := a.SpiTransfer(0, []byte{0x01})
+	gobottest.Assert(t, err.Error(), "spi chip select 0 has not been started")
+
+	gobottest.Assert(t, a.SpiStart(0, 3, 1000000), nil)
+	rx, err := a.SpiTransfer(0, []byte{0x01, 0x02})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, rx, []byte{0x01, 0x02})
+	s, _ := sim.Spi(0, 0)
+	gobottest.Assert(t, s.Mode, 3)
+	gobottest.Assert(t, s.Speed, 1000000)
+
+	a.SetSpiBus(0, 1)
+	gobottest.Assert(t, a.SpiStart(0, 0, 500000), nil)
+	g, _ := sim.Gpio(4)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, sim.Contents(pinmux), "mode2")
+	rx, _ = a.SpiTransfer(0, []byte{0x01, 0x00})
+	gobottest.Assert(t, rx, []byte{0x00, 0x2a})
+
+	// the pins of a started bus are reserved for it
+	gobottest.Assert(t, a.DigitalWrite("2", 1), errors.New("Pin 2 is in use by spi bus 1"))
+	gobottest.Assert(t, a.PwmWrite("2", 1), errors.New("Pin 2 is in use by spi bus 1"))
+	gobottest.Assert(t, sim.Contents(pinmux), "mode2")
+
+	a.SetSpiBus(1, 2)
+	gobottest.Assert(t, a.SpiStart(1, 0, 500000).Error(),
+		"spi bus 2 is routed to pin 99, which is not a valid pin")
+	a.Description().SpiPins[2] = nil
+	gobottest.Assert(t, os.IsNotExist(a.SpiStart(1, 0, 500000)), true)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestBoardAdaptorUart(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+	d := a.Description()
+	d.Uarts = map[int]string{1: "/dev/ttyS1"}
+	d.UartSetup = map[int][]Step{1: {{Gpio: 28, Direction: "high"}}}
+	d.UartPins = map[int][]string{1: {"1"}}
+	d.Pins["1"].Modes = map[string][]Step{ModeUart: {{File: pinmux, Value: "mode3"}}}
+
+	device, err := a.UartStart(1)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, device, "/dev/ttyS1")
+	g, _ := sim.Gpio(28)
+	gobottest.Assert(t, g.Level, 1)
+	gobottest.Assert(t, sim.Contents(pinmux), "mode3")
+	_, err = a.DigitalRead("1")
+	gobottest.Assert(t, err, errors.New("Pin 1 is in use by uart 1"))
+
+	_, err = a.UartStart(2)
+	gobottest.Assert(t, err, errors.New("Test Board has no uart 2"))
+}
+
+func TestBoardAdaptorPinAllocation(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+	sim.AddSpiDevice(1, 0, nil)
+	d := a.Description()
+	d.Pins["GPIO13"] = d.Pins["2"]
+	d.Analog["2"] = d.Analog["A0"]
+	d.SpiPins = map[int][]string{1: {"2"}}
+
+	gobottest.Assert(t, a.AllocatePin("2", "in", "button"), nil)
+	// pins sharing a header pin conflict
+	err := a.AllocatePin("GPIO13", "out", "led")
+	gobottest.Assert(t, err.Error(), `Pin "GPIO13" is already owned by "button" as pin "2" in mode "in"`)
+	// analog pins do not conflict with the digital pin of the same name
+	gobottest.Assert(t, a.AllocatePin("2", "analog", "sensor"), nil)
+
+	// buses allocate their pins when started
+	a.SetSpiBus(0, 1)
+	gobottest.Assert(t, a.SpiStart(0, 0, 500000).Error(),
+		`Pin "2" is already owned by "button" in mode "in"`)
+	a.ReleasePins("button")
+	gobottest.Assert(t, a.SpiStart(0, 0, 500000), nil)
+	gobottest.Assert(t, a.PinAllocations(), []gobot.PinAllocation{
+		{Pin: "2", Mode: "analog", Owner: "sensor"},
+		{Pin: "2", Mode: "spi", Owner: "spi bus 1"},
+	})
+}
+
+func TestBoardAdaptorConcurrency(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	a, sim := initTestBoardAdaptor()
+	d := a.Description()
+	d.Pins["GPIO4"] = d.Pins["1"]
+	sim.AddI2cDevice(1, 0x50)
+
+	gobottest.Concurrently(t, 24, func(i int) (err error) {
+		for j := 0; j < 20 && err == nil; j++ {
+			switch (i + j) % 8 {
+			case 0:
+				err = a.DigitalWrite("1", byte(j%2))
+			case 1:
+				_, err = a.DigitalRead("GPIO4")
+			case 2:
+				err = a.PwmWrite("2", byte(j))
+			case 3:
+				err = a.ServoWrite("2", byte(j))
+			case 4:
+				// switches pin 2 between its gpio and pwm modes
+				_, err = a.DigitalRead("2")
+			case 5:
+				_, err = a.AnalogRead("A0")
+			case 6:
+				err = a.DigitalWrite("led", byte(j%2))
+			case 7:
+				if err = a.I2cStart(0x40 + i%2*0x10); err == nil {
+					err = a.I2cWrite(0x40+i%2*0x10, []byte{0x00, byte(j)})
+				}
+			}
+		}
+		return
+	})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
@@ -0,0 +1,142 @@
+package board
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+const (
+	// NoGpio is the Gpio of a pin which is not a gpio, such as an analog input
+	NoGpio = -1
+
+	// ModeIn is the mode of a pin used as a digital input
+	ModeIn = "in"
+	// ModeOut is the mode of a pin used as a digital output
+	ModeOut = "out"
+	// ModePwm is the mode of a pin used as a pwm output
+	ModePwm = "pwm"
+	// ModeI2c is the mode of a pin routed to an i2c bus
+	ModeI2c = "i2c"
+	// ModeSpi is the mode of a pin routed to a spi bus
+	ModeSpi = "spi"
+	// ModeUart is the mode of a pin routed to a uart
+	ModeUart = "uart"
+)
+
+// Description describes the header pins of a single board linux computer and
+// how they are routed to the kernel gpio, pwm, industrial i/o, i2c, spi and
+// uart devices.
+type Description struct {
+	// Name is the name of the board
+	Name string `json:"name"`
+	// Pins maps the header pin names used with DigitalRead, DigitalWrite,
+	// PwmWrite and ServoWrite to the pin descriptions
+	Pins map[string]*Pin `json:"pins"`
+	// Analog maps the pin names used with AnalogRead to industrial i/o channels
+	Analog map[string]*Analog `json:"analog,omitempty"`
+	// I2cBus is the i2c bus used by devices unless another is selected with SetI2cBus
+	I2cBus int `json:"i2c_bus"`
+	// I2cSetup maps i2c bus numbers to the steps which route the bus to the
+	// header, run before the bus is started for the first time
+	I2cSetup map[int][]Step `json:"i2c_setup,omitempty"`
+	// I2cPins maps i2c bus numbers to the header pins the bus is routed to,
+	// which are switched to ModeI2c when the bus is started
+	I2cPins map[int][]string `json:"i2c_pins,omitempty"`
+	// SpiBus is the spi bus used by devices unless another is selected with SetSpiBus
+	SpiBus int `json:"spi_bus"`
+	// SpiSetup maps spi bus numbers to the steps which route the bus to the
+	// header, run before the bus is started for the first time
+	SpiSetup map[int][]Step `json:"spi_setup,omitempty"`
+	// SpiPins maps spi bus numbers to the header pins the bus is routed to,
+	// which are switched to ModeSpi when the bus is started
+	SpiPins map[int][]string `json:"spi_pins,omitempty"`
+	// Uarts maps uart numbers to their tty devices, eg. "/dev/ttyS1"
+	Uarts map[int]string `json:"uarts,omitempty"`
+	// UartSetup maps uart numbers to the steps which route the uart to the
+	// header, run before the uart is started for the first time
+	UartSetup map[int][]Step `json:"uart_setup,omitempty"`
+	// UartPins maps uart numbers to the header pins the uart is routed to,
+	// which are switched to ModeUart when the uart is started
+	UartPins map[int][]string `json:"uart_pins,omitempty"`
+	// Setup holds the steps run when the adaptor connects
+	Setup []Step `json:"setup,omitempty"`
+	// GpioChip is the gpio character device, eg. "/dev/gpiochip0", whose
+	// line offsets are the gpios of the pins. Gpios are exported through
+	// /sys/class/gpio when it is empty.
+	GpioChip string `json:"gpio_chip,omitempty"`
+}
+
+// Pin describes a header pin
+type Pin struct {
+	// Gpio is the kernel gpio number of the pin, or NoGpio
+	Gpio int `json:"gpio"`
+	// Label is the name of the gpio directory when it is not "gpio" followed
+	// by the gpio number
+	Label string `json:"label,omitempty"`
+	// Led is the led class directory of a pin driving an on-board led, such
+	// as "/sys/class/leds/beaglebone:green:usr0", which is written instead of a gpio
+	Led string `json:"led,omitempty"`
+	// Pwm is the pwm channel of the pin, if it has one
+	Pwm *Pwm `json:"pwm,omitempty"`
+	// Modes maps the pin modes, such as ModeIn, ModeOut, ModePwm or ModeSpi,
+	// to the steps which mux the pin for that mode
+	Modes map[string][]Step `json:"modes,omitempty"`
+	// Pulls maps gpio.PullNone, gpio.PullUp and gpio.PullDown to the steps
+	// which set the pull resistor of an input pin, for boards whose resistors
+	// are switched by other gpios
+	Pulls map[string][]Step `json:"pulls,omitempty"`
+}
+
+// Pwm describes a channel of a pwm chip in /sys/class/pwm
+type Pwm struct {
+	Chip    int `json:"chip"`
+	Channel int `json:"channel"`
+}
+
+// Analog describes a voltage channel of an industrial i/o device
+type Analog struct {
+	Device  int `json:"device"`
+	Channel int `json:"channel"`
+	// Bits is the resolution of the ADC, AnalogRead scales readings to 10 bits
+	Bits int `json:"bits"`
+}
+
+// Step is a single muxing step, which either writes Value to File, or sets
+// the direction of a gpio. The gpio is exported first, and unexported after
+// the step when Unexport is set.
+type Step struct {
+	// Gpio is the kernel gpio number the step configures
+	Gpio int `json:"gpio,omitempty"`
+	// Direction is "in", or "high" and "low" to drive an output
+	Direction string `json:"direction,omitempty"`
+	// Unexport unexports the gpio after the step
+	Unexport bool `json:"unexport,omitempty"`
+	// File is the path of the file the step writes to, eg. a pinmux file
+	File string `json:"file,omitempty"`
+	// Value is the value the step writes to File
+	Value string `json:"value,omitempty"`
+}
+
+// UnmarshalJSON decodes a pin, defaulting Gpio to NoGpio when absent
+func (p *Pin) UnmarshalJSON(data []byte) error {
+	type pin Pin
+	v := pin{Gpio: NoGpio}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = Pin(v)
+	return nil
+}
+
+// LoadDescription reads a board description from the json file at path
+func LoadDescription(path string) (d *Description, err error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	d = &Description{}
+	if err = json.Unmarshal(buf, d); err != nil {
+		return nil, err
+	}
+	return
+}
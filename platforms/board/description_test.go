@@ -0,0 +1,47 @@
+package board
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestLoadDescription(t *testing.T) {
+	f, _ := ioutil.TempFile("", "board")
+	defer os.Remove(f.Name())
+	f.WriteString(`{
+  "name": "My Board",
+  "i2c_bus": 2,
+  "pins": {
+    "7": { "gpio": 4 },
+    "12": { "gpio": 18, "pwm": { "chip": 0, "channel": 1 } },
+    "led": { "led": "/sys/class/leds/myboard:green:status" },
+    "10": { "gpio": 41, "modes": { "in": [{ "gpio": 226, "direction": "low" }] } }
+  },
+  "analog": { "A0": { "device": 1, "channel": 3, "bits": 12 } },
+  "i2c_setup": { "2": [{ "gpio": 28, "direction": "in", "unexport": true }] },
+  "setup": [{ "file": "/sys/kernel/debug/gpio_debug/gpio40/current_pinmux", "value": "mode0" }]
+}`)
+	f.Close()
+
+	d, err := LoadDescription(f.Name())
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, d.Name, "My Board")
+	gobottest.Assert(t, d.I2cBus, 2)
+	gobottest.Assert(t, d.Pins["7"].Gpio, 4)
+	gobottest.Assert(t, d.Pins["12"].Pwm, &Pwm{Chip: 0, Channel: 1})
+	gobottest.Assert(t, d.Pins["led"].Gpio, NoGpio)
+	gobottest.Assert(t, d.Pins["10"].Modes[ModeIn], []Step{{Gpio: 226, Direction: "low"}})
+	gobottest.Assert(t, d.Analog["A0"], &Analog{Device: 1, Channel: 3, Bits: 12})
+	gobottest.Assert(t, d.I2cSetup[2], []Step{{Gpio: 28, Direction: "in", Unexport: true}})
+	gobottest.Assert(t, d.Setup[0].Value, "mode0")
+
+	_, err = LoadDescription(f.Name() + ".missing")
+	gobottest.Assert(t, os.IsNotExist(err), true)
+
+	ioutil.WriteFile(f.Name(), []byte(`{"pins": [`), 0644)
+	_, err = LoadDescription(f.Name())
+	gobottest.Refute(t, err, nil)
+}
@@ -0,0 +1,10 @@
+/*
+Package board contains the Gobot adaptor for single board linux computers
+described by a board description file, which maps their header pins to
+gpios, pwm channels, analog channels and i2c buses. The raspi, chip,
+beaglebone and edison adaptors are built on it.
+
+For further information refer to board README:
+https://github.com/potix/gobot/blob/master/platforms/board/README.md
+*/
+package board
@@ -2,8 +2,14 @@ package chip
 
 import (
 	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/board"
 	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/platforms/i2c"
 	"github.com/potix/gobot/sysfs"
@@ -13,136 +19,166 @@ var _ gobot.Adaptor = (*ChipAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*ChipAdaptor)(nil)
 var _ gpio.DigitalWriter = (*ChipAdaptor)(nil)
+var _ gpio.AnalogReader = (*ChipAdaptor)(nil)
+var _ gpio.PwmWriter = (*ChipAdaptor)(nil)
+var _ gpio.ServoWriter = (*ChipAdaptor)(nil)
 
 var _ i2c.I2c = (*ChipAdaptor)(nil)
 
+// ChipAdaptor is the gobot.Adaptor representation for the C.H.I.P.
 type ChipAdaptor struct {
-	name        string
-	digitalPins map[int]sysfs.DigitalPin
-	i2cDevice   sysfs.I2cDevice
+	*board.BoardAdaptor
 }
 
-var pins = map[string]int{
-	"XIO-P0": 408,
-	"XIO-P1": 409,
-	"XIO-P2": 410,
-	"XIO-P3": 411,
-	"XIO-P4": 412,
-	"XIO-P5": 413,
-	"XIO-P6": 414,
-	"XIO-P7": 415,
+var glob = func(pattern string) (matches []string, err error) {
+	return filepath.Glob(pattern)
 }
 
-// NewChipAdaptor creates a ChipAdaptor with the specified name
-func NewChipAdaptor(name string) *ChipAdaptor {
-	c := &ChipAdaptor{
-		name:        name,
-		digitalPins: make(map[int]sysfs.DigitalPin),
-	}
-	return c
-}
-
-// Name returns the name of the ChipAdaptor
-func (c *ChipAdaptor) Name() string { return c.name }
-
-// Connect initializes the board
-func (c *ChipAdaptor) Connect() (errs []error) {
-	return
+// xioLabel is the label of the gpiochip of the pcf8574a expander driving the
+// XIO pins
+const xioLabel = "pcf8574a"
+
+// errXioNotFound is returned by xioBase when no gpiochip is labeled xioLabel
+var errXioNotFound = errors.New("XIO expander " + xioLabel + " not found in /sys/class/gpio")
+
+// headerPins maps the pins of the U13 and U14 headers which are not XIO pins
+// to their gpios. TWI1-SDA and TWI1-SCK are left out, as they carry
+// /dev/i2c-1.
+var headerPins = map[string]int{
+	"PWM0":      34,
+	"AP-EINT3":  35,
+	"TWI2-SCK":  49,
+	"TWI2-SDA":  50,
+	"LCD-D2":    98,
+	"LCD-D3":    99,
+	"LCD-D4":    100,
+	"LCD-D5":    101,
+	"LCD-D6":    102,
+	"LCD-D7":    103,
+	"LCD-D10":   106,
+	"LCD-D11":   107,
+	"LCD-D12":   108,
+	"LCD-D13":   109,
+	"LCD-D14":   110,
+	"LCD-D15":   111,
+	"LCD-D18":   114,
+	"LCD-D19":   115,
+	"LCD-D20":   116,
+	"LCD-D21":   117,
+	"LCD-D22":   118,
+	"LCD-D23":   119,
+	"LCD-CLK":   120,
+	"LCD-DE":    121,
+	"LCD-HSYNC": 122,
+	"LCD-VSYNC": 123,
+	"CSIPCK":    128,
+	"CSICK":     129,
+	"CSIHSYNC":  130,
+	"CSIVSYNC":  131,
+	"CSID0":     132,
+	"CSID1":     133,
+	"CSID2":     134,
+	"CSID3":     135,
+	"CSID4":     136,
+	"CSID5":     137,
+	"CSID6":     138,
+	"CSID7":     139,
+	"AP-EINT1":  193,
+	"UART1-TX":  195,
+	"UART1-RX":  196,
 }
 
-// Finalize closes connection to board and pins
-func (c *ChipAdaptor) Finalize() (errs []error) {
-	for _, pin := range c.digitalPins {
-		if pin != nil {
-			if err := pin.Unexport(); err != nil {
-				errs = append(errs, err)
-			}
-		}
+// defaultXioBase is the gpio of XIO-P0 on the 4.3 kernels
+const defaultXioBase = 408
+
+// description returns the board description of the C.H.I.P. with the XIO
+// pins starting at gpio xioBase. Devices use the TWI1-SDA and TWI1-SCK pins
+// of /dev/i2c-1 (pins 9 and 11 on header U13), and PWM0 is pwm channel 0.
+func description(xioBase int) *board.Description {
+	d := &board.Description{
+		Name:     "C.H.I.P.",
+		I2cBus:   1,
+		Pins:     make(map[string]*board.Pin),
+		Analog:   make(map[string]*board.Analog),
+		I2cPins:  map[int][]string{2: {"TWI2-SDA", "TWI2-SCK"}},
+		Uarts:    map[int]string{1: "/dev/ttyS1"},
+		UartPins: map[int][]string{1: {"UART1-TX", "UART1-RX"}},
 	}
-	if c.i2cDevice != nil {
-		if err := c.i2cDevice.Close(); err != nil {
-			errs = append(errs, err)
-		}
+	for name, i := range headerPins {
+		d.Pins[name] = &board.Pin{Gpio: i}
 	}
-	return errs
-}
-
-func (c *ChipAdaptor) translatePin(pin string) (i int, err error) {
-	if val, ok := pins[pin]; ok {
-		i = val
-	} else {
-		err = errors.New("Not a valid pin")
+	d.Pins["PWM0"].Pwm = &board.Pwm{Chip: 0, Channel: 0}
+	for i := 0; i < 8; i++ {
+		d.Pins[fmt.Sprintf("XIO-P%v", i)] = &board.Pin{Gpio: xioBase + i}
 	}
-	return
+	return d
 }
 
-// digitalPin returns matched digitalPin for specified values
-func (c *ChipAdaptor) digitalPin(pin string, dir string) (sysfsPin sysfs.DigitalPin, err error) {
-	i, err := c.translatePin(pin)
-
-	if err != nil {
-		return
+// NewChipAdaptor creates a ChipAdaptor with the specified name.
+// Valid pins are XIO-P0 through XIO-P7 (pins 13-20 on header U14), and the
+// LCD, CSI, UART1, TWI2, AP-EINT and PWM0 pins of headers U13 and U14.
+func NewChipAdaptor(name string) *ChipAdaptor {
+	return &ChipAdaptor{
+		BoardAdaptor: board.NewBoardAdaptor(name, description(defaultXioBase)),
 	}
+}
 
-	if c.digitalPins[i] == nil {
-		c.digitalPins[i] = sysfs.NewDigitalPin(i)
-		if err = c.digitalPins[i].Export(); err != nil {
-			return
-		}
+// Connect finds the gpio base of the XIO expander, which changes between
+// kernel versions, and the LRADC analog input, then runs the setup steps of
+// the board. The XIO pins keep the base of the 4.3 kernels when the expander
+// is not found, but an expander whose base cannot be read fails the connection.
+func (c *ChipAdaptor) Connect() (errs []error) {
+	base, err := xioBase()
+	if err == errXioNotFound {
+		base = defaultXioBase
+	} else if err != nil {
+		return []error{err}
 	}
-
-	if err = c.digitalPins[i].Direction(dir); err != nil {
-		return
+	d := c.Description()
+	for i := 0; i < 8; i++ {
+		d.Pins[fmt.Sprintf("XIO-P%v", i)].Gpio = base + i
 	}
 
-	return c.digitalPins[i], nil
-}
-
-// DigitalRead reads digital value from the specified pin.
-// Valids pins are XIO-P0 through XIO-P7 (pins 13-20 on header 14).
-func (c *ChipAdaptor) DigitalRead(pin string) (val int, err error) {
-	sysfsPin, err := c.digitalPin(pin, sysfs.IN)
-	if err != nil {
-		return
+	if device, ok := lradcDevice(); ok {
+		d.Analog["LRADC"] = &board.Analog{Device: device, Channel: 0, Bits: 6}
+	} else {
+		delete(d.Analog, "LRADC")
 	}
-	return sysfsPin.Read()
+	return c.BoardAdaptor.Connect()
 }
 
-// DigitalWrite writes digital value to the specified pin.
-// Valids pins are XIO-P0 through XIO-P7 (pins 13-20 on header 14).
-func (c *ChipAdaptor) DigitalWrite(pin string, val byte) (err error) {
-	sysfsPin, err := c.digitalPin(pin, sysfs.OUT)
+// xioBase returns the base of the gpiochip labeled xioLabel
+func xioBase() (int, error) {
+	chips, err := glob("/sys/class/gpio/gpiochip*")
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return sysfsPin.Write(int(val))
-}
-
-// I2cStart starts an i2c device in specified address.
-// This assumes that the bus used is /dev/i2c-1, which corresponds to
-// pins labeled TWI1-SDA and TW1-SCK (pins 9 and 11 on header 13).
-func (c *ChipAdaptor) I2cStart(address int) (err error) {
-	if c.i2cDevice == nil {
-		c.i2cDevice, err = sysfs.NewI2cDevice("/dev/i2c-1", address)
-	}
-	return err
-}
-
-// I2cWrite writes data to i2c device
-func (c *ChipAdaptor) I2cWrite(address int, data []byte) (err error) {
-	if err = c.i2cDevice.SetAddress(address); err != nil {
-		return
+	for _, chip := range chips {
+		label, err := sysfs.ReadAttribute(path.Join(chip, "label"))
+		if err != nil || label != xioLabel {
+			continue
+		}
+		base, err := sysfs.ReadAttribute(path.Join(chip, "base"))
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(base)
 	}
-	_, err = c.i2cDevice.Write(data)
-	return
+	return 0, errXioNotFound
 }
 
-// I2cRead returns value from i2c device using specified size
-func (c *ChipAdaptor) I2cRead(address int, size int) (data []byte, err error) {
-	if err = c.i2cDevice.SetAddress(address); err != nil {
-		return
+// lradcDevice returns the industrial i/o device number of the LRADC
+func lradcDevice() (int, bool) {
+	devices, _ := glob("/sys/bus/iio/devices/iio:device*")
+	for _, device := range devices {
+		name, err := sysfs.ReadAttribute(path.Join(device, "name"))
+		if err != nil || !strings.Contains(name, "lradc") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(path.Base(device), "iio:device"))
+		if err == nil {
+			return n, true
+		}
 	}
-	data = make([]byte, size)
-	_, err = c.i2cDevice.Read(data)
-	return
+	return 0, false
 }
@@ -2,45 +2,39 @@ package chip
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/potix/gobot/gobottest"
 	"github.com/potix/gobot/sysfs"
 )
 
-type NullReadWriteCloser struct {
-	contents []byte
-}
-
-func (n *NullReadWriteCloser) SetAddress(int) error {
-	return nil
-}
-
-func (n *NullReadWriteCloser) Write(b []byte) (int, error) {
-	n.contents = make([]byte, len(b))
-	copy(n.contents[:], b[:])
-
-	return len(b), nil
-}
-
-func (n *NullReadWriteCloser) Read(b []byte) (int, error) {
-	copy(b, n.contents)
-	return len(b), nil
-}
-
-var closeErr error = nil
-
-func (n *NullReadWriteCloser) Close() error {
-	return closeErr
-}
-
 func initTestChipAdaptor() *ChipAdaptor {
+	glob = func(pattern string) (matches []string, err error) {
+		if pattern == "/sys/class/gpio/gpiochip*" {
+			return []string{"/sys/class/gpio/gpiochip0", "/sys/class/gpio/gpiochip408"}, nil
+		}
+		return
+	}
+	fs := sysfs.NewMockFilesystem([]string{
+		"/sys/class/gpio/gpiochip0/label",
+		"/sys/class/gpio/gpiochip408/label",
+		"/sys/class/gpio/gpiochip408/base",
+	})
+	fs.Files["/sys/class/gpio/gpiochip0/label"].Contents = "1c20800.pinctrl\n"
+	fs.Files["/sys/class/gpio/gpiochip408/label"].Contents = "pcf8574a\n"
+	fs.Files["/sys/class/gpio/gpiochip408/base"].Contents = "408\n"
+	sysfs.SetFilesystem(fs)
+
 	a := NewChipAdaptor("myAdaptor")
 	a.Connect()
 	return a
 }
 
 func TestChipAdaptorDigitalIO(t *testing.T) {
+	defer func() { glob = filepath.Glob }()
 	a := initTestChipAdaptor()
 	fs := sysfs.NewMockFilesystem([]string{
 		"/sys/class/gpio/export",
@@ -64,6 +58,7 @@ func TestChipAdaptorDigitalIO(t *testing.T) {
 }
 
 func TestChipAdaptorI2c(t *testing.T) {
+	defer func() { glob = filepath.Glob }()
 	a := initTestChipAdaptor()
 	fs := sysfs.NewMockFilesystem([]string{
 		"/dev/i2c-1",
@@ -71,7 +66,6 @@ func TestChipAdaptorI2c(t *testing.T) {
 	sysfs.SetFilesystem(fs)
 	sysfs.SetSyscall(&sysfs.MockSyscall{})
 	a.I2cStart(0xff)
-	a.i2cDevice = &NullReadWriteCloser{}
 
 	a.I2cWrite(0xff, []byte{0x00, 0x01})
 	data, _ := a.I2cRead(0xff, 2)
@@ -79,3 +73,192 @@ func TestChipAdaptorI2c(t *testing.T) {
 
 	gobottest.Assert(t, len(a.Finalize()), 0)
 }
+
+func TestChipAdaptorI2cBuses(t *testing.T) {
+	defer func() { glob = filepath.Glob }()
+	a := initTestChipAdaptor()
+	fs := sysfs.NewMockFilesystem([]string{
+		"/dev/i2c-1",
+		"/dev/i2c-2",
+	})
+	sysfs.SetFilesystem(fs)
+	sysfs.SetSyscall(&sysfs.MockSyscall{})
+
+	a.SetI2cBus(0x68, 2)
+	gobottest.Assert(t, a.I2cStart(0x68), nil)
+	gobottest.Assert(t, a.I2cStart(0x09), nil)
+
+	gobottest.Assert(t, a.I2cWrite(0x68, []byte{0x6b, 0x00}), nil)
+	gobottest.Assert(t, a.I2cWrite(0x09, []byte{0x6f}), nil)
+	gobottest.Assert(t, fs.Files["/dev/i2c-2"].Contents, string([]byte{0x6b, 0x00}))
+	gobottest.Assert(t, fs.Files["/dev/i2c-1"].Contents, string([]byte{0x6f}))
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+// initChipSimulator returns a simulated C.H.I.P. running a kernel which puts
+// the XIO expander at gpio 1013
+func initChipSimulator() *sysfs.Simulator {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	glob = sim.Glob
+
+	sim.AddFile("/sys/class/gpio/gpiochip0/label", "1c20800.pinctrl\n")
+	sim.AddFile("/sys/class/gpio/gpiochip0/base", "0\n")
+	sim.AddFile("/sys/class/gpio/gpiochip1013/label", "pcf8574a\n")
+	sim.AddFile("/sys/class/gpio/gpiochip1013/base", "1013\n")
+	for i := 1013; i <= 1020; i++ {
+		sim.AddGpio(i)
+	}
+	for _, i := range headerPins {
+		sim.AddGpio(i)
+	}
+	sim.AddI2cBus(1, 0)
+	sim.AddI2cBus(2, 0)
+	sim.AddPwmChip(0, 1)
+	return sim
+}
+
+func TestChipAdaptorConnect(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	defer func() { glob = filepath.Glob }()
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	glob = sim.Glob
+	sim.AddFile("/sys/class/gpio/gpiochip0/label", "1c20800.pinctrl\n")
+
+	_, err := xioBase()
+	gobottest.Assert(t, err, errors.New("XIO expander pcf8574a not found in /sys/class/gpio"))
+
+	// the XIO pins fall back to the base of the 4.3 kernels
+	a := NewChipAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+	gobottest.Assert(t, a.Description().Pins["XIO-P0"].Gpio, defaultXioBase)
+
+	// an expander with an unreadable base is an error rather than a fallback
+	sim.AddFile("/sys/class/gpio/gpiochip408/label", "pcf8574a\n")
+	sim.AddFile("/sys/class/gpio/gpiochip408/base", "garbage\n")
+	a = NewChipAdaptor("myAdaptor")
+	errs := a.Connect()
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errs[0].Error(), `strconv.Atoi: parsing "garbage": invalid syntax`)
+}
+
+func TestChipAdaptorSimulator(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	defer func() { glob = filepath.Glob }()
+	sim := initChipSimulator()
+	sim.AddI2cDevice(1, 0x1e)
+	sim.SetI2cRegisters(1, 0x1e, 0x03, []byte{0x01, 0x02})
+	a := NewChipAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	gobottest.Assert(t, a.DigitalWrite("XIO-P0", 1), nil)
+	g, _ := sim.Gpio(1013)
+	gobottest.Assert(t, g.Level, 1)
+
+	sim.SetGpioLevel(1020, 1)
+	i, _ := a.DigitalRead("XIO-P7")
+	gobottest.Assert(t, i, 1)
+
+	// the pin is now an output, which the simulator drives no more
+	gobottest.Assert(t, a.DigitalWrite("XIO-P7", 0), nil)
+	sim.SetGpioLevel(1020, 1)
+	g, _ = sim.Gpio(1020)
+	gobottest.Assert(t, g.Level, 0)
+
+	gobottest.Assert(t, a.DigitalWrite("CSID3", 1), nil)
+	g, _ = sim.Gpio(135)
+	gobottest.Assert(t, g.Level, 1)
+	gobottest.Assert(t, a.DigitalWrite("LCD-D8", 1), errors.New("Not a valid pin"))
+
+	sim.SetFault("write", "/sys/class/gpio/gpio1014/direction", syscall.EBUSY)
+	err := a.DigitalWrite("XIO-P1", 1)
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EBUSY)
+
+	gobottest.Assert(t, a.I2cStart(0x1e), nil)
+	gobottest.Assert(t, a.I2cWrite(0x1e, []byte{0x03}), nil)
+	data, err := a.I2cRead(0x1e, 2)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, data, []byte{0x01, 0x02})
+
+	// TWI2 is reserved for i2c bus 2 once it is started
+	a.SetI2cBus(0x38, 2)
+	sim.AddI2cDevice(2, 0x38)
+	gobottest.Assert(t, a.I2cStart(0x38), nil)
+	gobottest.Assert(t, a.DigitalWrite("TWI2-SDA", 1), errors.New("Pin TWI2-SDA is in use by i2c bus 2"))
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(1013)
+	gobottest.Assert(t, g.Exported, false)
+}
+
+func TestChipAdaptorPwm(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	defer func() { glob = filepath.Glob }()
+	sim := initChipSimulator()
+	a := NewChipAdaptor("myAdaptor")
+	a.Connect()
+
+	gobottest.Assert(t, a.PwmWrite("PWM0", 51), nil)
+	p, _ := sim.Pwm(0, 0)
+	gobottest.Assert(t, p.Enabled, true)
+	gobottest.Assert(t, p.DutyCycle, 100000)
+
+	gobottest.Assert(t, a.ServoWrite("PWM0", 0), nil)
+	p, _ = sim.Pwm(0, 0)
+	gobottest.Assert(t, p.Period, 20000000)
+	gobottest.Assert(t, p.DutyCycle, 500000)
+
+	gobottest.Assert(t, a.PwmWrite("XIO-P0", 51), errors.New("Not a PWM pin"))
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestChipAdaptorAnalog(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	defer func() { glob = filepath.Glob }()
+	sim := initChipSimulator()
+	a := NewChipAdaptor("myAdaptor")
+	a.Connect()
+
+	// without the lradc driver there is no analog input
+	_, err := a.AnalogRead("LRADC")
+	gobottest.Assert(t, err, errors.New("Not a valid analog pin"))
+
+	sim.AddIioDevice(0, 4)
+	sim.AddFile("/sys/bus/iio/devices/iio:device0/name", "axp20x-adc\n")
+	sim.AddIioDevice(1, 1)
+	sim.AddFile("/sys/bus/iio/devices/iio:device1/name", "1c22800.lradc\n")
+	sim.SetAnalog(1, 0, 32)
+	gobottest.Assert(t, len(a.Connect()), 0)
+	i, err := a.AnalogRead("LRADC")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 512)
+}
+
+func TestChipAdaptorConcurrency(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	defer func() { glob = filepath.Glob }()
+	initChipSimulator()
+	a := NewChipAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	gobottest.Concurrently(t, 16, func(i int) (err error) {
+		for j := 0; j < 20 && err == nil; j++ {
+			switch (i + j) % 4 {
+			case 0:
+				err = a.DigitalWrite("XIO-P0", byte(j%2))
+			case 1:
+				_, err = a.DigitalRead("XIO-P1")
+			case 2:
+				err = a.PwmWrite("PWM0", byte(j))
+			case 3:
+				err = a.ServoWrite("PWM0", byte(j))
+			}
+		}
+		return
+	})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
@@ -2,27 +2,75 @@ package digispark
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"sync"
 
 	"github.com/potix/gobot"
 	"github.com/potix/gobot/platforms/gpio"
+	"github.com/potix/gobot/platforms/i2c"
+	"github.com/potix/gobot/platforms/spi"
 )
 
 var _ gobot.Adaptor = (*DigisparkAdaptor)(nil)
 
+var _ gpio.DigitalReader = (*DigisparkAdaptor)(nil)
 var _ gpio.DigitalWriter = (*DigisparkAdaptor)(nil)
+var _ gpio.AnalogReader = (*DigisparkAdaptor)(nil)
 var _ gpio.PwmWriter = (*DigisparkAdaptor)(nil)
 var _ gpio.ServoWriter = (*DigisparkAdaptor)(nil)
 
+var _ i2c.I2c = (*DigisparkAdaptor)(nil)
+
+var _ spi.Spi = (*DigisparkAdaptor)(nil)
+
+var _ gobot.PinAllocator = (*DigisparkAdaptor)(nil)
+
 // ErrConnection is the error resulting of a connection error with the digispark
 var ErrConnection = errors.New("connection error")
 
-// DigisparkAdaptor is the Gobot Adaptor for the Digispark
+// ErrI2cNak is the error resulting of an i2c device not acknowledging its address
+var ErrI2cNak = errors.New("i2c device did not acknowledge")
+
+// littleWire constants, as defined in littleWire.h
+const (
+	pinOutput   = 0
+	pinInput    = 1
+	vrefVcc     = 0
+	i2cWrite    = 0
+	i2cRead     = 1
+	noStop      = 0
+	endWithStop = 1
+	autoCs      = 1
+
+	// i2cWriteSize and i2cReadSize are the most bytes littleWire moves in
+	// one i2c message
+	i2cWriteSize = 4
+	i2cReadSize  = 8
+	// spiSize is the most bytes littleWire transfers in one spi message
+	spiSize = 4
+)
+
+// analogChannels maps the pins with an analog input to their ADC channel.
+// Pin 5 is also the reset pin, and its readings are noisy.
+var analogChannels = map[string]uint8{
+	"2": 1,
+	"5": 0,
+}
+
+// DigisparkAdaptor is the Gobot Adaptor for the Digispark. It is safe for
+// concurrent use: as every pin is driven through the same usb connection,
+// its mutex serializes the littleWire requests.
 type DigisparkAdaptor struct {
+	gobot.PinTable
 	name       string
+	mutex      sync.Mutex
 	littleWire lw
 	servo      bool
 	pwm        bool
+	analog     bool
+	i2c        bool
+	spi        bool
 	connect    func(*DigisparkAdaptor) (err error)
 }
 
@@ -56,6 +104,9 @@ func (d *DigisparkAdaptor) Finalize() (errs []error) { return }
 
 // DigitalWrite writes a value to the pin. Acceptable values are 1 or 0.
 func (d *DigisparkAdaptor) DigitalWrite(pin string, level byte) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	p, err := strconv.Atoi(pin)
 
 	if err != nil {
@@ -71,6 +122,9 @@ func (d *DigisparkAdaptor) DigitalWrite(pin string, level byte) (err error) {
 
 // PwmWrite writes the 0-254 value to the specified pin
 func (d *DigisparkAdaptor) PwmWrite(pin string, value byte) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	if d.pwm == false {
 		if err = d.littleWire.pwmInit(); err != nil {
 			return
@@ -87,6 +141,9 @@ func (d *DigisparkAdaptor) PwmWrite(pin string, value byte) (err error) {
 
 // ServoWrite writes the 0-180 degree val to the specified pin.
 func (d *DigisparkAdaptor) ServoWrite(pin string, angle uint8) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	if d.servo == false {
 		if err = d.littleWire.servoInit(); err != nil {
 			return
@@ -95,3 +152,169 @@ func (d *DigisparkAdaptor) ServoWrite(pin string, angle uint8) (err error) {
 	}
 	return d.littleWire.servoUpdateLocation(angle, angle)
 }
+
+// DigitalRead reads the level of the pin, which is switched to an input
+func (d *DigisparkAdaptor) DigitalRead(pin string) (val int, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	p, err := strconv.Atoi(pin)
+	if err != nil {
+		return
+	}
+
+	if err = d.littleWire.pinMode(uint8(p), pinInput); err != nil {
+		return
+	}
+
+	level, err := d.littleWire.digitalRead(uint8(p))
+	return int(level), err
+}
+
+// AnalogRead returns the 0-1023 value of analog pin "2" or "5", measured
+// against the USB supply voltage
+func (d *DigisparkAdaptor) AnalogRead(pin string) (val int, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	channel, ok := analogChannels[pin]
+	if !ok {
+		return 0, errors.New("Not a valid analog pin")
+	}
+
+	if d.analog == false {
+		if err = d.littleWire.analogInit(vrefVcc); err != nil {
+			return
+		}
+		d.analog = true
+	}
+
+	v, err := d.littleWire.analogRead(channel)
+	return int(v), err
+}
+
+// AllocatePin records that owner uses pin in mode. The analog pins are the
+// digital pins of the same name, so they conflict with them.
+func (d *DigisparkAdaptor) AllocatePin(pin string, mode string, owner string) error {
+	for _, a := range d.PinAllocations() {
+		if a.Pin == pin && a.Owner != owner && (a.Mode == "analog") != (mode == "analog") {
+			return &gobot.PinConflictError{Pin: pin, Mode: mode, Owner: a}
+		}
+	}
+	return d.PinTable.AllocatePin(pin, mode, owner)
+}
+
+// I2cStart initializes the i2c module, which uses pin 0 as SDA and pin 2 as SCL
+func (d *DigisparkAdaptor) I2cStart(address int) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.i2c == false {
+		if err = d.littleWire.i2cInit(); err != nil {
+			return
+		}
+		d.i2c = true
+	}
+	return
+}
+
+// I2cWrite writes buf to the i2c device at address
+func (d *DigisparkAdaptor) I2cWrite(address int, buf []byte) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.i2c == false {
+		return errors.New("i2c has not been started")
+	}
+
+	if err = d.littleWire.i2cStart(uint8(address), i2cWrite); err != nil {
+		return
+	}
+
+	for {
+		n := len(buf)
+		stop := uint8(endWithStop)
+		if n > i2cWriteSize {
+			n = i2cWriteSize
+			stop = noStop
+		}
+		if err = d.littleWire.i2cWrite(buf[:n], stop); err != nil {
+			return
+		}
+		buf = buf[n:]
+		if len(buf) == 0 {
+			return
+		}
+	}
+}
+
+// I2cRead returns size bytes read from the i2c device at address
+func (d *DigisparkAdaptor) I2cRead(address int, size int) (data []byte, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.i2c == false {
+		return nil, errors.New("i2c has not been started")
+	}
+
+	if err = d.littleWire.i2cStart(uint8(address), i2cRead); err != nil {
+		return
+	}
+
+	data = make([]byte, size)
+	for i := 0; i < size; i += i2cReadSize {
+		end := i + i2cReadSize
+		stop := uint8(noStop)
+		if end >= size {
+			end = size
+			stop = endWithStop
+		}
+		if err = d.littleWire.i2cRead(data[i:end], stop); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// SpiStart initializes the spi module, which uses pin 0 as MOSI, pin 1 as
+// MISO, pin 2 as SCK and pin 5 as the chip select. littleWire only supports
+// spi mode 0 on chip select 0, at a clock speed set by the firmware.
+func (d *DigisparkAdaptor) SpiStart(chip int, mode int, speed int) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if chip != 0 {
+		return fmt.Errorf("Digispark has no spi chip select %v", chip)
+	}
+	if mode != spi.Mode0 {
+		return fmt.Errorf("Digispark does not support spi mode %v", mode)
+	}
+
+	if d.spi == false {
+		if err = d.littleWire.spiInit(); err != nil {
+			return
+		}
+		d.spi = true
+	}
+	return
+}
+
+// SpiTransfer writes up to 4 bytes of data to the spi device, returning the
+// bytes read meanwhile
+func (d *DigisparkAdaptor) SpiTransfer(chip int, data []byte) (rx []byte, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.spi == false || chip != 0 {
+		return nil, fmt.Errorf("spi chip select %v has not been started", chip)
+	}
+	if len(data) > spiSize {
+		return nil, fmt.Errorf("Digispark spi transfers are limited to %v bytes", spiSize)
+	}
+
+	rx = make([]byte, len(data))
+	if err = d.littleWire.spiSendMessage(data, rx, autoCs); err != nil {
+		return nil, err
+	}
+	return
+}
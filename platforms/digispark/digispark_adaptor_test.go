@@ -16,6 +16,15 @@ type mock struct {
 	pin               uint8
 	mode              uint8
 	state             uint8
+	analogChannel     uint8
+	analogValue       uint
+	i2cAddress        uint8
+	i2cDirection      uint8
+	i2cWrites         [][]byte
+	i2cStops          []uint8
+	i2cData           []byte
+	spiWrites         [][]byte
+	spiReply          []byte
 }
 
 func (l *mock) digitalWrite(pin uint8, state uint8) error {
@@ -23,12 +32,22 @@ func (l *mock) digitalWrite(pin uint8, state uint8) error {
 	l.state = state
 	return l.error()
 }
+func (l *mock) digitalRead(pin uint8) (uint8, error) {
+	l.pin = pin
+	return l.state, l.error()
+}
 func (l *mock) pinMode(pin uint8, mode uint8) error {
 	l.pin = pin
 	l.mode = mode
 	return l.error()
 }
 
+func (l *mock) analogInit(voltageRef uint8) error { return l.error() }
+func (l *mock) analogRead(channel uint8) (uint, error) {
+	l.analogChannel = channel
+	return l.analogValue, l.error()
+}
+
 var pwmInitErrorFunc = func() error { return nil }
 
 func (l *mock) pwmInit() error { return pwmInitErrorFunc() }
@@ -49,6 +68,36 @@ func (l *mock) servoUpdateLocation(locationA uint8, locationB uint8) error {
 	return l.error()
 }
 
+func (l *mock) i2cInit() error { return l.error() }
+
+var i2cStartErrorFunc = func() error { return nil }
+
+func (l *mock) i2cStart(address7bit uint8, direction uint8) error {
+	l.i2cAddress = address7bit
+	l.i2cDirection = direction
+	return i2cStartErrorFunc()
+}
+func (l *mock) i2cWrite(sendBuffer []byte, endWithStop uint8) error {
+	l.i2cWrites = append(l.i2cWrites, append([]byte{}, sendBuffer...))
+	l.i2cStops = append(l.i2cStops, endWithStop)
+	return l.error()
+}
+func (l *mock) i2cRead(readBuffer []byte, endWithStop uint8) error {
+	n := copy(readBuffer, l.i2cData)
+	l.i2cData = l.i2cData[n:]
+	l.i2cStops = append(l.i2cStops, endWithStop)
+	return l.error()
+}
+func (l *mock) spiInit() error { return l.error() }
+func (l *mock) spiSendMessage(sendBuffer []byte, inputBuffer []byte, mode uint8) error {
+	l.spiWrites = append(l.spiWrites, append([]byte{}, sendBuffer...))
+	copy(inputBuffer, l.spiReply)
+	return l.error()
+}
+func (l *mock) onewireResetPulse() (bool, error)   { return true, l.error() }
+func (l *mock) onewireWriteByte(value uint8) error { return l.error() }
+func (l *mock) onewireReadByte() (uint8, error)    { return 0, l.error() }
+
 var errorFunc = func() error { return nil }
 
 func (l *mock) error() error { return errorFunc() }
@@ -59,6 +108,7 @@ func initTestDigisparkAdaptor() *DigisparkAdaptor {
 	a.littleWire = new(mock)
 	errorFunc = func() error { return nil }
 	pwmInitErrorFunc = func() error { return nil }
+	i2cStartErrorFunc = func() error { return nil }
 	return a
 }
 
@@ -125,3 +175,119 @@ func TestDigisparkAdaptorPwmWrite(t *testing.T) {
 	err = a.PwmWrite("1", uint8(100))
 	gobottest.Assert(t, err, errors.New("pwm error"))
 }
+
+func TestDigisparkAdaptorDigitalRead(t *testing.T) {
+	a := initTestDigisparkAdaptor()
+	a.littleWire.(*mock).state = 1
+	val, err := a.DigitalRead("2")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 1)
+	gobottest.Assert(t, a.littleWire.(*mock).pin, uint8(2))
+	gobottest.Assert(t, a.littleWire.(*mock).mode, uint8(1))
+
+	_, err = a.DigitalRead("?")
+	gobottest.Refute(t, err, nil)
+
+	errorFunc = func() error { return errors.New("pin mode error") }
+	_, err = a.DigitalRead("2")
+	gobottest.Assert(t, err, errors.New("pin mode error"))
+}
+
+func TestDigisparkAdaptorAnalogRead(t *testing.T) {
+	a := initTestDigisparkAdaptor()
+	a.littleWire.(*mock).analogValue = 512
+	val, err := a.AnalogRead("2")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 512)
+	gobottest.Assert(t, a.littleWire.(*mock).analogChannel, uint8(1))
+
+	a.AnalogRead("5")
+	gobottest.Assert(t, a.littleWire.(*mock).analogChannel, uint8(0))
+
+	_, err = a.AnalogRead("1")
+	gobottest.Assert(t, err, errors.New("Not a valid analog pin"))
+
+	a = initTestDigisparkAdaptor()
+	errorFunc = func() error { return errors.New("analog error") }
+	_, err = a.AnalogRead("2")
+	gobottest.Assert(t, err, errors.New("analog error"))
+}
+
+func TestDigisparkAdaptorAllocatePin(t *testing.T) {
+	a := initTestDigisparkAdaptor()
+	gobottest.Assert(t, a.AllocatePin("2", "analog", "sensor"), nil)
+	gobottest.Assert(t, a.AllocatePin("2", "in", "button").Error(),
+		`Pin "2" is already owned by "sensor" in mode "analog"`)
+	gobottest.Assert(t, a.AllocatePin("5", "in", "button"), nil)
+}
+
+func TestDigisparkAdaptorI2c(t *testing.T) {
+	a := initTestDigisparkAdaptor()
+	l := a.littleWire.(*mock)
+	gobottest.Assert(t, a.I2cWrite(0x09, []byte{0x6f}), errors.New("i2c has not been started"))
+
+	gobottest.Assert(t, a.I2cStart(0x09), nil)
+	// writes are split into messages of 4 bytes, the last one ending with a stop
+	gobottest.Assert(t, a.I2cWrite(0x09, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}), nil)
+	gobottest.Assert(t, l.i2cAddress, uint8(0x09))
+	gobottest.Assert(t, l.i2cDirection, uint8(0))
+	gobottest.Assert(t, l.i2cWrites, [][]byte{{0x01, 0x02, 0x03, 0x04}, {0x05, 0x06}})
+	gobottest.Assert(t, l.i2cStops, []uint8{0, 1})
+
+	// reads are split into messages of 8 bytes
+	l.i2cStops = nil
+	l.i2cData = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	data, err := a.I2cRead(0x09, 10)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, data, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	gobottest.Assert(t, l.i2cDirection, uint8(1))
+	gobottest.Assert(t, l.i2cStops, []uint8{0, 1})
+
+	i2cStartErrorFunc = func() error { return ErrI2cNak }
+	_, err = a.I2cRead(0x09, 1)
+	gobottest.Assert(t, err, ErrI2cNak)
+	gobottest.Assert(t, a.I2cWrite(0x09, []byte{0x00}), ErrI2cNak)
+}
+
+func TestDigisparkAdaptorSpi(t *testing.T) {
+	a := initTestDigisparkAdaptor()
+	l := a.littleWire.(*mock)
+	_, err := a.SpiTransfer(0, []byte{0x01})
+	gobottest.Assert(t, err, errors.New("spi chip select 0 has not been started"))
+
+	gobottest.Assert(t, a.SpiStart(1, 0, 1000000), errors.New("Digispark has no spi chip select 1"))
+	gobottest.Assert(t, a.SpiStart(0, 3, 1000000), errors.New("Digispark does not support spi mode 3"))
+	gobottest.Assert(t, a.SpiStart(0, 0, 1000000), nil)
+
+	l.spiReply = []byte{0x00, 0x02, 0xff}
+	rx, err := a.SpiTransfer(0, []byte{0x01, 0x80, 0x00})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, rx, []byte{0x00, 0x02, 0xff})
+	gobottest.Assert(t, l.spiWrites, [][]byte{{0x01, 0x80, 0x00}})
+
+	_, err = a.SpiTransfer(0, make([]byte, 5))
+	gobottest.Assert(t, err, errors.New("Digispark spi transfers are limited to 4 bytes"))
+}
+
+func TestDigisparkAdaptorConcurrency(t *testing.T) {
+	a := initTestDigisparkAdaptor()
+	gobottest.Assert(t, a.I2cStart(0x09), nil)
+
+	gobottest.Concurrently(t, 16, func(i int) (err error) {
+		for j := 0; j < 20 && err == nil; j++ {
+			switch (i + j) % 5 {
+			case 0:
+				err = a.DigitalWrite("0", byte(j%2))
+			case 1:
+				_, err = a.DigitalRead("1")
+			case 2:
+				err = a.PwmWrite("1", byte(j))
+			case 3:
+				_, err = a.AnalogRead("2")
+			case 4:
+				err = a.I2cWrite(0x09, []byte{0x6f})
+			}
+		}
+		return
+	})
+}
@@ -6,17 +6,32 @@ package digispark
 //typedef usb_dev_handle littleWire;
 import "C"
 
-import "errors"
+import (
+	"errors"
+	"unsafe"
+)
 
 type lw interface {
 	digitalWrite(uint8, uint8) error
+	digitalRead(uint8) (uint8, error)
 	pinMode(uint8, uint8) error
+	analogInit(uint8) error
+	analogRead(uint8) (uint, error)
 	pwmInit() error
 	pwmStop() error
 	pwmUpdateCompare(uint8, uint8) error
 	pwmUpdatePrescaler(uint) error
 	servoInit() error
 	servoUpdateLocation(uint8, uint8) error
+	i2cInit() error
+	i2cStart(uint8, uint8) error
+	i2cWrite([]byte, uint8) error
+	i2cRead([]byte, uint8) error
+	spiInit() error
+	spiSendMessage([]byte, []byte, uint8) error
+	onewireResetPulse() (bool, error)
+	onewireWriteByte(uint8) error
+	onewireReadByte() (uint8, error)
 	error() error
 }
 
@@ -35,11 +50,26 @@ func (l *littleWire) digitalWrite(pin uint8, state uint8) error {
 	return l.error()
 }
 
+func (l *littleWire) digitalRead(pin uint8) (uint8, error) {
+	state := C.digitalRead(l.lwHandle, C.uchar(pin))
+	return uint8(state), l.error()
+}
+
 func (l *littleWire) pinMode(pin uint8, mode uint8) error {
 	C.pinMode(l.lwHandle, C.uchar(pin), C.uchar(mode))
 	return l.error()
 }
 
+func (l *littleWire) analogInit(voltageRef uint8) error {
+	C.analog_init(l.lwHandle, C.uchar(voltageRef))
+	return l.error()
+}
+
+func (l *littleWire) analogRead(channel uint8) (uint, error) {
+	value := C.analogRead(l.lwHandle, C.uchar(channel))
+	return uint(value), l.error()
+}
+
 func (l *littleWire) pwmInit() error {
 	C.pwm_init(l.lwHandle)
 	return l.error()
@@ -70,6 +100,80 @@ func (l *littleWire) servoUpdateLocation(locationA uint8, locationB uint8) error
 	return l.error()
 }
 
+func (l *littleWire) i2cInit() error {
+	C.i2c_init(l.lwHandle)
+	return l.error()
+}
+
+// i2cStart addresses the device at the 7 bit address for a write (0) or read
+// (1), returning ErrI2cNak when the device does not acknowledge
+func (l *littleWire) i2cStart(address7bit uint8, direction uint8) error {
+	ack := C.i2c_start(l.lwHandle, C.uchar(address7bit), C.uchar(direction))
+	if err := l.error(); err != nil {
+		return err
+	}
+	if ack == 0 {
+		return ErrI2cNak
+	}
+	return nil
+}
+
+// i2cWrite writes up to 4 bytes, followed by a stop condition when
+// endWithStop is 1
+func (l *littleWire) i2cWrite(sendBuffer []byte, endWithStop uint8) error {
+	buf := make([]byte, 4)
+	n := copy(buf, sendBuffer)
+	C.i2c_write(l.lwHandle, (*C.uchar)(unsafe.Pointer(&buf[0])), C.uchar(n), C.uchar(endWithStop))
+	return l.error()
+}
+
+// i2cRead fills up to 8 bytes of readBuffer, followed by a stop condition
+// when endWithStop is 1
+func (l *littleWire) i2cRead(readBuffer []byte, endWithStop uint8) error {
+	buf := make([]byte, 8)
+	n := len(readBuffer)
+	if n > len(buf) {
+		n = len(buf)
+	}
+	C.i2c_read(l.lwHandle, (*C.uchar)(unsafe.Pointer(&buf[0])), C.uchar(n), C.uchar(endWithStop))
+	copy(readBuffer, buf[:n])
+	return l.error()
+}
+
+func (l *littleWire) spiInit() error {
+	C.spi_init(l.lwHandle)
+	return l.error()
+}
+
+// spiSendMessage transfers up to 4 bytes in spi mode 0, filling inputBuffer
+// with the bytes read meanwhile. The chip select is driven when mode is 1.
+func (l *littleWire) spiSendMessage(sendBuffer []byte, inputBuffer []byte, mode uint8) error {
+	send := make([]byte, 4)
+	input := make([]byte, 4)
+	n := copy(send, sendBuffer)
+	C.spi_sendMessage(l.lwHandle, (*C.uchar)(unsafe.Pointer(&send[0])),
+		(*C.uchar)(unsafe.Pointer(&input[0])), C.uchar(n), C.uchar(mode))
+	copy(inputBuffer, input[:n])
+	return l.error()
+}
+
+// onewireResetPulse sends a reset pulse, returning whether a device answered
+// with a presence pulse
+func (l *littleWire) onewireResetPulse() (bool, error) {
+	presence := C.onewire_resetPulse(l.lwHandle)
+	return presence != 0, l.error()
+}
+
+func (l *littleWire) onewireWriteByte(value uint8) error {
+	C.onewire_writeByte(l.lwHandle, C.uchar(value))
+	return l.error()
+}
+
+func (l *littleWire) onewireReadByte() (uint8, error) {
+	value := C.onewire_readByte(l.lwHandle)
+	return uint8(value), l.error()
+}
+
 func (l *littleWire) error() error {
 	str := C.GoString(C.littleWire_errorName())
 	if str != "" {
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/potix/gobot"
@@ -19,6 +20,9 @@ const (
 	Analog = 0x02
 	Pwm    = 0x03
 	Servo  = 0x04
+	// Pullup is the INPUT_PULLUP mode of Firmata 2.5, an input with the
+	// internal pull up resistor enabled
+	Pullup = 0x0B
 )
 
 // Sysex Codes
@@ -59,8 +63,13 @@ var (
 	ErrConnected = errors.New("client is already connected")
 )
 
-// Client represents a client connection to a firmata board
+// Client represents a client connection to a firmata board. A Client is safe
+// for concurrent use: its mutex guards the pin state, which is updated by the
+// goroutine reading from the board, and its writeMutex keeps the messages
+// written by different goroutines apart.
 type Client struct {
+	mutex            sync.Mutex
+	writeMutex       sync.Mutex
 	pins             []Pin
 	FirmwareName     string
 	ProtocolVersion  string
@@ -116,63 +125,85 @@ func New() *Client {
 
 // Disconnect disconnects the Client
 func (b *Client) Disconnect() (err error) {
-	b.connected = false
+	b.setConnected(false)
 	return b.connection.Close()
 }
 
 // Connected returns the current connection state of the Client
 func (b *Client) Connected() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	return b.connected
 }
 
-// Pins returns all available pins
+func (b *Client) setConnected(connected bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.connected = connected
+}
+
+// Pins returns a copy of all available pins
 func (b *Client) Pins() []Pin {
-	return b.pins
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	pins := make([]Pin, len(b.pins))
+	copy(pins, b.pins)
+	return pins
 }
 
 // Connect connects to the Client given conn. It first resets the firmata board
 // then continuously polls the firmata board for new information when it's
 // available.
 func (b *Client) Connect(conn io.ReadWriteCloser) (err error) {
-	if b.connected {
+	if b.Connected() {
 		return ErrConnected
 	}
 
 	b.connection = conn
 	b.Reset()
 
+	// the callbacks run in their own goroutines
+	var initMutex sync.Mutex
 	initFunc := b.ProtocolVersionQuery
+	setInitFunc := func(f func() error) {
+		initMutex.Lock()
+		defer initMutex.Unlock()
+		initFunc = f
+	}
 
 	gobot.Once(b.Event("ProtocolVersion"), func(data interface{}) {
-		initFunc = b.FirmwareQuery
+		setInitFunc(b.FirmwareQuery)
 	})
 
 	gobot.Once(b.Event("FirmwareQuery"), func(data interface{}) {
-		initFunc = b.CapabilitiesQuery
+		setInitFunc(b.CapabilitiesQuery)
 	})
 
 	gobot.Once(b.Event("CapabilityQuery"), func(data interface{}) {
-		initFunc = b.AnalogMappingQuery
+		setInitFunc(b.AnalogMappingQuery)
 	})
 
 	gobot.Once(b.Event("AnalogMappingQuery"), func(data interface{}) {
-		initFunc = func() error { return nil }
+		setInitFunc(func() error { return nil })
 		b.ReportDigital(0, 1)
 		b.ReportDigital(1, 1)
-		b.connected = true
+		b.setConnected(true)
 	})
 
 	for {
-		if err := initFunc(); err != nil {
+		initMutex.Lock()
+		f := initFunc
+		initMutex.Unlock()
+		if err := f(); err != nil {
 			return err
 		}
 		if err := b.process(); err != nil {
 			return err
 		}
-		if b.connected {
+		if b.Connected() {
 			go func() {
 				for {
-					if !b.connected {
+					if !b.Connected() {
 						break
 					}
 
@@ -194,6 +225,8 @@ func (b *Client) Reset() error {
 
 // SetPinMode sets the pin to mode.
 func (b *Client) SetPinMode(pin int, mode int) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	b.pins[byte(pin)].Mode = mode
 	return b.write([]byte{PinMode, byte(pin), byte(mode)})
 }
@@ -203,6 +236,8 @@ func (b *Client) DigitalWrite(pin int, value int) error {
 	port := byte(math.Floor(float64(pin) / 8))
 	portValue := byte(0)
 
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	b.pins[pin].Value = value
 
 	for i := byte(0); i < 8; i++ {
@@ -228,6 +263,8 @@ func (b *Client) ServoConfig(pin int, max int, min int) error {
 
 // AnalogWrite writes value to pin.
 func (b *Client) AnalogWrite(pin int, value int) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	b.pins[pin].Value = value
 	return b.write([]byte{AnalogMessage | byte(pin), byte(value & 0x7F), byte((value >> 7) & 0x7F)})
 }
@@ -311,6 +348,8 @@ func (b *Client) writeSysex(data []byte) (err error) {
 }
 
 func (b *Client) write(data []byte) (err error) {
+	b.writeMutex.Lock()
+	defer b.writeMutex.Unlock()
 	_, err = b.connection.Write(data[:])
 	return
 }
@@ -350,27 +389,31 @@ func (b *Client) process() (err error) {
 		value := uint(buf[1]) | uint(buf[2])<<7
 		pin := int((messageType & 0x0F))
 
+		b.mutex.Lock()
 		if len(b.analogPins) > pin {
 			if len(b.pins) > b.analogPins[pin] {
 				b.pins[b.analogPins[pin]].Value = int(value)
 				gobot.Publish(b.Event(fmt.Sprintf("AnalogRead%v", pin)), b.pins[b.analogPins[pin]].Value)
 			}
 		}
+		b.mutex.Unlock()
 	case DigitalMessageRangeStart <= messageType &&
 		DigitalMessageRangeEnd >= messageType:
 
 		port := messageType & 0x0F
 		portValue := buf[1] | (buf[2] << 7)
 
+		b.mutex.Lock()
 		for i := 0; i < 8; i++ {
 			pinNumber := int((8*byte(port) + byte(i)))
 			if len(b.pins) > pinNumber {
-				if b.pins[pinNumber].Mode == Input {
+				if b.pins[pinNumber].Mode == Input || b.pins[pinNumber].Mode == Pullup {
 					b.pins[pinNumber].Value = int((portValue >> (byte(i) & 0x07)) & 0x01)
 					gobot.Publish(b.Event(fmt.Sprintf("DigitalRead%v", pinNumber)), b.pins[pinNumber].Value)
 				}
 			}
 		}
+		b.mutex.Unlock()
 	case StartSysex == messageType:
 		currentBuffer := buf
 		for {
@@ -386,22 +429,22 @@ func (b *Client) process() (err error) {
 		command := currentBuffer[1]
 		switch command {
 		case CapabilityResponse:
-			b.pins = []Pin{}
+			pins := []Pin{}
 			supportedModes := 0
 			n := 0
 
 			for _, val := range currentBuffer[2:(len(currentBuffer) - 5)] {
 				if val == 127 {
 					modes := []int{}
-					for _, mode := range []int{Input, Output, Analog, Pwm, Servo} {
+					for _, mode := range []int{Input, Output, Analog, Pwm, Servo, Pullup} {
 						if (supportedModes & (1 << byte(mode))) != 0 {
 							modes = append(modes, mode)
 						}
 					}
 
-					b.pins = append(b.pins, Pin{SupportedModes: modes, Mode: Output})
-					b.AddEvent(fmt.Sprintf("DigitalRead%v", len(b.pins)-1))
-					b.AddEvent(fmt.Sprintf("PinState%v", len(b.pins)-1))
+					pins = append(pins, Pin{SupportedModes: modes, Mode: Output})
+					b.AddEvent(fmt.Sprintf("DigitalRead%v", len(pins)-1))
+					b.AddEvent(fmt.Sprintf("PinState%v", len(pins)-1))
 					supportedModes = 0
 					n = 0
 					continue
@@ -412,9 +455,13 @@ func (b *Client) process() (err error) {
 				}
 				n ^= 1
 			}
+			b.mutex.Lock()
+			b.pins = pins
+			b.mutex.Unlock()
 			gobot.Publish(b.Event("CapabilityQuery"), nil)
 		case AnalogMappingResponse:
 			pinIndex := 0
+			b.mutex.Lock()
 			b.analogPins = []int{}
 
 			for _, val := range currentBuffer[2 : len(b.pins)-1] {
@@ -427,9 +474,11 @@ func (b *Client) process() (err error) {
 				b.AddEvent(fmt.Sprintf("AnalogRead%v", pinIndex))
 				pinIndex++
 			}
+			b.mutex.Unlock()
 			gobot.Publish(b.Event("AnalogMappingQuery"), nil)
 		case PinStateResponse:
 			pin := currentBuffer[2]
+			b.mutex.Lock()
 			b.pins[pin].Mode = int(currentBuffer[3])
 			b.pins[pin].State = int(currentBuffer[4])
 
@@ -440,7 +489,9 @@ func (b *Client) process() (err error) {
 				b.pins[pin].State = int(uint(b.pins[pin].State) | uint(currentBuffer[6])<<14)
 			}
 
-			gobot.Publish(b.Event(fmt.Sprintf("PinState%v", pin)), b.pins[pin])
+			state := b.pins[pin]
+			b.mutex.Unlock()
+			gobot.Publish(b.Event(fmt.Sprintf("PinState%v", pin)), state)
 		case I2CReply:
 			reply := I2cReply{
 				Address:  int(byte(currentBuffer[2]) | byte(currentBuffer[3])<<7),
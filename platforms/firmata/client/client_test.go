@@ -1,6 +1,9 @@
 package client
 
 import (
+	"io"
+	"io/ioutil"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,9 +17,19 @@ func (readWriteCloser) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// testReadData is read by the goroutines of the connected clients
 var testReadData = []byte{}
+var testReadMutex sync.Mutex
+
+func setTestReadData(data []byte) {
+	testReadMutex.Lock()
+	defer testReadMutex.Unlock()
+	testReadData = data
+}
 
 func (readWriteCloser) Read(b []byte) (int, error) {
+	testReadMutex.Lock()
+	defer testReadMutex.Unlock()
 	size := len(b)
 	if len(testReadData) < size {
 		size = len(testReadData)
@@ -70,7 +83,7 @@ func initTestFirmata() *Client {
 		testCapabilitiesResponse,
 		testAnalogMappingResponse,
 	} {
-		testReadData = f()
+		setTestReadData(f())
 		b.process()
 	}
 
@@ -130,13 +143,19 @@ func TestProcess(t *testing.T) {
 			event:    "DigitalRead2",
 			data:     []byte{0x90, 0x04, 0x00},
 			expected: 1,
-			init:     func() { b.pins[2].Mode = Input },
+			init:     func() { b.SetPinMode(2, Input) },
 		},
 		{
 			event:    "DigitalRead4",
 			data:     []byte{0x90, 0x16, 0x00},
 			expected: 1,
-			init:     func() { b.pins[4].Mode = Input },
+			init:     func() { b.SetPinMode(4, Input) },
+		},
+		{
+			event:    "DigitalRead5",
+			data:     []byte{0x90, 0x20, 0x00},
+			expected: 1,
+			init:     func() { b.SetPinMode(5, Pullup) },
 		},
 		{
 			event:    "PinState13",
@@ -177,7 +196,7 @@ func TestProcess(t *testing.T) {
 			sem <- true
 		})
 
-		testReadData = test.data
+		setTestReadData(test.data)
 		go b.process()
 
 		select {
@@ -192,29 +211,82 @@ func TestConnect(t *testing.T) {
 	b := New()
 
 	response := testProtocolResponse()
+	setResponse := func(f func() []byte) {
+		testReadMutex.Lock()
+		defer testReadMutex.Unlock()
+		response = f()
+	}
 
 	go func() {
 		for {
+			testReadMutex.Lock()
 			testReadData = append(testReadData, response...)
+			testReadMutex.Unlock()
 			<-time.After(100 * time.Millisecond)
 		}
 	}()
 
 	gobot.Once(b.Event("ProtocolVersion"), func(data interface{}) {
-		response = testFirmwareResponse()
+		setResponse(testFirmwareResponse)
 	})
 
 	gobot.Once(b.Event("FirmwareQuery"), func(data interface{}) {
-		response = testCapabilitiesResponse()
+		setResponse(testCapabilitiesResponse)
 	})
 
 	gobot.Once(b.Event("CapabilityQuery"), func(data interface{}) {
-		response = testAnalogMappingResponse()
+		setResponse(testAnalogMappingResponse)
 	})
 
 	gobot.Once(b.Event("AnalogMappingQuery"), func(data interface{}) {
-		response = testProtocolResponse()
+		setResponse(testProtocolResponse)
 	})
 
 	gobottest.Assert(t, b.Connect(readWriteCloser{}), nil)
 }
+
+type pipeConnection struct {
+	*io.PipeReader
+	io.Writer
+}
+
+func TestClientConcurrency(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		for _, f := range []func() []byte{
+			testProtocolResponse,
+			testFirmwareResponse,
+			testCapabilitiesResponse,
+			testAnalogMappingResponse,
+		} {
+			if _, err := w.Write(f()); err != nil {
+				return
+			}
+		}
+		// digital reports of port 0 and analog reports of A0, until the
+		// client disconnects
+		for i := 0; ; i++ {
+			if _, err := w.Write([]byte{0x90, byte(i % 2 << 2), 0x00, 0xE0, byte(i & 0x7F), 0x00}); err != nil {
+				return
+			}
+		}
+	}()
+
+	b := New()
+	gobottest.Assert(t, b.Connect(pipeConnection{r, ioutil.Discard}), nil)
+
+	gobottest.Concurrently(t, 8, func(i int) (err error) {
+		pin := 2 + i
+		for j := 0; j < 50 && err == nil; j++ {
+			if j%2 == 0 {
+				err = b.SetPinMode(pin, Input)
+			} else if err = b.SetPinMode(pin, Output); err == nil {
+				err = b.DigitalWrite(pin, j%4/2)
+			}
+			_ = b.Pins()[pin].Value
+		}
+		return
+	})
+
+	gobottest.Assert(t, b.Disconnect(), nil)
+}
@@ -1,27 +1,35 @@
 package firmata
 
 import (
+	"fmt"
 	"io"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/potix/gobot"
 	"github.com/potix/gobot/platforms/firmata/client"
 	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/platforms/i2c"
-	"github.com/tarm/goserial"
+	"github.com/potix/gobot/transport"
 )
 
 var _ gobot.Adaptor = (*FirmataAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*FirmataAdaptor)(nil)
 var _ gpio.DigitalWriter = (*FirmataAdaptor)(nil)
+var _ gpio.DigitalNotifier = (*FirmataAdaptor)(nil)
 var _ gpio.AnalogReader = (*FirmataAdaptor)(nil)
 var _ gpio.PwmWriter = (*FirmataAdaptor)(nil)
 var _ gpio.ServoWriter = (*FirmataAdaptor)(nil)
+var _ gpio.PinConfigurer = (*FirmataAdaptor)(nil)
 
 var _ i2c.I2c = (*FirmataAdaptor)(nil)
 
+var _ gobot.PinAllocator = (*FirmataAdaptor)(nil)
+
+var _ gobot.Capabler = (*FirmataAdaptor)(nil)
+
 type firmataBoard interface {
 	Connect(io.ReadWriteCloser) error
 	Disconnect() error
@@ -37,32 +45,53 @@ type firmataBoard interface {
 	Event(string) *gobot.Event
 }
 
-// FirmataAdaptor is the Gobot Adaptor for Firmata based boards
+// FirmataAdaptor is the Gobot Adaptor for Firmata based boards. It is safe
+// for concurrent use: each pin has its own lock, held while the pin mode is
+// switched and the pin is read or written.
 type FirmataAdaptor struct {
-	name   string
-	port   string
-	board  firmataBoard
-	conn   io.ReadWriteCloser
-	openSP func(port string) (io.ReadWriteCloser, error)
+	gobot.PinTable
+	name     string
+	port     string
+	board    firmataBoard
+	conn     io.ReadWriteCloser
+	openSP   func(port string) (io.ReadWriteCloser, error)
+	pinMutex sync.Mutex
+	locks    map[int]*sync.Mutex
+	configs  map[int]gpio.PinConfig
+
+	notifyMutex sync.Mutex
+	notifiers   map[int]*firmataNotifier
+	notifyID    int
+}
+
+// firmataNotifier dispatches the values reported by the board for a pin to
+// the handlers of DigitalNotify
+type firmataNotifier struct {
+	event    *gobot.Event
+	handlers map[int]func(val int)
 }
 
 // NewFirmataAdaptor returns a new FirmataAdaptor with specified name and optionally accepts:
 //
-//	string: port the FirmataAdaptor uses to connect to a serial port with a baude rate of 57600
+//	string: port the FirmataAdaptor uses to connect to a serial port with a baude rate of 57600,
+//		or a transport spec such as "serial:///dev/ttyACM0?baud=115200" or "tcp://192.168.1.10:3030"
 //	io.ReadWriteCloser: connection the FirmataAdaptor uses to communication with the hardware
 //
-// If an io.ReadWriteCloser is not supplied, the FirmataAdaptor will open a connection
-// to a serial port with a baude rate of 57600. If an io.ReadWriteCloser
+// If an io.ReadWriteCloser is not supplied, the FirmataAdaptor will open the transport
+// given by port, with a baude rate of 57600 for a serial port. If an io.ReadWriteCloser
 // is supplied, then the FirmataAdaptor will use the provided io.ReadWriteCloser and use the
 // string port as a label to be displayed in the log and api.
 func NewFirmataAdaptor(name string, args ...interface{}) *FirmataAdaptor {
 	f := &FirmataAdaptor{
-		name:  name,
-		port:  "",
-		conn:  nil,
-		board: client.New(),
+		name:      name,
+		port:      "",
+		conn:      nil,
+		board:     client.New(),
+		locks:     make(map[int]*sync.Mutex),
+		configs:   make(map[int]gpio.PinConfig),
+		notifiers: make(map[int]*firmataNotifier),
 		openSP: func(port string) (io.ReadWriteCloser, error) {
-			return serial.OpenPort(&serial.Config{Name: port, Baud: 57600})
+			return transport.Open(port, 57600)
 		},
 	}
 
@@ -112,6 +141,49 @@ func (f *FirmataAdaptor) Finalize() (errs []error) {
 // Port returns the  FirmataAdaptors port
 func (f *FirmataAdaptor) Port() string { return f.port }
 
+// pinModes names the firmata pin modes
+var pinModes = map[int]string{
+	client.Input:  "in",
+	client.Output: "out",
+	client.Pwm:    "pwm",
+	client.Servo:  "servo",
+	client.Pullup: "pullup",
+}
+
+// Capabilities returns the pins the board reported when connecting, with the
+// modes they support, and the firmware of the board. Analog inputs are
+// listed apart, named after their analog channel as with AnalogRead.
+func (f *FirmataAdaptor) Capabilities() gobot.Capabilities {
+	c := gobot.Capabilities{Pins: []gobot.PinCapability{}}
+	if b, ok := f.board.(*client.Client); ok {
+		c.Firmware = b.FirmwareName
+		if b.ProtocolVersion != "" {
+			c.Metadata = map[string]string{"protocol_version": b.ProtocolVersion}
+		}
+	}
+
+	analog := []gobot.PinCapability{}
+	for i, p := range f.board.Pins() {
+		modes := []string{}
+		for _, mode := range p.SupportedModes {
+			if name, ok := pinModes[mode]; ok {
+				modes = append(modes, name)
+			}
+			if mode == client.Analog && i >= f.digitalPin(0) {
+				analog = append(analog, gobot.PinCapability{
+					Pin:   strconv.Itoa(i - f.digitalPin(0)),
+					Modes: []string{"analog"},
+				})
+			}
+		}
+		if len(modes) > 0 {
+			c.Pins = append(c.Pins, gobot.PinCapability{Pin: strconv.Itoa(i), Modes: modes})
+		}
+	}
+	c.Pins = append(c.Pins, analog...)
+	return c
+}
+
 // Name returns the  FirmataAdaptors name
 func (f *FirmataAdaptor) Name() string { return f.name }
 
@@ -121,6 +193,7 @@ func (f *FirmataAdaptor) ServoWrite(pin string, angle byte) (err error) {
 	if err != nil {
 		return err
 	}
+	defer f.lock(p)()
 
 	if f.board.Pins()[p].Mode != client.Servo {
 		err = f.board.SetPinMode(p, client.Servo)
@@ -138,6 +211,7 @@ func (f *FirmataAdaptor) PwmWrite(pin string, level byte) (err error) {
 	if err != nil {
 		return err
 	}
+	defer f.lock(p)()
 
 	if f.board.Pins()[p].Mode != client.Pwm {
 		err = f.board.SetPinMode(p, client.Pwm)
@@ -149,12 +223,70 @@ func (f *FirmataAdaptor) PwmWrite(pin string, level byte) (err error) {
 	return
 }
 
+// ConfigurePin sets the pull resistor and active level of the specified pin.
+// Inputs are pulled up with the INPUT_PULLUP mode of Firmata 2.5, and the
+// values of active low pins are inverted by the adaptor. Pull down
+// resistors, open drain outputs and debouncing are not supported.
+func (f *FirmataAdaptor) ConfigurePin(pin string, config gpio.PinConfig) (err error) {
+	if err = gpio.ValidPull(config.Pull); err != nil {
+		return
+	}
+	switch {
+	case config.Pull == gpio.PullDown:
+		return gpio.ErrPullUnsupported
+	case config.OpenDrain:
+		return gpio.ErrOpenDrainUnsupported
+	case config.Debounce > 0:
+		return gpio.ErrDebounceUnsupported
+	}
+	p, err := strconv.Atoi(pin)
+	if err != nil {
+		return
+	}
+	defer f.lock(p)()
+
+	f.pinMutex.Lock()
+	f.configs[p] = config
+	f.pinMutex.Unlock()
+
+	// switch an input to its new mode right away
+	mode := f.board.Pins()[p].Mode
+	if (mode == client.Input || mode == client.Pullup) && mode != inputMode(config) {
+		err = f.board.SetPinMode(p, inputMode(config))
+	}
+	return
+}
+
+// config returns the configuration of the specified pin
+func (f *FirmataAdaptor) config(pin int) gpio.PinConfig {
+	f.pinMutex.Lock()
+	defer f.pinMutex.Unlock()
+	return f.configs[pin]
+}
+
+// inputMode returns the mode of an input pin with config
+func inputMode(config gpio.PinConfig) int {
+	if config.Pull == gpio.PullUp {
+		return client.Pullup
+	}
+	return client.Input
+}
+
 // DigitalWrite writes a value to the pin. Acceptable values are 1 or 0.
 func (f *FirmataAdaptor) DigitalWrite(pin string, level byte) (err error) {
 	p, err := strconv.Atoi(pin)
 	if err != nil {
 		return
 	}
+	defer f.lock(p)()
+
+	if f.config(p).ActiveLow {
+		if level == 0 {
+			level = 1
+		} else {
+			level = 0
+		}
+	}
 
 	if f.board.Pins()[p].Mode != client.Output {
 		err = f.board.SetPinMode(p, client.Output)
@@ -174,9 +306,11 @@ func (f *FirmataAdaptor) DigitalRead(pin string) (val int, err error) {
 	if err != nil {
 		return
 	}
+	defer f.lock(p)()
 
-	if f.board.Pins()[p].Mode != client.Input {
-		if err = f.board.SetPinMode(p, client.Input); err != nil {
+	config := f.config(p)
+	if mode := inputMode(config); f.board.Pins()[p].Mode != mode {
+		if err = f.board.SetPinMode(p, mode); err != nil {
 			return
 		}
 		if err = f.board.ReportDigital(p, 1); err != nil {
@@ -185,7 +319,68 @@ func (f *FirmataAdaptor) DigitalRead(pin string) (val int, err error) {
 		<-time.After(10 * time.Millisecond)
 	}
 
-	return f.board.Pins()[p].Value, nil
+	val = f.board.Pins()[p].Value
+	if config.ActiveLow && val != -1 {
+		val ^= 1
+	}
+	return
+}
+
+// DigitalNotify calls handler with the values the board reports for the
+// specified pin, until the returned function is called. The pin is switched
+// to an input which the board reports the changes of.
+func (f *FirmataAdaptor) DigitalNotify(pin string, handler func(val int)) (stop func(), err error) {
+	if _, err = f.DigitalRead(pin); err != nil {
+		return
+	}
+	p, _ := strconv.Atoi(pin)
+	event := f.board.Event(fmt.Sprintf("DigitalRead%v", p))
+
+	f.notifyMutex.Lock()
+	defer f.notifyMutex.Unlock()
+
+	// a single dispatcher is registered for each event of the board, so that
+	// stopping a handler really removes it
+	n, ok := f.notifiers[p]
+	if !ok || n.event != event {
+		err = gobot.On(event, func(data interface{}) {
+			f.notify(p, event, data.(int))
+		})
+		if err != nil {
+			return nil, err
+		}
+		n = &firmataNotifier{event: event, handlers: make(map[int]func(val int))}
+		f.notifiers[p] = n
+	}
+	f.notifyID++
+	id := f.notifyID
+	n.handlers[id] = handler
+
+	return func() {
+		f.notifyMutex.Lock()
+		defer f.notifyMutex.Unlock()
+		delete(n.handlers, id)
+	}, nil
+}
+
+// notify calls the handlers of pin with a value the board reported through
+// event
+func (f *FirmataAdaptor) notify(pin int, event *gobot.Event, val int) {
+	f.notifyMutex.Lock()
+	handlers := []func(val int){}
+	if n, ok := f.notifiers[pin]; ok && n.event == event {
+		for _, handler := range n.handlers {
+			handlers = append(handlers, handler)
+		}
+	}
+	f.notifyMutex.Unlock()
+
+	if f.config(pin).ActiveLow {
+		val ^= 1
+	}
+	for _, handler := range handlers {
+		handler(val)
+	}
 }
 
 // AnalogRead retrieves value from analog pin.
@@ -197,6 +392,7 @@ func (f *FirmataAdaptor) AnalogRead(pin string) (val int, err error) {
 	}
 
 	p = f.digitalPin(p)
+	defer f.lock(p)()
 
 	if f.board.Pins()[p].Mode != client.Analog {
 		if err = f.board.SetPinMode(p, client.Analog); err != nil {
@@ -212,6 +408,20 @@ func (f *FirmataAdaptor) AnalogRead(pin string) (val int, err error) {
 	return f.board.Pins()[p].Value, nil
 }
 
+// lock locks the specified pin, returning the function unlocking it
+func (f *FirmataAdaptor) lock(pin int) func() {
+	f.pinMutex.Lock()
+	l, ok := f.locks[pin]
+	if !ok {
+		l = &sync.Mutex{}
+		f.locks[pin] = l
+	}
+	f.pinMutex.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
 // digitalPin converts pin number to digital mapping
 func (f *FirmataAdaptor) digitalPin(pin int) int {
 	return pin + 14
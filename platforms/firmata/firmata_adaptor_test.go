@@ -3,12 +3,15 @@ package firmata
 import (
 	"errors"
 	"io"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/potix/gobot"
 	"github.com/potix/gobot/gobottest"
 	"github.com/potix/gobot/platforms/firmata/client"
+	"github.com/potix/gobot/platforms/gpio"
 )
 
 type readWriteCloser struct{}
@@ -61,14 +64,20 @@ func (m mockFirmataBoard) Disconnect() error {
 func (m mockFirmataBoard) Pins() []client.Pin {
 	return m.pins
 }
-func (mockFirmataBoard) AnalogWrite(int, int) error   { return nil }
-func (mockFirmataBoard) SetPinMode(int, int) error    { return nil }
+func (mockFirmataBoard) AnalogWrite(int, int) error { return nil }
+func (m mockFirmataBoard) SetPinMode(pin int, mode int) error {
+	m.pins[pin].Mode = mode
+	return nil
+}
 func (mockFirmataBoard) ReportAnalog(int, int) error  { return nil }
 func (mockFirmataBoard) ReportDigital(int, int) error { return nil }
-func (mockFirmataBoard) DigitalWrite(int, int) error  { return nil }
-func (mockFirmataBoard) I2cRead(int, int) error       { return nil }
-func (mockFirmataBoard) I2cWrite(int, []byte) error   { return nil }
-func (mockFirmataBoard) I2cConfig(int) error          { return nil }
+func (m mockFirmataBoard) DigitalWrite(pin int, value int) error {
+	m.pins[pin].Value = value
+	return nil
+}
+func (mockFirmataBoard) I2cRead(int, int) error     { return nil }
+func (mockFirmataBoard) I2cWrite(int, []byte) error { return nil }
+func (mockFirmataBoard) I2cConfig(int) error        { return nil }
 
 func initTestFirmataAdaptor() *FirmataAdaptor {
 	a := NewFirmataAdaptor("board", "/dev/null")
@@ -117,6 +126,22 @@ func TestFirmataAdaptorConnect(t *testing.T) {
 
 }
 
+func TestFirmataAdaptorConnectTransport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	gobottest.Assert(t, err, nil)
+	defer l.Close()
+
+	a := NewFirmataAdaptor("board", "tcp://"+l.Addr().String())
+	a.board = newMockFirmataBoard()
+	gobottest.Assert(t, len(a.Connect()), 0)
+	gobottest.Refute(t, a.conn, nil)
+	a.conn.Close()
+
+	a = NewFirmataAdaptor("board", "serial:///dev/ttyACM0?parity=mark")
+	a.board = newMockFirmataBoard()
+	gobottest.Assert(t, a.Connect()[0], errors.New(`transport: invalid parity "mark"`))
+}
+
 func TestFirmataAdaptorServoWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
 	a.ServoWrite("1", 50)
@@ -139,6 +164,105 @@ func TestFirmataAdaptorDigitalRead(t *testing.T) {
 	gobottest.Assert(t, val, 1)
 }
 
+func TestFirmataAdaptorDigitalNotify(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	board := a.board.(*mockFirmataBoard)
+	board.AddEvent("DigitalRead2")
+	a.ConfigurePin("2", gpio.PinConfig{ActiveLow: true})
+
+	vals := make(chan int, 1)
+	stop, err := a.DigitalNotify("2", func(val int) {
+		vals <- val
+	})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, board.pins[2].Mode, client.Input)
+
+	gobot.Publish(board.Event("DigitalRead2"), 1)
+	select {
+	case val := <-vals:
+		gobottest.Assert(t, val, 0)
+	case <-time.After(time.Second):
+		t.Fatal("no notification")
+	}
+
+	stop()
+	gobot.Publish(board.Event("DigitalRead2"), 0)
+	select {
+	case <-vals:
+		t.Fatal("notified after stop")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// handlers are removed from the board event when stopped
+	for i := 0; i < 3; i++ {
+		stop, err = a.DigitalNotify("2", func(int) {})
+		gobottest.Assert(t, err, nil)
+		stop()
+	}
+	gobottest.Assert(t, len(board.Event("DigitalRead2").Callbacks), 1)
+	gobottest.Assert(t, len(a.notifiers[2].handlers), 0)
+
+	_, err = a.DigitalNotify("3", func(int) {})
+	gobottest.Assert(t, err, gobot.ErrUnknownEvent)
+	_, err = a.DigitalNotify("x", func(int) {})
+	gobottest.Refute(t, err, nil)
+}
+
+func TestFirmataAdaptorConfigurePin(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	pins := a.board.Pins()
+
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{Pull: gpio.PullUp, ActiveLow: true}), nil)
+	val, err := a.DigitalRead("1")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 0)
+	gobottest.Assert(t, pins[1].Mode, client.Pullup)
+
+	// an input is switched to its new mode right away
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{Pull: gpio.PullNone}), nil)
+	gobottest.Assert(t, pins[1].Mode, client.Input)
+
+	gobottest.Assert(t, a.ConfigurePin("2", gpio.PinConfig{ActiveLow: true}), nil)
+	gobottest.Assert(t, a.DigitalWrite("2", 1), nil)
+	gobottest.Assert(t, pins[2].Value, 0)
+
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{Pull: gpio.PullDown}), gpio.ErrPullUnsupported)
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{OpenDrain: true}), gpio.ErrOpenDrainUnsupported)
+	gobottest.Assert(t, a.ConfigurePin("1", gpio.PinConfig{Debounce: time.Millisecond}), gpio.ErrDebounceUnsupported)
+	gobottest.Refute(t, a.ConfigurePin("x", gpio.PinConfig{}), nil)
+}
+
+func TestFirmataAdaptorCapabilities(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	gobottest.Assert(t, a.Capabilities(), gobot.Capabilities{Pins: []gobot.PinCapability{}})
+
+	board := newMockFirmataBoard()
+	board.pins = make([]client.Pin, 16)
+	board.pins[3].SupportedModes = []int{client.Input, client.Output, client.Pwm, client.Servo, client.Pullup}
+	board.pins[13].SupportedModes = []int{client.Input, client.Output}
+	board.pins[15].SupportedModes = []int{client.Input, client.Output, client.Analog}
+	a.board = board
+	gobottest.Assert(t, a.Capabilities().Pins, []gobot.PinCapability{
+		{Pin: "3", Modes: []string{"in", "out", "pwm", "servo", "pullup"}},
+		{Pin: "13", Modes: []string{"in", "out"}},
+		{Pin: "15", Modes: []string{"in", "out"}},
+		{Pin: "1", Modes: []string{"analog"}},
+	})
+
+	b := client.New()
+	b.FirmwareName = "StandardFirmata.ino"
+	b.ProtocolVersion = "2.5"
+	a.board = b
+	c := gobot.NewCapabilities(a)
+	gobottest.Assert(t, c.Firmware, "StandardFirmata.ino")
+	gobottest.Assert(t, c.Metadata, map[string]string{"protocol_version": "2.5"})
+	gobottest.Assert(t, c.Port, "/dev/null")
+	gobottest.Assert(t, c.Interfaces, []string{
+		"AnalogReader", "DigitalNotifier", "DigitalReader", "DigitalWriter", "I2c",
+		"PinConfigurer", "PwmWriter", "ServoWriter",
+	})
+}
+
 func TestFirmataAdaptorAnalogRead(t *testing.T) {
 	a := initTestFirmataAdaptor()
 	val, err := a.AnalogRead("1")
@@ -166,3 +290,26 @@ func TestFirmataAdaptorI2cWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
 	a.I2cWrite(0x00, []byte{0x00, 0x01})
 }
+
+func TestFirmataAdaptorConcurrency(t *testing.T) {
+	a := initTestFirmataAdaptor()
+
+	gobottest.Concurrently(t, 16, func(i int) (err error) {
+		pin := strconv.Itoa(2 + i%4)
+		for j := 0; j < 5 && err == nil; j++ {
+			switch (i + j) % 5 {
+			case 0:
+				err = a.DigitalWrite(pin, byte(j%2))
+			case 1:
+				_, err = a.DigitalRead(pin)
+			case 2:
+				err = a.PwmWrite(pin, byte(j))
+			case 3:
+				err = a.ServoWrite(pin, byte(j))
+			case 4:
+				_, err = a.AnalogRead(strconv.Itoa(i % 4))
+			}
+		}
+		return
+	})
+}
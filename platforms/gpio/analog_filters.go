@@ -0,0 +1,101 @@
+package gpio
+
+import "sort"
+
+// AnalogFilter filters the readings of an analog sensor, returning the
+// filtered value of each reading. Filters keep the state of the readings
+// they filtered, so each sensor needs filters of its own.
+type AnalogFilter func(val float64) float64
+
+// MovingAverageFilter returns a filter averaging the last n readings, which
+// smooths out noise
+func MovingAverageFilter(n int) AnalogFilter {
+	window := newAnalogWindow(n)
+	return func(val float64) float64 {
+		vals := window(val)
+		sum := 0.0
+		for _, v := range vals {
+			sum += v
+		}
+		return sum / float64(len(vals))
+	}
+}
+
+// MedianFilter returns a filter taking the median of the last n readings,
+// which filters out the odd spike
+func MedianFilter(n int) AnalogFilter {
+	window := newAnalogWindow(n)
+	return func(val float64) float64 {
+		return median(window(val))
+	}
+}
+
+// ExponentialFilter returns a filter smoothing the readings exponentially,
+// weighing each reading by alpha, from 0 for a value which never changes
+// through 1 for the readings as they are
+func ExponentialFilter(alpha float64) AnalogFilter {
+	first := true
+	smoothed := 0.0
+	return func(val float64) float64 {
+		if first {
+			first = false
+			smoothed = val
+		} else {
+			smoothed += alpha * (val - smoothed)
+		}
+		return smoothed
+	}
+}
+
+// newAnalogWindow returns a function adding a reading to a window of the
+// last n, and returning the window
+func newAnalogWindow(n int) func(val float64) []float64 {
+	if n < 1 {
+		n = 1
+	}
+	window := make([]float64, 0, n)
+	return func(val float64) []float64 {
+		if len(window) == n {
+			window = append(window[:0], window[1:]...)
+		}
+		window = append(window, val)
+		return window
+	}
+}
+
+// AnalogConversion converts the readings of an analog sensor to engineering
+// units, such as degrees or volts
+type AnalogConversion func(raw float64) float64
+
+// LinearConversion returns a conversion mapping the readings from rawMin
+// through rawMax linearly to min through max
+func LinearConversion(rawMin float64, rawMax float64, min float64, max float64) AnalogConversion {
+	return func(raw float64) float64 {
+		return min + (raw-rawMin)/(rawMax-rawMin)*(max-min)
+	}
+}
+
+// LookupTableConversion returns a conversion interpolating linearly between
+// the points of table, which maps readings to values. Readings beyond the
+// table are converted to the value of the nearest point.
+func LookupTableConversion(table map[float64]float64) AnalogConversion {
+	raws := make([]float64, 0, len(table))
+	for raw := range table {
+		raws = append(raws, raw)
+	}
+	sort.Float64s(raws)
+	return func(raw float64) float64 {
+		if len(raws) == 0 {
+			return raw
+		}
+		i := sort.SearchFloat64s(raws, raw)
+		switch {
+		case i == 0:
+			return table[raws[0]]
+		case i == len(raws):
+			return table[raws[len(raws)-1]]
+		}
+		lo, hi := raws[i-1], raws[i]
+		return table[lo] + (raw-lo)/(hi-lo)*(table[hi]-table[lo])
+	}
+}
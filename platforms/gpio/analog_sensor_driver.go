@@ -1,6 +1,8 @@
 package gpio
 
 import (
+	"math"
+	"sync"
 	"time"
 
 	"github.com/potix/gobot"
@@ -9,12 +11,24 @@ import (
 var _ gobot.Driver = (*AnalogSensorDriver)(nil)
 
 // AnalogSensorDriver represents an Analog Sensor
+//
+// The readings of the sensor go through its filters, and are converted to
+// engineering units by its conversion. Its Data events are published when
+// the filtered reading moves by its deadband, and its Above and Below events
+// when the converted value crosses its threshold.
 type AnalogSensorDriver struct {
 	name       string
 	pin        string
 	halt       chan bool
 	interval   time.Duration
 	connection AnalogReader
+	mutex      sync.Mutex
+	filters    []AnalogFilter
+	deadband   float64
+	conversion AnalogConversion
+	threshold  *float64
+	hysteresis float64
+	value      float64
 	gobot.Eventer
 	gobot.Commander
 }
@@ -27,6 +41,7 @@ type AnalogSensorDriver struct {
 //
 // Adds the following API Commands:
 // 	"Read" - See AnalogSensor.Read
+//	"Value" - See AnalogSensor.Value
 func NewAnalogSensorDriver(a AnalogReader, name string, pin string, v ...time.Duration) *AnalogSensorDriver {
 	d := &AnalogSensorDriver{
 		name:       name,
@@ -43,30 +58,48 @@ func NewAnalogSensorDriver(a AnalogReader, name string, pin string, v ...time.Du
 	}
 
 	d.AddEvent(Data)
+	d.AddEvent(Value)
+	d.AddEvent(Above)
+	d.AddEvent(Below)
 	d.AddEvent(Error)
 
 	d.AddCommand("Read", func(params map[string]interface{}) interface{} {
 		val, err := d.Read()
 		return map[string]interface{}{"val": val, "err": err}
 	})
+	d.AddCommand("Value", func(params map[string]interface{}) interface{} {
+		return d.Value()
+	})
 
 	return d
 }
 
 // Start starts the AnalogSensorDriver and reads the Analog Sensor at the given interval.
 // Emits the Events:
-//	Data int - Event is emitted on change and represents the current reading from the sensor.
+//	Data int - Event is emitted on change by the deadband and represents the
+//		current filtered reading from the sensor.
+//	Value float64 - Event is emitted with Data and represents the current
+//		reading converted by the conversion.
+//	Above float64 - Event is emitted with the value when it rises above the threshold.
+//	Below float64 - Event is emitted with the value when it falls below the
+//		threshold by the hysteresis.
 //	Error error - Event is emitted on error reading from the sensor.
 func (a *AnalogSensorDriver) Start() (errs []error) {
-	value := 0
+	published := 0.0
+	above := false
 	go func() {
 		for {
 			newValue, err := a.Read()
 			if err != nil {
 				gobot.Publish(a.Event(Error), err)
-			} else if newValue != value && newValue != -1 {
-				value = newValue
-				gobot.Publish(a.Event(Data), value)
+			} else if newValue != -1 {
+				filtered, value := a.filter(float64(newValue))
+				if math.Abs(filtered-published) >= a.Deadband() && int(filtered+0.5) != int(published+0.5) {
+					published = filtered
+					gobot.Publish(a.Event(Data), int(filtered+0.5))
+					gobot.Publish(a.Event(Value), value)
+				}
+				above = a.cross(value, above)
 			}
 			select {
 			case <-time.After(a.interval):
@@ -90,6 +123,9 @@ func (a *AnalogSensorDriver) Name() string { return a.name }
 // Pin returns the AnalogSensorDrivers pin
 func (a *AnalogSensorDriver) Pin() string { return a.pin }
 
+// PinModes returns the AnalogSensorDrivers pin and its "analog" mode
+func (a *AnalogSensorDriver) PinModes() map[string]string { return map[string]string{a.pin: "analog"} }
+
 // Connection returns the AnalogSensorDrivers Connection
 func (a *AnalogSensorDriver) Connection() gobot.Connection { return a.connection.(gobot.Connection) }
 
@@ -97,3 +133,90 @@ func (a *AnalogSensorDriver) Connection() gobot.Connection { return a.connection
 func (a *AnalogSensorDriver) Read() (val int, err error) {
 	return a.connection.AnalogRead(a.Pin())
 }
+
+// SetFilters sets the filters the readings go through in turn, replacing the
+// filters set before. Readings are not filtered by default.
+func (a *AnalogSensorDriver) SetFilters(filters ...AnalogFilter) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.filters = filters
+}
+
+// SetDeadband sets how far the filtered reading has to move for Data to be
+// published, so that noise does not flood the subscribers. Data is published
+// on any change by default.
+func (a *AnalogSensorDriver) SetDeadband(deadband float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.deadband = deadband
+}
+
+// Deadband returns how far the filtered reading has to move for Data to be
+// published
+func (a *AnalogSensorDriver) Deadband() float64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.deadband
+}
+
+// SetConversion sets the conversion of the filtered readings to engineering
+// units, such as a LinearConversion or LookupTableConversion. Readings are
+// not converted by default.
+func (a *AnalogSensorDriver) SetConversion(conversion AnalogConversion) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.conversion = conversion
+}
+
+// SetThreshold sets the threshold whose crossings by the converted value are
+// published as Above and Below events. Once above the threshold, the value has
+// to fall below it by hysteresis to be below again, so that a value hovering
+// around the threshold does not flood the subscribers.
+func (a *AnalogSensorDriver) SetThreshold(threshold float64, hysteresis float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.threshold = &threshold
+	a.hysteresis = hysteresis
+}
+
+// Value returns the last reading filtered and converted
+func (a *AnalogSensorDriver) Value() float64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.value
+}
+
+// filter filters and converts the reading raw
+func (a *AnalogSensorDriver) filter(raw float64) (filtered float64, value float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	filtered = raw
+	for _, f := range a.filters {
+		filtered = f(filtered)
+	}
+	value = filtered
+	if a.conversion != nil {
+		value = a.conversion(filtered)
+	}
+	a.value = value
+	return
+}
+
+// cross publishes the crossings of the threshold by value, and returns
+// whether value is above the threshold, given whether the previous value was
+func (a *AnalogSensorDriver) cross(value float64, above bool) bool {
+	a.mutex.Lock()
+	threshold, hysteresis := a.threshold, a.hysteresis
+	a.mutex.Unlock()
+	switch {
+	case threshold == nil:
+		return false
+	case !above && value > *threshold:
+		gobot.Publish(a.Event(Above), value)
+		return true
+	case above && value < *threshold-hysteresis:
+		gobot.Publish(a.Event(Below), value)
+		return false
+	}
+	return above
+}
@@ -2,6 +2,7 @@ package gpio
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -98,3 +99,112 @@ func TestAnalogSensorDriverHalt(t *testing.T) {
 	}()
 	gobottest.Assert(t, len(d.Halt()), 0)
 }
+
+func TestAnalogFilters(t *testing.T) {
+	filter := func(f AnalogFilter, vals ...float64) (filtered []float64) {
+		for _, val := range vals {
+			filtered = append(filtered, f(val))
+		}
+		return
+	}
+
+	gobottest.Assert(t, filter(MovingAverageFilter(3), 3, 6, 9, 0, 30), []float64{3, 4.5, 6, 5, 13})
+	gobottest.Assert(t, filter(MedianFilter(3), 3, 6, 100, 9, 0), []float64{3, 4.5, 6, 9, 9})
+	gobottest.Assert(t, filter(ExponentialFilter(0.5), 10, 20, 20, 0), []float64{10, 15, 17.5, 8.75})
+	gobottest.Assert(t, filter(MovingAverageFilter(0), 1, 2), []float64{1, 2})
+}
+
+func TestAnalogConversions(t *testing.T) {
+	c := LinearConversion(0, 1000, -50, 50)
+	gobottest.Assert(t, c(0), -50.0)
+	gobottest.Assert(t, c(750), 25.0)
+
+	c = LookupTableConversion(map[float64]float64{100: 0, 500: 40, 300: 10})
+	gobottest.Assert(t, c(0), 0.0)
+	gobottest.Assert(t, c(200), 5.0)
+	gobottest.Assert(t, c(300), 10.0)
+	gobottest.Assert(t, c(450), 32.5)
+	gobottest.Assert(t, c(1000), 40.0)
+	gobottest.Assert(t, LookupTableConversion(nil)(7), 7.0)
+}
+
+// recordAnalog returns a channel receiving the events of d, named by name
+func recordAnalog(d *AnalogSensorDriver) chan string {
+	events := make(chan string, 100)
+	for _, name := range []string{Data, Above, Below} {
+		name := name
+		gobot.On(d.Event(name), func(data interface{}) {
+			events <- fmt.Sprintf("%v %v", name, data)
+		})
+	}
+	return events
+}
+
+func TestAnalogSensorDriverDeadband(t *testing.T) {
+	i := &gpioTestAnalogInput{}
+	d := NewAnalogSensorDriver(i, "bot", "1", time.Millisecond)
+	d.SetDeadband(10)
+	d.SetConversion(LinearConversion(0, 1000, 0, 100))
+	gobottest.Assert(t, d.Deadband(), 10.0)
+	values := make(chan float64, 100)
+	gobot.On(d.Event(Value), func(data interface{}) {
+		values <- data.(float64)
+	})
+	events := recordAnalog(d)
+	gobottest.Assert(t, len(d.Start()), 0)
+	defer d.Halt()
+
+	for _, val := range []int{5, 9, 500, 505, 495, 489} {
+		i.Set(val)
+		<-time.After(10 * time.Millisecond)
+	}
+	gobottest.Assert(t, nextGesture(events, time.Second), "data 500")
+	gobottest.Assert(t, nextGesture(events, time.Second), "data 489")
+	gobottest.Assert(t, nextGesture(events, 50*time.Millisecond), "")
+	gobottest.Assert(t, <-values, 50.0)
+	gobottest.Assert(t, <-values, 48.9)
+	gobottest.Assert(t, d.Command("Value")(nil), 48.9)
+}
+
+func TestAnalogSensorDriverThreshold(t *testing.T) {
+	i := &gpioTestAnalogInput{}
+	d := NewAnalogSensorDriver(i, "bot", "1", time.Millisecond)
+	d.SetDeadband(1000)
+	d.SetThreshold(100, 20)
+	events := recordAnalog(d)
+	d.Start()
+	defer d.Halt()
+
+	// the value hovering around the threshold crosses it once
+	for _, val := range []int{50, 110, 95, 105, 85, 70} {
+		i.Set(val)
+		<-time.After(10 * time.Millisecond)
+	}
+	gobottest.Assert(t, nextGesture(events, time.Second), "above 110")
+	gobottest.Assert(t, nextGesture(events, time.Second), "below 70")
+	gobottest.Assert(t, nextGesture(events, 50*time.Millisecond), "")
+}
+
+func TestGroveAnalogDrivers(t *testing.T) {
+	i := &gpioTestAnalogInput{}
+	i.Set(1023)
+	rotary := NewGroveRotaryDriver(i, "rotary", "1", time.Millisecond)
+	values := make(chan interface{}, 100)
+	gobot.On(rotary.Event(Value), func(data interface{}) {
+		values <- data
+	})
+	rotary.Start()
+	defer rotary.Halt()
+	gobottest.Assert(t, <-values, 300.0)
+	gobottest.Assert(t, rotary.Deadband(), 2.0)
+
+	piezo := NewGrovePiezoVibrationSensorDriver(i, "piezo", "1", time.Millisecond)
+	events := recordAnalog(piezo.AnalogSensorDriver)
+	piezo.Start()
+	defer piezo.Halt()
+	gobottest.Assert(t, nextGesture(events, time.Second) != "", true)
+	gobottest.Assert(t, nextGesture(events, time.Second) != "", true)
+
+	gobottest.Assert(t, NewGroveLightSensorDriver(i, "light", "1").Deadband(), 4.0)
+	gobottest.Assert(t, NewGroveSoundSensorDriver(i, "sound", "1").Deadband(), 8.0)
+}
@@ -15,8 +15,10 @@ type ButtonDriver struct {
 	name       string
 	halt       chan bool
 	interval   time.Duration
+	config     PinConfig
 	connection DigitalReader
 	gobot.Eventer
+	*ButtonGestures
 }
 
 // NewButtonDriver returns a new ButtonDriver with a polling interval of
@@ -42,26 +44,50 @@ func NewButtonDriver(a DigitalReader, name string, pin string, v ...time.Duratio
 	b.AddEvent(Push)
 	b.AddEvent(Release)
 	b.AddEvent(Error)
+	b.ButtonGestures = newButtonGestures(b.Eventer)
 
 	return b
 }
 
-// Start starts the ButtonDriver and polls the state of the button at the given interval.
+// SetPinConfig sets the pull resistor, active level and debounce period the
+// button pin is configured with when the ButtonDriver starts, eg. PullUp and
+// ActiveLow for a button connecting the pin to ground. The ButtonDriver
+// inverts the levels of active low buttons itself when the connection is not
+// a PinConfigurer, and debounces them itself when the connection can not.
+func (b *ButtonDriver) SetPinConfig(config PinConfig) { b.config = config }
+
+// PinConfig returns the configuration of the button pin
+func (b *ButtonDriver) PinConfig() PinConfig { return b.config }
+
+// Start configures the button pin, and polls the state of the button at the
+// given interval.
 //
 // Emits the Events:
 // 	Push int - On button push
 //	Release int - On button release
 //	Error error - On button error
+//
+// and the events of ButtonGestures.
 func (b *ButtonDriver) Start() (errs []error) {
-	state := 0
+	invert, debounce, err := configureButtonPin(b.Connection(), b.Pin(), b.config)
+	if err != nil {
+		return []error{err}
+	}
+	if debounce > b.Debounce() {
+		b.SetDebounce(debounce)
+	}
 	go func() {
 		for {
 			newValue, err := b.connection.DigitalRead(b.Pin())
 			if err != nil {
 				gobot.Publish(b.Event(Error), err)
-			} else if newValue != state && newValue != -1 {
-				state = newValue
-				b.update(newValue)
+			} else if newValue != -1 {
+				if invert {
+					newValue ^= 1
+				}
+				if b.ButtonGestures.update(newValue == 1) {
+					b.update(newValue)
+				}
 			}
 			select {
 			case <-time.After(b.interval):
@@ -76,6 +102,7 @@ func (b *ButtonDriver) Start() (errs []error) {
 // Halt stops polling the button for new information
 func (b *ButtonDriver) Halt() (errs []error) {
 	b.halt <- true
+	b.ButtonGestures.stop()
 	return
 }
 
@@ -85,6 +112,9 @@ func (b *ButtonDriver) Name() string { return b.name }
 // Pin returns the ButtonDrivers pin
 func (b *ButtonDriver) Pin() string { return b.pin }
 
+// PinModes returns the ButtonDrivers pin and its "in" mode
+func (b *ButtonDriver) PinModes() map[string]string { return map[string]string{b.pin: "in"} }
+
 // Connection returns the ButtonDrivers Connection
 func (b *ButtonDriver) Connection() gobot.Connection { return b.connection.(gobot.Connection) }
 
@@ -102,3 +102,24 @@ func TestButtonDriverStart(t *testing.T) {
 	}
 
 }
+
+func TestButtonDriverPinConfig(t *testing.T) {
+	var configured PinConfig
+	testAdaptorConfigurePin = func(pin string, config PinConfig) (err error) {
+		configured = config
+		return
+	}
+	defer func() { testAdaptorConfigurePin = func(string, PinConfig) (err error) { return } }()
+
+	d := initTestButtonDriver()
+	d.SetPinConfig(PinConfig{Pull: PullUp, ActiveLow: true})
+	gobottest.Assert(t, d.PinConfig(), PinConfig{Pull: PullUp, ActiveLow: true})
+	gobottest.Assert(t, len(d.Start()), 0)
+	gobottest.Assert(t, configured, PinConfig{Pull: PullUp, ActiveLow: true})
+	d.halt <- true
+
+	testAdaptorConfigurePin = func(string, PinConfig) (err error) {
+		return ErrPullUnsupported
+	}
+	gobottest.Assert(t, d.Start(), []error{ErrPullUnsupported})
+}
@@ -0,0 +1,209 @@
+package gpio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+// ButtonGestures debounces the pushes and releases of a button, and
+// recognizes the clicks, double clicks, long presses and holds they make. It
+// is embedded by the button drivers, which feed it the levels they poll.
+type ButtonGestures struct {
+	eventer         gobot.Eventer
+	mutex           sync.Mutex
+	debounce        time.Duration
+	doubleClickTime time.Duration
+	longPressTime   time.Duration
+	holdInterval    time.Duration
+	pushed          bool
+	candidate       bool
+	changedAt       time.Time
+	pushedAt        time.Time
+	long            bool
+	clickAt         time.Time
+	pressTimer      *time.Timer
+	clickTimer      *time.Timer
+}
+
+// newButtonGestures returns new ButtonGestures publishing to the gesture
+// events it adds to eventer. Pushes are not debounced, a second click within
+// 250 milliseconds makes a double click, long presses last a second, and
+// holds repeat every 250 milliseconds.
+func newButtonGestures(eventer gobot.Eventer) *ButtonGestures {
+	eventer.AddEvent(Click)
+	eventer.AddEvent(DoubleClick)
+	eventer.AddEvent(LongPress)
+	eventer.AddEvent(Hold)
+	return &ButtonGestures{
+		eventer:         eventer,
+		doubleClickTime: 250 * time.Millisecond,
+		longPressTime:   time.Second,
+		holdInterval:    250 * time.Millisecond,
+	}
+}
+
+// SetDebounce sets how long the button has to stay pushed or released for
+// the change to count, filtering out the bouncing of mechanical switches
+func (g *ButtonGestures) SetDebounce(debounce time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.debounce = debounce
+}
+
+// Debounce returns how long the button has to stay pushed or released for
+// the change to count
+func (g *ButtonGestures) Debounce() time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.debounce
+}
+
+// SetDoubleClickTime sets the time within which a second click makes a
+// double click. Clicks are published once it passed without a second click,
+// or right away when it is 0.
+func (g *ButtonGestures) SetDoubleClickTime(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.doubleClickTime = d
+}
+
+// SetLongPressTime sets how long the button has to be pushed for a long
+// press, or 0 to never make one
+func (g *ButtonGestures) SetLongPressTime(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.longPressTime = d
+}
+
+// SetHoldInterval sets the interval at which holds repeat after a long
+// press, or 0 to not repeat them
+func (g *ButtonGestures) SetHoldInterval(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.holdInterval = d
+}
+
+// update feeds the button state polled, and returns whether the debounced
+// state changed.
+//
+// Emits the Events:
+//	Click time.Duration - On a release, with how long the button was pushed
+//	DoubleClick time.Duration - On a second click, with the time between the clicks
+//	LongPress time.Duration - When the button was pushed for the long press time
+//	Hold time.Duration - At the hold interval after a long press, with how long
+//		the button is pushed
+func (g *ButtonGestures) update(pushed bool) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := time.Now()
+	if pushed != g.candidate {
+		g.candidate = pushed
+		g.changedAt = now
+	}
+	if g.candidate == g.pushed || now.Sub(g.changedAt) < g.debounce {
+		return false
+	}
+
+	g.pushed = g.candidate
+	if g.pushed {
+		g.push(now)
+	} else {
+		g.release(now)
+	}
+	return true
+}
+
+// push starts timing a long press. The caller holds the mutex.
+func (g *ButtonGestures) push(now time.Time) {
+	g.pushedAt = now
+	g.long = false
+	g.stopTimer(&g.pressTimer)
+	if g.longPressTime > 0 {
+		g.pressTimer = time.AfterFunc(g.longPressTime, func() { g.hold(now, LongPress) })
+	}
+}
+
+// hold publishes a long press or a hold of the push at pushedAt, while the
+// button is still pushed, and schedules the next hold
+func (g *ButtonGestures) hold(pushedAt time.Time, event string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if !g.pushed || g.pushedAt != pushedAt {
+		return
+	}
+	g.long = true
+	gobot.Publish(g.eventer.Event(event), time.Since(pushedAt))
+	if g.holdInterval > 0 {
+		g.pressTimer = time.AfterFunc(g.holdInterval, func() { g.hold(pushedAt, Hold) })
+	}
+}
+
+// release publishes a click, or a double click of a pending click, unless
+// the push was a long press. The caller holds the mutex.
+func (g *ButtonGestures) release(now time.Time) {
+	g.stopTimer(&g.pressTimer)
+	if g.long {
+		return
+	}
+	if !g.clickAt.IsZero() {
+		g.stopTimer(&g.clickTimer)
+		gobot.Publish(g.eventer.Event(DoubleClick), now.Sub(g.clickAt))
+		g.clickAt = time.Time{}
+		return
+	}
+	pushed := now.Sub(g.pushedAt)
+	if g.doubleClickTime == 0 {
+		gobot.Publish(g.eventer.Event(Click), pushed)
+		return
+	}
+	g.clickAt = now
+	g.clickTimer = time.AfterFunc(g.doubleClickTime, func() {
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		if g.clickAt != now {
+			return
+		}
+		g.clickAt = time.Time{}
+		gobot.Publish(g.eventer.Event(Click), pushed)
+	})
+}
+
+// stop cancels the pending gestures, and forgets the button state
+func (g *ButtonGestures) stop() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.stopTimer(&g.pressTimer)
+	g.stopTimer(&g.clickTimer)
+	g.pushed = false
+	g.candidate = false
+	g.long = false
+	g.clickAt = time.Time{}
+}
+
+// stopTimer stops the timer t points to, if any. The caller holds the mutex.
+func (g *ButtonGestures) stopTimer(t **time.Timer) {
+	if *t != nil {
+		(*t).Stop()
+		*t = nil
+	}
+}
+
+// configureButtonPin applies config to the button pin, and returns whether
+// the levels read need to be inverted and how long to debounce them, for the
+// parts of config the connection can not apply: active low and debouncing
+// are done by the driver when the connection is not a PinConfigurer, and
+// debouncing when the connection does not support it.
+func configureButtonPin(connection gobot.Connection, pin string, config PinConfig) (invert bool, debounce time.Duration, err error) {
+	err = configurePin(connection, pin, config)
+	switch {
+	case err == ErrPinConfigUnsupported && config.Pull == "" && !config.OpenDrain:
+		return config.ActiveLow, config.Debounce, nil
+	case err == ErrDebounceUnsupported:
+		debounce, config.Debounce = config.Debounce, 0
+		return false, debounce, configurePin(connection, pin, config)
+	}
+	return
+}
@@ -0,0 +1,189 @@
+package gpio
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+// recordGestures returns a channel receiving the names of the gesture events
+// of eventer
+func recordGestures(eventer gobot.Eventer) chan string {
+	events := make(chan string, 100)
+	for _, name := range []string{Push, Release, Click, DoubleClick, LongPress, Hold} {
+		name := name
+		gobot.On(eventer.Event(name), func(data interface{}) {
+			events <- name
+		})
+	}
+	return events
+}
+
+// nextGesture returns the name of the next gesture event, or "" when none is
+// published within timeout
+func nextGesture(events chan string, timeout time.Duration) string {
+	select {
+	case name := <-events:
+		return name
+	case <-time.After(timeout):
+		return ""
+	}
+}
+
+// pressButton sets the level of pin "1" of i for d
+func pressButton(i *gpioTestInputs, val int, d time.Duration) {
+	i.Set("1", val)
+	<-time.After(d)
+}
+
+func initTestButtonGestures() (*ButtonDriver, *gpioTestInputs, chan string) {
+	i := &gpioTestInputs{}
+	b := NewButtonDriver(i, "button", "1", time.Millisecond)
+	b.SetDoubleClickTime(50 * time.Millisecond)
+	b.SetLongPressTime(100 * time.Millisecond)
+	b.SetHoldInterval(30 * time.Millisecond)
+	return b, i, recordGestures(b.Eventer)
+}
+
+// assertGestures asserts the events published until none are for a while,
+// in any order as events are published concurrently
+func assertGestures(t *testing.T, events chan string, expected ...string) {
+	names := []string{}
+	for name := nextGesture(events, 100*time.Millisecond); name != ""; name = nextGesture(events, 100*time.Millisecond) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(expected)
+	gobottest.Assert(t, names, expected)
+}
+
+func TestButtonGesturesClick(t *testing.T) {
+	b, i, events := initTestButtonGestures()
+	gobottest.Assert(t, len(b.Start()), 0)
+	defer b.Halt()
+
+	pressButton(i, 1, 10*time.Millisecond)
+	pressButton(i, 0, 0)
+	assertGestures(t, events, Push, Release, Click)
+
+	pressButton(i, 1, 10*time.Millisecond)
+	pressButton(i, 0, 10*time.Millisecond)
+	pressButton(i, 1, 10*time.Millisecond)
+	pressButton(i, 0, 0)
+	assertGestures(t, events, Push, Release, Push, Release, DoubleClick)
+
+	// without a double click time clicks are published right away
+	b.SetDoubleClickTime(0)
+	pressButton(i, 1, 10*time.Millisecond)
+	pressButton(i, 0, 0)
+	for n := 0; n < 3; n++ {
+		gobottest.Refute(t, nextGesture(events, 30*time.Millisecond), "")
+	}
+}
+
+func TestButtonGesturesLongPress(t *testing.T) {
+	b, i, events := initTestButtonGestures()
+	b.Start()
+	defer b.Halt()
+
+	pressButton(i, 1, 170*time.Millisecond)
+	pressButton(i, 0, 0)
+	assertGestures(t, events, Push, LongPress, Hold, Hold, Release)
+
+	b.SetLongPressTime(0)
+	pressButton(i, 1, 150*time.Millisecond)
+	pressButton(i, 0, 0)
+	assertGestures(t, events, Push, Release, Click)
+}
+
+func TestButtonGesturesDebounce(t *testing.T) {
+	b, i, events := initTestButtonGestures()
+	b.SetDebounce(20 * time.Millisecond)
+	gobottest.Assert(t, b.Debounce(), 20*time.Millisecond)
+	b.Start()
+	defer b.Halt()
+
+	// bouncing contacts make a single push and release
+	for n := 0; n < 3; n++ {
+		pressButton(i, 1, 3*time.Millisecond)
+		pressButton(i, 0, 3*time.Millisecond)
+	}
+	pressButton(i, 1, 40*time.Millisecond)
+	for n := 0; n < 3; n++ {
+		pressButton(i, 0, 3*time.Millisecond)
+		pressButton(i, 1, 3*time.Millisecond)
+	}
+	pressButton(i, 0, 40*time.Millisecond)
+	assertGestures(t, events, Push, Release, Click)
+}
+
+func TestButtonDriverActiveLow(t *testing.T) {
+	i := &gpioTestInputs{}
+	i.Set("1", 1)
+	b := NewButtonDriver(i, "button", "1", time.Millisecond)
+	b.SetPinConfig(PinConfig{ActiveLow: true, Debounce: 5 * time.Millisecond})
+	events := recordGestures(b.Eventer)
+
+	// the connection is not a PinConfigurer, so the driver inverts the levels
+	gobottest.Assert(t, len(b.Start()), 0)
+	defer b.Halt()
+	gobottest.Assert(t, b.Debounce(), 5*time.Millisecond)
+	gobottest.Assert(t, nextGesture(events, 20*time.Millisecond), "")
+
+	pressButton(i, 0, 20*time.Millisecond)
+	gobottest.Assert(t, nextGesture(events, time.Second), Push)
+
+	// pull resistors can not be done by the driver
+	b = NewButtonDriver(i, "button", "1")
+	b.SetPinConfig(PinConfig{Pull: PullUp, ActiveLow: true})
+	gobottest.Assert(t, b.Start(), []error{ErrPinConfigUnsupported})
+}
+
+func TestButtonDriverDebounceUnsupported(t *testing.T) {
+	var configured PinConfig
+	testAdaptorConfigurePin = func(pin string, config PinConfig) (err error) {
+		if config.Debounce > 0 {
+			return ErrDebounceUnsupported
+		}
+		configured = config
+		return
+	}
+	defer func() { testAdaptorConfigurePin = func(string, PinConfig) (err error) { return } }()
+
+	b := initTestButtonDriver()
+	b.SetPinConfig(PinConfig{Pull: PullUp, ActiveLow: true, Debounce: 10 * time.Millisecond})
+	gobottest.Assert(t, len(b.Start()), 0)
+	gobottest.Assert(t, configured, PinConfig{Pull: PullUp, ActiveLow: true})
+	gobottest.Assert(t, b.Debounce(), 10*time.Millisecond)
+	b.Halt()
+}
+
+func TestMakeyButtonDriverGestures(t *testing.T) {
+	i := &gpioTestInputs{}
+	i.Set("1", 1)
+	m := NewMakeyButtonDriver(i, "makey", "1", time.Millisecond)
+	m.SetDoubleClickTime(0)
+	events := recordGestures(m.Eventer)
+	m.Start()
+	defer m.Halt()
+
+	pressButton(i, 0, 10*time.Millisecond)
+	pressButton(i, 1, 10*time.Millisecond)
+	assertGestures(t, events, Push, Release, Click)
+}
+
+func TestGroveButtonDriverGestures(t *testing.T) {
+	i := &gpioTestInputs{}
+	g := NewGroveTouchDriver(i, "touch", "1", time.Millisecond)
+	g.SetDoubleClickTime(0)
+	events := recordGestures(g.Eventer)
+	g.Start()
+	defer g.Halt()
+
+	pressButton(i, 1, 10*time.Millisecond)
+	pressButton(i, 0, 10*time.Millisecond)
+	assertGestures(t, events, Push, Release, Click)
+}
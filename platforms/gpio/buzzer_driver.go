@@ -1,6 +1,11 @@
 package gpio
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/potix/gobot"
@@ -127,16 +132,51 @@ const (
 
 var _ gobot.Driver = (*BuzzerDriver)(nil)
 
+// buzzerNoteGap is the silence ending each note of a melody, so that
+// repeated notes are heard apart
+var buzzerNoteGap = 10 * time.Millisecond
+
+// Note is a note of a melody
+type Note struct {
+	// Frequency is the pitch of the note in hertz, such as A4, or Rest for a
+	// silence
+	Frequency float64
+	// Duration is the length of the note in beats, such as Quarter
+	Duration float64
+}
+
+// Melody is a sequence of notes played at a tempo
+type Melody struct {
+	Name  string
+	BPM   float64
+	Notes []Note
+}
+
 // BuzzerDriver represents a digital buzzer
+//
+// The buzzer is driven by a pwm signal of the frequency of the notes when the
+// connection is a PwmPulseWriter able to generate it on the pin, and by
+// toggling its pin otherwise.
 type BuzzerDriver struct {
 	pin        string
 	name       string
 	connection DigitalWriter
+	mutex      sync.Mutex
 	high       bool
+	pwmPeriod  time.Duration
+	playing    chan bool
+	wg         sync.WaitGroup
 	BPM        float64
+	gobot.Commander
+	gobot.Eventer
 }
 
 // NewBuzzerDriver return a new BuzzerDriver given a DigitalWriter, name and pin.
+//
+// Adds the following API Commands:
+//	"Play" - See BuzzerDriver.PlayRTTTL, given an "rtttl" ringtone, or
+//		BuzzerDriver.Play, given "notes" as [frequency, duration] pairs
+//	"Stop" - See BuzzerDriver.Stop
 func NewBuzzerDriver(a DigitalWriter, name string, pin string) *BuzzerDriver {
 	l := &BuzzerDriver{
 		name:       name,
@@ -144,16 +184,46 @@ func NewBuzzerDriver(a DigitalWriter, name string, pin string) *BuzzerDriver {
 		connection: a,
 		high:       false,
 		BPM:        96.0,
+		Commander:  gobot.NewCommander(),
+		Eventer:    gobot.NewEventer(),
 	}
 
+	l.AddEvent(Done)
+	l.AddEvent(Error)
+
+	l.AddCommand("Play", func(params map[string]interface{}) interface{} {
+		if ringtone, ok := params["rtttl"].(string); ok {
+			return l.PlayRTTTL(ringtone)
+		}
+		notes := []Note{}
+		if pairs, ok := params["notes"].([]interface{}); ok {
+			for _, pair := range pairs {
+				if p, ok := pair.([]interface{}); ok && len(p) == 2 {
+					frequency, _ := p[0].(float64)
+					duration, _ := p[1].(float64)
+					notes = append(notes, Note{Frequency: frequency, Duration: duration})
+				}
+			}
+		}
+		return l.Play(notes)
+	})
+	l.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		return l.Stop()
+	})
+
 	return l
 }
 
 // Start implements the Driver interface
 func (l *BuzzerDriver) Start() (errs []error) { return }
 
-// Halt implements the Driver interface
-func (l *BuzzerDriver) Halt() (errs []error) { return }
+// Halt stops the melody playing
+func (l *BuzzerDriver) Halt() (errs []error) {
+	if err := l.Stop(); err != nil {
+		return []error{err}
+	}
+	return
+}
 
 // Name returns the BuzzerDrivers name
 func (l *BuzzerDriver) Name() string { return l.name }
@@ -161,6 +231,15 @@ func (l *BuzzerDriver) Name() string { return l.name }
 // Pin returns the BuzzerDrivers name
 func (l *BuzzerDriver) Pin() string { return l.pin }
 
+// PinModes returns the BuzzerDrivers pin and its "pwm" mode when the
+// connection is a PwmPulseWriter, or its "out" mode
+func (l *BuzzerDriver) PinModes() map[string]string {
+	if _, ok := l.connection.(PwmPulseWriter); ok {
+		return map[string]string{l.pin: "pwm"}
+	}
+	return map[string]string{l.pin: "out"}
+}
+
 // Connection returns the BuzzerDrivers Connection
 func (l *BuzzerDriver) Connection() gobot.Connection {
 	return l.connection.(gobot.Connection)
@@ -168,25 +247,19 @@ func (l *BuzzerDriver) Connection() gobot.Connection {
 
 // State return true if the buzzer is On and false if the led is Off
 func (l *BuzzerDriver) State() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	return l.high
 }
 
 // On sets the buzzer to a high state.
 func (l *BuzzerDriver) On() (err error) {
-	if err = l.connection.DigitalWrite(l.Pin(), 1); err != nil {
-		return
-	}
-	l.high = true
-	return
+	return l.write(1)
 }
 
 // Off sets the buzzer to a low state.
 func (l *BuzzerDriver) Off() (err error) {
-	if err = l.connection.DigitalWrite(l.Pin(), 0); err != nil {
-		return
-	}
-	l.high = false
-	return
+	return l.write(0)
 }
 
 // Toggle sets the buzzer to the opposite of it's current state
@@ -199,23 +272,307 @@ func (l *BuzzerDriver) Toggle() (err error) {
 	return
 }
 
+// write sets the level of the buzzer pin
+func (l *BuzzerDriver) write(level byte) (err error) {
+	if err = l.connection.DigitalWrite(l.Pin(), level); err != nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.high = level == 1
+	return
+}
+
+// Tone plays a tone of hz for duration beats at the BPM of the buzzer, and
+// returns once it is over
 func (l *BuzzerDriver) Tone(hz, duration float64) (err error) {
-	// calculation based off https://www.arduino.cc/en/Tutorial/Melody
-	tone := (1.0 / (2.0 * hz)) * 1000000.0
+	if _, err = l.tone(hz, l.beats(l.BPM, duration), nil); err != nil {
+		return
+	}
+	return l.silence()
+}
+
+// Play plays notes at the BPM of the buzzer in the background, stopping the
+// melody playing.
+//
+// Emits the Events:
+//	Done - Once the melody is over
+//	Error error - On a write error, which stops the melody
+func (l *BuzzerDriver) Play(notes []Note) error {
+	return l.PlayMelody(Melody{BPM: l.BPM, Notes: notes})
+}
+
+// PlayRTTTL plays an RTTTL ringtone in the background like Play, such as
+// "scale:d=4,o=5,b=120:c,d,e,f,g,a,b,c6"
+func (l *BuzzerDriver) PlayRTTTL(ringtone string) error {
+	melody, err := ParseRTTTL(ringtone)
+	if err != nil {
+		return err
+	}
+	return l.PlayMelody(melody)
+}
+
+// PlayMelody plays melody in the background like Play, at the tempo of the
+// melody
+func (l *BuzzerDriver) PlayMelody(melody Melody) error {
+	if err := l.Stop(); err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	playing := make(chan bool)
+	l.playing = playing
+	l.wg.Add(1)
+	go l.play(melody, playing)
+	return nil
+}
+
+// Stop stops the melody playing, and silences the buzzer
+func (l *BuzzerDriver) Stop() error {
+	l.mutex.Lock()
+	if l.playing == nil {
+		l.mutex.Unlock()
+		return nil
+	}
+	close(l.playing)
+	l.playing = nil
+	l.mutex.Unlock()
+	l.wg.Wait()
+	return l.silence()
+}
 
-	tempo := ((60 / l.BPM) * (duration * 1000))
+// IsPlaying returns whether a melody is playing
+func (l *BuzzerDriver) IsPlaying() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.playing != nil
+}
 
-	for i := 0.0; i < tempo*1000; i += tone * 2.0 {
-		if err = l.On(); err != nil {
+// play plays the notes of melody until they are over or playing is closed
+func (l *BuzzerDriver) play(melody Melody, playing chan bool) {
+	defer l.wg.Done()
+	defer func() {
+		l.mutex.Lock()
+		if l.playing == playing {
+			l.playing = nil
+		}
+		l.mutex.Unlock()
+	}()
+
+	for _, note := range melody.Notes {
+		duration := l.beats(melody.BPM, note.Duration)
+		gap := buzzerNoteGap
+		if gap > duration/2 {
+			gap = 0
+		}
+		stopped, err := l.tone(note.Frequency, duration-gap, playing)
+		if err == nil && !stopped {
+			err = l.silence()
+		}
+		if err != nil {
+			gobot.Publish(l.Event(Error), err)
 			return
 		}
-		<-time.After(time.Duration(tone) * time.Microsecond)
+		if stopped {
+			return
+		}
+		select {
+		case <-time.After(gap):
+		case <-playing:
+			return
+		}
+	}
+	gobot.Publish(l.Event(Done), nil)
+}
 
-		if err = l.Off(); err != nil {
+// beats returns how long beats last at bpm
+func (l *BuzzerDriver) beats(bpm float64, beats float64) time.Duration {
+	return time.Duration(60 / bpm * beats * float64(time.Second))
+}
+
+// tone sounds hz for duration, or stays silent for a Rest, leaving the buzzer
+// to be silenced. It returns early with stopped true when stop is closed.
+func (l *BuzzerDriver) tone(hz float64, duration time.Duration, stop chan bool) (stopped bool, err error) {
+	end := time.After(duration)
+	if hz <= 0 {
+		select {
+		case <-end:
+		case <-stop:
+			stopped = true
+		}
+		return
+	}
+
+	period := time.Duration(float64(time.Second) / hz)
+	if l.pwmTone(period) {
+		select {
+		case <-end:
+		case <-stop:
+			stopped = true
+		}
+		return
+	}
+
+	// calculation based off https://www.arduino.cc/en/Tutorial/Melody
+	ticker := time.NewTicker(period / 2)
+	defer ticker.Stop()
+	for {
+		if err = l.Toggle(); err != nil {
 			return
 		}
-		<-time.After(time.Duration(tone) * time.Microsecond)
+		select {
+		case <-ticker.C:
+		case <-end:
+			return false, nil
+		case <-stop:
+			return true, nil
+		}
+	}
+}
+
+// pwmTone drives the buzzer with a pwm signal of period, returning false when
+// the connection can not generate it on the pin, so that the pin is toggled
+// instead
+func (l *BuzzerDriver) pwmTone(period time.Duration) bool {
+	writer, ok := l.connection.(PwmPulseWriter)
+	if !ok || writer.PwmPulseWrite(l.Pin(), period, period/2) != nil {
+		return false
 	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.pwmPeriod = period
+	return true
+}
 
+// silence stops the buzzer sounding, by clearing the pulses of the pwm signal
+// driving it or by setting its pin low
+func (l *BuzzerDriver) silence() error {
+	l.mutex.Lock()
+	period := l.pwmPeriod
+	l.pwmPeriod = 0
+	l.mutex.Unlock()
+	if period > 0 {
+		return l.connection.(PwmPulseWriter).PwmPulseWrite(l.Pin(), period, 0)
+	}
+	return l.Off()
+}
+
+// rtttlNotes are the semitones of the RTTTL notes above c
+var rtttlNotes = map[byte]int{
+	'c': 0, 'd': 2, 'e': 4, 'f': 5, 'g': 7, 'a': 9, 'b': 11, 'h': 11,
+}
+
+// ParseRTTTL parses an RTTTL ringtone of a name, defaults for the duration
+// "d", octave "o" and beats per minute "b", and notes, such as
+// "scale:d=4,o=5,b=120:c,d,e,f,g,a,b,c6". The notes are a duration of a
+// whole note divided by 1 through 32, a note from a through g, h for b or p
+// for a pause, "#" for a sharp, an octave from 0 through 8, and "." to make
+// them last half as long again.
+func ParseRTTTL(ringtone string) (melody Melody, err error) {
+	sections := strings.Split(ringtone, ":")
+	if len(sections) != 3 {
+		return melody, fmt.Errorf("Invalid RTTTL ringtone %q, expected name:defaults:notes", ringtone)
+	}
+	melody.Name = strings.TrimSpace(sections[0])
+	melody.BPM = 63
+	duration, octave := 4, 6
+
+	for _, def := range strings.Split(sections[1], ",") {
+		def = strings.ToLower(strings.TrimSpace(def))
+		if def == "" {
+			continue
+		}
+		kv := strings.SplitN(def, "=", 2)
+		if len(kv) != 2 {
+			return melody, fmt.Errorf("Invalid RTTTL default %q", def)
+		}
+		val, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || val <= 0 {
+			return melody, fmt.Errorf("Invalid RTTTL default %q", def)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "d":
+			duration = val
+		case "o":
+			octave = val
+		case "b":
+			melody.BPM = float64(val)
+		default:
+			return melody, fmt.Errorf("Invalid RTTTL default %q", def)
+		}
+	}
+
+	for _, token := range strings.Split(sections[2], ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		note, err := parseRTTTLNote(token, duration, octave)
+		if err != nil {
+			return melody, err
+		}
+		melody.Notes = append(melody.Notes, note)
+	}
+	return
+}
+
+// parseRTTTLNote parses an RTTTL note, given the default duration and octave
+func parseRTTTLNote(token string, duration int, octave int) (note Note, err error) {
+	invalid := fmt.Errorf("Invalid RTTTL note %q", token)
+	i := 0
+	number := func() (n int, ok bool) {
+		start := i
+		for i < len(token) && token[i] >= '0' && token[i] <= '9' {
+			i++
+		}
+		n, e := strconv.Atoi(token[start:i])
+		return n, e == nil
+	}
+
+	if d, ok := number(); ok {
+		duration = d
+	}
+	if duration <= 0 || duration > 32 || i == len(token) {
+		return note, invalid
+	}
+
+	semitone, pause := 0, token[i] == 'p'
+	if !pause {
+		var ok bool
+		if semitone, ok = rtttlNotes[token[i]]; !ok {
+			return note, invalid
+		}
+	}
+	i++
+	if i < len(token) && token[i] == '#' {
+		semitone++
+		i++
+	}
+	dotted := false
+	if i < len(token) && token[i] == '.' {
+		dotted = true
+		i++
+	}
+	if o, ok := number(); ok {
+		octave = o
+	}
+	if i < len(token) && token[i] == '.' {
+		dotted = true
+		i++
+	}
+	if i != len(token) || octave > 8 {
+		return note, invalid
+	}
+
+	// a whole note lasts Whole beats
+	note.Duration = Whole / float64(duration)
+	if dotted {
+		note.Duration *= 1.5
+	}
+	if !pause {
+		// semitones from A4
+		n := float64(semitone + 12*(octave-4) - 9)
+		note.Frequency = A4 * math.Pow(2, n/12)
+	}
 	return
 }
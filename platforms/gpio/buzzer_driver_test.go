@@ -0,0 +1,139 @@
+package gpio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+// roundNotes rounds the frequencies of notes to those of the note constants
+func roundNotes(notes []Note) []Note {
+	rounded := []Note{}
+	for _, n := range notes {
+		rounded = append(rounded, Note{Frequency: math.Floor(n.Frequency*100+0.5) / 100, Duration: n.Duration})
+	}
+	return rounded
+}
+
+// waitForBuzzer waits for the event name of the buzzer, and returns its data
+func waitForBuzzer(t *testing.T, l *BuzzerDriver, name string) interface{} {
+	events := make(chan interface{}, 1)
+	gobot.Once(l.Event(name), func(data interface{}) {
+		events <- data
+	})
+	select {
+	case data := <-events:
+		return data
+	case <-time.After(time.Second):
+		t.Errorf("no %v event", name)
+	}
+	return nil
+}
+
+func TestBuzzerDriver(t *testing.T) {
+	l := NewBuzzerDriver(&gpioTestRecorder{}, "buzzer", "1")
+	gobottest.Assert(t, l.Name(), "buzzer")
+	gobottest.Assert(t, l.Pin(), "1")
+	gobottest.Assert(t, l.PinModes(), map[string]string{"1": "out"})
+	gobottest.Assert(t, len(l.Start()), 0)
+	gobottest.Assert(t, len(l.Halt()), 0)
+
+	gobottest.Assert(t, l.On(), nil)
+	gobottest.Assert(t, l.State(), true)
+	gobottest.Assert(t, l.Toggle(), nil)
+	gobottest.Assert(t, l.State(), false)
+}
+
+func TestParseRTTTL(t *testing.T) {
+	melody, err := ParseRTTTL("Scale: d=4, o=5, b=120: c, 8d#., 2p, a4, 16b.6, 32h")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, melody.Name, "Scale")
+	gobottest.Assert(t, melody.BPM, 120.0)
+	gobottest.Assert(t, roundNotes(melody.Notes), []Note{
+		{C5, Quarter},
+		{Eb5, Eighth * 1.5},
+		{Rest, Half},
+		{A4, Quarter},
+		{B6, 0.375},
+		{B5, 0.125},
+	})
+
+	// the defaults default to a quarter note, the sixth octave and 63 bpm
+	melody, err = ParseRTTTL("::a")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, melody.BPM, 63.0)
+	gobottest.Assert(t, roundNotes(melody.Notes), []Note{{A6, Quarter}})
+
+	_, err = ParseRTTTL("scale:c,d")
+	gobottest.Assert(t, err, errors.New("Invalid RTTTL ringtone \"scale:c,d\", expected name:defaults:notes"))
+	_, err = ParseRTTTL("scale:x=4:c")
+	gobottest.Assert(t, err, errors.New("Invalid RTTTL default \"x=4\""))
+	_, err = ParseRTTTL("scale:b=fast:c")
+	gobottest.Assert(t, err, errors.New("Invalid RTTTL default \"b=fast\""))
+	for _, note := range []string{"x", "64c", "8", "c9", "c#5x"} {
+		_, err = ParseRTTTL("scale::" + note)
+		gobottest.Assert(t, err, errors.New("Invalid RTTTL note \""+note+"\""))
+	}
+}
+
+func TestBuzzerDriverPlayPwm(t *testing.T) {
+	r := &gpioTestPulseRecorder{}
+	l := NewBuzzerDriver(r, "buzzer", "1")
+	l.BPM = 1200
+
+	gobottest.Assert(t, l.Play([]Note{{A4, Quarter}, {Rest, Quarter}, {A5, Quarter}}), nil)
+	gobottest.Assert(t, l.IsPlaying(), true)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, r.Period("1"), time.Second/440)
+	gobottest.Assert(t, r.Width("1"), time.Second/440/2)
+	waitForBuzzer(t, l, Done)
+	gobottest.Assert(t, l.IsPlaying(), false)
+	gobottest.Assert(t, r.Width("1"), time.Duration(0))
+
+	// melodies are stopped, and the buzzer silenced
+	gobottest.Assert(t, l.Command("Play")(map[string]interface{}{"rtttl": "a:d=1,b=30:a"}), nil)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, r.Period("1"), time.Second/1760)
+	gobottest.Assert(t, l.Command("Stop")(nil), nil)
+	gobottest.Assert(t, l.IsPlaying(), false)
+	gobottest.Assert(t, r.Width("1"), time.Duration(0))
+
+	gobottest.Assert(t, l.Command("Play")(map[string]interface{}{"rtttl": "a"}), errors.New("Invalid RTTTL ringtone \"a\", expected name:defaults:notes"))
+
+	gobottest.Assert(t, l.PinModes(), map[string]string{"1": "pwm"})
+
+	// the pin is toggled when the pwm can not generate the frequency
+	r = &gpioTestPulseRecorder{period: 10 * time.Millisecond}
+	l = NewBuzzerDriver(r, "buzzer", "1")
+	gobottest.Assert(t, l.Tone(A4, 0.1), nil)
+	gobottest.Assert(t, r.Width("1"), time.Duration(0))
+	gobottest.Assert(t, len(r.Writes()) > 2, true)
+	gobottest.Assert(t, r.Levels("1"), []byte{0})
+}
+
+func TestBuzzerDriverPlayDigital(t *testing.T) {
+	r := &gpioTestRecorder{}
+	l := NewBuzzerDriver(r, "buzzer", "1")
+	l.BPM = 600
+
+	notes := []interface{}{[]interface{}{1000.0, 1.0}, []interface{}{0.0, 1.0}}
+	gobottest.Assert(t, l.Command("Play")(map[string]interface{}{"notes": notes}), nil)
+	waitForBuzzer(t, l, Done)
+	// the pin is toggled at twice the frequency, for the 90 milliseconds of
+	// the note before the gap
+	writes := len(r.Writes())
+	gobottest.Assert(t, writes > 20 && writes <= 182, true)
+	gobottest.Assert(t, r.Levels("1"), []byte{0})
+	gobottest.Assert(t, l.State(), false)
+
+	r.SetError(errors.New("write error"))
+	gobottest.Assert(t, l.Play([]Note{{C4, Whole}}), nil)
+	gobottest.Assert(t, waitForBuzzer(t, l, Error), errors.New("write error"))
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, l.IsPlaying(), false)
+	gobottest.Assert(t, l.Tone(C4, Quarter), errors.New("write error"))
+}
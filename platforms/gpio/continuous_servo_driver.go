@@ -0,0 +1,105 @@
+package gpio
+
+import (
+	"math"
+	"sync"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*ContinuousServoDriver)(nil)
+
+// ContinuousServoDriver represents a continuous rotation servo, which turns
+// at a speed instead of to an angle. It stands still at the center of its
+// calibration, and turns at full speed at either end of it.
+type ContinuousServoDriver struct {
+	servo *ServoDriver
+	mutex sync.Mutex
+	speed float64
+	gobot.Commander
+}
+
+// NewContinuousServoDriver returns a new ContinuousServoDriver given a
+// ServoWriter, name and pin.
+//
+// Adds the following API Commands:
+//	"Run" - See ContinuousServoDriver.Run
+//	"Stop" - See ContinuousServoDriver.Stop
+//	"Speed" - See ContinuousServoDriver.Speed
+func NewContinuousServoDriver(a ServoWriter, name string, pin string) *ContinuousServoDriver {
+	c := &ContinuousServoDriver{
+		servo:     NewServoDriver(a, name, pin),
+		Commander: gobot.NewCommander(),
+	}
+
+	c.AddCommand("Run", func(params map[string]interface{}) interface{} {
+		return c.Run(params["speed"].(float64))
+	})
+	c.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		return c.Stop()
+	})
+	c.AddCommand("Speed", func(params map[string]interface{}) interface{} {
+		return c.Speed()
+	})
+
+	return c
+}
+
+// Name returns the ContinuousServoDrivers name
+func (c *ContinuousServoDriver) Name() string { return c.servo.Name() }
+
+// Pin returns the ContinuousServoDrivers pin
+func (c *ContinuousServoDriver) Pin() string { return c.servo.Pin() }
+
+// PinModes returns the ContinuousServoDrivers pin and its "servo" mode
+func (c *ContinuousServoDriver) PinModes() map[string]string { return c.servo.PinModes() }
+
+// Connection returns the ContinuousServoDrivers connection
+func (c *ContinuousServoDriver) Connection() gobot.Connection { return c.servo.Connection() }
+
+// Start stops the servo
+func (c *ContinuousServoDriver) Start() (errs []error) {
+	if err := c.Stop(); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// Halt stops the servo
+func (c *ContinuousServoDriver) Halt() (errs []error) {
+	if err := c.Stop(); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// SetCalibration sets the calibration of the servo, whose center is where the
+// servo stands still. Trim corrects a servo creeping when it should not.
+func (c *ContinuousServoDriver) SetCalibration(calibration ServoCalibration) error {
+	return c.servo.SetCalibration(calibration)
+}
+
+// Run turns the servo at speed, from -1 for full speed backward through 0 to
+// 1 for full speed forward. Speeds beyond are limited to full speed.
+func (c *ContinuousServoDriver) Run(speed float64) (err error) {
+	speed = math.Max(-1, math.Min(1, speed))
+	calibration := c.servo.Calibration()
+	center := (calibration.MinAngle + calibration.MaxAngle) / 2
+	if err = c.servo.write(center + speed*(calibration.MaxAngle-center)); err != nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.speed = speed
+	return
+}
+
+// Stop stops the servo turning
+func (c *ContinuousServoDriver) Stop() error { return c.Run(0) }
+
+// Speed returns the speed the servo turns at, from -1 through 1
+func (c *ContinuousServoDriver) Speed() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.speed
+}
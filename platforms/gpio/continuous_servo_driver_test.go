@@ -0,0 +1,45 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestContinuousServoDriver(t *testing.T) {
+	r := &gpioTestPulseRecorder{}
+	c := NewContinuousServoDriver(r, "wheel", "1")
+
+	gobottest.Assert(t, c.Name(), "wheel")
+	gobottest.Assert(t, c.Pin(), "1")
+	gobottest.Assert(t, c.PinModes(), map[string]string{"1": "servo"})
+
+	gobottest.Assert(t, len(c.Start()), 0)
+	gobottest.Assert(t, r.Width("1"), 1500*time.Microsecond)
+
+	gobottest.Assert(t, c.Run(1), nil)
+	gobottest.Assert(t, r.Width("1"), 2500*time.Microsecond)
+	gobottest.Assert(t, c.Command("Run")(map[string]interface{}{"speed": -0.5}), nil)
+	gobottest.Assert(t, r.Width("1"), 1000*time.Microsecond)
+	gobottest.Assert(t, c.Command("Speed")(nil), -0.5)
+	gobottest.Assert(t, c.Run(-2), nil)
+	gobottest.Assert(t, r.Width("1"), 500*time.Microsecond)
+	gobottest.Assert(t, c.Speed(), -1.0)
+
+	// the trim corrects a servo creeping when it should stand still
+	gobottest.Assert(t, c.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MaxAngle: 180, Trim: -9}), nil)
+	gobottest.Assert(t, c.Command("Stop")(nil), nil)
+	gobottest.Assert(t, r.Width("1"), 1450*time.Microsecond)
+	gobottest.Assert(t, c.Speed(), 0.0)
+
+	c.Run(1)
+	gobottest.Assert(t, len(c.Halt()), 0)
+	gobottest.Assert(t, c.Speed(), 0.0)
+
+	r.SetError(errors.New("write error"))
+	gobottest.Assert(t, c.Run(1), errors.New("write error"))
+	gobottest.Assert(t, c.Speed(), 0.0)
+	gobottest.Assert(t, c.Halt(), []error{errors.New("write error")})
+}
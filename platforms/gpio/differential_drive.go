@@ -0,0 +1,307 @@
+package gpio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*DifferentialDrive)(nil)
+
+// rampInterval is the interval at which a DifferentialDrive ramps the speeds
+// of its motors
+var rampInterval = 20 * time.Millisecond
+
+// DifferentialDrive represents a rover steered by the speeds of its left and
+// right motors. The motors are started and halted by the DifferentialDrive,
+// so only the DifferentialDrive is added to the robot.
+//
+// Speeds run from -1 for full speed backward through 1 for full speed
+// forward, and angular speeds from -1 for a full speed right turn through 1
+// for a full speed left turn.
+type DifferentialDrive struct {
+	name       string
+	left       *HBridgeDriver
+	right      *HBridgeDriver
+	mutex      sync.Mutex
+	leftTrim   float64
+	rightTrim  float64
+	trackWidth float64
+	ramp       float64
+	target     [2]float64
+	current    [2]float64
+	running    bool
+	halt       chan bool
+	wg         sync.WaitGroup
+	gobot.Commander
+	gobot.Eventer
+}
+
+// NewDifferentialDrive returns a new DifferentialDrive given a name and its
+// left and right motors.
+//
+// Adds the following API Commands:
+//	"Drive" - See DifferentialDrive.Drive
+//	"Arc" - See DifferentialDrive.Arc
+//	"Spin" - See DifferentialDrive.Spin
+//	"Stop" - See DifferentialDrive.Stop
+//	"Brake" - See DifferentialDrive.Brake
+//	"SetTrim" - See DifferentialDrive.SetTrim
+//	"Speeds" - See DifferentialDrive.Speeds
+func NewDifferentialDrive(name string, left *HBridgeDriver, right *HBridgeDriver) *DifferentialDrive {
+	d := &DifferentialDrive{
+		name:       name,
+		left:       left,
+		right:      right,
+		leftTrim:   1,
+		rightTrim:  1,
+		trackWidth: 1,
+		halt:       make(chan bool),
+		Commander:  gobot.NewCommander(),
+		Eventer:    gobot.NewEventer(),
+	}
+
+	d.AddEvent(Error)
+
+	d.AddCommand("Drive", func(params map[string]interface{}) interface{} {
+		return d.Drive(params["linear"].(float64), params["angular"].(float64))
+	})
+	d.AddCommand("Arc", func(params map[string]interface{}) interface{} {
+		return d.Arc(params["speed"].(float64), params["radius"].(float64))
+	})
+	d.AddCommand("Spin", func(params map[string]interface{}) interface{} {
+		return d.Spin(params["speed"].(float64))
+	})
+	d.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		return d.Stop()
+	})
+	d.AddCommand("Brake", func(params map[string]interface{}) interface{} {
+		return d.Brake()
+	})
+	d.AddCommand("SetTrim", func(params map[string]interface{}) interface{} {
+		d.SetTrim(params["left"].(float64), params["right"].(float64))
+		return nil
+	})
+	d.AddCommand("Speeds", func(params map[string]interface{}) interface{} {
+		left, right := d.Speeds()
+		return map[string]interface{}{"left": left, "right": right}
+	})
+
+	return d
+}
+
+// Name returns the DifferentialDrives name
+func (d *DifferentialDrive) Name() string { return d.name }
+
+// Connection returns the Connection of the left motor
+func (d *DifferentialDrive) Connection() gobot.Connection { return d.left.Connection() }
+
+// PinModes returns the pins of both motors and their modes
+func (d *DifferentialDrive) PinModes() map[string]string {
+	modes := d.left.PinModes()
+	for pin, mode := range d.right.PinModes() {
+		modes[pin] = mode
+	}
+	return modes
+}
+
+// Left returns the left motor
+func (d *DifferentialDrive) Left() *HBridgeDriver { return d.left }
+
+// Right returns the right motor
+func (d *DifferentialDrive) Right() *HBridgeDriver { return d.right }
+
+// Start starts both motors
+func (d *DifferentialDrive) Start() (errs []error) {
+	if errs = d.left.Start(); len(errs) > 0 {
+		return
+	}
+	return d.right.Start()
+}
+
+// Halt stops ramping, and halts both motors
+func (d *DifferentialDrive) Halt() (errs []error) {
+	d.stopRamp()
+	errs = append(errs, d.left.Halt()...)
+	return append(errs, d.right.Halt()...)
+}
+
+// SetTrim sets the factors the speeds of the left and right motors are
+// multiplied by, eg. 0.95 to slow down the faster motor of a rover which
+// pulls to one side. Both default to 1.
+func (d *DifferentialDrive) SetTrim(left float64, right float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.leftTrim, d.rightTrim = left, right
+}
+
+// SetRamp sets the rate, in speed per second, at which the speeds of the
+// motors change, eg. 2 to go from standstill to full speed in half a second,
+// or 0 to change speeds right away, which is the default
+func (d *DifferentialDrive) SetRamp(rate float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.ramp = math.Abs(rate)
+}
+
+// SetTrackWidth sets the distance between the wheels, in the unit of the
+// radius of Arc. It defaults to 1, measuring radii in track widths.
+func (d *DifferentialDrive) SetTrackWidth(width float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.trackWidth = width
+}
+
+// Speeds returns the current speeds of the left and right motors, before
+// trimming
+func (d *DifferentialDrive) Speeds() (left float64, right float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.current[0], d.current[1]
+}
+
+// Drive drives at the linear speed, turning at the angular speed. Speeds
+// the motors can not reach are scaled down, keeping the ratio of the motor
+// speeds, so turns keep their radius.
+//
+// Emits the Events:
+//	Error error - On a motor error while ramping
+func (d *DifferentialDrive) Drive(linear float64, angular float64) error {
+	return d.setSpeeds(linear-angular, linear+angular)
+}
+
+// Arc drives at speed along an arc of radius, turning left for a positive
+// radius and right for a negative one. A radius of 0 spins in place.
+func (d *DifferentialDrive) Arc(speed float64, radius float64) error {
+	if radius == 0 {
+		return d.Spin(speed)
+	}
+	d.mutex.Lock()
+	half := d.trackWidth / 2
+	d.mutex.Unlock()
+	return d.setSpeeds(speed*(radius-half)/radius, speed*(radius+half)/radius)
+}
+
+// Spin spins in place at speed, to the left for a positive speed
+func (d *DifferentialDrive) Spin(speed float64) error {
+	return d.setSpeeds(-speed, speed)
+}
+
+// DriveJoystick drives with the x and y axes of a joystick, as published by
+// the joystick driver: pushing the stick forward drives forward, and
+// pushing it sideways turns. Small deflections of the stick are ignored.
+func (d *DifferentialDrive) DriveJoystick(x int16, y int16) error {
+	axis := func(v int16) float64 {
+		f := math.Max(-1, float64(v)/32767)
+		if math.Abs(f) < 0.05 {
+			return 0
+		}
+		return f
+	}
+	return d.Drive(-axis(y), -axis(x))
+}
+
+// Stop ramps both motors down, letting them coast once stopped
+func (d *DifferentialDrive) Stop() error {
+	return d.setSpeeds(0, 0)
+}
+
+// Brake stops ramping and brakes both motors
+func (d *DifferentialDrive) Brake() (err error) {
+	d.stopRamp()
+	d.mutex.Lock()
+	d.target = [2]float64{}
+	d.current = [2]float64{}
+	d.mutex.Unlock()
+	if err = d.left.Brake(); err != nil {
+		return
+	}
+	return d.right.Brake()
+}
+
+// setSpeeds sets the target speeds of the motors, scaled down to the range
+// of the motors, and changes the motor speeds to them right away, or starts
+// ramping to them
+func (d *DifferentialDrive) setSpeeds(left float64, right float64) error {
+	if max := math.Max(math.Abs(left), math.Abs(right)); max > 1 {
+		left, right = left/max, right/max
+	}
+
+	d.mutex.Lock()
+	d.target = [2]float64{left, right}
+	if d.ramp == 0 {
+		d.current = d.target
+		d.mutex.Unlock()
+		d.stopRamp()
+		return d.write(left, right)
+	}
+	if !d.running {
+		d.running = true
+		d.wg.Add(1)
+		go d.rampSpeeds(d.halt)
+	}
+	d.mutex.Unlock()
+	return nil
+}
+
+// rampSpeeds changes the motor speeds towards their targets at the ramp
+// rate, until they reach them or halt is closed
+func (d *DifferentialDrive) rampSpeeds(halt chan bool) {
+	defer d.wg.Done()
+	for {
+		d.mutex.Lock()
+		step := d.ramp * rampInterval.Seconds()
+		for i := range d.current {
+			delta := d.target[i] - d.current[i]
+			if d.ramp == 0 || math.Abs(delta) <= step {
+				d.current[i] = d.target[i]
+			} else {
+				d.current[i] += math.Copysign(step, delta)
+			}
+		}
+		left, right := d.current[0], d.current[1]
+		done := d.current == d.target
+		if done {
+			d.running = false
+		}
+		d.mutex.Unlock()
+
+		if err := d.write(left, right); err != nil {
+			gobot.Publish(d.Event(Error), err)
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-time.After(rampInterval):
+		case <-halt:
+			return
+		}
+	}
+}
+
+// stopRamp stops ramping, waiting for the ramp to end
+func (d *DifferentialDrive) stopRamp() {
+	d.mutex.Lock()
+	if d.running {
+		close(d.halt)
+		d.halt = make(chan bool)
+		d.running = false
+	}
+	d.mutex.Unlock()
+	d.wg.Wait()
+}
+
+// write runs the motors at the trimmed speeds
+func (d *DifferentialDrive) write(left float64, right float64) (err error) {
+	d.mutex.Lock()
+	leftTrim, rightTrim := d.leftTrim, d.rightTrim
+	d.mutex.Unlock()
+	if err = d.left.Run(left * leftTrim); err != nil {
+		return
+	}
+	return d.right.Run(right * rightTrim)
+}
@@ -0,0 +1,192 @@
+package gpio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestDifferentialDrive() (*DifferentialDrive, *gpioTestRecorder) {
+	r := &gpioTestRecorder{}
+	d := NewDifferentialDrive("rover",
+		NewHBridgeDriver(r, "left", "1", "2", "3"),
+		NewHBridgeDriver(r, "right", "4", "5", "6"),
+	)
+	return d, r
+}
+
+// assertSpeeds asserts the speeds of the motors of d, within rounding
+func assertSpeeds(t *testing.T, d *DifferentialDrive, left float64, right float64) {
+	l, r := d.Left().Speed(), d.Right().Speed()
+	if math.Abs(l-left) > 1e-9 || math.Abs(r-right) > 1e-9 {
+		t.Errorf("speeds %v, %v, expected %v, %v", l, r, left, right)
+	}
+}
+
+func TestDifferentialDrive(t *testing.T) {
+	d, _ := initTestDifferentialDrive()
+	gobottest.Assert(t, d.Name(), "rover")
+	gobottest.Assert(t, d.Connection().Name(), "")
+	gobottest.Assert(t, d.Left().Name(), "left")
+	gobottest.Assert(t, d.Right().Name(), "right")
+	gobottest.Assert(t, d.PinModes(), map[string]string{
+		"1": "out", "2": "out", "3": "pwm",
+		"4": "out", "5": "out", "6": "pwm",
+	})
+
+	gobottest.Assert(t, len(d.Start()), 0)
+	gobottest.Assert(t, d.Left().State(), MotorCoast)
+	gobottest.Assert(t, d.Right().State(), MotorCoast)
+}
+
+func TestDifferentialDriveDrive(t *testing.T) {
+	d, _ := initTestDifferentialDrive()
+	d.Start()
+
+	gobottest.Assert(t, d.Drive(0.5, 0), nil)
+	assertSpeeds(t, d, 0.5, 0.5)
+
+	// turning left slows the left motor
+	gobottest.Assert(t, d.Drive(0.5, 0.25), nil)
+	assertSpeeds(t, d, 0.25, 0.75)
+
+	// speeds out of range are scaled down, keeping their ratio
+	gobottest.Assert(t, d.Drive(1, -1), nil)
+	assertSpeeds(t, d, 1, 0)
+	gobottest.Assert(t, d.Drive(1, 0.5), nil)
+	assertSpeeds(t, d, 1.0/3, 1)
+
+	gobottest.Assert(t, d.Spin(0.5), nil)
+	assertSpeeds(t, d, -0.5, 0.5)
+	left, right := d.Speeds()
+	gobottest.Assert(t, left, -0.5)
+	gobottest.Assert(t, right, 0.5)
+
+	gobottest.Assert(t, d.Stop(), nil)
+	gobottest.Assert(t, d.Left().State(), MotorCoast)
+	gobottest.Assert(t, d.Right().State(), MotorCoast)
+
+	d.Drive(1, 0)
+	gobottest.Assert(t, d.Brake(), nil)
+	gobottest.Assert(t, d.Left().State(), MotorBrake)
+	gobottest.Assert(t, d.Right().State(), MotorBrake)
+}
+
+func TestDifferentialDriveArc(t *testing.T) {
+	d, _ := initTestDifferentialDrive()
+	d.Start()
+
+	gobottest.Assert(t, d.Arc(0.5, 1), nil)
+	assertSpeeds(t, d, 0.25, 0.75)
+	gobottest.Assert(t, d.Arc(0.5, -1), nil)
+	assertSpeeds(t, d, 0.75, 0.25)
+
+	d.SetTrackWidth(2)
+	gobottest.Assert(t, d.Arc(0.5, 2), nil)
+	assertSpeeds(t, d, 0.25, 0.75)
+	gobottest.Assert(t, d.Arc(1, 2), nil)
+	assertSpeeds(t, d, 1.0/3, 1)
+
+	gobottest.Assert(t, d.Arc(0.5, 0), nil)
+	assertSpeeds(t, d, -0.5, 0.5)
+}
+
+func TestDifferentialDriveTrim(t *testing.T) {
+	d, r := initTestDifferentialDrive()
+	d.Start()
+	d.SetTrim(1, 0.8)
+
+	gobottest.Assert(t, d.Drive(1, 0), nil)
+	assertSpeeds(t, d, 1, 0.8)
+	gobottest.Assert(t, r.Levels("3", "6"), []byte{255, 204})
+	left, right := d.Speeds()
+	gobottest.Assert(t, left, 1.0)
+	gobottest.Assert(t, right, 1.0)
+}
+
+func TestDifferentialDriveJoystick(t *testing.T) {
+	d, _ := initTestDifferentialDrive()
+	d.Start()
+
+	// pushing the stick forward drives forward
+	gobottest.Assert(t, d.DriveJoystick(0, -32768), nil)
+	assertSpeeds(t, d, 1, 1)
+
+	// pushing it left turns left
+	gobottest.Assert(t, d.DriveJoystick(-32767, 0), nil)
+	assertSpeeds(t, d, -1, 1)
+
+	// the stick at rest stops
+	gobottest.Assert(t, d.DriveJoystick(200, -300), nil)
+	gobottest.Assert(t, d.Left().State(), MotorCoast)
+	gobottest.Assert(t, d.Right().State(), MotorCoast)
+}
+
+func TestDifferentialDriveRamp(t *testing.T) {
+	d, _ := initTestDifferentialDrive()
+	d.Start()
+	d.SetRamp(10)
+
+	gobottest.Assert(t, d.Drive(1, 0), nil)
+	<-time.After(2 * rampInterval)
+	left, right := d.Speeds()
+	gobottest.Assert(t, left > 0 && left < 1, true)
+	gobottest.Assert(t, right, left)
+
+	<-time.After(10 * rampInterval)
+	assertSpeeds(t, d, 1, 1)
+
+	gobottest.Assert(t, d.Stop(), nil)
+	<-time.After(2 * rampInterval)
+	left, _ = d.Speeds()
+	gobottest.Assert(t, left > 0 && left < 1, true)
+
+	// halting stops ramping
+	gobottest.Assert(t, len(d.Halt()), 0)
+	gobottest.Assert(t, d.Left().State(), MotorCoast)
+	<-time.After(2 * rampInterval)
+	gobottest.Assert(t, d.Left().State(), MotorCoast)
+}
+
+func TestDifferentialDriveRampError(t *testing.T) {
+	d, r := initTestDifferentialDrive()
+	d.Start()
+	d.SetRamp(100)
+	r.SetError(errors.New("write error"))
+
+	errs := make(chan error, 1)
+	gobot.Once(d.Event(Error), func(data interface{}) {
+		errs <- data.(error)
+	})
+	gobottest.Assert(t, d.Drive(1, 0), nil)
+	select {
+	case err := <-errs:
+		gobottest.Assert(t, err, errors.New("write error"))
+	case <-time.After(time.Second):
+		t.Fatal("no error event")
+	}
+	d.Halt()
+}
+
+func TestDifferentialDriveCommands(t *testing.T) {
+	d, _ := initTestDifferentialDrive()
+	d.Start()
+
+	gobottest.Assert(t, d.Command("Drive")(map[string]interface{}{"linear": 0.5, "angular": 0.0}), nil)
+	gobottest.Assert(t, d.Command("Speeds")(nil), map[string]interface{}{"left": 0.5, "right": 0.5})
+	gobottest.Assert(t, d.Command("Arc")(map[string]interface{}{"speed": 0.5, "radius": 1.0}), nil)
+	assertSpeeds(t, d, 0.25, 0.75)
+	gobottest.Assert(t, d.Command("Spin")(map[string]interface{}{"speed": -1.0}), nil)
+	assertSpeeds(t, d, 1, -1)
+	gobottest.Assert(t, d.Command("SetTrim")(map[string]interface{}{"left": 0.5, "right": 1.0}), nil)
+	gobottest.Assert(t, d.Command("Drive")(map[string]interface{}{"linear": 1.0, "angular": 0.0}), nil)
+	assertSpeeds(t, d, 0.5, 1)
+	gobottest.Assert(t, d.Command("Brake")(nil), nil)
+	gobottest.Assert(t, d.Left().State(), MotorBrake)
+	gobottest.Assert(t, d.Command("Stop")(nil), nil)
+	gobottest.Assert(t, d.Left().State(), MotorCoast)
+}
@@ -12,6 +12,7 @@ var _ gobot.Driver = (*DirectPinDriver)(nil)
 type DirectPinDriver struct {
 	name       string
 	pin        string
+	config     PinConfig
 	connection gobot.Connection
 	gobot.Commander
 }
@@ -63,11 +64,26 @@ func (d *DirectPinDriver) Name() string { return d.name }
 // Pin returns the DirectPinDrivers pin
 func (d *DirectPinDriver) Pin() string { return d.pin }
 
+// PinModes returns the DirectPinDrivers pin and its "direct" mode
+func (d *DirectPinDriver) PinModes() map[string]string { return map[string]string{d.pin: "direct"} }
+
 // Connection returns the DirectPinDrivers Connection
 func (d *DirectPinDriver) Connection() gobot.Connection { return d.connection }
 
-// Start implements the Driver interface
-func (d *DirectPinDriver) Start() (errs []error) { return }
+// SetPinConfig sets the pull resistor, active level, drive and debounce
+// period the pin is configured with when the DirectPinDriver starts
+func (d *DirectPinDriver) SetPinConfig(config PinConfig) { d.config = config }
+
+// PinConfig returns the configuration of the pin
+func (d *DirectPinDriver) PinConfig() PinConfig { return d.config }
+
+// Start configures the pin, when a PinConfig is set
+func (d *DirectPinDriver) Start() (errs []error) {
+	if err := configurePin(d.Connection(), d.Pin(), d.config); err != nil {
+		return []error{err}
+	}
+	return
+}
 
 // Halt implements the Driver interface
 func (d *DirectPinDriver) Halt() (errs []error) { return }
@@ -36,6 +36,7 @@ func TestDirectPinDriver(t *testing.T) {
 	d := initTestDirectPinDriver(newGpioTestAdaptor("adaptor"))
 	gobottest.Assert(t, d.Name(), "bot")
 	gobottest.Assert(t, d.Pin(), "1")
+	gobottest.Assert(t, d.PinModes(), map[string]string{"1": "direct"})
 	gobottest.Assert(t, d.Connection().Name(), "adaptor")
 
 	ret = d.Command("DigitalRead")(nil).(map[string]interface{})
@@ -110,3 +111,25 @@ func TestDirectPinDriverDigitalWrie(t *testing.T) {
 	d = initTestDirectPinDriver(&gpioTestBareAdaptor{})
 	gobottest.Assert(t, d.ServoWrite(1), ErrServoWriteUnsupported)
 }
+
+func TestDirectPinDriverPinConfig(t *testing.T) {
+	var configured PinConfig
+	testAdaptorConfigurePin = func(pin string, config PinConfig) (err error) {
+		configured = config
+		return
+	}
+	defer func() { testAdaptorConfigurePin = func(string, PinConfig) (err error) { return } }()
+
+	d := initTestDirectPinDriver(newGpioTestAdaptor("adaptor"))
+	gobottest.Assert(t, len(d.Start()), 0)
+	gobottest.Assert(t, configured, PinConfig{})
+
+	d.SetPinConfig(PinConfig{OpenDrain: true})
+	gobottest.Assert(t, len(d.Start()), 0)
+	gobottest.Assert(t, configured, PinConfig{OpenDrain: true})
+
+	d = initTestDirectPinDriver(&gpioTestBareAdaptor{})
+	gobottest.Assert(t, len(d.Start()), 0)
+	d.SetPinConfig(PinConfig{Pull: PullDown})
+	gobottest.Assert(t, d.Start(), []error{ErrPinConfigUnsupported})
+}
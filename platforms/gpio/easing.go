@@ -0,0 +1,40 @@
+package gpio
+
+// Easing maps the fraction of the duration of a motion or fade passed, from
+// 0 through 1, to the fraction of the way done
+type Easing func(t float64) float64
+
+var (
+	// EaseLinear moves at a constant speed
+	EaseLinear Easing = func(t float64) float64 { return t }
+	// EaseIn starts slowly and speeds up
+	EaseIn Easing = func(t float64) float64 { return t * t }
+	// EaseOut starts fast and slows down
+	EaseOut Easing = func(t float64) float64 { return 1 - (1-t)*(1-t) }
+	// EaseInOut starts slowly, speeds up and slows down again
+	EaseInOut Easing = func(t float64) float64 {
+		if t < 0.5 {
+			return 2 * t * t
+		}
+		return 1 - 2*(1-t)*(1-t)
+	}
+)
+
+// Easings maps the names of the easings, as given to API commands, to them
+var Easings = map[string]Easing{
+	"linear": EaseLinear,
+	"in":     EaseIn,
+	"out":    EaseOut,
+	"in_out": EaseInOut,
+}
+
+// easing returns the easing named name by the params of an API command, or
+// EaseLinear when there is none
+func easing(params map[string]interface{}) Easing {
+	if name, ok := params["easing"].(string); ok {
+		if e, ok := Easings[name]; ok {
+			return e
+		}
+	}
+	return EaseLinear
+}
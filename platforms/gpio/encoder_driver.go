@@ -0,0 +1,403 @@
+package gpio
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*EncoderDriver)(nil)
+
+// ErrEncoderMissedStep is the error resulting when both channels of an
+// encoder change between two reads, so the direction of the step is lost
+var ErrEncoderMissedStep = errors.New("encoder missed a step")
+
+// encoderSteps maps the previous and current levels of the channels of an
+// encoder, as prev<<2|cur with levels a<<1|b, to the step they make. Channel
+// A leads channel B turning forward. Both channels changing is a missed step.
+var encoderSteps = [16]int{
+	0, -1, 1, 2,
+	1, 0, 2, -1,
+	-1, 2, 0, 1,
+	2, 1, -1, 0,
+}
+
+// EncoderDriver represents a quadrature rotary encoder, such as a wheel
+// encoder or a knob, on its A and B channel pins. It counts every edge of
+// both channels, which is four counts per cycle of the encoder.
+//
+// The channels are read on the notifications of a DigitalNotifier
+// connection, and polled at the poll interval otherwise.
+type EncoderDriver struct {
+	name          string
+	connection    DigitalReader
+	pinA          string
+	pinB          string
+	indexPin      string
+	resetOnIndex  bool
+	pollInterval  time.Duration
+	interval      time.Duration
+	countsPerUnit float64
+	smoothing     float64
+	mutex         sync.Mutex
+	levels        int
+	index         int
+	count         int
+	direction     int
+	velocity      float64
+	lastCount     int
+	lastSample    time.Time
+	stops         []func()
+	halt          chan bool
+	wg            sync.WaitGroup
+	gobot.Commander
+	gobot.Eventer
+}
+
+// NewEncoderDriver returns a new EncoderDriver given a DigitalReader, name,
+// and the pins of channels A and B. It publishes its position and velocity
+// every 100 milliseconds and polls the channels every millisecond.
+//
+// Optionally accepts:
+//	time.Duration: Interval at which the position and velocity are published
+//
+// Adds the following API Commands:
+//	"Reset" - See EncoderDriver.Reset
+//	"Position" - See EncoderDriver.Count and EncoderDriver.Units
+//	"Velocity" - See EncoderDriver.Velocity and EncoderDriver.UnitVelocity
+func NewEncoderDriver(a DigitalReader, name string, pinA string, pinB string, v ...time.Duration) *EncoderDriver {
+	e := &EncoderDriver{
+		name:          name,
+		connection:    a,
+		pinA:          pinA,
+		pinB:          pinB,
+		pollInterval:  time.Millisecond,
+		interval:      100 * time.Millisecond,
+		countsPerUnit: 1,
+		smoothing:     0.5,
+		halt:          make(chan bool),
+		Commander:     gobot.NewCommander(),
+		Eventer:       gobot.NewEventer(),
+	}
+
+	if len(v) > 0 {
+		e.interval = v[0]
+	}
+
+	e.AddEvent(Position)
+	e.AddEvent(Velocity)
+	e.AddEvent(Index)
+	e.AddEvent(Error)
+
+	e.AddCommand("Reset", func(params map[string]interface{}) interface{} {
+		e.Reset()
+		return nil
+	})
+	e.AddCommand("Position", func(params map[string]interface{}) interface{} {
+		return map[string]interface{}{"count": e.Count(), "units": e.Units()}
+	})
+	e.AddCommand("Velocity", func(params map[string]interface{}) interface{} {
+		return map[string]interface{}{"counts": e.Velocity(), "units": e.UnitVelocity()}
+	})
+
+	return e
+}
+
+// Name returns the EncoderDrivers name
+func (e *EncoderDriver) Name() string { return e.name }
+
+// Connection returns the EncoderDrivers Connection
+func (e *EncoderDriver) Connection() gobot.Connection { return e.connection.(gobot.Connection) }
+
+// PinModes returns the channel and index pins of the EncoderDriver and their
+// "in" mode
+func (e *EncoderDriver) PinModes() map[string]string {
+	modes := map[string]string{e.pinA: "in", e.pinB: "in"}
+	if e.indexPin != "" {
+		modes[e.indexPin] = "in"
+	}
+	return modes
+}
+
+// SetIndexPin sets the pin of the index channel, which pulses once per
+// revolution. Each pulse publishes the Index event, and resets the count when
+// reset is true, so that the count measures from the index.
+func (e *EncoderDriver) SetIndexPin(pin string, reset bool) {
+	e.indexPin = pin
+	e.resetOnIndex = reset
+}
+
+// SetPollInterval sets the interval at which the channels are polled when
+// the connection is not a DigitalNotifier
+func (e *EncoderDriver) SetPollInterval(interval time.Duration) { e.pollInterval = interval }
+
+// SetCountsPerUnit sets the number of counts per user defined unit, eg. four
+// times the cycles per revolution of the encoder to measure revolutions, or
+// counts per meter travelled by a wheel. It defaults to 1.
+func (e *EncoderDriver) SetCountsPerUnit(counts float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.countsPerUnit = counts
+}
+
+// SetSmoothing sets the weight, from 0 through 1, of the previous velocity in
+// the filtered velocity. A smoothing of 0 leaves the velocity unfiltered. It
+// defaults to 0.5.
+func (e *EncoderDriver) SetSmoothing(smoothing float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.smoothing = math.Max(0, math.Min(1, smoothing))
+}
+
+// Start reads the channels, and starts counting their edges.
+//
+// Emits the Events:
+//	Position int - The count, when it changed since the last interval
+//	Velocity float64 - The filtered velocity in counts per second, when it
+//		changed since the last interval
+//	Index int - The count at an index pulse, before it is reset
+//	Error error - On a read error, or ErrEncoderMissedStep
+func (e *EncoderDriver) Start() (errs []error) {
+	levels, err := e.read()
+	if err != nil {
+		return []error{err}
+	}
+	if levels < 0 {
+		levels = 0
+	}
+	index := 0
+	if e.indexPin != "" {
+		if index, err = e.connection.DigitalRead(e.indexPin); err != nil {
+			return []error{err}
+		}
+		if index < 0 {
+			index = 0
+		}
+	}
+
+	e.mutex.Lock()
+	e.levels = levels
+	e.index = index
+	e.lastCount = e.count
+	e.lastSample = time.Now()
+	e.halt = make(chan bool)
+	count := e.count
+	e.mutex.Unlock()
+
+	if notifier, ok := e.connection.(DigitalNotifier); ok {
+		if err = e.notify(notifier); err != nil {
+			e.stopNotify()
+			return []error{err}
+		}
+	} else {
+		e.wg.Add(1)
+		go e.poll(e.halt)
+	}
+
+	e.wg.Add(1)
+	go e.publish(e.halt, count)
+	return
+}
+
+// Halt stops counting the edges of the channels
+func (e *EncoderDriver) Halt() (errs []error) {
+	e.stopNotify()
+	close(e.halt)
+	e.wg.Wait()
+	return
+}
+
+// Count returns the count of the edges of the channels, which is negative
+// when the encoder turned backward past its start
+func (e *EncoderDriver) Count() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.count
+}
+
+// Units returns the count in user defined units
+func (e *EncoderDriver) Units() float64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return float64(e.count) / e.countsPerUnit
+}
+
+// Direction returns the direction of the last step, 1 for forward and -1 for
+// backward, or 0 before the first step
+func (e *EncoderDriver) Direction() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.direction
+}
+
+// Velocity returns the filtered velocity in counts per second
+func (e *EncoderDriver) Velocity() float64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.velocity
+}
+
+// UnitVelocity returns the filtered velocity in user defined units per second
+func (e *EncoderDriver) UnitVelocity() float64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.velocity / e.countsPerUnit
+}
+
+// Reset resets the count and the velocity to 0
+func (e *EncoderDriver) Reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.count = 0
+	e.lastCount = 0
+	e.velocity = 0
+	e.direction = 0
+}
+
+// notify reads the channels on the notifications of notifier. As the
+// notifications may arrive out of order, both channels are read on each
+// notification instead of using the level notified.
+func (e *EncoderDriver) notify(notifier DigitalNotifier) error {
+	handle := func(int) { e.update() }
+	pins := []string{e.pinA, e.pinB}
+	if e.indexPin != "" {
+		pins = append(pins, e.indexPin)
+	}
+	for _, pin := range pins {
+		stop, err := notifier.DigitalNotify(pin, handle)
+		if err != nil {
+			return err
+		}
+		e.mutex.Lock()
+		e.stops = append(e.stops, stop)
+		e.mutex.Unlock()
+	}
+	return nil
+}
+
+// stopNotify stops the notifications of the channels
+func (e *EncoderDriver) stopNotify() {
+	e.mutex.Lock()
+	stops := e.stops
+	e.stops = nil
+	e.mutex.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// poll reads the channels at the poll interval, until halt is closed
+func (e *EncoderDriver) poll(halt chan bool) {
+	defer e.wg.Done()
+	for {
+		e.update()
+		select {
+		case <-time.After(e.pollInterval):
+		case <-halt:
+			return
+		}
+	}
+}
+
+// publish updates the velocity and publishes the position and velocity at
+// the interval, until halt is closed. The position is published when it
+// changed from the position published last.
+func (e *EncoderDriver) publish(halt chan bool, published int) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-time.After(e.interval):
+		case <-halt:
+			return
+		}
+
+		e.mutex.Lock()
+		now := time.Now()
+		velocity := float64(e.count-e.lastCount) / now.Sub(e.lastSample).Seconds()
+		velocity = e.smoothing*e.velocity + (1-e.smoothing)*velocity
+		if math.Abs(velocity) < 0.01 {
+			velocity = 0
+		}
+		changed := velocity != e.velocity
+		e.velocity = velocity
+		e.lastCount = e.count
+		e.lastSample = now
+		count := e.count
+		e.mutex.Unlock()
+
+		if count != published {
+			published = count
+			gobot.Publish(e.Event(Position), count)
+		}
+		if changed {
+			gobot.Publish(e.Event(Velocity), velocity)
+		}
+	}
+}
+
+// update reads the channels and the index, and counts the step they made
+func (e *EncoderDriver) update() {
+	levels, err := e.read()
+	if err != nil {
+		gobot.Publish(e.Event(Error), err)
+		return
+	}
+	index := 0
+	if e.indexPin != "" {
+		if index, err = e.connection.DigitalRead(e.indexPin); err != nil {
+			gobot.Publish(e.Event(Error), err)
+			return
+		}
+	}
+
+	if levels < 0 {
+		return
+	}
+
+	e.mutex.Lock()
+	step := encoderSteps[e.levels<<2|levels]
+	e.levels = levels
+	if step == 2 {
+		e.mutex.Unlock()
+		gobot.Publish(e.Event(Error), ErrEncoderMissedStep)
+		return
+	}
+	if step != 0 {
+		e.count += step
+		e.direction = step
+	}
+	pulse := index == 1 && e.index == 0
+	if index >= 0 {
+		e.index = index
+	}
+	count := e.count
+	if pulse && e.resetOnIndex {
+		e.lastCount -= e.count
+		e.count = 0
+	}
+	e.mutex.Unlock()
+
+	if pulse {
+		gobot.Publish(e.Event(Index), count)
+	}
+}
+
+// read returns the levels of the channels as a<<1|b, or -1 when a channel
+// could not be read in time
+func (e *EncoderDriver) read() (levels int, err error) {
+	a, err := e.connection.DigitalRead(e.pinA)
+	if err != nil {
+		return
+	}
+	b, err := e.connection.DigitalRead(e.pinB)
+	if err != nil {
+		return
+	}
+	if a < 0 || b < 0 {
+		return -1, nil
+	}
+	return a<<1 | b, nil
+}
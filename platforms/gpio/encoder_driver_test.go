@@ -0,0 +1,185 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestEncoderDriver() (*EncoderDriver, *gpioTestNotifier) {
+	n := &gpioTestNotifier{}
+	return NewEncoderDriver(n, "encoder", "1", "2", 10*time.Millisecond), n
+}
+
+// turnEncoder turns the encoder of n by cycles, backward for negative cycles
+func turnEncoder(n *gpioTestNotifier, cycles int) {
+	for c := 0; c < cycles; c++ {
+		n.Set("1", 1)
+		n.Set("2", 1)
+		n.Set("1", 0)
+		n.Set("2", 0)
+	}
+	for c := 0; c > cycles; c-- {
+		n.Set("2", 1)
+		n.Set("1", 1)
+		n.Set("2", 0)
+		n.Set("1", 0)
+	}
+}
+
+func TestEncoderDriver(t *testing.T) {
+	e, _ := initTestEncoderDriver()
+	gobottest.Assert(t, e.Name(), "encoder")
+	gobottest.Assert(t, e.Connection().Name(), "")
+	gobottest.Assert(t, e.PinModes(), map[string]string{"1": "in", "2": "in"})
+	e.SetIndexPin("3", false)
+	gobottest.Assert(t, e.PinModes(), map[string]string{"1": "in", "2": "in", "3": "in"})
+}
+
+func TestEncoderDriverCount(t *testing.T) {
+	e, n := initTestEncoderDriver()
+	gobottest.Assert(t, len(e.Start()), 0)
+	defer e.Halt()
+
+	turnEncoder(n, 2)
+	gobottest.Assert(t, e.Count(), 8)
+	gobottest.Assert(t, e.Direction(), 1)
+
+	turnEncoder(n, -3)
+	gobottest.Assert(t, e.Count(), -4)
+	gobottest.Assert(t, e.Direction(), -1)
+
+	e.SetCountsPerUnit(8)
+	gobottest.Assert(t, e.Units(), -0.5)
+
+	e.Reset()
+	gobottest.Assert(t, e.Count(), 0)
+	gobottest.Assert(t, e.Direction(), 0)
+}
+
+func TestEncoderDriverMissedStep(t *testing.T) {
+	e, n := initTestEncoderDriver()
+	e.Start()
+	defer e.Halt()
+
+	errs := make(chan error, 1)
+	gobot.Once(e.Event(Error), func(data interface{}) {
+		errs <- data.(error)
+	})
+	n.gpioTestInputs.Set("1", 1, "2", 1)
+	n.Set("2", 1)
+	select {
+	case err := <-errs:
+		gobottest.Assert(t, err, ErrEncoderMissedStep)
+	case <-time.After(time.Second):
+		t.Fatal("no error event")
+	}
+	gobottest.Assert(t, e.Count(), 0)
+
+	// counting goes on from the levels read
+	n.Set("1", 0)
+	gobottest.Assert(t, e.Count(), 1)
+}
+
+func TestEncoderDriverIndex(t *testing.T) {
+	e, n := initTestEncoderDriver()
+	e.SetIndexPin("3", true)
+	e.Start()
+	defer e.Halt()
+
+	index := make(chan int, 1)
+	gobot.Once(e.Event(Index), func(data interface{}) {
+		index <- data.(int)
+	})
+	turnEncoder(n, 3)
+	n.Set("3", 1)
+	select {
+	case count := <-index:
+		gobottest.Assert(t, count, 12)
+	case <-time.After(time.Second):
+		t.Fatal("no index event")
+	}
+	gobottest.Assert(t, e.Count(), 0)
+
+	n.Set("3", 0)
+	turnEncoder(n, 1)
+	gobottest.Assert(t, e.Count(), 4)
+}
+
+func TestEncoderDriverEvents(t *testing.T) {
+	e, n := initTestEncoderDriver()
+	e.SetSmoothing(0)
+	e.Start()
+	defer e.Halt()
+
+	positions := make(chan int, 1)
+	velocities := make(chan float64, 1)
+	gobot.Once(e.Event(Position), func(data interface{}) {
+		positions <- data.(int)
+	})
+	gobot.Once(e.Event(Velocity), func(data interface{}) {
+		velocities <- data.(float64)
+	})
+	turnEncoder(n, 5)
+	select {
+	case position := <-positions:
+		gobottest.Assert(t, position, 20)
+	case <-time.After(time.Second):
+		t.Fatal("no position event")
+	}
+	select {
+	case velocity := <-velocities:
+		gobottest.Assert(t, velocity > 0, true)
+	case <-time.After(time.Second):
+		t.Fatal("no velocity event")
+	}
+
+	// the velocity falls back to 0 once the encoder stops
+	<-time.After(50 * time.Millisecond)
+	gobottest.Assert(t, e.Velocity(), 0.0)
+	gobottest.Assert(t, e.UnitVelocity(), 0.0)
+}
+
+func TestEncoderDriverPoll(t *testing.T) {
+	i := &gpioTestInputs{}
+	e := NewEncoderDriver(i, "encoder", "1", "2")
+	gobottest.Assert(t, len(e.Start()), 0)
+
+	i.Set("1", 1)
+	<-time.After(10 * time.Millisecond)
+	i.Set("2", 1)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, e.Count(), 2)
+
+	gobottest.Assert(t, len(e.Halt()), 0)
+	i.Set("1", 0)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, e.Count(), 2)
+}
+
+func TestEncoderDriverErrors(t *testing.T) {
+	i := &gpioTestInputs{}
+	i.SetError(errors.New("read error"))
+	e := NewEncoderDriver(i, "encoder", "1", "2")
+	gobottest.Assert(t, e.Start(), []error{errors.New("read error")})
+
+	n := &gpioTestNotifier{err: errors.New("notify error")}
+	e = NewEncoderDriver(n, "encoder", "1", "2")
+	gobottest.Assert(t, e.Start(), []error{errors.New("notify error")})
+}
+
+func TestEncoderDriverCommands(t *testing.T) {
+	e, n := initTestEncoderDriver()
+	e.SetCountsPerUnit(4)
+	e.Start()
+	defer e.Halt()
+
+	turnEncoder(n, 2)
+	gobottest.Assert(t, e.Command("Position")(nil), map[string]interface{}{"count": 8, "units": 2.0})
+	gobottest.Assert(t, e.Command("Reset")(nil), nil)
+	gobottest.Assert(t, e.Command("Position")(nil), map[string]interface{}{"count": 0, "units": 0.0})
+	gobottest.Assert(t, e.Command("Velocity")(nil), map[string]interface{}{"counts": 0.0, "units": 0.0})
+}
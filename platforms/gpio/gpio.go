@@ -2,6 +2,8 @@ package gpio
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/potix/gobot"
 )
@@ -25,6 +27,27 @@ var (
 	// ErrServoOutOfRange is the error resulting when a driver attempts to use
 	// hardware capabilities which a connection does not support
 	ErrServoOutOfRange = errors.New("servo angle must be between 0-180")
+	// ErrServoCalibration is the error resulting when a servo is calibrated
+	// with an empty angle or pulse range
+	ErrServoCalibration = errors.New("servo calibration must have a minimum below its maximum")
+	// ErrPinConfigUnsupported is the error resulting when a driver attempts to
+	// configure a pin of a connection which is not a PinConfigurer
+	ErrPinConfigUnsupported = errors.New("ConfigurePin is not supported by this platform")
+	// ErrPullUnsupported is the error resulting when a driver attempts to use
+	// a pull resistor which a pin does not have
+	ErrPullUnsupported = errors.New("pull resistor is not supported by this pin")
+	// ErrOpenDrainUnsupported is the error resulting when a driver attempts to
+	// drive a pin which can not be driven as an open drain output
+	ErrOpenDrainUnsupported = errors.New("open drain output is not supported by this pin")
+	// ErrDebounceUnsupported is the error resulting when a driver attempts to
+	// debounce a pin which can not be debounced by the platform
+	ErrDebounceUnsupported = errors.New("debounce period is not supported by this pin")
+	// ErrPwmPeriodUnsupported is the error resulting when a driver attempts to
+	// write pulses at a period which the pwm of a pin can not generate
+	ErrPwmPeriodUnsupported = errors.New("pwm period is not supported by this pin")
+	// ErrLedPeriod is the error resulting when a led effect is started with a
+	// period which is not positive
+	ErrLedPeriod = errors.New("led effect period must be positive")
 )
 
 const (
@@ -38,8 +61,56 @@ const (
 	Data = "data"
 	// Vibration event
 	Vibration = "vibration"
+	// Done event
+	Done = "done"
+	// Position event
+	Position = "position"
+	// Velocity event
+	Velocity = "velocity"
+	// Index event
+	Index = "index"
+	// Distance event
+	Distance = "distance"
+	// Click event
+	Click = "click"
+	// DoubleClick event
+	DoubleClick = "double_click"
+	// LongPress event
+	LongPress = "long_press"
+	// Hold event
+	Hold = "hold"
+	// Value event
+	Value = "value"
+	// Above event
+	Above = "above"
+	// Below event
+	Below = "below"
 )
 
+const (
+	// PullNone disconnects the internal pull resistor of a pin
+	PullNone = "none"
+	// PullUp connects the internal pull up resistor of a pin
+	PullUp = "up"
+	// PullDown connects the internal pull down resistor of a pin
+	PullDown = "down"
+)
+
+// PinConfig describes the electrical configuration of a pin. Its zero value
+// leaves the pin as the platform configures it by default.
+type PinConfig struct {
+	// Pull is PullNone, PullUp or PullDown, or empty to keep the default
+	// resistor of the pin
+	Pull string
+	// ActiveLow inverts the values read from and written to the pin, as
+	// with a button connecting the pin to ground
+	ActiveLow bool
+	// OpenDrain drives outputs low only, leaving the pin floating otherwise
+	OpenDrain bool
+	// Debounce is the period input changes have to be stable for to be read
+	Debounce time.Duration
+}
+
 // PwmWriter interface represents an Adaptor which has Pwm capabilities
 type PwmWriter interface {
 	gobot.Adaptor
@@ -69,3 +140,69 @@ type DigitalReader interface {
 	gobot.Adaptor
 	DigitalRead(string) (val int, err error)
 }
+
+// DigitalNotifier interface represents an Adaptor which calls a handler with
+// the levels of its digital inputs as they change, so that they need not be
+// polled. The returned function stops the notifications.
+type DigitalNotifier interface {
+	gobot.Adaptor
+	DigitalNotify(pin string, handler func(val int)) (stop func(), err error)
+}
+
+// PulseReader interface represents an Adaptor which measures the width of
+// the next pulse at the given level on its digital inputs, timing its edges
+// more precisely than a driver polling DigitalRead, such as the BoardAdaptor
+// holding the pin while reading it. The width is 0 when the pulse does not
+// end within the timeout.
+type PulseReader interface {
+	gobot.Adaptor
+	PulseRead(pin string, level int, timeout time.Duration) (width time.Duration, err error)
+}
+
+// PwmPulseWriter interface represents an Adaptor which writes pwm signals of
+// a given period and pulse width, such as the signals of servos
+type PwmPulseWriter interface {
+	gobot.Adaptor
+	PwmPulseWrite(pin string, period time.Duration, width time.Duration) (err error)
+}
+
+// PinConfigurer interface represents an Adaptor which can configure the pull
+// resistors, active level, drive and debouncing of its pins
+type PinConfigurer interface {
+	gobot.Adaptor
+	ConfigurePin(string, PinConfig) (err error)
+}
+
+// ValidPull returns an error unless pull is empty, PullNone, PullUp or PullDown
+func ValidPull(pull string) error {
+	switch pull {
+	case "", PullNone, PullUp, PullDown:
+		return nil
+	}
+	return fmt.Errorf("Invalid pull %q, expected %q, %q or %q", pull, PullNone, PullUp, PullDown)
+}
+
+// configurePin applies config to pin of connection, unless config is the zero
+// PinConfig
+func configurePin(connection gobot.Connection, pin string, config PinConfig) error {
+	if config == (PinConfig{}) {
+		return nil
+	}
+	configurer, ok := connection.(PinConfigurer)
+	if !ok {
+		return ErrPinConfigUnsupported
+	}
+	return configurer.ConfigurePin(pin, config)
+}
+
+func init() {
+	gobot.RegisterInterface("AnalogReader", func(a gobot.Adaptor) bool { _, ok := a.(AnalogReader); return ok })
+	gobot.RegisterInterface("DigitalReader", func(a gobot.Adaptor) bool { _, ok := a.(DigitalReader); return ok })
+	gobot.RegisterInterface("DigitalNotifier", func(a gobot.Adaptor) bool { _, ok := a.(DigitalNotifier); return ok })
+	gobot.RegisterInterface("DigitalWriter", func(a gobot.Adaptor) bool { _, ok := a.(DigitalWriter); return ok })
+	gobot.RegisterInterface("PinConfigurer", func(a gobot.Adaptor) bool { _, ok := a.(PinConfigurer); return ok })
+	gobot.RegisterInterface("PulseReader", func(a gobot.Adaptor) bool { _, ok := a.(PulseReader); return ok })
+	gobot.RegisterInterface("PwmPulseWriter", func(a gobot.Adaptor) bool { _, ok := a.(PwmPulseWriter); return ok })
+	gobot.RegisterInterface("PwmWriter", func(a gobot.Adaptor) bool { _, ok := a.(PwmWriter); return ok })
+	gobot.RegisterInterface("ServoWriter", func(a gobot.Adaptor) bool { _, ok := a.(ServoWriter); return ok })
+}
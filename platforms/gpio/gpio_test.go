@@ -0,0 +1,13 @@
+package gpio
+
+import (
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestValidPull(t *testing.T) {
+	gobottest.Assert(t, ValidPull(""), nil)
+	gobottest.Assert(t, ValidPull(PullUp), nil)
+	gobottest.Assert(t, ValidPull("sideways").Error(), `Invalid pull "sideways", expected "none", "up" or "down"`)
+}
@@ -39,17 +39,24 @@ type GroveRotaryDriver struct {
 }
 
 // NewGroveRotaryDriver returns a new GroveRotaryDriver with a polling interval of
-// 10 Milliseconds given an AnalogReader, name and pin.
+// 10 Milliseconds given an AnalogReader, name and pin. Its readings are
+// filtered by a median of 3, published on changes of 2, and converted to the
+// 0-300 degree angle of the dial.
 //
 // Optinally accepts:
 // 	time.Duration: Interval at which the AnalogSensor is polled for new information
 //
 // Adds the following API Commands:
 // 	"Read" - See AnalogSensor.Read
+//	"Value" - See AnalogSensor.Value
 func NewGroveRotaryDriver(a AnalogReader, name string, pin string, v ...time.Duration) *GroveRotaryDriver {
-	return &GroveRotaryDriver{
+	sensor := &GroveRotaryDriver{
 		AnalogSensorDriver: NewAnalogSensorDriver(a, name, pin, v...),
 	}
+	sensor.SetFilters(MedianFilter(3))
+	sensor.SetDeadband(2)
+	sensor.SetConversion(LinearConversion(0, 1023, 0, 300))
+	return sensor
 }
 
 // GroveLedDriver represents an LED with a Grove connector
@@ -64,6 +71,9 @@ type GroveLedDriver struct {
 //	"Toggle" - See LedDriver.Toggle
 //	"On" - See LedDriver.On
 //	"Off" - See LedDriver.Off
+//	"Blink" - See LedDriver.Blink
+//	"Pulse" - See LedDriver.Pulse
+//	"Stop" - See LedEffects.Stop
 func NewGroveLedDriver(a DigitalWriter, name string, pin string) *GroveLedDriver {
 	return &GroveLedDriver{
 		LedDriver: NewLedDriver(a, name, pin),
@@ -77,17 +87,22 @@ type GroveLightSensorDriver struct {
 }
 
 // NewGroveLightSensorDriver returns a new GroveLightSensorDriver with a polling interval of
-// 10 Milliseconds given an AnalogReader, name and pin.
+// 10 Milliseconds given an AnalogReader, name and pin. Its readings are
+// smoothed exponentially, and published on changes of 4.
 //
 // Optinally accepts:
 // 	time.Duration: Interval at which the AnalogSensor is polled for new information
 //
 // Adds the following API Commands:
 // 	"Read" - See AnalogSensor.Read
+//	"Value" - See AnalogSensor.Value
 func NewGroveLightSensorDriver(a AnalogReader, name string, pin string, v ...time.Duration) *GroveLightSensorDriver {
-	return &GroveLightSensorDriver{
+	sensor := &GroveLightSensorDriver{
 		AnalogSensorDriver: NewAnalogSensorDriver(a, name, pin, v...),
 	}
+	sensor.SetFilters(ExponentialFilter(0.3))
+	sensor.SetDeadband(4)
+	return sensor
 }
 
 // GrovePiezoVibrationSensorDriver represents an analog vibration sensor
@@ -97,19 +112,22 @@ type GrovePiezoVibrationSensorDriver struct {
 }
 
 // NewGrovePiezoVibrationSensorDriver returns a new GrovePiezoVibrationSensorDriver with a polling interval of
-// 10 Milliseconds given an AnalogReader, name and pin.
+// 10 Milliseconds given an AnalogReader, name and pin. Its readings are left
+// unfiltered to catch the vibrations, which are above its threshold of 1000.
 //
 // Optinally accepts:
 // 	time.Duration: Interval at which the AnalogSensor is polled for new information
 //
 // Adds the following API Commands:
 // 	"Read" - See AnalogSensor.Read
+//	"Value" - See AnalogSensor.Value
 func NewGrovePiezoVibrationSensorDriver(a AnalogReader, name string, pin string, v ...time.Duration) *GrovePiezoVibrationSensorDriver {
 	sensor := &GrovePiezoVibrationSensorDriver{
 		AnalogSensorDriver: NewAnalogSensorDriver(a, name, pin, v...),
 	}
 
 	sensor.AddEvent(Vibration)
+	sensor.SetThreshold(1000, 100)
 
 	gobot.On(sensor.Event(Data), func(data interface{}) {
 		if data.(int) > 1000 {
@@ -134,7 +152,7 @@ func NewGroveBuzzerDriver(a DigitalWriter, name string, pin string) *GroveBuzzer
 }
 
 // GroveButtonDriver represents a button sensor
-// with a Grove connector
+// with a Grove connector, publishing the events of ButtonGestures as well
 type GroveButtonDriver struct {
 	*ButtonDriver
 }
@@ -157,21 +175,26 @@ type GroveSoundSensorDriver struct {
 }
 
 // NewGroveSoundSensorDriver returns a new GroveSoundSensorDriver with a polling interval of
-// 10 Milliseconds given an AnalogReader, name and pin.
+// 10 Milliseconds given an AnalogReader, name and pin. Its readings are
+// averaged over 8, and published on changes of 8.
 //
 // Optinally accepts:
 // 	time.Duration: Interval at which the AnalogSensor is polled for new information
 //
 // Adds the following API Commands:
 // 	"Read" - See AnalogSensor.Read
+//	"Value" - See AnalogSensor.Value
 func NewGroveSoundSensorDriver(a AnalogReader, name string, pin string, v ...time.Duration) *GroveSoundSensorDriver {
-	return &GroveSoundSensorDriver{
+	sensor := &GroveSoundSensorDriver{
 		AnalogSensorDriver: NewAnalogSensorDriver(a, name, pin, v...),
 	}
+	sensor.SetFilters(MovingAverageFilter(8))
+	sensor.SetDeadband(8)
+	return sensor
 }
 
 // GroveTouchDriver represents a touch button sensor
-// with a Grove connector
+// with a Grove connector, publishing the events of ButtonGestures as well
 type GroveTouchDriver struct {
 	*ButtonDriver
 }
@@ -91,6 +91,9 @@ func (a *GroveTemperatureSensorDriver) Name() string { return a.name }
 // Pin returns the GroveTemperatureSensorDrivers pin
 func (a *GroveTemperatureSensorDriver) Pin() string { return a.pin }
 
+// PinModes returns the GroveTemperatureSensorDrivers pin and its "analog" mode
+func (a *GroveTemperatureSensorDriver) PinModes() map[string]string { return map[string]string{a.pin: "analog"} }
+
 // Connection returns the GroveTemperatureSensorDrivers Connection
 func (a *GroveTemperatureSensorDriver) Connection() gobot.Connection {
 	return a.connection.(gobot.Connection)
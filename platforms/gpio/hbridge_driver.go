@@ -0,0 +1,261 @@
+package gpio
+
+import (
+	"math"
+	"sync"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*HBridgeDriver)(nil)
+
+const (
+	// MotorForward is the state of a motor running forward
+	MotorForward = "forward"
+	// MotorBackward is the state of a motor running backward
+	MotorBackward = "backward"
+	// MotorBrake is the state of a motor whose terminals are shorted, which
+	// stops it quickly and holds it
+	MotorBrake = "brake"
+	// MotorCoast is the state of a motor whose terminals are disconnected,
+	// which lets it spin down freely
+	MotorCoast = "coast"
+)
+
+// HBridgeDriver represents a dc motor driven by one channel of an H-bridge,
+// such as the L298N, L293D, TB6612FNG or DRV8833.
+//
+// Boards with an enable pin, such as the L298N and TB6612FNG, set the
+// direction with their two input pins and the speed with pwm on the enable
+// pin. Boards without one, such as the DRV8833, are driven with pwm on their
+// input pins.
+type HBridgeDriver struct {
+	name       string
+	connection DigitalWriter
+	in1Pin     string
+	in2Pin     string
+	enablePin  string
+	standbyPin string
+	mutex      sync.Mutex
+	speed      float64
+	state      string
+	inputs     []byte
+	gobot.Commander
+}
+
+// NewHBridgeDriver returns a new HBridgeDriver given a DigitalWriter, name,
+// the two input pins of the channel, and its pwm enable pin, or an empty
+// enable pin for boards driven with pwm on their input pins. The
+// connection needs to be a PwmWriter to run the motor at other speeds than
+// full speed.
+//
+// Adds the following API Commands:
+//	"Run" - See HBridgeDriver.Run
+//	"Brake" - See HBridgeDriver.Brake
+//	"Coast" - See HBridgeDriver.Coast
+//	"State" - See HBridgeDriver.State and HBridgeDriver.Speed
+func NewHBridgeDriver(a DigitalWriter, name string, in1Pin string, in2Pin string, enablePin string) *HBridgeDriver {
+	m := &HBridgeDriver{
+		name:       name,
+		connection: a,
+		in1Pin:     in1Pin,
+		in2Pin:     in2Pin,
+		enablePin:  enablePin,
+		state:      MotorCoast,
+		Commander:  gobot.NewCommander(),
+	}
+
+	m.AddCommand("Run", func(params map[string]interface{}) interface{} {
+		return m.Run(params["speed"].(float64))
+	})
+	m.AddCommand("Brake", func(params map[string]interface{}) interface{} {
+		return m.Brake()
+	})
+	m.AddCommand("Coast", func(params map[string]interface{}) interface{} {
+		return m.Coast()
+	})
+	m.AddCommand("State", func(params map[string]interface{}) interface{} {
+		return map[string]interface{}{"state": m.State(), "speed": m.Speed()}
+	})
+
+	return m
+}
+
+// Name returns the HBridgeDrivers name
+func (m *HBridgeDriver) Name() string { return m.name }
+
+// Connection returns the HBridgeDrivers Connection
+func (m *HBridgeDriver) Connection() gobot.Connection { return m.connection.(gobot.Connection) }
+
+// PinModes returns the pins of the HBridgeDriver: its "out" input and
+// standby pins, and its "pwm" enable pin, or "pwm" input pins when it has no
+// enable pin
+func (m *HBridgeDriver) PinModes() map[string]string {
+	modes := map[string]string{m.in1Pin: "out", m.in2Pin: "out"}
+	if m.enablePin != "" {
+		modes[m.enablePin] = "pwm"
+	} else {
+		modes[m.in1Pin], modes[m.in2Pin] = "pwm", "pwm"
+	}
+	if m.standbyPin != "" {
+		modes[m.standbyPin] = "out"
+	}
+	return modes
+}
+
+// SetStandbyPin sets the active low standby pin of boards such as the
+// TB6612FNG, which is driven high when the HBridgeDriver starts and low when
+// it halts
+func (m *HBridgeDriver) SetStandbyPin(pin string) { m.standbyPin = pin }
+
+// Start lets the motor coast, and takes the board out of standby
+func (m *HBridgeDriver) Start() (errs []error) {
+	if err := m.Coast(); err != nil {
+		return []error{err}
+	}
+	if m.standbyPin != "" {
+		if err := m.connection.DigitalWrite(m.standbyPin, 1); err != nil {
+			return []error{err}
+		}
+	}
+	return
+}
+
+// Halt lets the motor coast, and puts the board in standby
+func (m *HBridgeDriver) Halt() (errs []error) {
+	if err := m.Coast(); err != nil {
+		errs = append(errs, err)
+	}
+	if m.standbyPin != "" {
+		if err := m.connection.DigitalWrite(m.standbyPin, 0); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// Run runs the motor at speed, from -1 for full speed backward through 1 for
+// full speed forward. Speeds beyond are clamped. A speed of 0 lets the motor
+// coast.
+func (m *HBridgeDriver) Run(speed float64) (err error) {
+	speed = math.Max(-1, math.Min(1, speed))
+	if speed == 0 {
+		return m.Coast()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	level := byte(math.Abs(speed)*255 + 0.5)
+	in1, in2 := byte(1), byte(0)
+	state := MotorForward
+	if speed < 0 {
+		in1, in2 = 0, 1
+		state = MotorBackward
+	}
+	if m.enablePin != "" {
+		err = m.write(in1, in2, level)
+	} else {
+		err = m.write(in1*level, in2*level, 0)
+	}
+	if err != nil {
+		return
+	}
+	m.speed = speed
+	m.state = state
+	return
+}
+
+// Forward runs the motor forward at speed, from 0 through 1
+func (m *HBridgeDriver) Forward(speed float64) error { return m.Run(math.Abs(speed)) }
+
+// Backward runs the motor backward at speed, from 0 through 1
+func (m *HBridgeDriver) Backward(speed float64) error { return m.Run(-math.Abs(speed)) }
+
+// Brake shorts the motor terminals, stopping the motor quickly and holding it
+func (m *HBridgeDriver) Brake() (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.enablePin != "" {
+		err = m.write(1, 1, 255)
+	} else {
+		err = m.write(255, 255, 0)
+	}
+	if err != nil {
+		return
+	}
+	m.speed = 0
+	m.state = MotorBrake
+	return
+}
+
+// Coast disconnects the motor terminals, letting the motor spin down freely
+func (m *HBridgeDriver) Coast() (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err = m.write(0, 0, 0); err != nil {
+		return
+	}
+	m.speed = 0
+	m.state = MotorCoast
+	return
+}
+
+// Speed returns the speed the motor runs at, from -1 through 1
+func (m *HBridgeDriver) Speed() float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.speed
+}
+
+// State returns the state of the motor, MotorForward, MotorBackward,
+// MotorBrake or MotorCoast
+func (m *HBridgeDriver) State() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.state
+}
+
+// write writes the input pins, and the enable pin when there is one. Inputs
+// of boards without an enable pin are pwm levels, and digital levels
+// otherwise. The caller holds the mutex.
+func (m *HBridgeDriver) write(in1 byte, in2 byte, enable byte) (err error) {
+	if m.enablePin == "" {
+		if err = m.pwmWrite(m.in1Pin, in1); err != nil {
+			return
+		}
+		return m.pwmWrite(m.in2Pin, in2)
+	}
+	if m.inputs == nil || m.inputs[0] != in1 || m.inputs[1] != in2 {
+		// disable the channel while its inputs change
+		m.inputs = nil
+		if err = m.pwmWrite(m.enablePin, 0); err != nil {
+			return
+		}
+		if err = m.connection.DigitalWrite(m.in1Pin, in1); err != nil {
+			return
+		}
+		if err = m.connection.DigitalWrite(m.in2Pin, in2); err != nil {
+			return
+		}
+		m.inputs = []byte{in1, in2}
+		if enable == 0 {
+			return
+		}
+	}
+	return m.pwmWrite(m.enablePin, enable)
+}
+
+// pwmWrite writes level to a pwm pin, falling back to a digital write for
+// the levels 0 and 255 when the connection is not a PwmWriter
+func (m *HBridgeDriver) pwmWrite(pin string, level byte) error {
+	if writer, ok := m.connection.(PwmWriter); ok {
+		return writer.PwmWrite(pin, level)
+	}
+	switch level {
+	case 0:
+		return m.connection.DigitalWrite(pin, 0)
+	case 255:
+		return m.connection.DigitalWrite(pin, 1)
+	}
+	return ErrPwmWriteUnsupported
+}
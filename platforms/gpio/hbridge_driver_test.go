@@ -0,0 +1,122 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestHBridgeDriver() (*HBridgeDriver, *gpioTestRecorder) {
+	r := &gpioTestRecorder{}
+	return NewHBridgeDriver(r, "motor", "1", "2", "3"), r
+}
+
+func TestHBridgeDriver(t *testing.T) {
+	m, _ := initTestHBridgeDriver()
+	gobottest.Assert(t, m.Name(), "motor")
+	gobottest.Assert(t, m.Connection().Name(), "")
+	gobottest.Assert(t, m.State(), MotorCoast)
+	gobottest.Assert(t, m.PinModes(), map[string]string{"1": "out", "2": "out", "3": "pwm"})
+
+	m.SetStandbyPin("4")
+	gobottest.Assert(t, m.PinModes(), map[string]string{"1": "out", "2": "out", "3": "pwm", "4": "out"})
+
+	m = NewHBridgeDriver(&gpioTestRecorder{}, "motor", "1", "2", "")
+	gobottest.Assert(t, m.PinModes(), map[string]string{"1": "pwm", "2": "pwm"})
+}
+
+func TestHBridgeDriverStartHalt(t *testing.T) {
+	m, r := initTestHBridgeDriver()
+	m.SetStandbyPin("4")
+	gobottest.Assert(t, len(m.Start()), 0)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"3", 0}, {"1", 0}, {"2", 0}, {"4", 1},
+	})
+
+	m.Run(1)
+	r.Writes()
+	gobottest.Assert(t, len(m.Halt()), 0)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"3", 0}, {"1", 0}, {"2", 0}, {"4", 0},
+	})
+	gobottest.Assert(t, m.State(), MotorCoast)
+}
+
+func TestHBridgeDriverRun(t *testing.T) {
+	m, r := initTestHBridgeDriver()
+	m.Start()
+	r.Writes()
+
+	gobottest.Assert(t, m.Run(0.5), nil)
+	gobottest.Assert(t, m.State(), MotorForward)
+	gobottest.Assert(t, m.Speed(), 0.5)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"3", 0}, {"1", 1}, {"2", 0}, {"3", 128},
+	})
+
+	// the channel stays enabled while the direction is kept
+	gobottest.Assert(t, m.Forward(1), nil)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{{"3", 255}})
+
+	gobottest.Assert(t, m.Backward(0.2), nil)
+	gobottest.Assert(t, m.State(), MotorBackward)
+	gobottest.Assert(t, m.Speed(), -0.2)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 1, 51})
+
+	gobottest.Assert(t, m.Run(-3), nil)
+	gobottest.Assert(t, m.Speed(), -1.0)
+
+	gobottest.Assert(t, m.Brake(), nil)
+	gobottest.Assert(t, m.State(), MotorBrake)
+	gobottest.Assert(t, m.Speed(), 0.0)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{1, 1, 255})
+
+	gobottest.Assert(t, m.Run(0), nil)
+	gobottest.Assert(t, m.State(), MotorCoast)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 0, 0})
+}
+
+func TestHBridgeDriverNoEnablePin(t *testing.T) {
+	r := &gpioTestRecorder{}
+	m := NewHBridgeDriver(r, "motor", "1", "2", "")
+
+	gobottest.Assert(t, m.Run(0.5), nil)
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{128, 0})
+	gobottest.Assert(t, m.Run(-1), nil)
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{0, 255})
+	gobottest.Assert(t, m.Brake(), nil)
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{255, 255})
+	gobottest.Assert(t, m.Coast(), nil)
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{0, 0})
+}
+
+func TestHBridgeDriverDigitalOnly(t *testing.T) {
+	m := NewHBridgeDriver(&gpioTestDigitalWriter{}, "motor", "1", "2", "3")
+	gobottest.Assert(t, m.Run(1), nil)
+	gobottest.Assert(t, m.Brake(), nil)
+	gobottest.Assert(t, m.Run(0.5), ErrPwmWriteUnsupported)
+	gobottest.Assert(t, m.State(), MotorBrake)
+}
+
+func TestHBridgeDriverError(t *testing.T) {
+	m, r := initTestHBridgeDriver()
+	m.Start()
+	r.SetError(errors.New("write error"))
+	gobottest.Assert(t, m.Run(1), errors.New("write error"))
+	gobottest.Assert(t, m.State(), MotorCoast)
+	gobottest.Assert(t, m.Start(), []error{errors.New("write error")})
+	gobottest.Assert(t, len(m.Halt()), 1)
+}
+
+func TestHBridgeDriverCommands(t *testing.T) {
+	m, r := initTestHBridgeDriver()
+	m.Start()
+
+	gobottest.Assert(t, m.Command("Run")(map[string]interface{}{"speed": -0.5}), nil)
+	gobottest.Assert(t, m.Command("State")(nil), map[string]interface{}{"state": MotorBackward, "speed": -0.5})
+	gobottest.Assert(t, m.Command("Brake")(nil), nil)
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{1, 1})
+	gobottest.Assert(t, m.Command("Coast")(nil), nil)
+	gobottest.Assert(t, m.Command("State")(nil), map[string]interface{}{"state": MotorCoast, "speed": 0.0})
+}
@@ -1,5 +1,10 @@
 package gpio
 
+import (
+	"sync"
+	"time"
+)
+
 type gpioTestBareAdaptor struct{}
 
 func (t *gpioTestBareAdaptor) Connect() (errs []error)  { return }
@@ -32,6 +37,9 @@ var testAdaptorAnalogRead = func() (val int, err error) {
 var testAdaptorDigitalRead = func() (val int, err error) {
 	return 1, nil
 }
+var testAdaptorConfigurePin = func(string, PinConfig) (err error) {
+	return nil
+}
 
 func (t *gpioTestAdaptor) DigitalWrite(string, byte) (err error) {
 	return testAdaptorDigitalWrite()
@@ -48,6 +56,9 @@ func (t *gpioTestAdaptor) AnalogRead(string) (val int, err error) {
 func (t *gpioTestAdaptor) DigitalRead(string) (val int, err error) {
 	return testAdaptorDigitalRead()
 }
+func (t *gpioTestAdaptor) ConfigurePin(pin string, config PinConfig) (err error) {
+	return testAdaptorConfigurePin(pin, config)
+}
 func (t *gpioTestAdaptor) Connect() (errs []error)  { return }
 func (t *gpioTestAdaptor) Finalize() (errs []error) { return }
 func (t *gpioTestAdaptor) Name() string             { return t.name }
@@ -59,3 +70,200 @@ func newGpioTestAdaptor(name string) *gpioTestAdaptor {
 		port: "/dev/null",
 	}
 }
+
+// gpioTestWrite is a level written to a pin
+type gpioTestWrite struct {
+	pin string
+	val byte
+}
+
+// gpioTestRecorder is a DigitalWriter, PwmWriter and ServoWriter recording
+// the levels written to its pins
+type gpioTestRecorder struct {
+	gpioTestBareAdaptor
+	mutex  sync.Mutex
+	writes []gpioTestWrite
+	levels map[string]byte
+	err    error
+}
+
+func (r *gpioTestRecorder) DigitalWrite(pin string, val byte) (err error) {
+	return r.record(pin, val)
+}
+
+func (r *gpioTestRecorder) PwmWrite(pin string, val byte) (err error) {
+	return r.record(pin, val)
+}
+
+func (r *gpioTestRecorder) ServoWrite(pin string, angle byte) (err error) {
+	return r.record(pin, angle)
+}
+
+func (r *gpioTestRecorder) record(pin string, val byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.writes = append(r.writes, gpioTestWrite{pin: pin, val: val})
+	if r.levels == nil {
+		r.levels = make(map[string]byte)
+	}
+	r.levels[pin] = val
+	return nil
+}
+
+// Writes returns the levels written so far, and forgets them
+func (r *gpioTestRecorder) Writes() []gpioTestWrite {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	writes := r.writes
+	r.writes = nil
+	return writes
+}
+
+// Levels returns the last levels written to pins
+func (r *gpioTestRecorder) Levels(pins ...string) []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	levels := make([]byte, len(pins))
+	for i, pin := range pins {
+		levels[i] = r.levels[pin]
+	}
+	return levels
+}
+
+func (r *gpioTestRecorder) SetError(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.err = err
+}
+
+// gpioTestPulseRecorder is a PwmPulseWriter recording the pulse widths
+// written to its pins, at any period or only at period when it is set
+type gpioTestPulseRecorder struct {
+	gpioTestRecorder
+	period  time.Duration
+	periods map[string]time.Duration
+	widths  map[string]time.Duration
+}
+
+func (r *gpioTestPulseRecorder) PwmPulseWrite(pin string, period time.Duration, width time.Duration) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if r.period != 0 && period != r.period {
+		return ErrPwmPeriodUnsupported
+	}
+	if r.widths == nil {
+		r.periods = make(map[string]time.Duration)
+		r.widths = make(map[string]time.Duration)
+	}
+	r.periods[pin] = period
+	r.widths[pin] = width
+	return nil
+}
+
+// Period returns the last pulse period written to pin
+func (r *gpioTestPulseRecorder) Period(pin string) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.periods[pin]
+}
+
+// Width returns the last pulse width written to pin
+func (r *gpioTestPulseRecorder) Width(pin string) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.widths[pin]
+}
+
+// gpioTestInputs is a DigitalReader whose input levels are set by the test
+type gpioTestInputs struct {
+	gpioTestBareAdaptor
+	mutex  sync.Mutex
+	levels map[string]int
+	err    error
+}
+
+func (i *gpioTestInputs) DigitalRead(pin string) (val int, err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.levels[pin], i.err
+}
+
+// Set sets the levels of pins, given as pin and level pairs
+func (i *gpioTestInputs) Set(pinLevels ...interface{}) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.levels == nil {
+		i.levels = make(map[string]int)
+	}
+	for n := 0; n < len(pinLevels); n += 2 {
+		i.levels[pinLevels[n].(string)] = pinLevels[n+1].(int)
+	}
+}
+
+func (i *gpioTestInputs) SetError(err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.err = err
+}
+
+// gpioTestNotifier is a DigitalNotifier calling its handlers right away when
+// the level of a pin is set
+type gpioTestNotifier struct {
+	gpioTestInputs
+	handlers map[string]func(int)
+	err      error
+}
+
+func (n *gpioTestNotifier) DigitalNotify(pin string, handler func(int)) (stop func(), err error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.err != nil {
+		return nil, n.err
+	}
+	if n.handlers == nil {
+		n.handlers = make(map[string]func(int))
+	}
+	n.handlers[pin] = handler
+	return func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		delete(n.handlers, pin)
+	}, nil
+}
+
+// Set sets the level of pin, and notifies its handler
+func (n *gpioTestNotifier) Set(pin string, val int) {
+	n.gpioTestInputs.Set(pin, val)
+	n.mutex.Lock()
+	handler := n.handlers[pin]
+	n.mutex.Unlock()
+	if handler != nil {
+		handler(val)
+	}
+}
+
+// gpioTestAnalogInput is an AnalogReader whose readings are set by the test
+type gpioTestAnalogInput struct {
+	gpioTestBareAdaptor
+	mutex sync.Mutex
+	val   int
+}
+
+func (i *gpioTestAnalogInput) AnalogRead(pin string) (val int, err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.val, nil
+}
+
+// Set sets the reading of the pins
+func (i *gpioTestAnalogInput) Set(val int) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.val = val
+}
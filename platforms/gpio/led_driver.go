@@ -1,6 +1,12 @@
 package gpio
 
-import "github.com/potix/gobot"
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
 
 var _ gobot.Driver = (*LedDriver)(nil)
 
@@ -9,8 +15,11 @@ type LedDriver struct {
 	pin        string
 	name       string
 	connection DigitalWriter
+	mutex      sync.Mutex
 	high       bool
+	*LedEffects
 	gobot.Commander
+	gobot.Eventer
 }
 
 // NewLedDriver return a new LedDriver given a DigitalWriter, name and pin.
@@ -20,6 +29,9 @@ type LedDriver struct {
 //	"Toggle" - See LedDriver.Toggle
 //	"On" - See LedDriver.On
 //	"Off" - See LedDriver.Off
+//	"Blink" - See LedDriver.Blink, given an interval in milliseconds
+//	"Pulse" - See LedDriver.Pulse, given a period in milliseconds
+//	"Stop" - See LedEffects.Stop
 func NewLedDriver(a DigitalWriter, name string, pin string) *LedDriver {
 	l := &LedDriver{
 		name:       name,
@@ -27,7 +39,9 @@ func NewLedDriver(a DigitalWriter, name string, pin string) *LedDriver {
 		connection: a,
 		high:       false,
 		Commander:  gobot.NewCommander(),
+		Eventer:    gobot.NewEventer(),
 	}
+	l.LedEffects = newLedEffects(l.Eventer)
 
 	l.AddCommand("Brightness", func(params map[string]interface{}) interface{} {
 		level := byte(params["level"].(float64))
@@ -46,14 +60,32 @@ func NewLedDriver(a DigitalWriter, name string, pin string) *LedDriver {
 		return l.Off()
 	})
 
+	l.AddCommand("Blink", func(params map[string]interface{}) interface{} {
+		interval := time.Duration(params["interval"].(float64)) * time.Millisecond
+		return l.Blink(interval)
+	})
+
+	l.AddCommand("Pulse", func(params map[string]interface{}) interface{} {
+		period, _ := params["period"].(float64)
+		return l.Pulse(time.Duration(period) * time.Millisecond)
+	})
+
+	l.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		l.Stop()
+		return nil
+	})
+
 	return l
 }
 
 // Start implements the Driver interface
 func (l *LedDriver) Start() (errs []error) { return }
 
-// Halt implements the Driver interface
-func (l *LedDriver) Halt() (errs []error) { return }
+// Halt stops the effect of the led
+func (l *LedDriver) Halt() (errs []error) {
+	l.Stop()
+	return
+}
 
 // Name returns the LedDrivers name
 func (l *LedDriver) Name() string { return l.name }
@@ -61,6 +93,9 @@ func (l *LedDriver) Name() string { return l.name }
 // Pin returns the LedDrivers name
 func (l *LedDriver) Pin() string { return l.pin }
 
+// PinModes returns the LedDrivers pin and its "out" mode
+func (l *LedDriver) PinModes() map[string]string { return map[string]string{l.pin: "out"} }
+
 // Connection returns the LedDrivers Connection
 func (l *LedDriver) Connection() gobot.Connection {
 	return l.connection.(gobot.Connection)
@@ -68,25 +103,21 @@ func (l *LedDriver) Connection() gobot.Connection {
 
 // State return true if the led is On and false if the led is Off
 func (l *LedDriver) State() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	return l.high
 }
 
-// On sets the led to a high state.
+// On sets the led to a high state, stopping its effect.
 func (l *LedDriver) On() (err error) {
-	if err = l.connection.DigitalWrite(l.Pin(), 1); err != nil {
-		return
-	}
-	l.high = true
-	return
+	l.Stop()
+	return l.write(true)
 }
 
-// Off sets the led to a low state.
+// Off sets the led to a low state, stopping its effect.
 func (l *LedDriver) Off() (err error) {
-	if err = l.connection.DigitalWrite(l.Pin(), 0); err != nil {
-		return
-	}
-	l.high = false
-	return
+	l.Stop()
+	return l.write(false)
 }
 
 // Toggle sets the led to the opposite of it's current state
@@ -99,10 +130,52 @@ func (l *LedDriver) Toggle() (err error) {
 	return
 }
 
-// Brightness sets the led to the specified level of brightness
+// Brightness sets the led to the specified level of brightness, stopping its
+// effect
 func (l *LedDriver) Brightness(level byte) (err error) {
-	if writer, ok := l.connection.(PwmWriter); ok {
-		return writer.PwmWrite(l.Pin(), level)
+	writer, ok := l.connection.(PwmWriter)
+	if !ok {
+		return ErrPwmWriteUnsupported
+	}
+	l.Stop()
+	return writer.PwmWrite(l.Pin(), level)
+}
+
+// Blink blinks the led in the background, switching it on and off every
+// interval, until the effect is stopped
+func (l *LedDriver) Blink(interval time.Duration) error {
+	l.run(blinkStep(interval, interval, l.write))
+	return nil
+}
+
+// Pulse fades the led in and out in the background, once every period, until
+// the effect is stopped. The period must be positive.
+func (l *LedDriver) Pulse(period time.Duration) error {
+	if period <= 0 {
+		return ErrLedPeriod
+	}
+	writer, ok := l.connection.(PwmWriter)
+	if !ok {
+		return ErrPwmWriteUnsupported
 	}
-	return ErrPwmWriteUnsupported
+	l.run(func(elapsed time.Duration) (time.Duration, error) {
+		level := (1 - math.Cos(2*math.Pi*float64(elapsed)/float64(period))) / 2
+		return ledEffectInterval, writer.PwmWrite(l.Pin(), byte(level*255+0.5))
+	})
+	return nil
+}
+
+// write sets the led on or off
+func (l *LedDriver) write(on bool) (err error) {
+	level := byte(0)
+	if on {
+		level = 1
+	}
+	if err = l.connection.DigitalWrite(l.Pin(), level); err != nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.high = on
+	return
 }
@@ -3,6 +3,7 @@ package gpio
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/potix/gobot/gobottest"
 )
@@ -24,6 +25,7 @@ func TestLedDriver(t *testing.T) {
 
 	gobottest.Assert(t, d.Name(), "bot")
 	gobottest.Assert(t, d.Pin(), "1")
+	gobottest.Assert(t, d.PinModes(), map[string]string{"1": "out"})
 	gobottest.Assert(t, d.Connection().Name(), "adaptor")
 
 	testAdaptorDigitalWrite = func() (err error) {
@@ -77,3 +79,39 @@ func TestLedDriverBrightness(t *testing.T) {
 	}
 	gobottest.Assert(t, d.Brightness(150), errors.New("pwm error"))
 }
+
+func TestLedDriverBlink(t *testing.T) {
+	r := &gpioTestRecorder{}
+	d := NewLedDriver(r, "bot", "1")
+
+	gobottest.Assert(t, d.Command("Blink")(map[string]interface{}{"interval": 30.0}), nil)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, d.State(), true)
+	<-time.After(30 * time.Millisecond)
+	gobottest.Assert(t, d.State(), false)
+	gobottest.Assert(t, d.IsRunning(), true)
+
+	// setting the led stops the effect
+	gobottest.Assert(t, d.On(), nil)
+	gobottest.Assert(t, d.IsRunning(), false)
+	<-time.After(40 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1"), []byte{1})
+}
+
+func TestLedDriverPulse(t *testing.T) {
+	d := NewLedDriver(&gpioTestDigitalWriter{}, "bot", "1")
+	gobottest.Assert(t, d.Pulse(time.Second), ErrPwmWriteUnsupported)
+
+	r := &gpioTestRecorder{}
+	d = NewLedDriver(r, "bot", "1")
+	gobottest.Assert(t, d.Pulse(0), ErrLedPeriod)
+	gobottest.Assert(t, d.Pulse(-time.Second), ErrLedPeriod)
+	gobottest.Assert(t, d.Command("Pulse")(map[string]interface{}{}), ErrLedPeriod)
+	gobottest.Assert(t, d.IsRunning(), false)
+	gobottest.Assert(t, d.Command("Pulse")(map[string]interface{}{"period": 200.0}), nil)
+	<-time.After(100 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1")[0] > 200, true)
+	d.Command("Stop")(nil)
+	gobottest.Assert(t, d.IsRunning(), false)
+	gobottest.Assert(t, len(d.Halt()), 0)
+}
@@ -0,0 +1,109 @@
+package gpio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+// ledEffectInterval is the interval at which fading effects are stepped
+var ledEffectInterval = 20 * time.Millisecond
+
+// ledEffectStep steps an effect elapsed after its start, and returns when to
+// step it next, or 0 once the effect is over
+type ledEffectStep func(elapsed time.Duration) (next time.Duration, err error)
+
+// LedEffects runs the effects of a led driver, such as blinking and fading,
+// in the background, one at a time. It is embedded by the led drivers.
+type LedEffects struct {
+	eventer gobot.Eventer
+	mutex   sync.Mutex
+	running chan bool
+	wg      sync.WaitGroup
+}
+
+// newLedEffects returns new LedEffects publishing to the events they add to
+// eventer
+func newLedEffects(eventer gobot.Eventer) *LedEffects {
+	eventer.AddEvent(Done)
+	eventer.AddEvent(Error)
+	return &LedEffects{eventer: eventer}
+}
+
+// Stop stops the running effect, leaving the led as it is
+func (e *LedEffects) Stop() {
+	e.mutex.Lock()
+	if e.running != nil {
+		close(e.running)
+		e.running = nil
+	}
+	e.mutex.Unlock()
+	e.wg.Wait()
+}
+
+// IsRunning returns whether an effect is running
+func (e *LedEffects) IsRunning() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.running != nil
+}
+
+// run stops the running effect, and steps step in the background until it is
+// over or stopped.
+//
+// Emits the Events:
+//	Done - Once the effect is over
+//	Error error - On a write error, which stops the effect
+func (e *LedEffects) run(step ledEffectStep) {
+	e.Stop()
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	running := make(chan bool)
+	e.running = running
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer func() {
+			e.mutex.Lock()
+			if e.running == running {
+				e.running = nil
+			}
+			e.mutex.Unlock()
+		}()
+
+		start := time.Now()
+		for {
+			next, err := step(time.Since(start))
+			if err != nil {
+				gobot.Publish(e.eventer.Event(Error), err)
+				return
+			}
+			if next <= 0 {
+				gobot.Publish(e.eventer.Event(Done), nil)
+				return
+			}
+			select {
+			case <-time.After(next):
+			case <-running:
+				return
+			}
+		}
+	}()
+}
+
+// blinkStep returns the step of an effect calling write with true for on
+// and false for off, staying on for on and off for off
+func blinkStep(on time.Duration, off time.Duration, write func(on bool) error) ledEffectStep {
+	lit := false
+	return func(time.Duration) (next time.Duration, err error) {
+		lit = !lit
+		if err = write(lit); err != nil {
+			return
+		}
+		if lit {
+			return on, nil
+		}
+		return off, nil
+	}
+}
@@ -17,6 +17,7 @@ type MakeyButtonDriver struct {
 	Active     bool
 	interval   time.Duration
 	gobot.Eventer
+	*ButtonGestures
 }
 
 // NewMakeyButtonDriver returns a new MakeyButtonDriver with a polling interval of
@@ -42,6 +43,7 @@ func NewMakeyButtonDriver(a DigitalReader, name string, pin string, v ...time.Du
 	m.AddEvent(Error)
 	m.AddEvent(Push)
 	m.AddEvent(Release)
+	m.ButtonGestures = newButtonGestures(m.Eventer)
 
 	return m
 }
@@ -52,6 +54,9 @@ func (b *MakeyButtonDriver) Name() string { return b.name }
 // Pin returns the MakeyButtonDrivers pin
 func (b *MakeyButtonDriver) Pin() string { return b.pin }
 
+// PinModes returns the MakeyButtonDrivers pin and its "in" mode
+func (b *MakeyButtonDriver) PinModes() map[string]string { return map[string]string{b.pin: "in"} }
+
 // Connection returns the MakeyButtonDrivers Connection
 func (b *MakeyButtonDriver) Connection() gobot.Connection { return b.connection.(gobot.Connection) }
 
@@ -61,15 +66,16 @@ func (b *MakeyButtonDriver) Connection() gobot.Connection { return b.connection.
 // 	Push int - On button push
 //	Release int - On button release
 //	Error error - On button error
+//
+// and the events of ButtonGestures.
 func (b *MakeyButtonDriver) Start() (errs []error) {
-	state := 1
 	go func() {
 		for {
 			newValue, err := b.connection.DigitalRead(b.Pin())
 			if err != nil {
 				gobot.Publish(b.Event(Error), err)
-			} else if newValue != state && newValue != -1 {
-				state = newValue
+			} else if newValue != -1 && b.ButtonGestures.update(newValue == 0) {
+				// the makey button is active low
 				if newValue == 0 {
 					b.Active = true
 					gobot.Publish(b.Event(Push), newValue)
@@ -91,5 +97,6 @@ func (b *MakeyButtonDriver) Start() (errs []error) {
 // Halt stops polling the makey button for new information
 func (b *MakeyButtonDriver) Halt() (errs []error) {
 	b.halt <- true
+	b.ButtonGestures.stop()
 	return
 }
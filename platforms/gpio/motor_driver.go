@@ -40,6 +40,21 @@ func (m *MotorDriver) Name() string { return m.name }
 // Connection returns the MotorDrivers Connection
 func (m *MotorDriver) Connection() gobot.Connection { return m.connection.(gobot.Connection) }
 
+// PinModes returns the pins of the MotorDriver which are set: the "pwm" speed
+// pin, and the "out" switch, direction, forward and backward pins
+func (m *MotorDriver) PinModes() map[string]string {
+	modes := make(map[string]string)
+	for _, pin := range []string{m.SwitchPin, m.DirectionPin, m.ForwardPin, m.BackwardPin} {
+		if pin != "" {
+			modes[pin] = "out"
+		}
+	}
+	if m.SpeedPin != "" {
+		modes[m.SpeedPin] = "pwm"
+	}
+	return modes
+}
+
 // Start implements the Driver interface
 func (m *MotorDriver) Start() (errs []error) { return }
 
@@ -14,7 +14,11 @@ func TestMotorDriver(t *testing.T) {
 	d := NewMotorDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
 	gobottest.Assert(t, d.Name(), "bot")
 	gobottest.Assert(t, d.Connection().Name(), "adaptor")
+	gobottest.Assert(t, d.PinModes(), map[string]string{"1": "pwm"})
 
+	d.ForwardPin = "2"
+	d.BackwardPin = "3"
+	gobottest.Assert(t, d.PinModes(), map[string]string{"1": "pwm", "2": "out", "3": "out"})
 }
 func TestMotorDriverStart(t *testing.T) {
 	d := initTestMotorDriver()
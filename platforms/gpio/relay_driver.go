@@ -55,6 +55,9 @@ func (l *RelayDriver) Name() string { return l.name }
 // Pin returns the RelayDrivers name
 func (l *RelayDriver) Pin() string { return l.pin }
 
+// PinModes returns the RelayDrivers pin and its "out" mode
+func (l *RelayDriver) PinModes() map[string]string { return map[string]string{l.pin: "out"} }
+
 // Connection returns the RelayDrivers Connection
 func (l *RelayDriver) Connection() gobot.Connection {
 	return l.connection.(gobot.Connection)
@@ -0,0 +1,355 @@
+package gpio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*RgbLedDriver)(nil)
+
+// rgbStrobeFlash is how long the flashes of the strobe effect last
+var rgbStrobeFlash = 20 * time.Millisecond
+
+// RgbLedDriver represents an RGB led, whose red, green and blue leds are
+// driven by pwm. The leds of a common anode led light up as their pins go
+// low, which the driver takes care of.
+type RgbLedDriver struct {
+	name        string
+	connection  PwmWriter
+	redPin      string
+	greenPin    string
+	bluePin     string
+	mutex       sync.Mutex
+	commonAnode bool
+	gamma       float64
+	red         byte
+	green       byte
+	blue        byte
+	*LedEffects
+	gobot.Commander
+	gobot.Eventer
+}
+
+// NewRgbLedDriver returns a new RgbLedDriver given a PwmWriter, name and the
+// pins of the red, green and blue leds.
+//
+// Adds the following API Commands:
+//	"SetRGB" - See RgbLedDriver.SetRGB, given "r", "g" and "b"
+//	"SetHex" - See RgbLedDriver.SetHex, given "hex"
+//	"SetHSV" - See RgbLedDriver.SetHSV, given "h", "s" and "v"
+//	"RGB" - See RgbLedDriver.RGB, returning "r", "g" and "b"
+//	"On" - See RgbLedDriver.On
+//	"Off" - See RgbLedDriver.Off
+//	"FadeTo" - See RgbLedDriver.FadeTo, given "hex", a duration in
+//		milliseconds and optionally the name of an easing in Easings
+//	"Blink" - See RgbLedDriver.Blink, given an interval in milliseconds
+//	"Breathe" - See RgbLedDriver.Breathe, given a period in milliseconds
+//	"Rainbow" - See RgbLedDriver.Rainbow, given a period in milliseconds
+//	"Strobe" - See RgbLedDriver.Strobe, given an interval in milliseconds
+//	"Stop" - See LedEffects.Stop
+func NewRgbLedDriver(a PwmWriter, name string, redPin string, greenPin string, bluePin string) *RgbLedDriver {
+	l := &RgbLedDriver{
+		name:       name,
+		connection: a,
+		redPin:     redPin,
+		greenPin:   greenPin,
+		bluePin:    bluePin,
+		gamma:      1,
+		Commander:  gobot.NewCommander(),
+		Eventer:    gobot.NewEventer(),
+	}
+	l.LedEffects = newLedEffects(l.Eventer)
+
+	l.AddCommand("SetRGB", func(params map[string]interface{}) interface{} {
+		return l.SetRGB(byte(params["r"].(float64)), byte(params["g"].(float64)), byte(params["b"].(float64)))
+	})
+	l.AddCommand("SetHex", func(params map[string]interface{}) interface{} {
+		return l.SetHex(params["hex"].(string))
+	})
+	l.AddCommand("SetHSV", func(params map[string]interface{}) interface{} {
+		return l.SetHSV(params["h"].(float64), params["s"].(float64), params["v"].(float64))
+	})
+	l.AddCommand("RGB", func(params map[string]interface{}) interface{} {
+		r, g, b := l.RGB()
+		return map[string]interface{}{"r": r, "g": g, "b": b}
+	})
+	l.AddCommand("On", func(params map[string]interface{}) interface{} {
+		return l.On()
+	})
+	l.AddCommand("Off", func(params map[string]interface{}) interface{} {
+		return l.Off()
+	})
+	l.AddCommand("FadeTo", func(params map[string]interface{}) interface{} {
+		r, g, b, err := parseHexColor(params["hex"].(string))
+		if err != nil {
+			return err
+		}
+		duration := time.Duration(params["duration"].(float64)) * time.Millisecond
+		return l.FadeTo(r, g, b, duration, easing(params))
+	})
+	l.AddCommand("Blink", func(params map[string]interface{}) interface{} {
+		return l.Blink(time.Duration(params["interval"].(float64)) * time.Millisecond)
+	})
+	l.AddCommand("Breathe", func(params map[string]interface{}) interface{} {
+		period, _ := params["period"].(float64)
+		return l.Breathe(time.Duration(period) * time.Millisecond)
+	})
+	l.AddCommand("Rainbow", func(params map[string]interface{}) interface{} {
+		period, _ := params["period"].(float64)
+		return l.Rainbow(time.Duration(period) * time.Millisecond)
+	})
+	l.AddCommand("Strobe", func(params map[string]interface{}) interface{} {
+		return l.Strobe(time.Duration(params["interval"].(float64)) * time.Millisecond)
+	})
+	l.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		l.Stop()
+		return nil
+	})
+
+	return l
+}
+
+// Name returns the RgbLedDrivers name
+func (l *RgbLedDriver) Name() string { return l.name }
+
+// Connection returns the RgbLedDrivers Connection
+func (l *RgbLedDriver) Connection() gobot.Connection { return l.connection.(gobot.Connection) }
+
+// PinModes returns the "pwm" pins of the RgbLedDriver
+func (l *RgbLedDriver) PinModes() map[string]string {
+	return map[string]string{l.redPin: "pwm", l.greenPin: "pwm", l.bluePin: "pwm"}
+}
+
+// Start writes the color of the led, which is off until set
+func (l *RgbLedDriver) Start() (errs []error) {
+	if err := l.write(l.RGB()); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// Halt stops the effect of the led
+func (l *RgbLedDriver) Halt() (errs []error) {
+	l.Stop()
+	return
+}
+
+// SetCommonAnode selects whether the led has a common anode, lighting up as
+// its pins go low, instead of a common cathode
+func (l *RgbLedDriver) SetCommonAnode(commonAnode bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.commonAnode = commonAnode
+}
+
+// SetGamma sets the gamma correcting the levels written, 1 by default, which
+// writes them as they are. As leds look brighter at low levels than they are,
+// a gamma of about 2.2 makes fades look even.
+func (l *RgbLedDriver) SetGamma(gamma float64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.gamma = gamma
+}
+
+// RGB returns the color the led is set to. The blink, breathe and strobe
+// effects light the led up in it.
+func (l *RgbLedDriver) RGB() (r byte, g byte, b byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.red, l.green, l.blue
+}
+
+// SetRGB sets the led to the color of r, g and b, stopping its effect
+func (l *RgbLedDriver) SetRGB(r byte, g byte, b byte) error {
+	l.Stop()
+	return l.set(r, g, b)
+}
+
+// SetHex sets the led to a hex color such as "#ff8000" or "f80", stopping
+// its effect
+func (l *RgbLedDriver) SetHex(hex string) error {
+	r, g, b, err := parseHexColor(hex)
+	if err != nil {
+		return err
+	}
+	return l.SetRGB(r, g, b)
+}
+
+// SetHSV sets the led to the color of the hue h in degrees, saturation s from
+// 0 through 1 and value v from 0 through 1, stopping its effect
+func (l *RgbLedDriver) SetHSV(h float64, s float64, v float64) error {
+	return l.SetRGB(hsvToRGB(h, s, v))
+}
+
+// On sets the led to white, stopping its effect
+func (l *RgbLedDriver) On() error { return l.SetRGB(255, 255, 255) }
+
+// Off switches the led off, stopping its effect
+func (l *RgbLedDriver) Off() error { return l.SetRGB(0, 0, 0) }
+
+// FadeTo fades the led from its color to the color of r, g and b over
+// duration in the background, at the pace of easing, or EaseLinear when it is
+// nil. It stops the effect of the led, and emits Done once the led is set to
+// the color.
+func (l *RgbLedDriver) FadeTo(r byte, g byte, b byte, duration time.Duration, easing Easing) error {
+	if easing == nil {
+		easing = EaseLinear
+	}
+	l.Stop()
+	fromR, fromG, fromB := l.RGB()
+	l.run(func(elapsed time.Duration) (time.Duration, error) {
+		t := 1.0
+		if duration > 0 {
+			t = math.Min(1, float64(elapsed)/float64(duration))
+		}
+		e := easing(t)
+		err := l.set(mix(fromR, r, e), mix(fromG, g, e), mix(fromB, b, e))
+		if err != nil || t == 1 {
+			return 0, err
+		}
+		return ledEffectInterval, nil
+	})
+	return nil
+}
+
+// Blink blinks the led in its color in the background, switching it on and
+// off every interval, until the effect is stopped
+func (l *RgbLedDriver) Blink(interval time.Duration) error {
+	l.run(blinkStep(interval, interval, l.light(1)))
+	return nil
+}
+
+// Strobe flashes the led in its color in the background, every interval,
+// until the effect is stopped
+func (l *RgbLedDriver) Strobe(interval time.Duration) error {
+	off := interval - rgbStrobeFlash
+	if off <= 0 {
+		off = rgbStrobeFlash
+	}
+	l.run(blinkStep(rgbStrobeFlash, off, l.light(1)))
+	return nil
+}
+
+// Breathe fades the led in and out in its color in the background, once every
+// period, until the effect is stopped. The period must be positive.
+func (l *RgbLedDriver) Breathe(period time.Duration) error {
+	if period <= 0 {
+		return ErrLedPeriod
+	}
+	l.run(func(elapsed time.Duration) (time.Duration, error) {
+		brightness := (1 - math.Cos(2*math.Pi*float64(elapsed)/float64(period))) / 2
+		return ledEffectInterval, l.light(brightness)(true)
+	})
+	return nil
+}
+
+// Rainbow cycles the led through the hues in the background, once every
+// period, until the effect is stopped. The period must be positive.
+func (l *RgbLedDriver) Rainbow(period time.Duration) error {
+	if period <= 0 {
+		return ErrLedPeriod
+	}
+	l.run(func(elapsed time.Duration) (time.Duration, error) {
+		hue := math.Mod(360*float64(elapsed)/float64(period), 360)
+		return ledEffectInterval, l.write(hsvToRGB(hue, 1, 1))
+	})
+	return nil
+}
+
+// light returns a function lighting the led up in its color at brightness
+// from 0 through 1 when on, and switching it off otherwise
+func (l *RgbLedDriver) light(brightness float64) func(on bool) error {
+	return func(on bool) error {
+		if !on {
+			return l.write(0, 0, 0)
+		}
+		r, g, b := l.RGB()
+		return l.write(mix(0, r, brightness), mix(0, g, brightness), mix(0, b, brightness))
+	}
+}
+
+// set writes the color of r, g and b, and sets the led to it
+func (l *RgbLedDriver) set(r byte, g byte, b byte) (err error) {
+	if err = l.write(r, g, b); err != nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.red, l.green, l.blue = r, g, b
+	return
+}
+
+// write writes the color of r, g and b to the pins, gamma corrected and
+// inverted for a common anode
+func (l *RgbLedDriver) write(r byte, g byte, b byte) error {
+	l.mutex.Lock()
+	gamma, commonAnode := l.gamma, l.commonAnode
+	l.mutex.Unlock()
+
+	for _, p := range []struct {
+		pin   string
+		level byte
+	}{{l.redPin, r}, {l.greenPin, g}, {l.bluePin, b}} {
+		level := byte(math.Pow(float64(p.level)/255, gamma)*255 + 0.5)
+		if commonAnode {
+			level = 255 - level
+		}
+		if err := l.connection.PwmWrite(p.pin, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mix returns the level t of the way from one level to another
+func mix(from byte, to byte, t float64) byte {
+	return byte(float64(from) + (float64(to)-float64(from))*t + 0.5)
+}
+
+// parseHexColor parses a hex color such as "#ff8000" or "f80"
+func parseHexColor(hex string) (r byte, g byte, b byte, err error) {
+	digits := strings.TrimPrefix(hex, "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	val, e := strconv.ParseUint(digits, 16, 32)
+	if len(digits) != 6 || e != nil {
+		return 0, 0, 0, fmt.Errorf("Invalid hex color %q", hex)
+	}
+	return byte(val >> 16), byte(val >> 8), byte(val), nil
+}
+
+// hsvToRGB converts the hue h in degrees, saturation s and value v from 0
+// through 1 to rgb levels
+func hsvToRGB(h float64, s float64, v float64) (r byte, g byte, b byte) {
+	h = math.Mod(math.Mod(h, 360)+360, 360)
+	s = math.Max(0, math.Min(1, s))
+	v = math.Max(0, math.Min(1, v))
+
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf = c, x
+	case h < 120:
+		rf, gf = x, c
+	case h < 180:
+		gf, bf = c, x
+	case h < 240:
+		gf, bf = x, c
+	case h < 300:
+		rf, bf = x, c
+	default:
+		rf, bf = c, x
+	}
+	m := v - c
+	level := func(f float64) byte { return byte((f+m)*255 + 0.5) }
+	return level(rf), level(gf), level(bf)
+}
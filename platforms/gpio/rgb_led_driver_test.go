@@ -0,0 +1,165 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestRgbLedDriver() (*RgbLedDriver, *gpioTestRecorder) {
+	r := &gpioTestRecorder{}
+	return NewRgbLedDriver(r, "rgb", "1", "2", "3"), r
+}
+
+// waitForLed waits for the Done event of an effect of eventer
+func waitForLed(t *testing.T, eventer gobot.Eventer) {
+	done := make(chan bool, 1)
+	gobot.Once(eventer.Event(Done), func(data interface{}) {
+		done <- true
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("the effect is not done")
+	}
+}
+
+func TestRgbLedDriver(t *testing.T) {
+	l, r := initTestRgbLedDriver()
+	gobottest.Assert(t, l.Name(), "rgb")
+	gobottest.Assert(t, l.PinModes(), map[string]string{"1": "pwm", "2": "pwm", "3": "pwm"})
+
+	// a common anode led is switched off at start
+	l.SetCommonAnode(true)
+	gobottest.Assert(t, len(l.Start()), 0)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{255, 255, 255})
+
+	gobottest.Assert(t, l.SetRGB(255, 128, 0), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 127, 255})
+	l.SetCommonAnode(false)
+
+	gobottest.Assert(t, l.SetHex("#ff8000"), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{255, 128, 0})
+	gobottest.Assert(t, l.Command("SetHex")(map[string]interface{}{"hex": "0f8"}), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 255, 136})
+	gobottest.Assert(t, l.SetHex("#12345"), errors.New("Invalid hex color \"#12345\""))
+	gobottest.Assert(t, l.SetHex("zzzzzz"), errors.New("Invalid hex color \"zzzzzz\""))
+
+	gobottest.Assert(t, l.Command("SetRGB")(map[string]interface{}{"r": 1.0, "g": 2.0, "b": 3.0}), nil)
+	gobottest.Assert(t, l.Command("RGB")(nil), map[string]interface{}{"r": byte(1), "g": byte(2), "b": byte(3)})
+
+	gobottest.Assert(t, l.On(), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{255, 255, 255})
+	gobottest.Assert(t, l.Off(), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 0, 0})
+
+	r.SetError(errors.New("pwm error"))
+	gobottest.Assert(t, l.SetRGB(1, 1, 1), errors.New("pwm error"))
+	red, green, blue := l.RGB()
+	gobottest.Assert(t, []byte{red, green, blue}, []byte{0, 0, 0})
+}
+
+func TestRgbLedDriverHSV(t *testing.T) {
+	l, r := initTestRgbLedDriver()
+	for _, c := range []struct {
+		h, s, v float64
+		rgb     []byte
+	}{
+		{0, 1, 1, []byte{255, 0, 0}},
+		{120, 1, 1, []byte{0, 255, 0}},
+		{240, 1, 0.5, []byte{0, 0, 128}},
+		{-60, 1, 1, []byte{255, 0, 255}},
+		{30, 1, 1, []byte{255, 128, 0}},
+		{0, 0, 1, []byte{255, 255, 255}},
+	} {
+		gobottest.Assert(t, l.SetHSV(c.h, c.s, c.v), nil)
+		gobottest.Assert(t, r.Levels("1", "2", "3"), c.rgb)
+	}
+	gobottest.Assert(t, l.Command("SetHSV")(map[string]interface{}{"h": 180.0, "s": 1.0, "v": 1.0}), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 255, 255})
+}
+
+func TestRgbLedDriverGamma(t *testing.T) {
+	l, r := initTestRgbLedDriver()
+	l.SetGamma(2)
+	gobottest.Assert(t, l.SetRGB(255, 128, 0), nil)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{255, 64, 0})
+	red, green, blue := l.RGB()
+	gobottest.Assert(t, []byte{red, green, blue}, []byte{255, 128, 0})
+}
+
+func TestRgbLedDriverFadeTo(t *testing.T) {
+	l, r := initTestRgbLedDriver()
+	gobottest.Assert(t, l.FadeTo(200, 100, 50, 100*time.Millisecond, EaseInOut), nil)
+	gobottest.Assert(t, l.IsRunning(), true)
+	<-time.After(50 * time.Millisecond)
+	red, _, _ := l.RGB()
+	gobottest.Assert(t, red > 0 && red < 200, true)
+	waitForLed(t, l.Eventer)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{200, 100, 50})
+	gobottest.Assert(t, l.IsRunning(), false)
+
+	// a fade is stopped by setting a color
+	gobottest.Assert(t, l.Command("FadeTo")(map[string]interface{}{"hex": "#000000", "duration": 1000.0, "easing": "out"}), nil)
+	<-time.After(30 * time.Millisecond)
+	gobottest.Assert(t, l.SetHex("#0000ff"), nil)
+	gobottest.Assert(t, l.IsRunning(), false)
+	<-time.After(30 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1", "2", "3"), []byte{0, 0, 255})
+
+	gobottest.Assert(t, l.Command("FadeTo")(map[string]interface{}{"hex": "red", "duration": 0.0}), errors.New("Invalid hex color \"red\""))
+}
+
+func TestRgbLedDriverEffects(t *testing.T) {
+	l, r := initTestRgbLedDriver()
+	l.SetRGB(200, 0, 0)
+
+	gobottest.Assert(t, l.Command("Blink")(map[string]interface{}{"interval": 30.0}), nil)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1"), []byte{200})
+	<-time.After(30 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1"), []byte{0})
+	l.Command("Stop")(nil)
+	gobottest.Assert(t, l.IsRunning(), false)
+	red, _, _ := l.RGB()
+	gobottest.Assert(t, red, byte(200))
+
+	r.Writes()
+	gobottest.Assert(t, l.Command("Strobe")(map[string]interface{}{"interval": 50.0}), nil)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1"), []byte{200})
+	<-time.After(20 * time.Millisecond)
+	gobottest.Assert(t, r.Levels("1"), []byte{0})
+
+	gobottest.Assert(t, l.Breathe(0), ErrLedPeriod)
+	gobottest.Assert(t, l.Command("Breathe")(map[string]interface{}{}), ErrLedPeriod)
+	gobottest.Assert(t, l.Command("Breathe")(map[string]interface{}{"period": 200.0}), nil)
+	<-time.After(100 * time.Millisecond)
+	level := r.Levels("1")[0]
+	gobottest.Assert(t, level > 100 && level <= 200, true)
+
+	gobottest.Assert(t, l.Rainbow(-time.Second), ErrLedPeriod)
+	gobottest.Assert(t, l.Command("Rainbow")(map[string]interface{}{}), ErrLedPeriod)
+	gobottest.Assert(t, l.Command("Rainbow")(map[string]interface{}{"period": 300.0}), nil)
+	<-time.After(110 * time.Millisecond)
+	// a third of the way the hue is about green
+	gobottest.Assert(t, r.Levels("2")[0] > 200, true)
+	gobottest.Assert(t, len(l.Halt()), 0)
+	gobottest.Assert(t, l.IsRunning(), false)
+
+	errs := make(chan interface{}, 1)
+	gobot.Once(l.Event(Error), func(data interface{}) {
+		errs <- data
+	})
+	r.SetError(errors.New("pwm error"))
+	l.Blink(time.Second)
+	select {
+	case err := <-errs:
+		gobottest.Assert(t, err, errors.New("pwm error"))
+	case <-time.After(time.Second):
+		t.Errorf("error not published")
+	}
+}
@@ -1,15 +1,78 @@
 package gpio
 
-import "github.com/potix/gobot"
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
 
 var _ gobot.Driver = (*ServoDriver)(nil)
 
+var (
+	// servoPeriod is the period of the pulses sent to servos
+	servoPeriod = 20 * time.Millisecond
+	// servoStepInterval is the interval at which servo motions are stepped
+	servoStepInterval = 20 * time.Millisecond
+)
+
+// ServoCalibration describes how a servo turns. Servos turn from MinAngle at
+// pulses of MinPulse microseconds to MaxAngle at pulses of MaxPulse
+// microseconds, which vary from servo to servo.
+type ServoCalibration struct {
+	// MinPulse is the pulse width in microseconds at MinAngle
+	MinPulse float64
+	// MaxPulse is the pulse width in microseconds at MaxAngle
+	MaxPulse float64
+	// MinAngle is the lowest angle the servo turns to
+	MinAngle float64
+	// MaxAngle is the highest angle the servo turns to
+	MaxAngle float64
+	// Trim is added to the angles, to correct a horn which is a bit off
+	Trim float64
+	// Inverted turns the servo the other way, as with a servo mounted upside
+	// down
+	Inverted bool
+}
+
+// DefaultServoCalibration is the calibration of servos turning from 0 to
+// 180 degrees at pulses of 0.5 to 2.5 milliseconds
+var DefaultServoCalibration = ServoCalibration{
+	MinPulse: 500,
+	MaxPulse: 2500,
+	MinAngle: 0,
+	MaxAngle: 180,
+}
+
+// pulse returns the pulse width in microseconds which turns the servo to
+// angle, trimmed, inverted and limited to the angle range
+func (c ServoCalibration) pulse(angle float64) float64 {
+	angle += c.Trim
+	if c.Inverted {
+		angle = c.MinAngle + c.MaxAngle - angle
+	}
+	angle = math.Max(c.MinAngle, math.Min(c.MaxAngle, angle))
+	return c.MinPulse + (angle-c.MinAngle)/(c.MaxAngle-c.MinAngle)*(c.MaxPulse-c.MinPulse)
+}
+
 // ServoDriver Represents a Servo
+//
+// The ServoDriver writes the pulse widths of its calibration when the
+// connection is a PwmPulseWriter able to generate its 20 millisecond period.
+// Otherwise it writes the 0-180 degree angles of ServoWrite, which the
+// adaptors send as pulses of about 0.5 to 2.5 milliseconds.
 type ServoDriver struct {
-	name       string
-	pin        string
-	connection ServoWriter
+	name        string
+	pin         string
+	connection  ServoWriter
+	mutex       sync.Mutex
+	calibration ServoCalibration
+	angle       float64
+	motion      chan bool
+	wg          sync.WaitGroup
 	gobot.Commander
+	gobot.Eventer
 	CurrentAngle byte
 }
 
@@ -17,6 +80,10 @@ type ServoDriver struct {
 //
 // Adds the following API Commands:
 // 	"Move" - See ServoDriver.Move
+//	"MoveTo" - See ServoDriver.MoveTo, given a duration in milliseconds and
+//		optionally the name of an easing in Easings
+//	"Stop" - See ServoDriver.Stop
+//	"Angle" - See ServoDriver.Angle
 //	"Min" - See ServoDriver.Min
 //	"Center" - See ServoDriver.Center
 //	"Max" - See ServoDriver.Max
@@ -25,14 +92,30 @@ func NewServoDriver(a ServoWriter, name string, pin string) *ServoDriver {
 		name:         name,
 		connection:   a,
 		pin:          pin,
+		calibration:  DefaultServoCalibration,
 		Commander:    gobot.NewCommander(),
+		Eventer:      gobot.NewEventer(),
 		CurrentAngle: 0,
 	}
 
+	s.AddEvent(Done)
+	s.AddEvent(Error)
+
 	s.AddCommand("Move", func(params map[string]interface{}) interface{} {
 		angle := byte(params["angle"].(float64))
 		return s.Move(angle)
 	})
+	s.AddCommand("MoveTo", func(params map[string]interface{}) interface{} {
+		duration := time.Duration(params["duration"].(float64)) * time.Millisecond
+		return s.MoveTo(params["angle"].(float64), duration, easing(params))
+	})
+	s.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		s.Stop()
+		return nil
+	})
+	s.AddCommand("Angle", func(params map[string]interface{}) interface{} {
+		return s.Angle()
+	})
 	s.AddCommand("Min", func(params map[string]interface{}) interface{} {
 		return s.Min()
 	})
@@ -53,39 +136,202 @@ func (s *ServoDriver) Name() string { return s.name }
 // Pin returns the ServoDrivers pin
 func (s *ServoDriver) Pin() string { return s.pin }
 
+// PinModes returns the ServoDrivers pin and its "servo" mode
+func (s *ServoDriver) PinModes() map[string]string { return map[string]string{s.pin: "servo"} }
+
 // Connection returns the ServoDrivers connection
 func (s *ServoDriver) Connection() gobot.Connection { return s.connection.(gobot.Connection) }
 
 // Start implements the Driver interface
 func (s *ServoDriver) Start() (errs []error) { return }
 
-// Halt implements the Driver interface
-func (s *ServoDriver) Halt() (errs []error) { return }
+// Halt stops the motion of the servo
+func (s *ServoDriver) Halt() (errs []error) {
+	s.Stop()
+	return
+}
 
-// Move sets the servo to the specified angle. Acceptable angles are 0-180
+// SetCalibration sets the calibration of the servo, which defaults to
+// DefaultServoCalibration
+func (s *ServoDriver) SetCalibration(c ServoCalibration) error {
+	if c.MinAngle >= c.MaxAngle || c.MinPulse >= c.MaxPulse {
+		return ErrServoCalibration
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calibration = c
+	return nil
+}
+
+// Calibration returns the calibration of the servo
+func (s *ServoDriver) Calibration() ServoCalibration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calibration
+}
+
+// Angle returns the angle the servo was last set to
+func (s *ServoDriver) Angle() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.angle
+}
+
+// Move sets the servo to the specified angle. Acceptable angles are those of
+// the calibration, 0-180 by default. Move stops the motion of the servo.
 func (s *ServoDriver) Move(angle uint8) (err error) {
-	if !(angle >= 0 && angle <= 180) {
+	s.Stop()
+	if !s.inRange(float64(angle)) {
 		return ErrServoOutOfRange
 	}
-	s.CurrentAngle = angle
-	return s.connection.ServoWrite(s.Pin(), s.angleToSpan(angle))
+	return s.write(float64(angle))
 }
 
 // Min sets the servo to it's minimum position
 func (s *ServoDriver) Min() (err error) {
-	return s.Move(0)
+	s.Stop()
+	return s.write(s.Calibration().MinAngle)
 }
 
 // Center sets the servo to it's center position
 func (s *ServoDriver) Center() (err error) {
-	return s.Move(90)
+	s.Stop()
+	c := s.Calibration()
+	return s.write((c.MinAngle + c.MaxAngle) / 2)
 }
 
 // Max sets the servo to its maximum position
 func (s *ServoDriver) Max() (err error) {
-	return s.Move(180)
+	s.Stop()
+	return s.write(s.Calibration().MaxAngle)
+}
+
+// MoveTo turns the servo to angle over duration in the background, at the
+// pace of easing, or EaseLinear when it is nil. It stops the previous motion
+// of the servo.
+//
+// Emits the Events:
+//	Done float64 - The angle, once the servo reached it
+//	Error error - On a write error, which stops the motion
+func (s *ServoDriver) MoveTo(angle float64, duration time.Duration, easing Easing) error {
+	if !s.inRange(angle) {
+		return ErrServoOutOfRange
+	}
+	s.move(angle, time.Now(), duration, easing)
+	return nil
+}
+
+// MoveServos turns each servo of moves to its angle over duration, at the
+// pace of easing, so that the servos start and arrive together. It returns
+// ErrServoOutOfRange without moving any servo when an angle is out of range.
+func MoveServos(moves map[*ServoDriver]float64, duration time.Duration, easing Easing) error {
+	for s, angle := range moves {
+		if !s.inRange(angle) {
+			return ErrServoOutOfRange
+		}
+	}
+	start := time.Now()
+	for s, angle := range moves {
+		s.move(angle, start, duration, easing)
+	}
+	return nil
+}
+
+// Stop stops the motion of the servo where it is
+func (s *ServoDriver) Stop() {
+	s.mutex.Lock()
+	if s.motion != nil {
+		close(s.motion)
+		s.motion = nil
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+// IsMoving returns whether the servo is in motion
+func (s *ServoDriver) IsMoving() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.motion != nil
+}
+
+// inRange returns whether angle is within the angle range of the servo
+func (s *ServoDriver) inRange(angle float64) bool {
+	c := s.Calibration()
+	return angle >= c.MinAngle && angle <= c.MaxAngle
+}
+
+// move starts moving the servo to angle, timing the motion from start
+func (s *ServoDriver) move(angle float64, start time.Time, duration time.Duration, easing Easing) {
+	s.Stop()
+	if easing == nil {
+		easing = EaseLinear
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	motion := make(chan bool)
+	s.motion = motion
+	s.wg.Add(1)
+	go s.run(s.angle, angle, start, duration, easing, motion)
+}
+
+// run steps the servo from one angle to another, until it arrives or motion
+// is closed
+func (s *ServoDriver) run(from float64, to float64, start time.Time, duration time.Duration, easing Easing, motion chan bool) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		if s.motion == motion {
+			s.motion = nil
+		}
+		s.mutex.Unlock()
+	}()
+
+	for {
+		t := 1.0
+		if duration > 0 {
+			t = math.Min(1, float64(time.Since(start))/float64(duration))
+		}
+		if err := s.write(from + (to-from)*easing(t)); err != nil {
+			gobot.Publish(s.Event(Error), err)
+			return
+		}
+		if t == 1 {
+			gobot.Publish(s.Event(Done), to)
+			return
+		}
+		select {
+		case <-time.After(servoStepInterval):
+		case <-motion:
+			return
+		}
+	}
+}
+
+// write sets the servo to angle
+func (s *ServoDriver) write(angle float64) (err error) {
+	pulse := s.Calibration().pulse(angle)
+	err = ErrPwmPeriodUnsupported
+	if writer, ok := s.connection.(PwmPulseWriter); ok {
+		err = writer.PwmPulseWrite(s.Pin(), servoPeriod, time.Duration(pulse*float64(time.Microsecond)+0.5))
+	}
+	if err == ErrPwmPeriodUnsupported {
+		err = s.connection.ServoWrite(s.Pin(), s.pulseToAngle(pulse))
+	}
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.angle = angle
+	s.CurrentAngle = byte(math.Max(0, math.Min(255, angle+0.5)))
+	return
 }
 
-func (s *ServoDriver) angleToSpan(angle byte) byte {
-	return byte(angle * (255 / 180))
+// pulseToAngle converts a pulse width in microseconds to the 0-180 degree
+// angle of ServoWrite, whose pulses range from 0.5 to 2.5 milliseconds
+func (s *ServoDriver) pulseToAngle(pulse float64) byte {
+	c := DefaultServoCalibration
+	angle := (pulse - c.MinPulse) / (c.MaxPulse - c.MinPulse) * c.MaxAngle
+	return byte(math.Max(0, math.Min(c.MaxAngle, angle)) + 0.5)
 }
@@ -3,7 +3,9 @@ package gpio
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/potix/gobot"
 	"github.com/potix/gobot/gobottest"
 )
 
@@ -18,11 +20,13 @@ func TestServoDriver(t *testing.T) {
 
 	gobottest.Assert(t, d.Name(), "bot")
 	gobottest.Assert(t, d.Pin(), "1")
+	gobottest.Assert(t, d.PinModes(), map[string]string{"1": "servo"})
 	gobottest.Assert(t, d.Connection().Name(), "adaptor")
 
 	testAdaptorServoWrite = func() (err error) {
 		return errors.New("pwm error")
 	}
+	defer func() { testAdaptorServoWrite = func() (err error) { return } }()
 
 	err = d.Command("Min")(nil)
 	gobottest.Assert(t, err.(error), errors.New("pwm error"))
@@ -73,3 +77,153 @@ func TestServoDriverCenter(t *testing.T) {
 	d.Center()
 	gobottest.Assert(t, d.CurrentAngle, uint8(90))
 }
+
+func TestServoDriverServoWrite(t *testing.T) {
+	r := &gpioTestRecorder{}
+	d := NewServoDriver(r, "bot", "1")
+
+	// ServoWrite is given the angle of the pulse width, rounded
+	gobottest.Assert(t, d.Move(100), nil)
+	gobottest.Assert(t, r.Levels("1"), []byte{100})
+	gobottest.Assert(t, d.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MaxAngle: 90}), nil)
+	gobottest.Assert(t, d.Move(45), nil)
+	gobottest.Assert(t, r.Levels("1"), []byte{90})
+	gobottest.Assert(t, d.Max(), nil)
+	gobottest.Assert(t, r.Levels("1"), []byte{135})
+}
+
+func TestServoDriverCalibration(t *testing.T) {
+	r := &gpioTestPulseRecorder{}
+	d := NewServoDriver(r, "bot", "1")
+	gobottest.Assert(t, d.Calibration(), DefaultServoCalibration)
+
+	gobottest.Assert(t, d.Move(90), nil)
+	gobottest.Assert(t, r.Width("1"), 1500*time.Microsecond)
+	gobottest.Assert(t, r.Period("1"), 20*time.Millisecond)
+
+	gobottest.Assert(t, d.SetCalibration(ServoCalibration{MinPulse: 2000, MaxPulse: 1000, MaxAngle: 100}), ErrServoCalibration)
+	gobottest.Assert(t, d.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MinAngle: 10, MaxAngle: 10}), ErrServoCalibration)
+	gobottest.Assert(t, d.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MaxAngle: 100}), nil)
+	gobottest.Assert(t, d.Move(100), nil)
+	gobottest.Assert(t, r.Width("1"), 2000*time.Microsecond)
+	gobottest.Assert(t, d.Move(101), ErrServoOutOfRange)
+	gobottest.Assert(t, d.Center(), nil)
+	gobottest.Assert(t, r.Width("1"), 1500*time.Microsecond)
+	gobottest.Assert(t, d.Angle(), 50.0)
+
+	// the trim moves the servo, but not beyond its range
+	d.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MaxAngle: 100, Trim: 10})
+	gobottest.Assert(t, d.Move(0), nil)
+	gobottest.Assert(t, r.Width("1"), 1100*time.Microsecond)
+	gobottest.Assert(t, d.Move(95), nil)
+	gobottest.Assert(t, r.Width("1"), 2000*time.Microsecond)
+
+	d.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MaxAngle: 100, Trim: 10, Inverted: true})
+	gobottest.Assert(t, d.Move(0), nil)
+	gobottest.Assert(t, r.Width("1"), 1900*time.Microsecond)
+
+	r.SetError(errors.New("write error"))
+	gobottest.Assert(t, d.Move(50), errors.New("write error"))
+	gobottest.Assert(t, d.Angle(), 0.0)
+}
+
+func TestServoDriverFixedPwmPeriod(t *testing.T) {
+	// a pwm which can not generate the servo period, such as pi-blaster's
+	r := &gpioTestPulseRecorder{period: 10 * time.Millisecond}
+	d := NewServoDriver(r, "bot", "1")
+
+	gobottest.Assert(t, d.Move(45), nil)
+	gobottest.Assert(t, r.Width("1"), time.Duration(0))
+	gobottest.Assert(t, r.Levels("1"), []byte{45})
+	gobottest.Assert(t, d.Angle(), 45.0)
+}
+
+func TestServoDriverMoveTo(t *testing.T) {
+	r := &gpioTestPulseRecorder{}
+	d := NewServoDriver(r, "bot", "1")
+	done := make(chan interface{}, 1)
+	gobot.On(d.Event(Done), func(data interface{}) {
+		done <- data
+	})
+
+	gobottest.Assert(t, d.MoveTo(200, time.Second, nil), ErrServoOutOfRange)
+	gobottest.Assert(t, d.MoveTo(180, 100*time.Millisecond, EaseInOut), nil)
+	gobottest.Assert(t, d.IsMoving(), true)
+	<-time.After(50 * time.Millisecond)
+	angle := d.Angle()
+	gobottest.Assert(t, angle > 0 && angle < 180, true)
+
+	select {
+	case data := <-done:
+		gobottest.Assert(t, data, 180.0)
+	case <-time.After(time.Second):
+		t.Errorf("servo did not arrive")
+	}
+	gobottest.Assert(t, d.Angle(), 180.0)
+	gobottest.Assert(t, r.Width("1"), 2500*time.Microsecond)
+	gobottest.Assert(t, d.IsMoving(), false)
+
+	// moves are stopped where they are
+	gobottest.Assert(t, d.Command("MoveTo")(map[string]interface{}{"angle": 0.0, "duration": 1000.0, "easing": "in"}), nil)
+	<-time.After(50 * time.Millisecond)
+	d.Command("Stop")(nil)
+	gobottest.Assert(t, d.IsMoving(), false)
+	angle = d.Command("Angle")(nil).(float64)
+	gobottest.Assert(t, angle > 0 && angle < 180, true)
+	<-time.After(50 * time.Millisecond)
+	gobottest.Assert(t, d.Angle(), angle)
+
+	d.MoveTo(0, time.Second, EaseLinear)
+	gobottest.Assert(t, len(d.Halt()), 0)
+	gobottest.Assert(t, d.IsMoving(), false)
+}
+
+func TestServoDriverMoveToError(t *testing.T) {
+	r := &gpioTestPulseRecorder{}
+	d := NewServoDriver(r, "bot", "1")
+	errs := make(chan interface{}, 1)
+	gobot.On(d.Event(Error), func(data interface{}) {
+		errs <- data
+	})
+
+	r.SetError(errors.New("write error"))
+	gobottest.Assert(t, d.MoveTo(90, 100*time.Millisecond, nil), nil)
+	select {
+	case data := <-errs:
+		gobottest.Assert(t, data, errors.New("write error"))
+	case <-time.After(time.Second):
+		t.Errorf("error not published")
+	}
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, d.IsMoving(), false)
+}
+
+func TestMoveServos(t *testing.T) {
+	r := &gpioTestPulseRecorder{}
+	a := NewServoDriver(r, "a", "1")
+	b := NewServoDriver(r, "b", "2")
+	b.SetCalibration(ServoCalibration{MinPulse: 1000, MaxPulse: 2000, MaxAngle: 90})
+	done := make(chan interface{}, 2)
+	for _, s := range []*ServoDriver{a, b} {
+		gobot.On(s.Event(Done), func(data interface{}) {
+			done <- data
+		})
+	}
+
+	gobottest.Assert(t, MoveServos(map[*ServoDriver]float64{a: 180, b: 100}, 50*time.Millisecond, nil), ErrServoOutOfRange)
+	gobottest.Assert(t, a.IsMoving(), false)
+	gobottest.Assert(t, b.IsMoving(), false)
+
+	start := time.Now()
+	gobottest.Assert(t, MoveServos(map[*ServoDriver]float64{a: 180, b: 90}, 50*time.Millisecond, EaseOut), nil)
+	for n := 0; n < 2; n++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("servos did not arrive")
+		}
+	}
+	gobottest.Assert(t, time.Since(start) < 500*time.Millisecond, true)
+	gobottest.Assert(t, r.Width("1"), 2500*time.Microsecond)
+	gobottest.Assert(t, r.Width("2"), 2000*time.Microsecond)
+}
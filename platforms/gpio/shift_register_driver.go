@@ -0,0 +1,332 @@
+package gpio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*ShiftRegister595Driver)(nil)
+var _ DigitalWriter = (*ShiftRegister595Driver)(nil)
+
+var _ gobot.Driver = (*ShiftRegister165Driver)(nil)
+var _ DigitalReader = (*ShiftRegister165Driver)(nil)
+
+// parseShiftRegisterPin returns the index in a chain of registers of a pin
+// such as "sr1.5", for the sixth pin of the second register, or "13", the
+// same pin counted along the chain
+func parseShiftRegisterPin(pin string, registers int) (int, error) {
+	invalid := fmt.Errorf("Invalid shift register pin %q", pin)
+	index := 0
+	if strings.HasPrefix(pin, "sr") {
+		parts := strings.Split(strings.TrimPrefix(pin, "sr"), ".")
+		if len(parts) != 2 {
+			return 0, invalid
+		}
+		register, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, invalid
+		}
+		bit, err := strconv.Atoi(parts[1])
+		if err != nil || bit < 0 || bit > 7 {
+			return 0, invalid
+		}
+		index = register*8 + bit
+	} else {
+		var err error
+		if index, err = strconv.Atoi(pin); err != nil {
+			return 0, invalid
+		}
+	}
+	if index < 0 || index >= registers*8 {
+		return 0, invalid
+	}
+	return index, nil
+}
+
+// pulsePin writes a high then low level to pin
+func pulsePin(connection DigitalWriter, pin string) (err error) {
+	if err = connection.DigitalWrite(pin, 1); err != nil {
+		return
+	}
+	return connection.DigitalWrite(pin, 0)
+}
+
+// ShiftRegister595Driver represents a chain of 74HC595 shift registers,
+// expanding three output pins to 8 outputs per register. The first register
+// is the one whose serial input is connected to the data pin.
+//
+// The chain is a DigitalWriter itself, whose pins are named "sr0.0" through
+// "sr0.7" for the outputs Q0 through Q7 of the first register, "sr1.0" for Q0
+// of the second and so on, so that drivers such as the LedDriver can be
+// attached to them.
+type ShiftRegister595Driver struct {
+	name       string
+	connection DigitalWriter
+	dataPin    string
+	clockPin   string
+	latchPin   string
+	mutex      sync.Mutex
+	bits       []byte
+	batching   int
+	gobot.Commander
+}
+
+// NewShiftRegister595Driver returns a new ShiftRegister595Driver given a
+// DigitalWriter, name, the pins connected to the serial input, shift clock
+// and latch of the chain, and the number of registers chained.
+//
+// Adds the following API Commands:
+//	"Clear" - See ShiftRegister595Driver.Clear
+func NewShiftRegister595Driver(a DigitalWriter, name string, dataPin string, clockPin string, latchPin string, registers int) *ShiftRegister595Driver {
+	s := &ShiftRegister595Driver{
+		name:       name,
+		connection: a,
+		dataPin:    dataPin,
+		clockPin:   clockPin,
+		latchPin:   latchPin,
+		bits:       make([]byte, registers*8),
+		Commander:  gobot.NewCommander(),
+	}
+
+	s.AddCommand("Clear", func(params map[string]interface{}) interface{} {
+		return s.Clear()
+	})
+
+	return s
+}
+
+// Name returns the ShiftRegister595Drivers name
+func (s *ShiftRegister595Driver) Name() string { return s.name }
+
+// Connection returns the ShiftRegister595Drivers Connection
+func (s *ShiftRegister595Driver) Connection() gobot.Connection {
+	return s.connection.(gobot.Connection)
+}
+
+// PinModes returns the "out" data, clock and latch pins of the
+// ShiftRegister595Driver
+func (s *ShiftRegister595Driver) PinModes() map[string]string {
+	return map[string]string{s.dataPin: "out", s.clockPin: "out", s.latchPin: "out"}
+}
+
+// Connect switches the outputs of the chain off, so that the chain can be
+// used as a connection as well
+func (s *ShiftRegister595Driver) Connect() (errs []error) { return s.Start() }
+
+// Finalize implements the Adaptor interface
+func (s *ShiftRegister595Driver) Finalize() (errs []error) { return }
+
+// Start switches the outputs of the chain off
+func (s *ShiftRegister595Driver) Start() (errs []error) {
+	if err := s.connection.DigitalWrite(s.clockPin, 0); err != nil {
+		return []error{err}
+	}
+	if err := s.connection.DigitalWrite(s.latchPin, 0); err != nil {
+		return []error{err}
+	}
+	if err := s.Clear(); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// Halt implements the Driver interface, leaving the outputs as they are
+func (s *ShiftRegister595Driver) Halt() (errs []error) { return }
+
+// DigitalWrite writes level to the output pin, and latches the outputs of
+// the chain unless in a Batch
+func (s *ShiftRegister595Driver) DigitalWrite(pin string, level byte) error {
+	index, err := parseShiftRegisterPin(pin, len(s.bits)/8)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if level > 0 {
+		level = 1
+	}
+	s.bits[index] = level
+	if s.batching > 0 {
+		return nil
+	}
+	return s.latch()
+}
+
+// Clear switches all the outputs of the chain off
+func (s *ShiftRegister595Driver) Clear() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.bits {
+		s.bits[i] = 0
+	}
+	return s.latch()
+}
+
+// Batch runs f, and latches the outputs written by f at once when it
+// returns, instead of shifting the whole chain out for each of them. The
+// writes of other goroutines during f are latched with them.
+func (s *ShiftRegister595Driver) Batch(f func() error) error {
+	s.mutex.Lock()
+	s.batching++
+	s.mutex.Unlock()
+
+	ferr := f()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batching--
+	if err := s.latch(); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// latch shifts the outputs out, the last output of the last register first,
+// and latches them. The caller holds the mutex.
+func (s *ShiftRegister595Driver) latch() (err error) {
+	for i := len(s.bits) - 1; i >= 0; i-- {
+		if err = s.connection.DigitalWrite(s.dataPin, s.bits[i]); err != nil {
+			return
+		}
+		if err = pulsePin(s.connection, s.clockPin); err != nil {
+			return
+		}
+	}
+	return pulsePin(s.connection, s.latchPin)
+}
+
+// ShiftRegister165Driver represents a chain of 74HC165 shift registers,
+// expanding three pins to 8 inputs per register. The first register is the
+// one whose serial output is connected to the data pin. The clock inhibit
+// pins are expected to be tied low.
+//
+// The chain is a DigitalReader itself, whose pins are named "sr0.0" through
+// "sr0.7" for the inputs D0 through D7 of the first register, "sr1.0" for D0
+// of the second and so on, so that drivers such as the ButtonDriver can be
+// attached to them.
+type ShiftRegister165Driver struct {
+	name       string
+	connection DigitalWriter
+	dataPin    string
+	clockPin   string
+	loadPin    string
+	registers  int
+	mutex      sync.Mutex
+	gobot.Commander
+}
+
+// NewShiftRegister165Driver returns a new ShiftRegister165Driver given a
+// DigitalWriter, which needs to be a DigitalReader as well, name, the pins
+// connected to the serial output, clock and shift/load input of the chain,
+// and the number of registers chained.
+//
+// Adds the following API Commands:
+//	"ReadAll" - See ShiftRegister165Driver.ReadAll
+func NewShiftRegister165Driver(a DigitalWriter, name string, dataPin string, clockPin string, loadPin string, registers int) *ShiftRegister165Driver {
+	s := &ShiftRegister165Driver{
+		name:       name,
+		connection: a,
+		dataPin:    dataPin,
+		clockPin:   clockPin,
+		loadPin:    loadPin,
+		registers:  registers,
+		Commander:  gobot.NewCommander(),
+	}
+
+	s.AddCommand("ReadAll", func(params map[string]interface{}) interface{} {
+		levels, err := s.ReadAll()
+		return map[string]interface{}{"levels": levels, "err": err}
+	})
+
+	return s
+}
+
+// Name returns the ShiftRegister165Drivers name
+func (s *ShiftRegister165Driver) Name() string { return s.name }
+
+// Connection returns the ShiftRegister165Drivers Connection
+func (s *ShiftRegister165Driver) Connection() gobot.Connection {
+	return s.connection.(gobot.Connection)
+}
+
+// PinModes returns the "in" data pin and "out" clock and load pins of the
+// ShiftRegister165Driver
+func (s *ShiftRegister165Driver) PinModes() map[string]string {
+	return map[string]string{s.dataPin: "in", s.clockPin: "out", s.loadPin: "out"}
+}
+
+// Connect prepares the chain for reading, so that the chain can be used as a
+// connection as well
+func (s *ShiftRegister165Driver) Connect() (errs []error) { return s.Start() }
+
+// Finalize implements the Adaptor interface
+func (s *ShiftRegister165Driver) Finalize() (errs []error) { return }
+
+// Start prepares the chain for reading
+func (s *ShiftRegister165Driver) Start() (errs []error) {
+	if _, ok := s.connection.(DigitalReader); !ok {
+		return []error{ErrDigitalReadUnsupported}
+	}
+	if err := s.connection.DigitalWrite(s.clockPin, 0); err != nil {
+		return []error{err}
+	}
+	if err := s.connection.DigitalWrite(s.loadPin, 1); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// Halt implements the Driver interface
+func (s *ShiftRegister165Driver) Halt() (errs []error) { return }
+
+// DigitalRead reads the chain, and returns the level of the input pin
+func (s *ShiftRegister165Driver) DigitalRead(pin string) (val int, err error) {
+	index, err := parseShiftRegisterPin(pin, s.registers)
+	if err != nil {
+		return
+	}
+	levels, err := s.ReadAll()
+	if err != nil {
+		return
+	}
+	return int(levels[index]), nil
+}
+
+// ReadAll reads the levels of all the inputs of the chain, indexed as the
+// pins are counted along the chain
+func (s *ShiftRegister165Driver) ReadAll() (levels []byte, err error) {
+	reader, ok := s.connection.(DigitalReader)
+	if !ok {
+		return nil, ErrDigitalReadUnsupported
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// the inputs are loaded while the load pin is low
+	if err = s.connection.DigitalWrite(s.loadPin, 0); err != nil {
+		return
+	}
+	if err = s.connection.DigitalWrite(s.loadPin, 1); err != nil {
+		return
+	}
+
+	// D7 of the first register comes out first
+	levels = make([]byte, s.registers*8)
+	for r := 0; r < s.registers; r++ {
+		for bit := 7; bit >= 0; bit-- {
+			val, err := reader.DigitalRead(s.dataPin)
+			if err != nil {
+				return nil, err
+			}
+			levels[r*8+bit] = byte(val)
+			if err = pulsePin(s.connection, s.clockPin); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,196 @@
+package gpio
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+// gpioTestShiftRegisters simulates a chain of 74HC595 registers on the pins
+// "data", "clock" and "latch", and a chain of 74HC165 registers on the pins
+// "serial", "shift" and "load"
+type gpioTestShiftRegisters struct {
+	gpioTestBareAdaptor
+	levels  map[string]byte
+	shifted []byte
+	outputs []byte
+	latches int
+	inputs  []byte
+	loaded  []byte
+	read    int
+	err     error
+}
+
+func newGpioTestShiftRegisters(registers int) *gpioTestShiftRegisters {
+	return &gpioTestShiftRegisters{
+		levels:  make(map[string]byte),
+		shifted: make([]byte, registers*8),
+		outputs: make([]byte, registers*8),
+		inputs:  make([]byte, registers*8),
+	}
+}
+
+func (s *gpioTestShiftRegisters) DigitalWrite(pin string, level byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	rising := level == 1 && s.levels[pin] == 0
+	s.levels[pin] = level
+	switch {
+	case pin == "clock" && rising:
+		s.shifted = append([]byte{s.levels["data"]}, s.shifted[:len(s.shifted)-1]...)
+	case pin == "latch" && rising:
+		s.outputs = append([]byte{}, s.shifted...)
+		s.latches++
+	case pin == "load" && level == 0:
+		// the bits come out from D7 of the first register
+		s.loaded = nil
+		for r := 0; r < len(s.inputs)/8; r++ {
+			for bit := 7; bit >= 0; bit-- {
+				s.loaded = append(s.loaded, s.inputs[r*8+bit])
+			}
+		}
+		s.read = 0
+	case pin == "shift" && rising && s.levels["load"] == 1:
+		s.read++
+	}
+	return nil
+}
+
+func (s *gpioTestShiftRegisters) DigitalRead(pin string) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if pin != "serial" || s.read >= len(s.loaded) {
+		return 0, nil
+	}
+	return int(s.loaded[s.read]), nil
+}
+
+func TestParseShiftRegisterPin(t *testing.T) {
+	for pin, index := range map[string]int{"sr0.0": 0, "sr0.7": 7, "sr1.5": 13, "13": 13, "23": 23} {
+		i, err := parseShiftRegisterPin(pin, 3)
+		gobottest.Assert(t, err, nil)
+		gobottest.Assert(t, i, index)
+	}
+	for _, pin := range []string{"sr0.8", "sr3.0", "sr1", "sr-1.0", "srx.1", "24", "-1", "a"} {
+		_, err := parseShiftRegisterPin(pin, 3)
+		gobottest.Assert(t, err, errors.New("Invalid shift register pin \""+pin+"\""))
+	}
+}
+
+func TestShiftRegister595Driver(t *testing.T) {
+	c := newGpioTestShiftRegisters(2)
+	s := NewShiftRegister595Driver(c, "sr", "data", "clock", "latch", 2)
+	gobottest.Assert(t, s.Name(), "sr")
+	gobottest.Assert(t, s.PinModes(), map[string]string{"data": "out", "clock": "out", "latch": "out"})
+
+	c.outputs[3] = 1
+	gobottest.Assert(t, len(s.Connect()), 0)
+	gobottest.Assert(t, c.outputs, make([]byte, 16))
+
+	gobottest.Assert(t, s.DigitalWrite("sr0.0", 1), nil)
+	gobottest.Assert(t, s.DigitalWrite("sr1.5", 1), nil)
+	gobottest.Assert(t, c.outputs, []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0})
+	gobottest.Assert(t, s.DigitalWrite("sr0.0", 0), nil)
+	gobottest.Assert(t, c.outputs[0], byte(0))
+	gobottest.Assert(t, s.DigitalWrite("sr2.0", 1), errors.New("Invalid shift register pin \"sr2.0\""))
+
+	// the writes of a batch are latched at once
+	latches := c.latches
+	gobottest.Assert(t, s.Batch(func() error {
+		for pin := 0; pin < 16; pin += 2 {
+			if err := s.DigitalWrite(strconv.Itoa(pin), 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}), nil)
+	gobottest.Assert(t, c.latches, latches+1)
+	gobottest.Assert(t, c.outputs, []byte{1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 1, 1, 0})
+
+	gobottest.Assert(t, s.Batch(func() error { return errors.New("batch error") }), errors.New("batch error"))
+	gobottest.Assert(t, c.latches, latches+2)
+
+	gobottest.Assert(t, s.Command("Clear")(nil), nil)
+	gobottest.Assert(t, c.outputs, make([]byte, 16))
+
+	c.err = errors.New("write error")
+	gobottest.Assert(t, s.DigitalWrite("sr0.1", 1), errors.New("write error"))
+	gobottest.Assert(t, s.Start(), []error{errors.New("write error")})
+	gobottest.Assert(t, len(s.Halt()), 0)
+	gobottest.Assert(t, len(s.Finalize()), 0)
+}
+
+func TestShiftRegister595DriverLed(t *testing.T) {
+	c := newGpioTestShiftRegisters(2)
+	s := NewShiftRegister595Driver(c, "sr", "data", "clock", "latch", 2)
+	l := NewLedDriver(s, "led", "sr1.2")
+	gobottest.Assert(t, l.Connection().Name(), "sr")
+	s.Start()
+
+	gobottest.Assert(t, l.On(), nil)
+	gobottest.Assert(t, c.outputs[10], byte(1))
+	gobottest.Assert(t, l.Toggle(), nil)
+	gobottest.Assert(t, c.outputs[10], byte(0))
+}
+
+func TestShiftRegister165Driver(t *testing.T) {
+	c := newGpioTestShiftRegisters(2)
+	s := NewShiftRegister165Driver(c, "sr", "serial", "shift", "load", 2)
+	gobottest.Assert(t, s.Name(), "sr")
+	gobottest.Assert(t, s.PinModes(), map[string]string{"serial": "in", "shift": "out", "load": "out"})
+	gobottest.Assert(t, len(s.Connect()), 0)
+
+	c.inputs[0] = 1
+	c.inputs[7] = 1
+	c.inputs[13] = 1
+	levels, err := s.ReadAll()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, levels, []byte{1, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 1, 0, 0})
+	gobottest.Assert(t, s.Command("ReadAll")(nil).(map[string]interface{})["levels"], levels)
+
+	val, err := s.DigitalRead("sr1.5")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 1)
+	val, _ = s.DigitalRead("sr1.4")
+	gobottest.Assert(t, val, 0)
+	_, err = s.DigitalRead("sr5.0")
+	gobottest.Assert(t, err, errors.New("Invalid shift register pin \"sr5.0\""))
+
+	c.err = errors.New("read error")
+	_, err = s.DigitalRead("sr0.0")
+	gobottest.Assert(t, err, errors.New("read error"))
+	gobottest.Assert(t, len(s.Halt()), 0)
+	gobottest.Assert(t, len(s.Finalize()), 0)
+
+	s = NewShiftRegister165Driver(&gpioTestDigitalWriter{}, "sr", "serial", "shift", "load", 1)
+	gobottest.Assert(t, s.Start(), []error{ErrDigitalReadUnsupported})
+	_, err = s.ReadAll()
+	gobottest.Assert(t, err, ErrDigitalReadUnsupported)
+}
+
+func TestShiftRegister165DriverButton(t *testing.T) {
+	c := newGpioTestShiftRegisters(1)
+	s := NewShiftRegister165Driver(c, "sr", "serial", "shift", "load", 1)
+	b := NewButtonDriver(s, "button", "sr0.3", time.Millisecond)
+	b.SetDoubleClickTime(0)
+	gobottest.Assert(t, len(s.Start()), 0)
+
+	pushes := make(chan bool, 1)
+	gobot.Once(b.Event(Push), func(data interface{}) {
+		pushes <- true
+	})
+	c.inputs[3] = 1
+	gobottest.Assert(t, len(b.Start()), 0)
+	defer b.Halt()
+	select {
+	case <-pushes:
+	case <-time.After(time.Second):
+		t.Errorf("button not pushed")
+	}
+}
@@ -0,0 +1,539 @@
+package gpio
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*StepperDriver)(nil)
+
+const (
+	// StepperFull energizes two coils at a time, for full torque
+	StepperFull = "full"
+	// StepperHalf alternates between one and two energized coils, doubling
+	// the steps per revolution
+	StepperHalf = "half"
+	// StepperWave energizes one coil at a time, drawing the least current
+	StepperWave = "wave"
+)
+
+var (
+	// ErrStepperPins is the error resulting when a StepperDriver is started
+	// with neither 2 nor 4 coil pins
+	ErrStepperPins = errors.New("StepperDriver needs 2 or 4 coil pins")
+	// ErrStepperMode is the error resulting when a stepping mode is unknown, or
+	// can not be driven by the pins of a StepperDriver
+	ErrStepperMode = errors.New("stepping mode is not supported by this StepperDriver")
+)
+
+// stepperSequences maps the stepping modes to the levels of the coil pins,
+// in the order A, B, A', B', for each step of the sequence. The steps j of
+// the full and wave sequences are the steps 2j+1 and 2j of the half step
+// sequence.
+var stepperSequences = map[string][][]byte{
+	StepperFull: {{1, 1, 0, 0}, {0, 1, 1, 0}, {0, 0, 1, 1}, {1, 0, 0, 1}},
+	StepperHalf: {{1, 0, 0, 0}, {1, 1, 0, 0}, {0, 1, 0, 0}, {0, 1, 1, 0},
+		{0, 0, 1, 0}, {0, 0, 1, 1}, {0, 0, 0, 1}, {1, 0, 0, 1}},
+	StepperWave: {{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}},
+}
+
+// stepperSequence2 is the full step sequence of motors with 2 coil pins,
+// which are driven through inverters
+var stepperSequence2 = [][]byte{{0, 1}, {1, 1}, {1, 0}, {0, 0}}
+
+// StepperDriver represents a stepper motor, driven either through its coil
+// pins, eg. by an ULN2003 or L293D board, or by a step/dir driver board such
+// as the A4988 or DRV8825.
+//
+// Moves run in the background at up to the maximum speed, ramping the speed
+// up and down with the acceleration when it is set. A move or run started
+// during another takes over from it at the current speed.
+type StepperDriver struct {
+	name               string
+	connection         DigitalWriter
+	pins               []string
+	stepPin            string
+	dirPin             string
+	enablePin          string
+	mode               string
+	sequence           [][]byte
+	phase              int
+	levels             map[string]byte
+	stepsPerRevolution int
+	mutex              sync.Mutex
+	maxSpeed           float64
+	acceleration       float64
+	position           int
+	target             int
+	continuous         bool
+	runSpeed           float64
+	velocity           float64
+	running            bool
+	halt               chan bool
+	wg                 sync.WaitGroup
+	gobot.Commander
+	gobot.Eventer
+}
+
+// NewStepperDriver returns a new StepperDriver in full step mode given a
+// DigitalWriter, name, the steps per revolution of the motor and its 2 or 4
+// coil pins. 4 coil pins are given in the order A, B, A', B', which are
+// IN1 through IN4 of an ULN2003 board.
+//
+// Adds the following API Commands:
+//	"Move" - See StepperDriver.Move
+//	"MoveTo" - See StepperDriver.MoveTo
+//	"Run" - See StepperDriver.Run
+//	"Stop" - See StepperDriver.Stop
+//	"Position" - See StepperDriver.Position
+//	"SetMaxSpeed" - See StepperDriver.SetMaxSpeed
+//	"SetAcceleration" - See StepperDriver.SetAcceleration
+func NewStepperDriver(a DigitalWriter, name string, stepsPerRevolution int, pins ...string) *StepperDriver {
+	s := newStepperDriver(a, name, stepsPerRevolution)
+	s.pins = pins
+	s.mode = StepperFull
+	s.sequence = stepperSequences[StepperFull]
+	if len(pins) == 2 {
+		s.sequence = stepperSequence2
+	}
+	return s
+}
+
+// NewStepDirStepperDriver returns a new StepperDriver given a DigitalWriter,
+// name, the steps per revolution of the motor, and the step and direction
+// pins of a driver board such as the A4988 or DRV8825. The stepping mode is
+// selected on the board, and the steps per revolution count microsteps.
+func NewStepDirStepperDriver(a DigitalWriter, name string, stepsPerRevolution int, stepPin string, dirPin string) *StepperDriver {
+	s := newStepperDriver(a, name, stepsPerRevolution)
+	s.stepPin = stepPin
+	s.dirPin = dirPin
+	return s
+}
+
+func newStepperDriver(a DigitalWriter, name string, stepsPerRevolution int) *StepperDriver {
+	s := &StepperDriver{
+		name:               name,
+		connection:         a,
+		stepsPerRevolution: stepsPerRevolution,
+		levels:             make(map[string]byte),
+		maxSpeed:           100,
+		halt:               make(chan bool),
+		Commander:          gobot.NewCommander(),
+		Eventer:            gobot.NewEventer(),
+	}
+
+	s.AddEvent(Done)
+	s.AddEvent(Error)
+
+	s.AddCommand("Move", func(params map[string]interface{}) interface{} {
+		return s.Move(int(params["steps"].(float64)))
+	})
+	s.AddCommand("MoveTo", func(params map[string]interface{}) interface{} {
+		return s.MoveTo(int(params["position"].(float64)))
+	})
+	s.AddCommand("Run", func(params map[string]interface{}) interface{} {
+		return s.Run(params["speed"].(float64))
+	})
+	s.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		s.Stop()
+		return nil
+	})
+	s.AddCommand("Position", func(params map[string]interface{}) interface{} {
+		return s.Position()
+	})
+	s.AddCommand("SetMaxSpeed", func(params map[string]interface{}) interface{} {
+		s.SetMaxSpeed(params["speed"].(float64))
+		return nil
+	})
+	s.AddCommand("SetAcceleration", func(params map[string]interface{}) interface{} {
+		s.SetAcceleration(params["acceleration"].(float64))
+		return nil
+	})
+
+	return s
+}
+
+// Name returns the StepperDrivers name
+func (s *StepperDriver) Name() string { return s.name }
+
+// Connection returns the StepperDrivers Connection
+func (s *StepperDriver) Connection() gobot.Connection { return s.connection.(gobot.Connection) }
+
+// PinModes returns the coil, or step, direction and enable pins of the
+// StepperDriver and their "out" mode
+func (s *StepperDriver) PinModes() map[string]string {
+	modes := make(map[string]string)
+	for _, pin := range append([]string{s.stepPin, s.dirPin, s.enablePin}, s.pins...) {
+		if pin != "" {
+			modes[pin] = "out"
+		}
+	}
+	return modes
+}
+
+// SetEnablePin sets the active low enable pin of a step/dir driver board,
+// which is driven low when the StepperDriver starts and high when it halts
+func (s *StepperDriver) SetEnablePin(pin string) { s.enablePin = pin }
+
+// Start energizes the coils, or enables the driver board
+func (s *StepperDriver) Start() (errs []error) {
+	if s.stepPin == "" && len(s.pins) != 2 && len(s.pins) != 4 {
+		return []error{ErrStepperPins}
+	}
+	if s.enablePin != "" {
+		if err := s.connection.DigitalWrite(s.enablePin, 0); err != nil {
+			return []error{err}
+		}
+	}
+	if s.stepPin == "" {
+		if err := s.writeCoils(); err != nil {
+			return []error{err}
+		}
+	}
+	return
+}
+
+// Halt stops the motor right away, and releases the coils, or disables the
+// driver board
+func (s *StepperDriver) Halt() (errs []error) {
+	s.mutex.Lock()
+	if s.running {
+		close(s.halt)
+		s.halt = make(chan bool)
+		s.running = false
+		s.velocity = 0
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+
+	if s.enablePin != "" {
+		if err := s.connection.DigitalWrite(s.enablePin, 1); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, pin := range s.pins {
+		if err := s.connection.DigitalWrite(pin, 0); err != nil {
+			errs = append(errs, err)
+		}
+		s.levels[pin] = 0
+	}
+	return
+}
+
+// SetMode sets the stepping mode of a motor driven through its coil pins to
+// StepperFull, StepperHalf or StepperWave. Motors with 2 coil pins only
+// support StepperFull.
+func (s *StepperDriver) SetMode(mode string) error {
+	sequence, ok := stepperSequences[mode]
+	if !ok || s.stepPin != "" || (len(s.pins) == 2 && mode != StepperFull) {
+		return ErrStepperMode
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.pins) == 4 {
+		// keep the rotor as close as possible to where it is, through the
+		// step of the half step sequence it is at
+		half := s.phase
+		switch s.mode {
+		case StepperFull:
+			half = 2*s.phase + 1
+		case StepperWave:
+			half = 2 * s.phase
+		}
+		s.phase = half
+		if mode != StepperHalf {
+			s.phase = half / 2
+		}
+		s.sequence = sequence
+	}
+	s.mode = mode
+	return nil
+}
+
+// Mode returns the stepping mode of the StepperDriver
+func (s *StepperDriver) Mode() string { return s.mode }
+
+// StepsPerRevolution returns the steps per revolution of the motor
+func (s *StepperDriver) StepsPerRevolution() int { return s.stepsPerRevolution }
+
+// SetMaxSpeed sets the speed moves run at in steps per second
+func (s *StepperDriver) SetMaxSpeed(speed float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxSpeed = math.Abs(speed)
+}
+
+// SetRPM sets the speed moves run at in revolutions per minute
+func (s *StepperDriver) SetRPM(rpm float64) {
+	s.SetMaxSpeed(rpm * float64(s.stepsPerRevolution) / 60)
+}
+
+// SetAcceleration sets the acceleration and deceleration of the motor in
+// steps per second per second, or 0 to start and stop at full speed
+func (s *StepperDriver) SetAcceleration(acceleration float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.acceleration = math.Abs(acceleration)
+}
+
+// Position returns the position of the motor in steps
+func (s *StepperDriver) Position() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.position
+}
+
+// SetPosition sets the current position of the motor, eg. to 0 when it is
+// at its home position. It is ignored while the motor moves.
+func (s *StepperDriver) SetPosition(position int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.running {
+		s.position = position
+		s.target = position
+	}
+}
+
+// Speed returns the current speed of the motor in steps per second, which is
+// negative while it runs backward
+func (s *StepperDriver) Speed() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.velocity
+}
+
+// IsRunning returns true while the motor moves
+func (s *StepperDriver) IsRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.running
+}
+
+// Move moves the motor steps steps from its current position, backward for
+// negative steps.
+//
+// Emits the Events:
+//	Done int - With the position, once the motor stops there
+//	Error error - On a write error, which stops the motor
+func (s *StepperDriver) Move(steps int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.moveTo(s.position + steps)
+}
+
+// MoveTo moves the motor to position.
+//
+// Emits the Events:
+//	Done int - With the position, once the motor stops there
+//	Error error - On a write error, which stops the motor
+func (s *StepperDriver) MoveTo(position int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.moveTo(position)
+}
+
+// moveTo moves to position, the caller holds the mutex
+func (s *StepperDriver) moveTo(position int) error {
+	if s.maxSpeed == 0 {
+		return errors.New("StepperDriver speed is 0")
+	}
+	s.target = position
+	s.continuous = false
+	s.start()
+	return nil
+}
+
+// Run runs the motor at speed steps per second until it is stopped,
+// backward for a negative speed. The motor speeds up or slows down to speed
+// with the acceleration.
+func (s *StepperDriver) Run(speed float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.continuous = true
+	s.runSpeed = speed
+	s.start()
+	return nil
+}
+
+// Stop slows the motor down to a stop with the acceleration, or stops it
+// right away when there is none. Emits a Done event once it stopped.
+func (s *StepperDriver) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.running {
+		s.continuous = true
+		s.runSpeed = 0
+	}
+}
+
+// start starts moving, unless the motor already moves, the caller holds the
+// mutex
+func (s *StepperDriver) start() {
+	if s.running {
+		return
+	}
+	s.running = true
+	s.wg.Add(1)
+	go s.run(s.halt)
+}
+
+// run steps the motor until it stops or halt is closed
+func (s *StepperDriver) run(halt chan bool) {
+	defer s.wg.Done()
+	for {
+		s.mutex.Lock()
+		select {
+		case <-halt:
+			s.mutex.Unlock()
+			return
+		default:
+		}
+		dir, delay, done := s.nextStep()
+		position := s.position
+		s.mutex.Unlock()
+
+		if done {
+			gobot.Publish(s.Event(Done), position)
+			return
+		}
+		if dir != 0 {
+			if err := s.step(dir); err != nil {
+				s.mutex.Lock()
+				s.running = false
+				s.velocity = 0
+				s.mutex.Unlock()
+				gobot.Publish(s.Event(Error), err)
+				return
+			}
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-halt:
+			return
+		}
+	}
+}
+
+// nextStep updates the velocity of the motor for its next step, returning the
+// direction of the step and the time until the following one. The caller
+// holds the mutex.
+func (s *StepperDriver) nextStep() (dir int, delay time.Duration, done bool) {
+	speed := s.runSpeed
+	if !s.continuous {
+		distance := s.target - s.position
+		if distance == 0 {
+			s.velocity = 0
+			s.running = false
+			return 0, 0, true
+		}
+		speed = sign(float64(distance)) * s.maxSpeed
+		if s.velocity != 0 && sign(s.velocity) != sign(speed) {
+			speed = 0
+		} else if s.acceleration > 0 &&
+			s.velocity*s.velocity >= 2*s.acceleration*math.Abs(float64(distance)) {
+			// slow down to stop at the target
+			speed = 0
+		}
+	}
+
+	v := s.velocity
+	a := s.acceleration
+	switch {
+	case a == 0:
+		v = speed
+	case v == 0:
+		v = sign(speed) * math.Min(math.Sqrt(2*a), math.Abs(speed))
+	case sign(v) == sign(speed):
+		if math.Abs(v) < math.Abs(speed) {
+			v = sign(v) * math.Min(math.Sqrt(v*v+2*a), math.Abs(speed))
+		} else {
+			v = sign(v) * math.Max(math.Sqrt(math.Max(v*v-2*a, 0)), math.Abs(speed))
+		}
+	default:
+		// slow down, before reversing
+		v = sign(v) * math.Sqrt(math.Max(v*v-2*a, 0))
+	}
+	s.velocity = v
+
+	if v == 0 {
+		if s.continuous && speed == 0 {
+			s.running = false
+			return 0, 0, true
+		}
+		return 0, 0, false
+	}
+	return int(sign(v)), time.Duration(float64(time.Second) / math.Abs(v)), false
+}
+
+// step steps the motor once in dir, 1 forward or -1 backward
+func (s *StepperDriver) step(dir int) (err error) {
+	if s.stepPin != "" {
+		level := byte(0)
+		if dir > 0 {
+			level = 1
+		}
+		if err = s.write(s.dirPin, level); err != nil {
+			return
+		}
+		if err = s.connection.DigitalWrite(s.stepPin, 1); err != nil {
+			return
+		}
+		if err = s.connection.DigitalWrite(s.stepPin, 0); err != nil {
+			return
+		}
+	} else {
+		s.mutex.Lock()
+		s.phase = (s.phase + dir + len(s.sequence)) % len(s.sequence)
+		s.mutex.Unlock()
+		if err = s.writeCoils(); err != nil {
+			return
+		}
+	}
+	s.mutex.Lock()
+	s.position += dir
+	s.mutex.Unlock()
+	return
+}
+
+// writeCoils writes the levels of the current step of the sequence to the
+// coil pins
+func (s *StepperDriver) writeCoils() error {
+	s.mutex.Lock()
+	levels := s.sequence[s.phase]
+	s.mutex.Unlock()
+	for i, pin := range s.pins {
+		if err := s.write(pin, levels[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes level to pin, unless it was last written with it
+func (s *StepperDriver) write(pin string, level byte) error {
+	if l, ok := s.levels[pin]; ok && l == level {
+		return nil
+	}
+	if err := s.connection.DigitalWrite(pin, level); err != nil {
+		delete(s.levels, pin)
+		return err
+	}
+	s.levels[pin] = level
+	return nil
+}
+
+// sign returns -1, 0 or 1 for negative, zero and positive values
+func sign(v float64) float64 {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
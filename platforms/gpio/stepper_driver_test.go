@@ -0,0 +1,252 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestStepperDriver(pins ...string) (*StepperDriver, *gpioTestRecorder) {
+	r := &gpioTestRecorder{}
+	s := NewStepperDriver(r, "stepper", 200, pins...)
+	s.SetMaxSpeed(10000)
+	return s, r
+}
+
+// moveStepper runs move and returns the position of the Done event
+func moveStepper(t *testing.T, s *StepperDriver, move func() error) int {
+	done := make(chan int, 1)
+	gobot.Once(s.Event(Done), func(data interface{}) {
+		done <- data.(int)
+	})
+	gobottest.Assert(t, move(), nil)
+	select {
+	case position := <-done:
+		return position
+	case <-time.After(time.Second):
+		t.Fatal("the stepper did not stop")
+	}
+	return 0
+}
+
+func TestStepperDriver(t *testing.T) {
+	s, _ := initTestStepperDriver("1", "2", "3", "4")
+	gobottest.Assert(t, s.Name(), "stepper")
+	gobottest.Assert(t, s.Connection().Name(), "")
+	gobottest.Assert(t, s.Mode(), StepperFull)
+	gobottest.Assert(t, s.StepsPerRevolution(), 200)
+	gobottest.Assert(t, s.PinModes(), map[string]string{"1": "out", "2": "out", "3": "out", "4": "out"})
+
+	s, _ = initTestStepperDriver("1", "2", "3")
+	gobottest.Assert(t, s.Start(), []error{ErrStepperPins})
+
+	s = NewStepDirStepperDriver(&gpioTestRecorder{}, "stepper", 3200, "5", "6")
+	s.SetEnablePin("7")
+	gobottest.Assert(t, s.PinModes(), map[string]string{"5": "out", "6": "out", "7": "out"})
+	gobottest.Assert(t, s.SetMode(StepperHalf), ErrStepperMode)
+}
+
+func TestStepperDriverFullStep(t *testing.T) {
+	s, r := initTestStepperDriver("1", "2", "3", "4")
+	gobottest.Assert(t, len(s.Start()), 0)
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{1, 1, 0, 0})
+
+	gobottest.Assert(t, moveStepper(t, s, func() error { return s.Move(2) }), 2)
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{0, 0, 1, 1})
+	// only the pins which change are written
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"1", 1}, {"2", 1}, {"3", 0}, {"4", 0},
+		{"1", 0}, {"3", 1},
+		{"2", 0}, {"4", 1},
+	})
+
+	gobottest.Assert(t, moveStepper(t, s, func() error { return s.MoveTo(-1) }), -1)
+	gobottest.Assert(t, s.Position(), -1)
+	gobottest.Assert(t, s.IsRunning(), false)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"2", 1}, {"4", 0},
+		{"1", 1}, {"3", 0},
+		{"2", 0}, {"4", 1},
+	})
+
+	gobottest.Assert(t, len(s.Halt()), 0)
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{0, 0, 0, 0})
+}
+
+func TestStepperDriverModes(t *testing.T) {
+	s, r := initTestStepperDriver("1", "2", "3", "4")
+	s.Start()
+	moveStepper(t, s, func() error { return s.Move(3) })
+	r.Writes()
+
+	// full step 3 is half step 7, which stays energized
+	gobottest.Assert(t, s.SetMode(StepperHalf), nil)
+	gobottest.Assert(t, s.Mode(), StepperHalf)
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{1, 0, 0, 1})
+	moveStepper(t, s, func() error { return s.Move(1) })
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{1, 0, 0, 0})
+	moveStepper(t, s, func() error { return s.Move(2) })
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{0, 1, 0, 0})
+
+	// half step 2 is wave step 1
+	gobottest.Assert(t, s.SetMode(StepperWave), nil)
+	moveStepper(t, s, func() error { return s.Move(1) })
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{0, 0, 1, 0})
+	gobottest.Assert(t, s.Position(), 7)
+
+	gobottest.Assert(t, s.SetMode("micro"), ErrStepperMode)
+}
+
+func TestStepperDriverTwoPins(t *testing.T) {
+	s, r := initTestStepperDriver("1", "2")
+	gobottest.Assert(t, len(s.Start()), 0)
+	gobottest.Assert(t, s.SetMode(StepperHalf), ErrStepperMode)
+	gobottest.Assert(t, s.SetMode(StepperFull), nil)
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{0, 1})
+
+	moveStepper(t, s, func() error { return s.Move(1) })
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{1, 1})
+	moveStepper(t, s, func() error { return s.Move(-2) })
+	gobottest.Assert(t, r.Levels("1", "2"), []byte{0, 0})
+}
+
+func TestStepperDriverStepDir(t *testing.T) {
+	r := &gpioTestRecorder{}
+	s := NewStepDirStepperDriver(r, "stepper", 3200, "5", "6")
+	s.SetEnablePin("7")
+	s.SetMaxSpeed(10000)
+	gobottest.Assert(t, len(s.Start()), 0)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{{"7", 0}})
+
+	moveStepper(t, s, func() error { return s.Move(2) })
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"6", 1}, {"5", 1}, {"5", 0}, {"5", 1}, {"5", 0},
+	})
+	moveStepper(t, s, func() error { return s.Move(-1) })
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{
+		{"6", 0}, {"5", 1}, {"5", 0},
+	})
+	gobottest.Assert(t, s.Position(), 1)
+
+	gobottest.Assert(t, len(s.Halt()), 0)
+	gobottest.Assert(t, r.Writes(), []gpioTestWrite{{"7", 1}})
+}
+
+func TestStepperDriverRamp(t *testing.T) {
+	s, _ := initTestStepperDriver("1", "2", "3", "4")
+	s.SetMaxSpeed(100)
+	s.SetAcceleration(200)
+	s.target = 100
+
+	// the speed ramps up to the maximum speed, and back down to stop at the
+	// target
+	velocities := []float64{}
+	for {
+		dir, delay, done := s.nextStep()
+		if done {
+			break
+		}
+		gobottest.Assert(t, dir, 1)
+		gobottest.Assert(t, delay, time.Duration(float64(time.Second)/s.velocity))
+		velocities = append(velocities, s.velocity)
+		s.position += dir
+	}
+	gobottest.Assert(t, len(velocities), 100)
+	gobottest.Assert(t, velocities[0], 20.0)
+	gobottest.Assert(t, velocities[1] > velocities[0], true)
+	gobottest.Assert(t, velocities[50], 100.0)
+	gobottest.Assert(t, velocities[98] < velocities[97], true)
+	gobottest.Assert(t, velocities[99] < 30, true)
+
+	// a move reversing the motor slows it down first
+	s.position = 0
+	s.velocity = 100
+	s.target = -10
+	dir, _, _ := s.nextStep()
+	gobottest.Assert(t, dir, 1)
+	gobottest.Assert(t, s.velocity < 100, true)
+
+	// without acceleration the motor starts at the maximum speed
+	s.SetAcceleration(0)
+	s.velocity = 0
+	dir, delay, _ := s.nextStep()
+	gobottest.Assert(t, dir, -1)
+	gobottest.Assert(t, delay, 10*time.Millisecond)
+}
+
+func TestStepperDriverRunStop(t *testing.T) {
+	s, _ := initTestStepperDriver("1", "2", "3", "4")
+	s.SetAcceleration(100000)
+	s.Start()
+
+	position := moveStepper(t, s, func() error {
+		err := s.Run(-2000)
+		<-time.After(20 * time.Millisecond)
+		gobottest.Assert(t, s.IsRunning(), true)
+		gobottest.Assert(t, s.Speed() < 0, true)
+		s.Stop()
+		return err
+	})
+	gobottest.Assert(t, position < 0, true)
+	gobottest.Assert(t, s.Speed(), 0.0)
+}
+
+func TestStepperDriverHalt(t *testing.T) {
+	s, r := initTestStepperDriver("1", "2", "3", "4")
+	s.SetMaxSpeed(10)
+	s.Start()
+	gobottest.Assert(t, s.Move(1000), nil)
+	<-time.After(10 * time.Millisecond)
+	gobottest.Assert(t, s.IsRunning(), true)
+
+	gobottest.Assert(t, len(s.Halt()), 0)
+	gobottest.Assert(t, s.IsRunning(), false)
+	gobottest.Assert(t, s.Position() < 1000, true)
+	gobottest.Assert(t, r.Levels("1", "2", "3", "4"), []byte{0, 0, 0, 0})
+
+	s.SetPosition(0)
+	gobottest.Assert(t, s.Position(), 0)
+	s.SetMaxSpeed(0)
+	gobottest.Refute(t, s.Move(1), nil)
+}
+
+func TestStepperDriverError(t *testing.T) {
+	s, r := initTestStepperDriver("1", "2", "3", "4")
+	s.Start()
+	r.SetError(errors.New("write error"))
+
+	errs := make(chan error, 1)
+	gobot.Once(s.Event(Error), func(data interface{}) {
+		errs <- data.(error)
+	})
+	gobottest.Assert(t, s.Move(1), nil)
+	select {
+	case err := <-errs:
+		gobottest.Assert(t, err, errors.New("write error"))
+	case <-time.After(time.Second):
+		t.Fatal("no error event")
+	}
+	gobottest.Assert(t, s.Position(), 0)
+}
+
+func TestStepperDriverCommands(t *testing.T) {
+	s, _ := initTestStepperDriver("1", "2", "3", "4")
+	s.Start()
+
+	moveStepper(t, s, func() error {
+		s.Command("SetMaxSpeed")(map[string]interface{}{"speed": 5000.0})
+		s.Command("SetAcceleration")(map[string]interface{}{"acceleration": 0.0})
+		s.Command("Move")(map[string]interface{}{"steps": 5.0})
+		return nil
+	})
+	gobottest.Assert(t, s.Command("Position")(nil), 5)
+
+	moveStepper(t, s, func() error {
+		s.Command("MoveTo")(map[string]interface{}{"position": 1.0})
+		return nil
+	})
+	gobottest.Assert(t, s.Command("Position")(nil), 1)
+}
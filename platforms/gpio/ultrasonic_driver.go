@@ -0,0 +1,287 @@
+package gpio
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*UltrasonicDriver)(nil)
+
+// ErrUltrasonicOutOfRange is the error resulting when no echo returns within
+// the timeout of an UltrasonicDriver, or the echo is beyond its maximum
+// distance
+var ErrUltrasonicOutOfRange = errors.New("ultrasonic echo out of range")
+
+// UltrasonicDriver represents an ultrasonic range finder triggered by a pulse
+// on its trigger pin, which answers with a pulse on its echo pin as long as
+// the sound takes to the obstacle and back, such as the HC-SR04 or a
+// Maxbotix sensor in pulse width mode. Sensors with a single signal pin use
+// the same pin to trigger and echo.
+//
+// The echo is timed by the connection when it is a PulseReader, such as the
+// BoardAdaptor, and by polling the echo pin otherwise, which needs a fast
+// DigitalRead to be precise.
+type UltrasonicDriver struct {
+	name        string
+	connection  DigitalWriter
+	triggerPin  string
+	echoPin     string
+	interval    time.Duration
+	timeout     time.Duration
+	maxDistance float64
+	temperature float64
+	samples     int
+	pingMutex   sync.Mutex
+	mutex       sync.Mutex
+	window      []float64
+	distance    float64
+	halt        chan bool
+	gobot.Commander
+	gobot.Eventer
+}
+
+// NewUltrasonicDriver returns a new UltrasonicDriver given a DigitalWriter,
+// which needs to be a DigitalReader as well, name, trigger pin and echo pin.
+// It measures the distance every 100 milliseconds.
+//
+// Optionally accepts:
+//	time.Duration: Interval at which the distance is measured
+//
+// Adds the following API Commands:
+//	"Distance" - See UltrasonicDriver.Distance
+//	"Measure" - See UltrasonicDriver.Measure
+func NewUltrasonicDriver(a DigitalWriter, name string, triggerPin string, echoPin string, v ...time.Duration) *UltrasonicDriver {
+	u := &UltrasonicDriver{
+		name:        name,
+		connection:  a,
+		triggerPin:  triggerPin,
+		echoPin:     echoPin,
+		interval:    100 * time.Millisecond,
+		timeout:     30 * time.Millisecond,
+		maxDistance: 400,
+		temperature: 20,
+		samples:     5,
+		halt:        make(chan bool),
+		Commander:   gobot.NewCommander(),
+		Eventer:     gobot.NewEventer(),
+	}
+
+	if len(v) > 0 {
+		u.interval = v[0]
+	}
+
+	u.AddEvent(Distance)
+	u.AddEvent(Error)
+
+	u.AddCommand("Distance", func(params map[string]interface{}) interface{} {
+		return u.Distance()
+	})
+	u.AddCommand("Measure", func(params map[string]interface{}) interface{} {
+		distance, err := u.Measure()
+		if err != nil {
+			return err
+		}
+		return distance
+	})
+
+	return u
+}
+
+// Name returns the UltrasonicDrivers name
+func (u *UltrasonicDriver) Name() string { return u.name }
+
+// Connection returns the UltrasonicDrivers Connection
+func (u *UltrasonicDriver) Connection() gobot.Connection { return u.connection.(gobot.Connection) }
+
+// PinModes returns the "out" trigger pin and "in" echo pin of the
+// UltrasonicDriver, or the "inout" pin of a sensor with a single signal pin
+func (u *UltrasonicDriver) PinModes() map[string]string {
+	if u.triggerPin == u.echoPin {
+		return map[string]string{u.triggerPin: "inout"}
+	}
+	return map[string]string{u.triggerPin: "out", u.echoPin: "in"}
+}
+
+// SetTimeout sets how long to wait for an echo, 30 milliseconds by default,
+// which is the time the sound takes to travel 5 meters and back
+func (u *UltrasonicDriver) SetTimeout(timeout time.Duration) { u.timeout = timeout }
+
+// SetMaxDistance sets the distance in centimeters beyond which echoes are out
+// of range, 400 by default
+func (u *UltrasonicDriver) SetMaxDistance(distance float64) { u.maxDistance = distance }
+
+// SetTemperature sets the air temperature in degrees celsius, which the speed
+// of sound depends on, 20 by default
+func (u *UltrasonicDriver) SetTemperature(celsius float64) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.temperature = celsius
+}
+
+// SetSamples sets the number of measurements the distance is the median of,
+// which filters out the odd stray echo. It defaults to 5, and 1 leaves the
+// distance unfiltered.
+func (u *UltrasonicDriver) SetSamples(samples int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if samples < 1 {
+		samples = 1
+	}
+	u.samples = samples
+	u.window = nil
+}
+
+// Start measures the distance at the interval.
+//
+// Emits the Events:
+//	Distance float64 - The median of the last distances measured, in centimeters
+//	Error error - On a measuring error, or ErrUltrasonicOutOfRange
+func (u *UltrasonicDriver) Start() (errs []error) {
+	if _, ok := u.connection.(DigitalReader); !ok {
+		return []error{ErrDigitalReadUnsupported}
+	}
+	if err := u.connection.DigitalWrite(u.triggerPin, 0); err != nil {
+		return []error{err}
+	}
+	go func() {
+		for {
+			if distance, err := u.Measure(); err != nil {
+				gobot.Publish(u.Event(Error), err)
+			} else {
+				gobot.Publish(u.Event(Distance), u.filter(distance))
+			}
+			select {
+			case <-time.After(u.interval):
+			case <-u.halt:
+				return
+			}
+		}
+	}()
+	return
+}
+
+// Halt stops measuring the distance
+func (u *UltrasonicDriver) Halt() (errs []error) {
+	u.halt <- true
+	return
+}
+
+// Distance returns the median of the last distances measured, in
+// centimeters
+func (u *UltrasonicDriver) Distance() float64 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.distance
+}
+
+// Measure triggers the sensor, and returns the distance in centimeters to
+// the obstacle echoing it
+func (u *UltrasonicDriver) Measure() (distance float64, err error) {
+	width, err := u.ping()
+	if err != nil {
+		return
+	}
+	u.mutex.Lock()
+	// the speed of sound in meters per second
+	speed := 331.3 + 0.606*u.temperature
+	u.mutex.Unlock()
+	// the echo travels to the obstacle and back
+	distance = width.Seconds() * speed * 100 / 2
+	if distance > u.maxDistance {
+		return 0, ErrUltrasonicOutOfRange
+	}
+	return
+}
+
+// ping sends a trigger pulse of 10 microseconds, and returns the width of the
+// echo pulse. Pings are sent one at a time, so that an echo is not taken for
+// the echo of another ping.
+func (u *UltrasonicDriver) ping() (width time.Duration, err error) {
+	u.pingMutex.Lock()
+	defer u.pingMutex.Unlock()
+
+	if err = u.connection.DigitalWrite(u.triggerPin, 1); err != nil {
+		return
+	}
+	busyWait(10 * time.Microsecond)
+	if err = u.connection.DigitalWrite(u.triggerPin, 0); err != nil {
+		return
+	}
+
+	if reader, ok := u.connection.(PulseReader); ok {
+		if width, err = reader.PulseRead(u.echoPin, 1, u.timeout); err != nil {
+			return
+		}
+		if width <= 0 {
+			return 0, ErrUltrasonicOutOfRange
+		}
+		return
+	}
+
+	reader, ok := u.connection.(DigitalReader)
+	if !ok {
+		return 0, ErrDigitalReadUnsupported
+	}
+	deadline := time.Now().Add(u.timeout)
+	start, err := u.waitFor(reader, 1, deadline)
+	if err != nil {
+		return
+	}
+	end, err := u.waitFor(reader, 0, deadline)
+	if err != nil {
+		return
+	}
+	return end.Sub(start), nil
+}
+
+// waitFor polls the echo pin until it reads level, returning the time it
+// changed, or ErrUltrasonicOutOfRange once deadline passed
+func (u *UltrasonicDriver) waitFor(reader DigitalReader, level int, deadline time.Time) (at time.Time, err error) {
+	for {
+		val, err := reader.DigitalRead(u.echoPin)
+		at = time.Now()
+		if err != nil {
+			return at, err
+		}
+		if val == level {
+			return at, nil
+		}
+		if at.After(deadline) {
+			return at, ErrUltrasonicOutOfRange
+		}
+	}
+}
+
+// filter adds distance to the measurements, and returns their median
+func (u *UltrasonicDriver) filter(distance float64) float64 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.window = append(u.window, distance)
+	if len(u.window) > u.samples {
+		u.window = u.window[len(u.window)-u.samples:]
+	}
+	u.distance = median(u.window)
+	return u.distance
+}
+
+// median returns the median of values
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// busyWait spins for d, which is more precise than sleeping for the
+// microseconds a trigger pulse lasts
+func busyWait(d time.Duration) {
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
@@ -0,0 +1,203 @@
+package gpio
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+// gpioTestSonar is an ultrasonic sensor on pins "1" and "2", echoing a pulse
+// of width after each trigger pulse, and jammed when triggered during an echo
+type gpioTestSonar struct {
+	gpioTestBareAdaptor
+	mutex   sync.Mutex
+	width   time.Duration
+	trigger time.Time
+	jammed  bool
+	err     error
+}
+
+func (s *gpioTestSonar) DigitalWrite(pin string, val byte) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if val == 1 && time.Since(s.trigger) < 100*time.Microsecond+s.width {
+		s.jammed = true
+	}
+	if val == 0 {
+		s.trigger = time.Now()
+	}
+	return s.err
+}
+
+func (s *gpioTestSonar) DigitalRead(pin string) (val int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	since := time.Since(s.trigger)
+	if s.width > 0 && since > 100*time.Microsecond && since < 100*time.Microsecond+s.width {
+		return 1, s.err
+	}
+	return 0, s.err
+}
+
+func (s *gpioTestSonar) SetWidth(width time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.width = width
+}
+
+// gpioTestPulseSonar is a sonar whose connection times its echoes
+type gpioTestPulseSonar struct {
+	gpioTestSonar
+	level   int
+	timeout time.Duration
+}
+
+func (s *gpioTestPulseSonar) PulseRead(pin string, level int, timeout time.Duration) (width time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.level, s.timeout = level, timeout
+	return s.width, s.err
+}
+
+func TestUltrasonicDriver(t *testing.T) {
+	u := NewUltrasonicDriver(&gpioTestSonar{}, "sonar", "1", "2")
+	gobottest.Assert(t, u.Name(), "sonar")
+	gobottest.Assert(t, u.Connection().Name(), "")
+	gobottest.Assert(t, u.PinModes(), map[string]string{"1": "out", "2": "in"})
+	u = NewUltrasonicDriver(&gpioTestSonar{}, "sonar", "1", "1")
+	gobottest.Assert(t, u.PinModes(), map[string]string{"1": "inout"})
+
+	u = NewUltrasonicDriver(&gpioTestDigitalWriter{}, "sonar", "1", "2")
+	gobottest.Assert(t, u.Start(), []error{ErrDigitalReadUnsupported})
+}
+
+func TestUltrasonicDriverPulseRead(t *testing.T) {
+	s := &gpioTestPulseSonar{}
+	s.SetWidth(5824 * time.Microsecond)
+	u := NewUltrasonicDriver(s, "sonar", "1", "2")
+
+	distance, err := u.Measure()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, math.Abs(distance-100) < 0.1, true)
+	gobottest.Assert(t, s.level, 1)
+	gobottest.Assert(t, s.timeout, 30*time.Millisecond)
+
+	// sound is slower in cold air, so the same echo is closer
+	u.SetTemperature(0)
+	distance, _ = u.Measure()
+	gobottest.Assert(t, math.Abs(distance-96.5) < 0.1, true)
+
+	u.SetMaxDistance(50)
+	_, err = u.Measure()
+	gobottest.Assert(t, err, ErrUltrasonicOutOfRange)
+
+	s.SetWidth(0)
+	_, err = u.Measure()
+	gobottest.Assert(t, err, ErrUltrasonicOutOfRange)
+}
+
+func TestUltrasonicDriverBusyWait(t *testing.T) {
+	s := &gpioTestSonar{}
+	s.SetWidth(5824 * time.Microsecond)
+	u := NewUltrasonicDriver(s, "sonar", "1", "2")
+
+	distance, err := u.Measure()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, math.Abs(distance-100) < 10, true)
+
+	// no echo times out
+	s.SetWidth(0)
+	u.SetTimeout(5 * time.Millisecond)
+	start := time.Now()
+	_, err = u.Measure()
+	gobottest.Assert(t, err, ErrUltrasonicOutOfRange)
+	gobottest.Assert(t, time.Since(start) < 100*time.Millisecond, true)
+
+	// an echo longer than the timeout does too
+	s.SetWidth(20 * time.Millisecond)
+	_, err = u.Measure()
+	gobottest.Assert(t, err, ErrUltrasonicOutOfRange)
+
+	// measurements do not trigger the sensor during each others echoes
+	s = &gpioTestSonar{}
+	s.SetWidth(5824 * time.Microsecond)
+	u = NewUltrasonicDriver(s, "sonar", "1", "2")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.Measure()
+		}()
+	}
+	wg.Wait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	gobottest.Assert(t, s.jammed, false)
+}
+
+func TestUltrasonicDriverMedian(t *testing.T) {
+	u := NewUltrasonicDriver(&gpioTestSonar{}, "sonar", "1", "2")
+	u.SetSamples(3)
+	gobottest.Assert(t, u.filter(10), 10.0)
+	gobottest.Assert(t, u.filter(12), 11.0)
+	// a stray echo is filtered out
+	gobottest.Assert(t, u.filter(300), 12.0)
+	gobottest.Assert(t, u.filter(11), 12.0)
+	gobottest.Assert(t, u.filter(13), 13.0)
+	gobottest.Assert(t, u.Distance(), 13.0)
+
+	u.SetSamples(1)
+	gobottest.Assert(t, u.filter(300), 300.0)
+}
+
+func TestUltrasonicDriverStart(t *testing.T) {
+	s := &gpioTestPulseSonar{}
+	s.SetWidth(5824 * time.Microsecond)
+	u := NewUltrasonicDriver(s, "sonar", "1", "2", 10*time.Millisecond)
+
+	distances := make(chan float64, 1)
+	gobot.Once(u.Event(Distance), func(data interface{}) {
+		distances <- data.(float64)
+	})
+	gobottest.Assert(t, len(u.Start()), 0)
+	select {
+	case distance := <-distances:
+		gobottest.Assert(t, math.Abs(distance-100) < 0.1, true)
+	case <-time.After(time.Second):
+		t.Fatal("no distance event")
+	}
+
+	errs := make(chan error, 1)
+	gobot.Once(u.Event(Error), func(data interface{}) {
+		errs <- data.(error)
+	})
+	s.SetWidth(0)
+	select {
+	case err := <-errs:
+		gobottest.Assert(t, err, ErrUltrasonicOutOfRange)
+	case <-time.After(time.Second):
+		t.Fatal("no error event")
+	}
+	gobottest.Assert(t, len(u.Halt()), 0)
+}
+
+func TestUltrasonicDriverCommands(t *testing.T) {
+	s := &gpioTestPulseSonar{}
+	s.SetWidth(5824 * time.Microsecond)
+	u := NewUltrasonicDriver(s, "sonar", "1", "2")
+
+	distance := u.Command("Measure")(nil).(float64)
+	gobottest.Assert(t, math.Abs(distance-100) < 0.1, true)
+	gobottest.Assert(t, u.Command("Distance")(nil), 0.0)
+
+	s.mutex.Lock()
+	s.err = errors.New("write error")
+	s.mutex.Unlock()
+	gobottest.Assert(t, u.Command("Measure")(nil), errors.New("write error"))
+}
@@ -38,3 +38,7 @@ type I2c interface {
 	I2cReader
 	I2cWriter
 }
+
+func init() {
+	gobot.RegisterInterface("I2c", func(a gobot.Adaptor) bool { _, ok := a.(I2c); return ok })
+}
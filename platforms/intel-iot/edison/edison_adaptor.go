@@ -1,13 +1,14 @@
 package edison
 
 import (
-	"errors"
-	"os"
+	"fmt"
 	"strconv"
 
 	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/board"
 	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/platforms/i2c"
+	"github.com/potix/gobot/platforms/spi"
 	"github.com/potix/gobot/sysfs"
 )
 
@@ -17,34 +18,30 @@ var _ gpio.DigitalReader = (*EdisonAdaptor)(nil)
 var _ gpio.DigitalWriter = (*EdisonAdaptor)(nil)
 var _ gpio.AnalogReader = (*EdisonAdaptor)(nil)
 var _ gpio.PwmWriter = (*EdisonAdaptor)(nil)
+var _ gpio.ServoWriter = (*EdisonAdaptor)(nil)
+var _ gpio.PinConfigurer = (*EdisonAdaptor)(nil)
 
 var _ i2c.I2c = (*EdisonAdaptor)(nil)
 
-func writeFile(path string, data []byte) (i int, err error) {
-	file, err := sysfs.OpenFile(path, os.O_WRONLY, 0644)
-	defer file.Close()
-	if err != nil {
-		return
-	}
-
-	return file.Write(data)
-}
-
-func readFile(path string) ([]byte, error) {
-	file, err := sysfs.OpenFile(path, os.O_RDONLY, 0644)
-	defer file.Close()
-	if err != nil {
-		return make([]byte, 0), err
-	}
-
-	buf := make([]byte, 200)
-	var i = 0
-	i, err = file.Read(buf)
-	if i == 0 {
-		return buf, err
-	}
-	return buf[:i], err
-}
+var _ spi.Spi = (*EdisonAdaptor)(nil)
+
+const (
+	// Arduino is the board type of the Arduino breakout board, whose pins
+	// are named "0" through "13". Devices use /dev/i2c-6, which is wired to
+	// the SDA and SCL pins once the breakout muxes are set, and
+	// /dev/spidev5.1, which is wired to pins 10 through 13.
+	Arduino = "arduino"
+	// Miniboard is the board type of the mini breakout board, whose pins are
+	// named after their header pin, eg. "J17-1", or their gpio, eg. "GP182".
+	// The pins are not level shifted and use 1.8V logic. Devices use
+	// /dev/i2c-1 on GP19 (SCL) and GP20 (SDA), and /dev/spidev5.1 on GP109
+	// (SCK), GP111 (CS), GP114 (MISO) and GP115 (MOSI).
+	Miniboard = "miniboard"
+	// Sparkfun is the board type of the SparkFun blocks, whose pins are named
+	// after their gpio, eg. "GP44". Devices use the same buses as on the mini
+	// breakout board.
+	Sparkfun = "sparkfun"
+)
 
 type mux struct {
 	pin   int
@@ -58,14 +55,19 @@ type sysfsPin struct {
 	mux          []mux
 }
 
+// busPin describes how a pin of the Arduino breakout board is routed to a
+// spi or uart pin of the Edison
+type busPin struct {
+	mux []mux
+	// gpio is the Edison pin whose pinmux selects the bus function
+	gpio  int
+	input bool
+}
+
 // EdisonAdaptor represents an Intel Edison
 type EdisonAdaptor struct {
-	name        string
-	tristate    sysfs.DigitalPin
-	digitalPins map[int]sysfs.DigitalPin
-	pwmPins     map[int]*pwmPin
-	i2cDevice   sysfs.I2cDevice
-	connect     func(e *EdisonAdaptor) (err error)
+	*board.BoardAdaptor
+	boardType string
 }
 
 var sysfsPinMap = map[string]sysfsPin{
@@ -180,340 +182,191 @@ var sysfsPinMap = map[string]sysfsPin{
 	},
 }
 
-// changePinMode writes pin mode to current_pinmux file
-func changePinMode(pin, mode string) (err error) {
-	_, err = writeFile(
-		"/sys/kernel/debug/gpio_debug/gpio"+pin+"/current_pinmux",
-		[]byte("mode"+mode),
-	)
-	return
+// spiPins routes spi bus 5 to pins 10 (SS), 11 (MOSI), 12 (MISO) and 13 (SCK)
+var spiPins = map[string]busPin{
+	"10": busPin{mux: []mux{mux{263, sysfs.HIGH}, mux{240, sysfs.HIGH}}, gpio: 111},
+	"11": busPin{mux: []mux{mux{262, sysfs.HIGH}, mux{241, sysfs.HIGH}}, gpio: 115},
+	"12": busPin{mux: []mux{mux{242, sysfs.HIGH}}, gpio: 114, input: true},
+	"13": busPin{mux: []mux{mux{243, sysfs.HIGH}}, gpio: 109},
 }
 
-// NewEdisonAdaptor returns a new EdisonAdaptor with specified name
-func NewEdisonAdaptor(name string) *EdisonAdaptor {
-	return &EdisonAdaptor{
-		name: name,
-		//i2cDevices: make(map[int]io.ReadWriteCloser),
-		//i2cDevices: make(map[int]io.ReadWriteCloser),
-		connect: func(e *EdisonAdaptor) (err error) {
-			e.tristate = sysfs.NewDigitalPin(214)
-			if err = e.tristate.Export(); err != nil {
-				return err
-			}
-			if err = e.tristate.Direction(sysfs.OUT); err != nil {
-				return err
-			}
-			if err = e.tristate.Write(sysfs.LOW); err != nil {
-				return err
-			}
-
-			for _, i := range []int{263, 262} {
-				io := sysfs.NewDigitalPin(i)
-				if err = io.Export(); err != nil {
-					return err
-				}
-				if err = io.Direction(sysfs.OUT); err != nil {
-					return err
-				}
-				if err = io.Write(sysfs.HIGH); err != nil {
-					return err
-				}
-				if err = io.Unexport(); err != nil {
-					return err
-				}
-			}
-
-			for _, i := range []int{240, 241, 242, 243} {
-				io := sysfs.NewDigitalPin(i)
-				if err = io.Export(); err != nil {
-					return err
-				}
-				if err = io.Direction(sysfs.OUT); err != nil {
-					return err
-				}
-				if err = io.Write(sysfs.LOW); err != nil {
-					return err
-				}
-				if err = io.Unexport(); err != nil {
-					return err
-				}
-
-			}
-
-			for _, i := range []string{"111", "115", "114", "109"} {
-				if err = changePinMode(i, "1"); err != nil {
-					return err
-				}
-			}
-
-			for _, i := range []string{"131", "129", "40"} {
-				if err = changePinMode(i, "0"); err != nil {
-					return err
-				}
-			}
+// uartPins routes uart 1 to pins 0 (RX) and 1 (TX)
+var uartPins = map[string]busPin{
+	"0": busPin{gpio: 130, input: true},
+	"1": busPin{gpio: 131},
+}
 
-			err = e.tristate.Write(sysfs.HIGH)
-			return
-		},
+// pinmux returns the step which writes mode to the current_pinmux file of gpio
+func pinmux(gpio int, mode string) board.Step {
+	return board.Step{
+		File:  fmt.Sprintf("/sys/kernel/debug/gpio_debug/gpio%v/current_pinmux", gpio),
+		Value: "mode" + mode,
 	}
 }
 
-// Name returns the EdisonAdaptors name
-func (e *EdisonAdaptor) Name() string { return e.name }
-
-// Connect initializes the Edison for use with the Arduino beakout board
-func (e *EdisonAdaptor) Connect() (errs []error) {
-	e.digitalPins = make(map[int]sysfs.DigitalPin)
-	e.pwmPins = make(map[int]*pwmPin)
-	if err := e.connect(e); err != nil {
-		return []error{err}
+// gpioSteps returns the steps which set each gpio to direction, unexporting
+// them afterwards when unexport is set
+func gpioSteps(direction string, unexport bool, gpios ...int) (steps []board.Step) {
+	for _, i := range gpios {
+		steps = append(steps, board.Step{Gpio: i, Direction: direction, Unexport: unexport})
 	}
 	return
 }
 
-// Finalize releases all i2c devices and exported analog, digital, pwm pins.
-func (e *EdisonAdaptor) Finalize() (errs []error) {
-	if err := e.tristate.Unexport(); err != nil {
-		errs = append(errs, err)
-	}
-	for _, pin := range e.digitalPins {
-		if pin != nil {
-			if err := pin.Unexport(); err != nil {
-				errs = append(errs, err)
-			}
-		}
-	}
-	for _, pin := range e.pwmPins {
-		if pin != nil {
-			if err := pin.enable("0"); err != nil {
-				errs = append(errs, err)
-			}
-			if err := pin.unexport(); err != nil {
-				errs = append(errs, err)
-			}
+// muxSteps returns the steps which set the mux gpios
+func muxSteps(muxes []mux) (steps []board.Step) {
+	for _, m := range muxes {
+		direction := "low"
+		if m.value == sysfs.HIGH {
+			direction = "high"
 		}
+		steps = append(steps, board.Step{Gpio: m.pin, Direction: direction})
 	}
-	if e.i2cDevice != nil {
-		if err := e.i2cDevice.Close(); errs != nil {
-			errs = append(errs, err)
-		}
-	}
-	return errs
+	return
 }
 
-// digitalPin returns matched digitalPin for specified values
-func (e *EdisonAdaptor) digitalPin(pin string, dir string) (sysfsPin sysfs.DigitalPin, err error) {
-	i := sysfsPinMap[pin]
-	if e.digitalPins[i.pin] == nil {
-		e.digitalPins[i.pin] = sysfs.NewDigitalPin(i.pin)
-		if err = e.digitalPins[i.pin].Export(); err != nil {
-			return
-		}
-
-		e.digitalPins[i.resistor] = sysfs.NewDigitalPin(i.resistor)
-		if err = e.digitalPins[i.resistor].Export(); err != nil {
-			return
-		}
-
-		e.digitalPins[i.levelShifter] = sysfs.NewDigitalPin(i.levelShifter)
-		if err = e.digitalPins[i.levelShifter].Export(); err != nil {
-			return
-		}
-
-		if len(i.mux) > 0 {
-			for _, mux := range i.mux {
-				e.digitalPins[mux.pin] = sysfs.NewDigitalPin(mux.pin)
-				if err = e.digitalPins[mux.pin].Export(); err != nil {
-					return
-				}
-
-				if err = e.digitalPins[mux.pin].Direction(sysfs.OUT); err != nil {
-					return
-				}
-
-				if err = e.digitalPins[mux.pin].Write(mux.value); err != nil {
-					return
-				}
-
-			}
-		}
-	}
-
-	if dir == "in" {
-		if err = e.digitalPins[i.pin].Direction(sysfs.IN); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.resistor].Direction(sysfs.OUT); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.resistor].Write(sysfs.LOW); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.levelShifter].Direction(sysfs.OUT); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.levelShifter].Write(sysfs.LOW); err != nil {
-			return
-		}
-
-	} else if dir == "out" {
-		if err = e.digitalPins[i.pin].Direction(sysfs.OUT); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.resistor].Direction(sysfs.IN); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.levelShifter].Direction(sysfs.OUT); err != nil {
-			return
-		}
-
-		if err = e.digitalPins[i.levelShifter].Write(sysfs.HIGH); err != nil {
-			return
-		}
-
+// busSteps returns the steps which route a pin of the Arduino breakout board
+// to a bus, with the pullup resistor disconnected and the level shifter
+// set for the direction of the bus pin
+func busSteps(p sysfsPin, b busPin) []board.Step {
+	levelShifter := "high"
+	if b.input {
+		levelShifter = "low"
 	}
-	return e.digitalPins[i.pin], nil
+	return append(muxSteps(b.mux),
+		board.Step{Gpio: p.resistor, Direction: sysfs.IN},
+		board.Step{Gpio: p.levelShifter, Direction: levelShifter},
+		pinmux(b.gpio, "1"),
+	)
 }
 
-// DigitalRead reads digital value from pin
-func (e *EdisonAdaptor) DigitalRead(pin string) (i int, err error) {
-	sysfsPin, err := e.digitalPin(pin, "in")
-	if err != nil {
-		return
+// description returns a new board description for the board type, or nil for
+// an unknown board type
+func description(boardType string) *board.Description {
+	switch boardType {
+	case Arduino:
+		return arduinoDescription()
+	case Miniboard:
+		return nativeDescription("Intel Edison mini breakout", true)
+	case Sparkfun:
+		return nativeDescription("Intel Edison SparkFun blocks", false)
 	}
-	return sysfsPin.Read()
+	return nil
 }
 
-// DigitalWrite writes a value to the pin. Acceptable values are 1 or 0.
-func (e *EdisonAdaptor) DigitalWrite(pin string, val byte) (err error) {
-	sysfsPin, err := e.digitalPin(pin, "out")
-	if err != nil {
-		return
+// arduinoDescription returns the board description of the Arduino breakout
+// board, where each pin is routed through a pullup resistor gpio, a level
+// shifter gpio and, for some pins, mux gpios. The resistor gpio pulls an
+// input up while it is high and down while it is low, and disconnects the
+// resistor while it is an input itself.
+func arduinoDescription() *board.Description {
+	d := &board.Description{
+		Name:     "Intel Edison Arduino breakout",
+		I2cBus:   6,
+		SpiBus:   5,
+		Pins:     make(map[string]*board.Pin),
+		Analog:   make(map[string]*board.Analog),
+		I2cSetup: make(map[int][]board.Step),
+		SpiPins:  map[int][]string{5: {"10", "11", "12", "13"}},
+		Uarts:    map[int]string{1: "/dev/ttyMFD1"},
+		UartPins: map[int][]string{1: {"0", "1"}},
 	}
-	return sysfsPin.Write(int(val))
-}
 
-// PwmWrite writes the 0-254 value to the specified pin
-func (e *EdisonAdaptor) PwmWrite(pin string, val byte) (err error) {
-	sysPin := sysfsPinMap[pin]
-	if sysPin.pwmPin != -1 {
-		if e.pwmPins[sysPin.pwmPin] == nil {
-			if err = e.DigitalWrite(pin, 1); err != nil {
-				return
-			}
-			if err = changePinMode(strconv.Itoa(int(sysPin.pin)), "1"); err != nil {
-				return
-			}
-			e.pwmPins[sysPin.pwmPin] = newPwmPin(sysPin.pwmPin)
-			if err = e.pwmPins[sysPin.pwmPin].export(); err != nil {
-				return
-			}
-			if err = e.pwmPins[sysPin.pwmPin].enable("1"); err != nil {
-				return
-			}
+	for name, p := range sysfsPinMap {
+		mux := muxSteps(p.mux)
+		pin := &board.Pin{
+			Gpio: p.pin,
+			Modes: map[string][]board.Step{
+				board.ModeIn: append(mux,
+					board.Step{Gpio: p.resistor, Direction: "low"},
+					board.Step{Gpio: p.levelShifter, Direction: "low"},
+				),
+				board.ModeOut: append(mux[:len(mux):len(mux)],
+					board.Step{Gpio: p.resistor, Direction: sysfs.IN},
+					board.Step{Gpio: p.levelShifter, Direction: "high"},
+				),
+			},
+			Pulls: map[string][]board.Step{
+				gpio.PullNone: {{Gpio: p.resistor, Direction: sysfs.IN}},
+				gpio.PullUp:   {{Gpio: p.resistor, Direction: "high"}},
+				gpio.PullDown: {{Gpio: p.resistor, Direction: "low"}},
+			},
 		}
-		p, err := e.pwmPins[sysPin.pwmPin].period()
-		if err != nil {
-			return err
+		if p.pwmPin != -1 {
+			pin.Pwm = &board.Pwm{Chip: 0, Channel: p.pwmPin}
+			pin.Modes[board.ModeIn] = append(pin.Modes[board.ModeIn], pinmux(p.pin, "0"))
+			pin.Modes[board.ModeOut] = append(pin.Modes[board.ModeOut], pinmux(p.pin, "0"))
+			pin.Modes[board.ModePwm] = append(mux[:len(mux):len(mux)],
+				board.Step{Gpio: p.resistor, Direction: sysfs.IN},
+				board.Step{Gpio: p.levelShifter, Direction: "high"},
+				board.Step{Gpio: p.pin, Direction: "high"},
+				pinmux(p.pin, "1"),
+			)
 		}
-		period, err := strconv.Atoi(p)
-		if err != nil {
-			return err
+		if b, ok := spiPins[name]; ok {
+			pin.Modes[board.ModeSpi] = busSteps(p, b)
 		}
-		duty := gobot.FromScale(float64(val), 0, 255.0)
-		return e.pwmPins[sysPin.pwmPin].writeDuty(strconv.Itoa(int(float64(period) * duty)))
+		if b, ok := uartPins[name]; ok {
+			pin.Modes[board.ModeIn] = append(pin.Modes[board.ModeIn], pinmux(p.pin, "0"))
+			pin.Modes[board.ModeOut] = append(pin.Modes[board.ModeOut], pinmux(p.pin, "0"))
+			pin.Modes[board.ModeUart] = busSteps(p, b)
+		}
+		d.Pins[name] = pin
 	}
-	return errors.New("Not a PWM pin")
-}
 
-// AnalogRead returns value from analog reading of specified pin
-func (e *EdisonAdaptor) AnalogRead(pin string) (val int, err error) {
-	buf, err := readFile(
-		"/sys/bus/iio/devices/iio:device1/in_voltage" + pin + "_raw",
-	)
-	if err != nil {
-		return
+	for i := 0; i <= 5; i++ {
+		d.Analog[strconv.Itoa(i)] = &board.Analog{Device: 1, Channel: i, Bits: 12}
 	}
 
-	val, err = strconv.Atoi(string(buf[0 : len(buf)-1]))
-
-	return val / 4, err
-}
-
-// I2cStart initializes i2c device for addresss
-func (e *EdisonAdaptor) I2cStart(address int) (err error) {
-	if e.i2cDevice != nil {
-		return
+	// the tristate buffer on gpio214 disconnects the shield pins while the
+	// muxes are changed
+	d.Setup = append(d.Setup, board.Step{Gpio: 214, Direction: "low"})
+	d.Setup = append(d.Setup, gpioSteps("high", true, 263, 262)...)
+	d.Setup = append(d.Setup, gpioSteps("low", true, 240, 241, 242, 243)...)
+	for _, i := range []int{111, 115, 114, 109} {
+		d.Setup = append(d.Setup, pinmux(i, "1"))
 	}
-
-	if err = e.tristate.Write(sysfs.LOW); err != nil {
-		return
+	for _, i := range []int{131, 129, 40} {
+		d.Setup = append(d.Setup, pinmux(i, "0"))
 	}
+	d.Setup = append(d.Setup, board.Step{Gpio: 214, Direction: "high"})
 
-	for _, i := range []int{14, 165, 212, 213} {
-		io := sysfs.NewDigitalPin(i)
-		if err = io.Export(); err != nil {
-			return
-		}
-		if err = io.Direction(sysfs.IN); err != nil {
-			return
-		}
-		if err = io.Unexport(); err != nil {
-			return
-		}
-	}
+	// route i2c-6 to the SDA and SCL pins
+	i2c := []board.Step{{Gpio: 214, Direction: "low"}}
+	i2c = append(i2c, gpioSteps(sysfs.IN, true, 14, 165, 212, 213)...)
+	i2c = append(i2c, gpioSteps("low", true, 236, 237, 204, 205)...)
+	i2c = append(i2c, pinmux(28, "1"), pinmux(27, "1"))
+	d.I2cSetup[6] = append(i2c, board.Step{Gpio: 214, Direction: "high"})
 
-	for _, i := range []int{236, 237, 204, 205} {
-		io := sysfs.NewDigitalPin(i)
-		if err = io.Export(); err != nil {
-			return
-		}
-		if err = io.Direction(sysfs.OUT); err != nil {
-			return
-		}
-		if err = io.Write(sysfs.LOW); err != nil {
-			return
-		}
-		if err = io.Unexport(); err != nil {
-			return
-		}
-	}
+	return d
+}
 
-	for _, i := range []string{"28", "27"} {
-		if err = changePinMode(i, "1"); err != nil {
-			return
-		}
+// NewEdisonAdaptor returns a new EdisonAdaptor with specified name. The
+// Edison is expected on the Arduino breakout board, unless another board is
+// given with the optional boardType: Arduino, Miniboard or Sparkfun.
+func NewEdisonAdaptor(name string, boardType ...string) *EdisonAdaptor {
+	e := &EdisonAdaptor{boardType: Arduino}
+	if len(boardType) > 0 {
+		e.boardType = boardType[0]
 	}
-
-	if err = e.tristate.Write(sysfs.HIGH); err != nil {
-		return
+	d := description(e.boardType)
+	if d == nil {
+		// Connect reports the unknown board type, and no pin is valid meanwhile
+		d = &board.Description{Name: "Intel Edison"}
 	}
-
-	e.i2cDevice, err = sysfs.NewI2cDevice("/dev/i2c-6", address)
-	return
+	e.BoardAdaptor = board.NewBoardAdaptor(name, d)
+	return e
 }
 
-// I2cWrite writes data to i2c device
-func (e *EdisonAdaptor) I2cWrite(address int, data []byte) (err error) {
-	if err = e.i2cDevice.SetAddress(address); err != nil {
-		return err
-	}
-	_, err = e.i2cDevice.Write(data)
-	return
-}
+// BoardType returns the type of the board the Edison is mounted on
+func (e *EdisonAdaptor) BoardType() string { return e.boardType }
 
-// I2cRead returns size bytes from the i2c device
-func (e *EdisonAdaptor) I2cRead(address int, size int) (data []byte, err error) {
-	data = make([]byte, size)
-	if err = e.i2cDevice.SetAddress(address); err != nil {
-		return
+// Connect runs the setup steps of the board the Edison is mounted on
+func (e *EdisonAdaptor) Connect() (errs []error) {
+	switch e.boardType {
+	case Arduino, Miniboard, Sparkfun:
+		return e.BoardAdaptor.Connect()
 	}
-	_, err = e.i2cDevice.Read(data)
-	return
+	return []error{fmt.Errorf("Unknown board type %q, expected %q, %q or %q",
+		e.boardType, Arduino, Miniboard, Sparkfun)}
 }
@@ -2,42 +2,21 @@ package edison
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"syscall"
 	"testing"
 
 	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/sysfs"
 )
 
-type NullReadWriteCloser struct {
-	contents []byte
-}
-
-func (n *NullReadWriteCloser) SetAddress(int) error {
-	return nil
-}
-
-func (n *NullReadWriteCloser) Write(b []byte) (int, error) {
-	n.contents = make([]byte, len(b))
-	copy(n.contents[:], b[:])
-
-	return len(b), nil
-}
-
-func (n *NullReadWriteCloser) Read(b []byte) (int, error) {
-	copy(b, n.contents)
-	return len(b), nil
-}
-
-var closeErr error = nil
-
-func (n *NullReadWriteCloser) Close() error {
-	return closeErr
-}
-
 func initTestEdisonAdaptor() (*EdisonAdaptor, *sysfs.MockFilesystem) {
 	a := NewEdisonAdaptor("myAdaptor")
 	fs := sysfs.NewMockFilesystem([]string{
 		"/sys/bus/iio/devices/iio:device1/in_voltage0_raw",
+		"/sys/bus/iio/devices/iio:device1/in_voltage_scale",
 		"/sys/kernel/debug/gpio_debug/gpio111/current_pinmux",
 		"/sys/kernel/debug/gpio_debug/gpio115/current_pinmux",
 		"/sys/kernel/debug/gpio_debug/gpio114/current_pinmux",
@@ -134,7 +113,6 @@ func TestEdisonAdaptorFinalize(t *testing.T) {
 
 	gobottest.Assert(t, len(a.Finalize()), 0)
 
-	closeErr = errors.New("close error")
 	sysfs.SetFilesystem(sysfs.NewMockFilesystem([]string{}))
 	gobottest.Refute(t, len(a.Finalize()), 0)
 }
@@ -157,13 +135,29 @@ func TestEdisonAdaptorI2c(t *testing.T) {
 	sysfs.SetSyscall(&sysfs.MockSyscall{})
 	a.I2cStart(0xff)
 
-	a.i2cDevice = &NullReadWriteCloser{}
 	a.I2cWrite(0xff, []byte{0x00, 0x01})
 
 	data, _ := a.I2cRead(0xff, 2)
 	gobottest.Assert(t, data, []byte{0x00, 0x01})
 }
 
+func TestEdisonAdaptorI2cBuses(t *testing.T) {
+	a, _ := initTestEdisonAdaptor()
+	fs := sysfs.NewMockFilesystem([]string{
+		"/dev/i2c-1",
+	})
+	sysfs.SetFilesystem(fs)
+	sysfs.SetSyscall(&sysfs.MockSyscall{})
+
+	// i2c-1 is not routed through the breakout muxes
+	a.SetI2cBus(0x68, 1)
+	gobottest.Assert(t, a.I2cStart(0x68), nil)
+	gobottest.Assert(t, a.I2cWrite(0x68, []byte{0x6b, 0x00}), nil)
+	gobottest.Assert(t, fs.Files["/dev/i2c-1"].Contents, string([]byte{0x6b, 0x00}))
+
+	gobottest.Refute(t, a.I2cStart(0x09), nil)
+}
+
 func TestEdisonAdaptorPwm(t *testing.T) {
 	a, fs := initTestEdisonAdaptor()
 
@@ -181,4 +175,250 @@ func TestEdisonAdaptorAnalog(t *testing.T) {
 	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage0_raw"].Contents = "1000\n"
 	i, _ := a.AnalogRead("0")
 	gobottest.Assert(t, i, 250)
+
+	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage_scale"].Contents = "1.220703125\n"
+	mv, _ := a.AnalogReadMillivolts("0")
+	gobottest.Assert(t, mv, 1220.703125)
+
+	_, err := a.AnalogRead("6")
+	gobottest.Assert(t, err, errors.New("Not a valid analog pin"))
+}
+
+func initEdisonSimulator() *sysfs.Simulator {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+
+	for i := 0; i < 264; i++ {
+		sim.AddGpio(i)
+		sim.AddFile(fmt.Sprintf("/sys/kernel/debug/gpio_debug/gpio%v/current_pinmux", i), "mode0")
+	}
+	sim.AddPwmChip(0, 4, 5000)
+	sim.AddIioDevice(1, 8)
+	sim.AddFile("/sys/bus/iio/devices/iio:device1/in_voltage_scale", "1.220703125\n")
+	sim.AddI2cBus(1, 0)
+	sim.AddI2cBus(6, 0)
+	sim.AddSpiDevice(5, 1, nil)
+	return sim
+}
+
+func pinmuxOf(sim *sysfs.Simulator, i int) string {
+	return sim.Contents(fmt.Sprintf("/sys/kernel/debug/gpio_debug/gpio%v/current_pinmux", i))
+}
+
+func TestEdisonAdaptorSimulator(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	sim := initEdisonSimulator()
+	sim.SetAnalog(1, 0, 4092)
+	sim.AddI2cDevice(6, 0x62)
+
+	a := NewEdisonAdaptor("myAdaptor")
+	gobottest.Assert(t, a.BoardType(), Arduino)
+	gobottest.Assert(t, len(a.Connect()), 0)
+	g, _ := sim.Gpio(214)
+	gobottest.Assert(t, g.Level, 1)
+
+	// pin 13 is gpio40, with its level shifter on gpio261
+	gobottest.Assert(t, a.DigitalWrite("13", 1), nil)
+	g, _ = sim.Gpio(40)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 1)
+	g, _ = sim.Gpio(261)
+	gobottest.Assert(t, g.Level, 1)
+
+	// pin 2 is gpio128
+	sim.SetGpioLevel(128, 1)
+	i, err := a.DigitalRead("2")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1)
+
+	// pin 5 is pwm1 on gpio13
+	gobottest.Assert(t, a.PwmWrite("5", 255), nil)
+	gobottest.Assert(t, sim.Contents("/sys/kernel/debug/gpio_debug/gpio13/current_pinmux"), "mode1")
+	p, _ := sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Enabled, true)
+	gobottest.Assert(t, p.DutyCycle, 5000)
+	gobottest.Assert(t, a.PwmWrite("4", 255), errors.New("Not a PWM pin"))
+
+	i, _ = a.AnalogRead("0")
+	gobottest.Assert(t, i, 1023)
+	mv, _ := a.AnalogReadMillivolts("0")
+	gobottest.Assert(t, mv, 4995.1171875)
+
+	gobottest.Assert(t, a.I2cStart(0x62), nil)
+	gobottest.Assert(t, a.I2cWrite(0x62, []byte{0x40, 0x0f, 0xff}), nil)
+	gobottest.Assert(t, sim.I2cWrites(6, 0x62), [][]byte{{0x40, 0x0f, 0xff}})
+	sim.SetI2cNak(6, 0x62, true)
+	err = a.I2cWrite(0x62, []byte{0x40, 0x00, 0x00})
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.ENXIO)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Exported, false)
+	g, _ = sim.Gpio(40)
+	gobottest.Assert(t, g.Exported, false)
+}
+
+func TestEdisonAdaptorConfigurePin(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	sim := initEdisonSimulator()
+	a := NewEdisonAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	// pin 7 is gpio48, with its pullup resistor on gpio223
+	gobottest.Assert(t, a.ConfigurePin("7", gpio.PinConfig{Pull: gpio.PullUp, ActiveLow: true}), nil)
+	i, err := a.DigitalRead("7")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1)
+	g, _ := sim.Gpio(223)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 1)
+	g, _ = sim.Gpio(48)
+	gobottest.Assert(t, g.ActiveLow, true)
+
+	gobottest.Assert(t, a.ConfigurePin("7", gpio.PinConfig{Pull: gpio.PullNone}), nil)
+	g, _ = sim.Gpio(223)
+	gobottest.Assert(t, g.Direction, sysfs.IN)
+
+	// the pull resistor is connected again once the pin is an input
+	gobottest.Assert(t, a.ConfigurePin("7", gpio.PinConfig{Pull: gpio.PullDown}), nil)
+	gobottest.Assert(t, a.DigitalWrite("7", 1), nil)
+	g, _ = sim.Gpio(223)
+	gobottest.Assert(t, g.Direction, sysfs.IN)
+	a.DigitalRead("7")
+	g, _ = sim.Gpio(223)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 0)
+
+	gobottest.Assert(t, a.ConfigurePin("7", gpio.PinConfig{OpenDrain: true}), gpio.ErrOpenDrainUnsupported)
+
+	// the mini breakout has no pullup resistor gpios
+	a = NewEdisonAdaptor("myAdaptor", Miniboard)
+	gobottest.Assert(t, a.ConfigurePin("GP44", gpio.PinConfig{Pull: gpio.PullUp}), gpio.ErrPullUnsupported)
+}
+
+func TestEdisonAdaptorBoardType(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	initEdisonSimulator()
+
+	a := NewEdisonAdaptor("myAdaptor", "uno")
+	gobottest.Assert(t, a.Connect()[0],
+		errors.New(`Unknown board type "uno", expected "arduino", "miniboard" or "sparkfun"`))
+	gobottest.Assert(t, a.DigitalWrite("13", 1), errors.New("Not a valid pin"))
+
+	a = NewEdisonAdaptor("myAdaptor", Sparkfun)
+	gobottest.Assert(t, a.BoardType(), Sparkfun)
+	gobottest.Assert(t, a.Description().Name, "Intel Edison SparkFun blocks")
+	gobottest.Assert(t, len(a.Connect()), 0)
+	// the Arduino breakout pin names are not valid on other boards
+	gobottest.Assert(t, a.DigitalWrite("13", 1), errors.New("Not a valid pin"))
+	gobottest.Assert(t, a.DigitalWrite("J17-1", 1), errors.New("Not a valid pin"))
+	_, err := a.AnalogRead("0")
+	gobottest.Assert(t, err, errors.New("Not a valid analog pin"))
+
+	// each adaptor owns its description
+	a.Description().Pins["GP44"].Gpio = 1
+	gobottest.Assert(t, NewEdisonAdaptor("other", Sparkfun).Description().Pins["GP44"].Gpio, 44)
+}
+
+func TestEdisonAdaptorArduinoBuses(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	sim := initEdisonSimulator()
+
+	a := NewEdisonAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	// pins 10 to 13 are muxed to spi bus 5
+	gobottest.Assert(t, a.SpiStart(1, 0, 1000000), nil)
+	for _, i := range []int{263, 262, 240, 241, 242, 243} {
+		g, _ := sim.Gpio(i)
+		gobottest.Assert(t, g.Level, 1)
+	}
+	g, _ := sim.Gpio(260)
+	gobottest.Assert(t, g.Level, 0)
+	g, _ = sim.Gpio(261)
+	gobottest.Assert(t, g.Level, 1)
+	gobottest.Assert(t, pinmuxOf(sim, 109), "mode1")
+	rx, _ := a.SpiTransfer(1, []byte{0x01, 0x02})
+	gobottest.Assert(t, rx, []byte{0x01, 0x02})
+	gobottest.Assert(t, a.DigitalWrite("13", 1), errors.New("Pin 13 is in use by spi bus 5"))
+	gobottest.Assert(t, a.PwmWrite("10", 1), errors.New("Pin 10 is in use by spi bus 5"))
+
+	// pins 0 and 1 are muxed to uart 1
+	gobottest.Assert(t, pinmuxOf(sim, 131), "mode0")
+	device, err := a.UartStart(1)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, device, "/dev/ttyMFD1")
+	gobottest.Assert(t, pinmuxOf(sim, 130), "mode1")
+	gobottest.Assert(t, pinmuxOf(sim, 131), "mode1")
+	g, _ = sim.Gpio(249)
+	gobottest.Assert(t, g.Level, 1)
+	_, err = a.DigitalRead("0")
+	gobottest.Assert(t, err, errors.New("Pin 0 is in use by uart 1"))
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestEdisonAdaptorMiniboard(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	sim := initEdisonSimulator()
+	sim.AddI2cDevice(1, 0x62)
+
+	a := NewEdisonAdaptor("myAdaptor", Miniboard)
+	gobottest.Assert(t, a.Description().Name, "Intel Edison mini breakout")
+	gobottest.Assert(t, len(a.Connect()), 0)
+	// the breakout has no tristate buffer to enable
+	g, _ := sim.Gpio(214)
+	gobottest.Assert(t, g.Exported, false)
+
+	// J18-1 and GP13 are the same pin, with pwm channel 1
+	gobottest.Assert(t, a.PwmWrite("J18-1", 255), nil)
+	gobottest.Assert(t, pinmuxOf(sim, 13), "mode1")
+	p, _ := sim.Pwm(0, 1)
+	gobottest.Assert(t, p.DutyCycle, 5000)
+	gobottest.Assert(t, a.DigitalWrite("GP13", 1), nil)
+	gobottest.Assert(t, pinmuxOf(sim, 13), "mode0")
+	g, _ = sim.Gpio(13)
+	gobottest.Assert(t, g.Level, 1)
+	gobottest.Assert(t, a.PwmWrite("GP44", 1), errors.New("Not a PWM pin"))
+
+	gobottest.Assert(t, a.I2cStart(0x62), nil)
+	gobottest.Assert(t, pinmuxOf(sim, 19), "mode1")
+	gobottest.Assert(t, pinmuxOf(sim, 20), "mode1")
+	gobottest.Assert(t, a.I2cWrite(0x62, []byte{0x40}), nil)
+	gobottest.Assert(t, sim.I2cWrites(1, 0x62), [][]byte{{0x40}})
+	gobottest.Assert(t, a.DigitalWrite("J17-8", 1), errors.New("Pin J17-8 is in use by i2c bus 1"))
+
+	gobottest.Assert(t, a.SpiStart(1, 0, 1000000), nil)
+	gobottest.Assert(t, pinmuxOf(sim, 115), "mode1")
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestEdisonAdaptorConcurrency(t *testing.T) {
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+	initEdisonSimulator()
+	a := NewEdisonAdaptor("myAdaptor")
+	gobottest.Assert(t, len(a.Connect()), 0)
+
+	gobottest.Concurrently(t, 16, func(i int) (err error) {
+		for j := 0; j < 20 && err == nil; j++ {
+			switch (i + j) % 5 {
+			case 0:
+				err = a.DigitalWrite("13", byte(j%2))
+			case 1:
+				_, err = a.DigitalRead("2")
+			case 2:
+				err = a.PwmWrite("5", byte(j))
+			case 3:
+				// switches pin 5 between its gpio and pwm modes
+				_, err = a.DigitalRead("5")
+			case 4:
+				_, err = a.AnalogRead("0")
+			}
+		}
+		return
+	})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
 }
@@ -0,0 +1,114 @@
+package edison
+
+import (
+	"fmt"
+
+	"github.com/potix/gobot/platforms/board"
+)
+
+// miniboardHeader maps the pins of the J17 to J20 headers of the mini
+// breakout board to the gpios they are wired to. The SparkFun blocks expose
+// the same gpios.
+var miniboardHeader = map[string]int{
+	"J17-1":  182,
+	"J17-5":  135,
+	"J17-7":  27,
+	"J17-8":  20,
+	"J17-9":  28,
+	"J17-10": 111,
+	"J17-11": 109,
+	"J17-12": 115,
+	"J17-14": 128,
+	"J18-1":  13,
+	"J18-2":  165,
+	"J18-6":  19,
+	"J18-7":  12,
+	"J18-8":  183,
+	"J18-10": 110,
+	"J18-11": 114,
+	"J18-12": 129,
+	"J18-13": 130,
+	"J19-4":  44,
+	"J19-5":  46,
+	"J19-6":  48,
+	"J19-8":  131,
+	"J19-9":  14,
+	"J19-10": 40,
+	"J19-11": 43,
+	"J19-12": 77,
+	"J19-13": 82,
+	"J19-14": 83,
+	"J20-3":  134,
+	"J20-4":  45,
+	"J20-5":  47,
+	"J20-6":  49,
+	"J20-7":  15,
+	"J20-8":  84,
+	"J20-9":  42,
+	"J20-10": 41,
+	"J20-11": 78,
+	"J20-12": 79,
+	"J20-13": 80,
+	"J20-14": 81,
+}
+
+// nativePwm maps the gpios with a pwm function to their pwm channels
+var nativePwm = map[int]int{12: 0, 13: 1, 182: 2, 183: 3}
+
+// gpioName returns the name of the pin of gpio i, eg. "GP44"
+func gpioName(i int) string { return fmt.Sprintf("GP%v", i) }
+
+// nativeDescription returns the board description of a board wiring the
+// Edison pins straight to its header, where each pin is muxed between its
+// gpio and its pwm, i2c, spi or uart function by its pinmux file. The pins
+// are also named after the mini breakout header pins when header is set.
+func nativeDescription(name string, header bool) *board.Description {
+	d := &board.Description{
+		Name:   name,
+		I2cBus: 1,
+		SpiBus: 5,
+		Pins:   make(map[string]*board.Pin),
+		I2cPins: map[int][]string{
+			1: {gpioName(19), gpioName(20)},
+			6: {gpioName(27), gpioName(28)},
+		},
+		SpiPins: map[int][]string{
+			5: {gpioName(109), gpioName(110), gpioName(111), gpioName(114), gpioName(115)},
+		},
+		Uarts:    map[int]string{1: "/dev/ttyMFD1"},
+		UartPins: map[int][]string{1: {gpioName(130), gpioName(131)}},
+	}
+
+	for headerPin, i := range miniboardHeader {
+		pin := &board.Pin{
+			Gpio: i,
+			Modes: map[string][]board.Step{
+				board.ModeIn:  {pinmux(i, "0")},
+				board.ModeOut: {pinmux(i, "0")},
+			},
+		}
+		if channel, ok := nativePwm[i]; ok {
+			pin.Pwm = &board.Pwm{Chip: 0, Channel: channel}
+			pin.Modes[board.ModePwm] = []board.Step{pinmux(i, "1")}
+		}
+		d.Pins[gpioName(i)] = pin
+		if header {
+			d.Pins[headerPin] = pin
+		}
+	}
+
+	for mode, buses := range map[string]map[int][]string{
+		board.ModeI2c:  d.I2cPins,
+		board.ModeSpi:  d.SpiPins,
+		board.ModeUart: d.UartPins,
+	} {
+		for _, pins := range buses {
+			for _, name := range pins {
+				p := d.Pins[name]
+				p.Modes[mode] = []board.Step{pinmux(p.Gpio, "1")}
+			}
+		}
+	}
+
+	return d
+}
@@ -4,7 +4,7 @@ import (
 	"io"
 
 	"github.com/potix/gobot"
-	"github.com/tarm/goserial"
+	"github.com/potix/gobot/transport"
 )
 
 var _ gobot.Adaptor = (*MavlinkAdaptor)(nil)
@@ -16,13 +16,15 @@ type MavlinkAdaptor struct {
 	connect func(string) (io.ReadWriteCloser, error)
 }
 
-// NewMavLinkAdaptor creates a new mavlink adaptor with specified name and port
+// NewMavLinkAdaptor creates a new mavlink adaptor with specified name and port.
+// The port is a serial port name, or a transport spec such as "udp://:14550".
+// A serial port is opened with a baud rate of 57600 unless the spec sets one.
 func NewMavlinkAdaptor(name string, port string) *MavlinkAdaptor {
 	return &MavlinkAdaptor{
 		name: name,
 		port: port,
 		connect: func(port string) (io.ReadWriteCloser, error) {
-			return serial.OpenPort(&serial.Config{Name: port, Baud: 57600})
+			return transport.Open(port, 57600)
 		},
 	}
 }
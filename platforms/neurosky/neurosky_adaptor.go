@@ -4,7 +4,7 @@ import (
 	"io"
 
 	"github.com/potix/gobot"
-	"github.com/tarm/goserial"
+	"github.com/potix/gobot/transport"
 )
 
 var _ gobot.Adaptor = (*NeuroskyAdaptor)(nil)
@@ -16,13 +16,15 @@ type NeuroskyAdaptor struct {
 	connect func(*NeuroskyAdaptor) (io.ReadWriteCloser, error)
 }
 
-// NewNeuroskyAdaptor creates a neurosky adaptor with specified name
+// NewNeuroskyAdaptor creates a neurosky adaptor with specified name and port.
+// The port is a serial port name, or a transport spec such as "tcp://192.168.1.10:3333".
+// A serial port is opened with a baud rate of 57600 unless the spec sets one.
 func NewNeuroskyAdaptor(name string, port string) *NeuroskyAdaptor {
 	return &NeuroskyAdaptor{
 		name: name,
 		port: port,
 		connect: func(n *NeuroskyAdaptor) (io.ReadWriteCloser, error) {
-			return serial.OpenPort(&serial.Config{Name: n.Port(), Baud: 57600})
+			return transport.Open(n.Port(), 57600)
 		},
 	}
 }
@@ -0,0 +1,12 @@
+/*
+Package onewire provides the Gobot adaptor and drivers for 1-Wire devices
+connected through the Linux w1 subsystem.
+
+Installing:
+
+  go get github.com/potix/gobot/platforms/onewire
+
+For further information refer to onewire README:
+https://github.com/potix/gobot/blob/master/platforms/onewire/README.md
+*/
+package onewire
@@ -0,0 +1,219 @@
+package onewire
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/potix/gobot"
+)
+
+var _ gobot.Driver = (*DS18B20Driver)(nil)
+
+// ds18b20Family is the 1-Wire family code of the DS18B20
+const ds18b20Family = "28-"
+
+// ds18b20Retries is the number of times a reading is retried after a CRC failure
+const ds18b20Retries = 3
+
+// DS18B20Driver represents a DS18B20 1-Wire temperature sensor
+type DS18B20Driver struct {
+	name        string
+	id          string
+	device      string
+	halt        chan bool
+	interval    time.Duration
+	connection  OneWire
+	mutex       sync.Mutex
+	temperature float64
+	gobot.Eventer
+	gobot.Commander
+}
+
+// NewDS18B20Driver returns a new DS18B20Driver with a polling interval of
+// 1 Second given a OneWire adaptor, name and device id such as "28-0000075a6e5c".
+// If id is empty the driver uses the first DS18B20 found on the bus, and looks
+// for a new one whenever that device is unplugged.
+//
+// Optinally accepts:
+// 	time.Duration: Interval at which the sensor is polled for new information
+//
+// Adds the following API Commands:
+// 	"Temperature" - See DS18B20Driver.Temperature
+func NewDS18B20Driver(a OneWire, name string, id string, v ...time.Duration) *DS18B20Driver {
+	d := &DS18B20Driver{
+		name:       name,
+		connection: a,
+		id:         id,
+		device:     id,
+		Eventer:    gobot.NewEventer(),
+		Commander:  gobot.NewCommander(),
+		interval:   1 * time.Second,
+		halt:       make(chan bool),
+	}
+
+	if len(v) > 0 {
+		d.interval = v[0]
+	}
+
+	d.AddEvent(Temperature)
+	d.AddEvent(Error)
+
+	d.AddCommand("Temperature", func(params map[string]interface{}) interface{} {
+		temperature, err := d.Temperature()
+		return map[string]interface{}{"temperature": temperature, "err": err}
+	})
+
+	return d
+}
+
+// Name returns the DS18B20Drivers name
+func (d *DS18B20Driver) Name() string { return d.name }
+
+// ID returns the id of the device currently read by the DS18B20Driver
+func (d *DS18B20Driver) ID() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.device
+}
+
+// Connection returns the DS18B20Drivers Connection
+func (d *DS18B20Driver) Connection() gobot.Connection { return d.connection.(gobot.Connection) }
+
+// Start starts the DS18B20Driver and reads the sensor at the given interval.
+// Emits the Events:
+//	Temperature float64 - Event is emitted on every reading, in degrees celsius.
+//	Error error - Event is emitted on error reading from the sensor.
+func (d *DS18B20Driver) Start() (errs []error) {
+	go func() {
+		for {
+			temperature, err := d.Temperature()
+			if err != nil {
+				gobot.Publish(d.Event(Error), err)
+			} else {
+				gobot.Publish(d.Event(Temperature), temperature)
+			}
+			select {
+			case <-time.After(d.interval):
+			case <-d.halt:
+				return
+			}
+		}
+	}()
+	return
+}
+
+// Halt stops polling the sensor for new information
+func (d *DS18B20Driver) Halt() (errs []error) {
+	d.halt <- true
+	return
+}
+
+// Temperature reads the sensor and returns the temperature in degrees celsius.
+// Readings which fail the CRC check are retried before ErrCRC is returned.
+func (d *DS18B20Driver) Temperature() (temperature float64, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if err = d.find(); err != nil {
+		return
+	}
+	for i := 0; i < ds18b20Retries; i++ {
+		var data []byte
+		if data, err = d.connection.OneWireRead(d.device); err != nil {
+			// the device may have been unplugged
+			if d.present() {
+				return
+			}
+			if d.id == "" {
+				d.device = ""
+			}
+			return 0, ErrDeviceNotFound
+		}
+		if temperature, err = parseDS18B20(data); err != ErrCRC {
+			break
+		}
+	}
+	if err == nil {
+		d.temperature = temperature
+	}
+	return
+}
+
+// LastTemperature returns the last temperature successfully read from the sensor
+func (d *DS18B20Driver) LastTemperature() float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.temperature
+}
+
+// find selects the first DS18B20 on the bus when the driver has no device id.
+// The caller holds the mutex.
+func (d *DS18B20Driver) find() (err error) {
+	if d.device != "" {
+		return
+	}
+	ids, err := d.connection.OneWireDevices()
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		if strings.HasPrefix(id, ds18b20Family) {
+			d.device = id
+			return
+		}
+	}
+	return ErrDeviceNotFound
+}
+
+// present returns true if the device is on the bus. The caller holds the
+// mutex.
+func (d *DS18B20Driver) present() bool {
+	ids, err := d.connection.OneWireDevices()
+	if err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == d.device {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDS18B20 parses the w1_slave file of a DS18B20, which looks like
+//	72 01 4b 46 7f ff 0e 10 57 : crc=57 YES
+//	72 01 4b 46 7f ff 0e 10 57 t=23125
+func parseDS18B20(data []byte) (temperature float64, err error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 2 {
+		return 0, ErrInvalidData
+	}
+
+	fields := strings.Fields(lines[0])
+	if len(fields) < 9 {
+		return 0, ErrInvalidData
+	}
+	scratchpad, err := hex.DecodeString(strings.Join(fields[:9], ""))
+	if err != nil {
+		return 0, ErrInvalidData
+	}
+	if !strings.HasSuffix(lines[0], "YES") || crc8(scratchpad[:8]) != scratchpad[8] {
+		return 0, ErrCRC
+	}
+	// 0x0550 is the power-on reset value, read before any conversion completed
+	if scratchpad[0] == 0x50 && scratchpad[1] == 0x05 {
+		return 0, ErrInvalidData
+	}
+
+	i := strings.Index(lines[1], "t=")
+	if i == -1 {
+		return 0, ErrInvalidData
+	}
+	milli, err := strconv.Atoi(strings.TrimSpace(lines[1][i+2:]))
+	if err != nil {
+		return 0, ErrInvalidData
+	}
+	return float64(milli) / 1000.0, nil
+}
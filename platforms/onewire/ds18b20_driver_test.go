@@ -0,0 +1,121 @@
+package onewire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestDS18B20Driver(id string) *DS18B20Driver {
+	return NewDS18B20Driver(NewOneWireAdaptor("w1"), "sensor", id)
+}
+
+func TestDS18B20Driver(t *testing.T) {
+	d := initTestDS18B20Driver("28-0000075a6e5c")
+	gobottest.Assert(t, d.Name(), "sensor")
+	gobottest.Assert(t, d.ID(), "28-0000075a6e5c")
+	gobottest.Assert(t, d.Connection().Name(), "w1")
+	gobottest.Assert(t, d.interval, 1*time.Second)
+
+	d = NewDS18B20Driver(NewOneWireAdaptor("w1"), "sensor", "", 100*time.Millisecond)
+	gobottest.Assert(t, d.interval, 100*time.Millisecond)
+}
+
+func TestDS18B20DriverTemperature(t *testing.T) {
+	initTestOneWireFilesystem()
+	d := initTestDS18B20Driver("28-0000075a6e5c")
+
+	temperature, err := d.Temperature()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, temperature, 23.125)
+	gobottest.Assert(t, d.LastTemperature(), 23.125)
+
+	ret := d.Command("Temperature")(nil).(map[string]interface{})
+	gobottest.Assert(t, ret["temperature"].(float64), 23.125)
+	gobottest.Assert(t, ret["err"], nil)
+}
+
+func TestDS18B20DriverCRC(t *testing.T) {
+	fs := initTestOneWireFilesystem()
+	d := initTestDS18B20Driver("28-0000075a6e5c")
+
+	fs.Files["/sys/bus/w1/devices/28-0000075a6e5c/w1_slave"].Contents =
+		"72 01 4b 46 7f ff 0e 10 58 : crc=58 NO\n72 01 4b 46 7f ff 0e 10 58 t=23125\n"
+	_, err := d.Temperature()
+	gobottest.Assert(t, err, ErrCRC)
+
+	// the kernel reported success but the scratchpad is corrupted
+	fs.Files["/sys/bus/w1/devices/28-0000075a6e5c/w1_slave"].Contents =
+		"73 01 4b 46 7f ff 0e 10 57 : crc=57 YES\n73 01 4b 46 7f ff 0e 10 57 t=23187\n"
+	_, err = d.Temperature()
+	gobottest.Assert(t, err, ErrCRC)
+
+	fs.Files["/sys/bus/w1/devices/28-0000075a6e5c/w1_slave"].Contents = "garbage"
+	_, err = d.Temperature()
+	gobottest.Assert(t, err, ErrInvalidData)
+}
+
+func TestDS18B20DriverHotPlug(t *testing.T) {
+	fs := initTestOneWireFilesystem()
+	d := initTestDS18B20Driver("")
+
+	_, err := d.Temperature()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, d.ID(), "28-0000075a6e5c")
+
+	// unplug the sensor
+	fs.Files["/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves"].Contents = "not found.\n"
+	delete(fs.Files, "/sys/bus/w1/devices/28-0000075a6e5c/w1_slave")
+	_, err = d.Temperature()
+	gobottest.Assert(t, err, ErrDeviceNotFound)
+	gobottest.Assert(t, d.ID(), "")
+
+	// plug in another one
+	fs.Files["/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves"].Contents = "28-0000075b1a2d\n"
+	fs.Add("/sys/bus/w1/devices/28-0000075b1a2d/w1_slave").Contents =
+		"91 01 4b 46 7f ff 0f 10 25 : crc=25 YES\n91 01 4b 46 7f ff 0f 10 25 t=25062\n"
+	temperature, err := d.Temperature()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, temperature, 25.062)
+	gobottest.Assert(t, d.ID(), "28-0000075b1a2d")
+}
+
+func TestDS18B20DriverStart(t *testing.T) {
+	sem := make(chan bool, 1)
+	fs := initTestOneWireFilesystem()
+	d := NewDS18B20Driver(NewOneWireAdaptor("w1"), "sensor", "28-0000075a6e5c", 10*time.Millisecond)
+
+	gobot.Once(d.Event(Temperature), func(data interface{}) {
+		gobottest.Assert(t, data.(float64), 23.125)
+		sem <- true
+	})
+	gobottest.Assert(t, len(d.Start()), 0)
+
+	select {
+	case <-sem:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("DS18B20 Event \"Temperature\" was not published")
+	}
+
+	// the sensor is unplugged while the driver is halted, so that the bus is
+	// not changed under the polling
+	gobottest.Assert(t, len(d.Halt()), 0)
+	fs.Files["/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves"].Contents = "not found.\n"
+	delete(fs.Files, "/sys/bus/w1/devices/28-0000075a6e5c/w1_slave")
+
+	gobot.Once(d.Event(Error), func(data interface{}) {
+		gobottest.Assert(t, data.(error), ErrDeviceNotFound)
+		sem <- true
+	})
+	gobottest.Assert(t, len(d.Start()), 0)
+
+	select {
+	case <-sem:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("DS18B20 Event \"Error\" was not published")
+	}
+
+	gobottest.Assert(t, len(d.Halt()), 0)
+}
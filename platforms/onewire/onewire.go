@@ -0,0 +1,50 @@
+package onewire
+
+import (
+	"errors"
+
+	"github.com/potix/gobot"
+)
+
+var (
+	// ErrCRC is the error resulting when the data read from a device fails
+	// its CRC check
+	ErrCRC = errors.New("1-wire CRC check failed")
+	// ErrDeviceNotFound is the error resulting when a device is not present
+	// on the bus
+	ErrDeviceNotFound = errors.New("1-wire device not found")
+	// ErrInvalidData is the error resulting when the data read from a device
+	// can not be parsed
+	ErrInvalidData = errors.New("1-wire device returned invalid data")
+)
+
+const (
+	// Error event
+	Error = "error"
+	// Temperature event
+	Temperature = "temperature"
+)
+
+// OneWire interface represents an Adaptor which has 1-Wire capabilities
+type OneWire interface {
+	gobot.Adaptor
+	// OneWireDevices returns the ids of the devices currently on the bus
+	OneWireDevices() (ids []string, err error)
+	// OneWireRead returns the data the kernel reports for device id
+	OneWireRead(id string) (data []byte, err error)
+}
+
+// crc8 returns the Dallas/Maxim CRC of data
+func crc8(data []byte) (crc byte) {
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ b) & 0x01
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8c
+			}
+			b >>= 1
+		}
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package onewire
+
+import (
+	"github.com/potix/gobot"
+	"github.com/potix/gobot/sysfs"
+)
+
+var _ gobot.Adaptor = (*OneWireAdaptor)(nil)
+
+var _ OneWire = (*OneWireAdaptor)(nil)
+
+// OneWireAdaptor is the Gobot Adaptor for a Linux w1 bus master
+type OneWireAdaptor struct {
+	name   string
+	master int
+}
+
+// NewOneWireAdaptor returns a new OneWireAdaptor with specified name and optionally accepts:
+//
+//	int: bus master number, defaults to 1 (/sys/bus/w1/devices/w1_bus_master1)
+func NewOneWireAdaptor(name string, v ...int) *OneWireAdaptor {
+	o := &OneWireAdaptor{
+		name:   name,
+		master: 1,
+	}
+
+	if len(v) > 0 {
+		o.master = v[0]
+	}
+
+	return o
+}
+
+// Name returns the OneWireAdaptors name
+func (o *OneWireAdaptor) Name() string { return o.name }
+
+// Connect checks that the bus master is present
+func (o *OneWireAdaptor) Connect() (errs []error) {
+	if _, err := sysfs.OneWireSlaves(o.master); err != nil {
+		return []error{err}
+	}
+	return
+}
+
+// Finalize implements the Adaptor interface
+func (o *OneWireAdaptor) Finalize() (errs []error) { return }
+
+// OneWireDevices returns the ids of the devices currently on the bus
+func (o *OneWireAdaptor) OneWireDevices() (ids []string, err error) {
+	return sysfs.OneWireSlaves(o.master)
+}
+
+// OneWireRead returns the contents of the w1_slave file of device id
+func (o *OneWireAdaptor) OneWireRead(id string) (data []byte, err error) {
+	return sysfs.NewOneWireDevice(id).Read()
+}
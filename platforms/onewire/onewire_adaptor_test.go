@@ -0,0 +1,53 @@
+package onewire
+
+import (
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/sysfs"
+)
+
+func initTestOneWireFilesystem() *sysfs.MockFilesystem {
+	fs := sysfs.NewMockFilesystem([]string{
+		"/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves",
+		"/sys/bus/w1/devices/28-0000075a6e5c/w1_slave",
+	})
+	sysfs.SetFilesystem(fs)
+	fs.Files["/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves"].Contents = "28-0000075a6e5c\n"
+	fs.Files["/sys/bus/w1/devices/28-0000075a6e5c/w1_slave"].Contents =
+		"72 01 4b 46 7f ff 0e 10 57 : crc=57 YES\n72 01 4b 46 7f ff 0e 10 57 t=23125\n"
+	return fs
+}
+
+func TestOneWireAdaptor(t *testing.T) {
+	a := NewOneWireAdaptor("w1")
+	gobottest.Assert(t, a.Name(), "w1")
+	gobottest.Assert(t, a.master, 1)
+
+	a = NewOneWireAdaptor("w1", 2)
+	gobottest.Assert(t, a.master, 2)
+}
+
+func TestOneWireAdaptorConnect(t *testing.T) {
+	initTestOneWireFilesystem()
+	gobottest.Assert(t, len(NewOneWireAdaptor("w1").Connect()), 0)
+	gobottest.Refute(t, len(NewOneWireAdaptor("w1", 2).Connect()), 0)
+	gobottest.Assert(t, len(NewOneWireAdaptor("w1").Finalize()), 0)
+}
+
+func TestOneWireAdaptorDevices(t *testing.T) {
+	initTestOneWireFilesystem()
+	a := NewOneWireAdaptor("w1")
+
+	ids, err := a.OneWireDevices()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, ids, []string{"28-0000075a6e5c"})
+
+	data, err := a.OneWireRead("28-0000075a6e5c")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, string(data[len(data)-7:]), "t=23125")
+}
+
+func TestCrc8(t *testing.T) {
+	gobottest.Assert(t, crc8([]byte{0x72, 0x01, 0x4b, 0x46, 0x7f, 0xff, 0x0e, 0x10}), byte(0x57))
+}
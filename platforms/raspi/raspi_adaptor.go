@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/potix/gobot"
+	"github.com/potix/gobot/platforms/board"
 	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/platforms/i2c"
+	"github.com/potix/gobot/platforms/spi"
 	"github.com/potix/gobot/sysfs"
 )
 
@@ -18,174 +21,214 @@ var _ gobot.Adaptor = (*RaspiAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*RaspiAdaptor)(nil)
 var _ gpio.DigitalWriter = (*RaspiAdaptor)(nil)
+var _ gpio.PwmWriter = (*RaspiAdaptor)(nil)
+var _ gpio.ServoWriter = (*RaspiAdaptor)(nil)
+var _ gpio.PwmPulseWriter = (*RaspiAdaptor)(nil)
+var _ gpio.PinConfigurer = (*RaspiAdaptor)(nil)
 
 var _ i2c.I2c = (*RaspiAdaptor)(nil)
 
+var _ spi.Spi = (*RaspiAdaptor)(nil)
+
+var _ gobot.Capabler = (*RaspiAdaptor)(nil)
+
+const (
+	// gpioChip is the gpio character device of the BCM gpios
+	gpioChip = "/dev/gpiochip0"
+	// piBlasterPeriod is the period of the pulses of pi-blaster
+	piBlasterPeriod = 10 * time.Millisecond
+)
+
 var readFile = func() ([]byte, error) {
 	return ioutil.ReadFile("/proc/cpuinfo")
 }
 
+// RaspiAdaptor is the gobot.Adaptor representation for the Raspberry Pi
 type RaspiAdaptor struct {
-	name        string
-	revision    string
-	i2cLocation string
-	digitalPins map[int]sysfs.DigitalPin
-	pwmPins     []int
-	i2cDevice   sysfs.I2cDevice
+	*board.BoardAdaptor
+	info      RaspiInfo
+	revision  string
+	mutex     sync.Mutex
+	piBlaster bool
+	pwmPins   []int
+}
+
+// header26 maps the physical pins of the 26 pin header of revision 2 boards
+// to BCM gpios
+var header26 = map[string]int{
+	"3":  2,
+	"5":  3,
+	"7":  4,
+	"8":  14,
+	"10": 15,
+	"11": 17,
+	"12": 18,
+	"13": 27,
+	"15": 22,
+	"16": 23,
+	"18": 24,
+	"19": 10,
+	"21": 9,
+	"22": 25,
+	"23": 11,
+	"24": 8,
+	"26": 7,
+}
+
+// header26Rev1 holds the pins of revision 1 boards differing from revision 2 boards
+var header26Rev1 = map[string]int{
+	"3":  0,
+	"5":  1,
+	"13": 21,
+}
+
+// header40 maps the physical pins 27 through 40 of the 40 pin header to BCM gpios
+var header40 = map[string]int{
+	"27": 0,
+	"28": 1,
+	"29": 5,
+	"31": 6,
+	"32": 12,
+	"33": 13,
+	"35": 19,
+	"36": 16,
+	"37": 26,
+	"38": 20,
+	"40": 21,
 }
 
-var pins = map[string]map[string]int{
-	"3": map[string]int{
-		"1": 0,
-		"2": 2,
-		"3": 2,
-	},
-	"5": map[string]int{
-		"1": 1,
-		"2": 3,
-		"3": 3,
-	},
-	"7": map[string]int{
-		"*": 4,
-	},
-	"8": map[string]int{
-		"*": 14,
-	},
-	"10": map[string]int{
-		"*": 15,
-	},
-	"11": map[string]int{
-		"*": 17,
-	},
-	"12": map[string]int{
-		"*": 18,
-	},
-	"13": map[string]int{
-		"1": 21,
-		"2": 27,
-		"3": 27,
-	},
-	"15": map[string]int{
-		"*": 22,
-	},
-	"16": map[string]int{
-		"*": 23,
-	},
-	"18": map[string]int{
-		"*": 24,
-	},
-	"19": map[string]int{
-		"*": 10,
-	},
-	"21": map[string]int{
-		"*": 9,
-	},
-	"22": map[string]int{
-		"*": 25,
-	},
-	"23": map[string]int{
-		"*": 11,
-	},
-	"24": map[string]int{
-		"*": 8,
-	},
-	"26": map[string]int{
-		"*": 7,
-	},
-	"29": map[string]int{
-		"3": 5,
-	},
-	"31": map[string]int{
-		"3": 6,
-	},
-	"32": map[string]int{
-		"3": 12,
-	},
-	"33": map[string]int{
-		"3": 13,
-	},
-	"35": map[string]int{
-		"3": 19,
-	},
-	"36": map[string]int{
-		"3": 16,
-	},
-	"37": map[string]int{
-		"3": 26,
-	},
-	"38": map[string]int{
-		"3": 20,
-	},
-	"40": map[string]int{
-		"3": 21,
-	},
+// hardwarePwm maps the BCM gpios with a hardware pwm function to the channels
+// of pwmchip0, which the pwm-2chan overlay routes to them
+var hardwarePwm = map[int]board.Pwm{
+	12: {Chip: 0, Channel: 0},
+	18: {Chip: 0, Channel: 0},
+	13: {Chip: 0, Channel: 1},
+	19: {Chip: 0, Channel: 1},
 }
 
-// NewRaspiAdaptor creates a RaspiAdaptor with specified name and
+// NewRaspiAdaptor creates a RaspiAdaptor with specified name, for the board
+// revision read from /proc/cpuinfo
 func NewRaspiAdaptor(name string) *RaspiAdaptor {
 	r := &RaspiAdaptor{
-		name:        name,
-		digitalPins: make(map[int]sysfs.DigitalPin),
-		pwmPins:     []int{},
+		piBlaster: true,
+		pwmPins:   []int{},
 	}
 	content, _ := readFile()
-	for _, v := range strings.Split(string(content), "\n") {
-		if strings.Contains(v, "Revision") {
-			s := strings.Split(string(v), " ")
-			version, _ := strconv.ParseInt("0x"+s[len(s)-1], 0, 64)
-			r.i2cLocation = "/dev/i2c-1"
-			if version <= 3 {
-				r.revision = "1"
-				r.i2cLocation = "/dev/i2c-0"
-			} else if version <= 15 {
-				r.revision = "2"
-			} else {
-				r.revision = "3"
-			}
-		}
-	}
+	r.info, r.revision = decodeRevision(parseRevision(string(content)))
+	r.BoardAdaptor = board.NewBoardAdaptor(name, description(r.info, r.revision))
 
 	return r
 }
-func (r *RaspiAdaptor) Name() string { return r.name }
 
-// Connect starts conection with board and creates
-// digitalPins and pwmPins adaptor maps
+// description returns the board description of a board with a header layout.
+// Pins are named after both their physical pin number, eg. "7", and their BCM
+// gpio, eg. "GPIO4".
+func description(info RaspiInfo, layout string) *board.Description {
+	d := &board.Description{
+		Name:   strings.TrimSpace("Raspberry Pi " + info.Model),
+		I2cBus: 1,
+		SpiBus: 0,
+		Pins:   make(map[string]*board.Pin),
+	}
+	header := map[string]int{}
+	for pin, gpio := range header26 {
+		header[pin] = gpio
+	}
+	if layout == "1" {
+		d.I2cBus = 0
+		for pin, gpio := range header26Rev1 {
+			header[pin] = gpio
+		}
+	}
+	if layout == "3" {
+		for pin, gpio := range header40 {
+			header[pin] = gpio
+		}
+	}
+	for pin, gpio := range header {
+		p := &board.Pin{Gpio: gpio}
+		if pwm, ok := hardwarePwm[gpio]; ok {
+			p.Pwm = &board.Pwm{Chip: pwm.Chip, Channel: pwm.Channel}
+		}
+		d.Pins[pin] = p
+		d.Pins[fmt.Sprintf("GPIO%v", gpio)] = p
+	}
+	return d
+}
+
+// Connect drives the gpios through the gpio character device when the kernel
+// provides one, so that their pull resistors and debouncing can be set with
+// ConfigurePin, and through sysfs otherwise
 func (r *RaspiAdaptor) Connect() (errs []error) {
-	return
+	if f, err := sysfs.OpenFile(gpioChip, os.O_RDWR, 0644); err == nil {
+		f.Close()
+		r.Description().GpioChip = gpioChip
+	}
+	return r.BoardAdaptor.Connect()
 }
 
-// Finalize closes connection to board and pins
-func (r *RaspiAdaptor) Finalize() (errs []error) {
-	for _, pin := range r.digitalPins {
-		if pin != nil {
-			if err := pin.Unexport(); err != nil {
-				errs = append(errs, err)
-			}
+// Info returns the model, memory and manufacturer of the board
+func (r *RaspiAdaptor) Info() RaspiInfo { return r.info }
+
+// Capabilities returns the header pins with the modes they support, and the
+// revision, model and processor of the board. Every gpio supports pwm while
+// pi-blaster is used.
+func (r *RaspiAdaptor) Capabilities() gobot.Capabilities {
+	c := r.BoardAdaptor.Capabilities()
+	if r.usePiBlaster() {
+		for i := range c.Pins {
+			c.Pins[i].Modes = []string{"in", "out", "pwm", "servo"}
 		}
 	}
-	for _, pin := range r.pwmPins {
-		if err := r.piBlaster(fmt.Sprintf("release %v\n", pin)); err != nil {
-			errs = append(errs, err)
-		}
+	if c.Metadata == nil {
+		c.Metadata = map[string]string{}
 	}
-	if r.i2cDevice != nil {
-		if err := r.i2cDevice.Close(); err != nil {
+	c.Metadata["revision"] = r.info.Revision
+	c.Metadata["model"] = r.info.Model
+	c.Metadata["processor"] = r.info.Processor
+	if r.info.Memory > 0 {
+		c.Metadata["memory"] = fmt.Sprintf("%vMB", r.info.Memory)
+	}
+	return c
+}
+
+// SetPiBlaster selects whether PwmWrite and ServoWrite use pi-blaster, which
+// drives any pin, or the hardware pwm of GPIO12, GPIO13, GPIO18 and GPIO19.
+// pi-blaster is used by default. As pi-blaster times its pulses with the pwm
+// peripheral, both can not be used at once.
+func (r *RaspiAdaptor) SetPiBlaster(enabled bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.piBlaster = enabled
+}
+
+// usePiBlaster returns true if pwm is written through pi-blaster
+func (r *RaspiAdaptor) usePiBlaster() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.piBlaster
+}
+
+// Finalize closes connection to board and pins, and releases the pins used
+// with pi-blaster
+func (r *RaspiAdaptor) Finalize() (errs []error) {
+	errs = r.BoardAdaptor.Finalize()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, pin := range r.pwmPins {
+		if err := r.piBlasterWrite(fmt.Sprintf("release %v\n", pin)); err != nil {
 			errs = append(errs, err)
 		}
 	}
 	return errs
 }
 
+// translatePin returns the gpio of a header pin
 func (r *RaspiAdaptor) translatePin(pin string) (i int, err error) {
-	if val, ok := pins[pin][r.revision]; ok {
-		i = val
-	} else if val, ok := pins[pin]["*"]; ok {
-		i = val
-	} else {
-		err = errors.New("Not a valid pin")
-		return
+	if p, ok := r.Description().Pins[pin]; ok {
+		return p.Gpio, nil
 	}
+	err = errors.New("Not a valid pin")
 	return
 }
 
@@ -195,6 +238,8 @@ func (r *RaspiAdaptor) pwmPin(pin string) (i int, err error) {
 		return
 	}
 
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	newPin := true
 	for _, pin := range r.pwmPins {
 		if i == pin {
@@ -210,93 +255,57 @@ func (r *RaspiAdaptor) pwmPin(pin string) (i int, err error) {
 	return
 }
 
-// digitalPin returns matched digitalPin for specified values
-func (r *RaspiAdaptor) digitalPin(pin string, dir string) (sysfsPin sysfs.DigitalPin, err error) {
-	i, err := r.translatePin(pin)
-
-	if err != nil {
-		return
-	}
-
-	if r.digitalPins[i] == nil {
-		r.digitalPins[i] = sysfs.NewDigitalPin(i)
-		if err = r.digitalPins[i].Export(); err != nil {
-			return
-		}
-	}
-
-	if err = r.digitalPins[i].Direction(dir); err != nil {
-		return
+// PwmWrite writes the 0-255 value to the specified pin through pi-blaster,
+// or to its hardware pwm channel when pi-blaster is disabled
+func (r *RaspiAdaptor) PwmWrite(pin string, val byte) (err error) {
+	if !r.usePiBlaster() {
+		return r.BoardAdaptor.PwmWrite(pin, val)
 	}
-
-	return r.digitalPins[i], nil
-}
-
-// DigitalRead reads digital value from pin
-func (r *RaspiAdaptor) DigitalRead(pin string) (val int, err error) {
-	sysfsPin, err := r.digitalPin(pin, sysfs.IN)
+	sysfsPin, err := r.pwmPin(pin)
 	if err != nil {
-		return
+		return err
 	}
-	return sysfsPin.Read()
+	return r.piBlasterWrite(fmt.Sprintf("%v=%v\n", sysfsPin, gobot.FromScale(float64(val), 0, 255)))
 }
 
-// DigitalWrite writes digital value to specified pin
-func (r *RaspiAdaptor) DigitalWrite(pin string, val byte) (err error) {
-	sysfsPin, err := r.digitalPin(pin, sysfs.OUT)
+// ServoWrite writes the 0-180 degree angle to the specified pin through
+// pi-blaster, or to its hardware pwm channel when pi-blaster is disabled
+func (r *RaspiAdaptor) ServoWrite(pin string, angle byte) (err error) {
+	if !r.usePiBlaster() {
+		return r.BoardAdaptor.ServoWrite(pin, angle)
+	}
+	sysfsPin, err := r.pwmPin(pin)
 	if err != nil {
 		return err
 	}
-	return sysfsPin.Write(int(val))
-}
 
-// I2cStart starts a i2c device in specified address
-func (r *RaspiAdaptor) I2cStart(address int) (err error) {
-	if r.i2cDevice == nil {
-		r.i2cDevice, err = sysfs.NewI2cDevice(r.i2cLocation, address)
-	}
-	return err
-}
+	val := (gobot.ToScale(gobot.FromScale(float64(angle), 0, 180), 0, 200) / 1000.0) + 0.05
 
-// I2CWrite writes data to i2c device
-func (r *RaspiAdaptor) I2cWrite(address int, data []byte) (err error) {
-	if err = r.i2cDevice.SetAddress(address); err != nil {
-		return
-	}
-	_, err = r.i2cDevice.Write(data)
-	return
+	return r.piBlasterWrite(fmt.Sprintf("%v=%v\n", sysfsPin, val))
 }
 
-// I2cRead returns value from i2c device using specified size
-func (r *RaspiAdaptor) I2cRead(address int, size int) (data []byte, err error) {
-	if err = r.i2cDevice.SetAddress(address); err != nil {
-		return
+// PwmPulseWrite writes pulses of width every period to the specified pin
+// through pi-blaster, or to its hardware pwm channel when pi-blaster is
+// disabled. pi-blaster has a fixed period of 10 milliseconds, so it returns
+// gpio.ErrPwmPeriodUnsupported for any other period.
+func (r *RaspiAdaptor) PwmPulseWrite(pin string, period time.Duration, width time.Duration) (err error) {
+	if !r.usePiBlaster() {
+		return r.BoardAdaptor.PwmPulseWrite(pin, period, width)
 	}
-	data = make([]byte, size)
-	_, err = r.i2cDevice.Read(data)
-	return
-}
-
-func (r *RaspiAdaptor) PwmWrite(pin string, val byte) (err error) {
-	sysfsPin, err := r.pwmPin(pin)
-	if err != nil {
-		return err
+	if period != piBlasterPeriod {
+		return gpio.ErrPwmPeriodUnsupported
 	}
-	return r.piBlaster(fmt.Sprintf("%v=%v\n", sysfsPin, gobot.FromScale(float64(val), 0, 255)))
-}
-
-func (r *RaspiAdaptor) ServoWrite(pin string, angle byte) (err error) {
 	sysfsPin, err := r.pwmPin(pin)
 	if err != nil {
 		return err
 	}
 
-	val := (gobot.ToScale(gobot.FromScale(float64(angle), 0, 180), 0, 200) / 1000.0) + 0.05
+	val := gobot.FromScale(float64(width), 0, float64(piBlasterPeriod))
 
-	return r.piBlaster(fmt.Sprintf("%v=%v\n", sysfsPin, val))
+	return r.piBlasterWrite(fmt.Sprintf("%v=%v\n", sysfsPin, val))
 }
 
-func (r *RaspiAdaptor) piBlaster(data string) (err error) {
+func (r *RaspiAdaptor) piBlasterWrite(data string) (err error) {
 	fi, err := sysfs.OpenFile("/dev/pi-blaster", os.O_WRONLY|os.O_APPEND, 0644)
 	defer fi.Close()
 
@@ -1,39 +1,20 @@
 package raspi
 
 import (
+	"errors"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/potix/gobot"
 	"github.com/potix/gobot/gobottest"
+	"github.com/potix/gobot/platforms/board"
+	"github.com/potix/gobot/platforms/gpio"
 	"github.com/potix/gobot/sysfs"
 )
 
-type NullReadWriteCloser struct {
-	contents []byte
-}
-
-func (n *NullReadWriteCloser) SetAddress(int) error {
-	return nil
-}
-
-func (n *NullReadWriteCloser) Write(b []byte) (int, error) {
-	n.contents = make([]byte, len(b))
-	copy(n.contents[:], b[:])
-
-	return len(b), nil
-}
-
-func (n *NullReadWriteCloser) Read(b []byte) (int, error) {
-	copy(b, n.contents)
-	return len(b), nil
-}
-
-var closeErr error = nil
-
-func (n *NullReadWriteCloser) Close() error {
-	return closeErr
-}
-
 func initTestRaspiAdaptor() *RaspiAdaptor {
 	readFile = func() ([]byte, error) {
 		return []byte(`
@@ -57,7 +38,7 @@ Serial          : 000000003bc748ea
 	}
 	a := NewRaspiAdaptor("myAdaptor")
 	gobottest.Assert(t, a.Name(), "myAdaptor")
-	gobottest.Assert(t, a.i2cLocation, "/dev/i2c-1")
+	gobottest.Assert(t, a.Description().I2cBus, 1)
 	gobottest.Assert(t, a.revision, "3")
 
 	readFile = func() ([]byte, error) {
@@ -68,7 +49,7 @@ Serial          : 000000003bc748ea
 `), nil
 	}
 	a = NewRaspiAdaptor("myAdaptor")
-	gobottest.Assert(t, a.i2cLocation, "/dev/i2c-1")
+	gobottest.Assert(t, a.Description().I2cBus, 1)
 	gobottest.Assert(t, a.revision, "2")
 
 	readFile = func() ([]byte, error) {
@@ -79,9 +60,76 @@ Serial          : 000000003bc748ea
 `), nil
 	}
 	a = NewRaspiAdaptor("myAdaptor")
-	gobottest.Assert(t, a.i2cLocation, "/dev/i2c-0")
+	gobottest.Assert(t, a.Description().I2cBus, 0)
 	gobottest.Assert(t, a.revision, "1")
 
+	readFile = func() ([]byte, error) {
+		return []byte(`
+Hardware        : BCM2835
+Revision        : a22082
+Serial          : 000000003bc748ea
+`), nil
+	}
+	a = NewRaspiAdaptor("myAdaptor")
+	gobottest.Assert(t, a.Description().I2cBus, 1)
+	gobottest.Assert(t, a.Description().Name, "Raspberry Pi 3B")
+	gobottest.Assert(t, a.revision, "3")
+	gobottest.Assert(t, a.Info().Manufacturer, "Embest")
+	gobottest.Assert(t, a.Info().Memory, 1024)
+}
+
+func TestRaspiAdaptorCapabilities(t *testing.T) {
+	readFile = func() ([]byte, error) {
+		return []byte(`
+Hardware        : BCM2835
+Revision        : a22082
+Serial          : 000000003bc748ea
+`), nil
+	}
+	a := NewRaspiAdaptor("myAdaptor")
+	c := a.Capabilities()
+	gobottest.Assert(t, len(c.Pins), 2*28)
+	gobottest.Assert(t, c.Pins[0], gobot.PinCapability{Pin: "10", Modes: []string{"in", "out", "pwm", "servo"}})
+	gobottest.Assert(t, c.Metadata, map[string]string{
+		"board":     "Raspberry Pi 3B",
+		"revision":  "a22082",
+		"model":     "3B",
+		"processor": "BCM2837",
+		"memory":    "1024MB",
+	})
+
+	a.SetPiBlaster(false)
+	c = a.Capabilities()
+	gobottest.Assert(t, c.Pins[0], gobot.PinCapability{Pin: "10", Modes: []string{"in", "out"}})
+	for _, p := range c.Pins {
+		if p.Pin == "GPIO18" {
+			gobottest.Assert(t, p.Modes, []string{"in", "out", "pwm", "servo"})
+		}
+	}
+}
+
+func TestRaspiAdaptorPins(t *testing.T) {
+	a := initTestRaspiAdaptor()
+	pins := a.Description().Pins
+	gobottest.Assert(t, pins["3"].Gpio, 2)
+	gobottest.Assert(t, pins["13"].Gpio, 27)
+	gobottest.Assert(t, pins["27"].Gpio, 0)
+	gobottest.Assert(t, pins["40"].Gpio, 21)
+	gobottest.Assert(t, pins["GPIO21"], pins["40"])
+	gobottest.Assert(t, pins["12"].Pwm, &board.Pwm{Chip: 0, Channel: 0})
+	gobottest.Assert(t, pins["GPIO13"].Pwm, &board.Pwm{Chip: 0, Channel: 1})
+	gobottest.Assert(t, len(pins), 28+28)
+
+	readFile = func() ([]byte, error) {
+		return []byte("Revision        : 0002\n"), nil
+	}
+	pins = NewRaspiAdaptor("myAdaptor").Description().Pins
+	gobottest.Assert(t, pins["3"].Gpio, 0)
+	gobottest.Assert(t, pins["13"].Gpio, 21)
+	gobottest.Assert(t, pins["GPIO21"], pins["13"])
+	_, ok := pins["40"]
+	gobottest.Assert(t, ok, false)
+	gobottest.Assert(t, len(pins), 17+17)
 }
 func TestRaspiAdaptorFinalize(t *testing.T) {
 	a := initTestRaspiAdaptor()
@@ -121,6 +169,15 @@ func TestRaspiAdaptorDigitalPWM(t *testing.T) {
 	gobottest.Assert(t, a.ServoWrite("11", 255), nil)
 
 	gobottest.Assert(t, strings.Split(fs.Files["/dev/pi-blaster"].Contents, "\n")[0], "17=0.25")
+
+	gobottest.Assert(t, a.PwmPulseWrite("11", 10*time.Millisecond, 1500*time.Microsecond), nil)
+
+	gobottest.Assert(t, strings.Split(fs.Files["/dev/pi-blaster"].Contents, "\n")[0], "17=0.15")
+
+	// pi-blaster can not change its period
+	gobottest.Assert(t, a.PwmPulseWrite("11", 20*time.Millisecond, 1500*time.Microsecond), gpio.ErrPwmPeriodUnsupported)
+
+	gobottest.Assert(t, strings.Split(fs.Files["/dev/pi-blaster"].Contents, "\n")[0], "17=0.15")
 }
 
 func TestRaspiAdaptorDigitalIO(t *testing.T) {
@@ -152,9 +209,206 @@ func TestRaspiAdaptorI2c(t *testing.T) {
 	sysfs.SetFilesystem(fs)
 	sysfs.SetSyscall(&sysfs.MockSyscall{})
 	a.I2cStart(0xff)
-	a.i2cDevice = &NullReadWriteCloser{}
 
 	a.I2cWrite(0xff, []byte{0x00, 0x01})
 	data, _ := a.I2cRead(0xff, 2)
 	gobottest.Assert(t, data, []byte{0x00, 0x01})
 }
+
+func TestRaspiAdaptorI2cBuses(t *testing.T) {
+	a := initTestRaspiAdaptor()
+	fs := sysfs.NewMockFilesystem([]string{
+		"/dev/i2c-0",
+		"/dev/i2c-1",
+	})
+	sysfs.SetFilesystem(fs)
+	sysfs.SetSyscall(&sysfs.MockSyscall{})
+
+	a.SetI2cBus(0x68, 0)
+	gobottest.Assert(t, a.I2cStart(0x68), nil)
+	gobottest.Assert(t, a.I2cStart(0x09), nil)
+
+	gobottest.Assert(t, a.I2cWrite(0x68, []byte{0x6b, 0x00}), nil)
+	gobottest.Assert(t, a.I2cWrite(0x09, []byte{0x6f}), nil)
+	gobottest.Assert(t, fs.Files["/dev/i2c-0"].Contents, string([]byte{0x6b, 0x00}))
+	gobottest.Assert(t, fs.Files["/dev/i2c-1"].Contents, string([]byte{0x6f}))
+
+	a.SetI2cBus(0x20, 2)
+	_, err := a.I2cRead(0x20, 1)
+	gobottest.Assert(t, err.Error(), "i2c bus /dev/i2c-2 has not been started")
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestRaspiAdaptorSimulator(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+
+	sim.AddGpio(4)
+	sim.AddGpio(17)
+	sim.AddGpio(27)
+	sim.AddFile("/dev/pi-blaster", "")
+	sim.AddI2cBus(1, 0)
+	sim.AddI2cDevice(1, 0x52)
+	a := initTestRaspiAdaptor()
+	// without a gpio chip the gpios are exported through sysfs
+	gobottest.Assert(t, a.Description().GpioChip, "")
+
+	gobottest.Assert(t, a.DigitalWrite("7", 1), nil)
+	g, _ := sim.Gpio(4)
+	gobottest.Assert(t, g.Direction, sysfs.OUT)
+	gobottest.Assert(t, g.Level, 1)
+
+	sim.SetGpioLevel(17, 1)
+	i, err := a.DigitalRead("11")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1)
+
+	// a failed export is retried on the next use of the pin
+	sim.SetFault("write", "/sys/class/gpio/export", syscall.EACCES)
+	err = a.DigitalWrite("13", 1)
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EACCES)
+	sim.ClearFault("write", "/sys/class/gpio/export")
+	gobottest.Assert(t, a.DigitalWrite("13", 1), nil)
+	g, _ = sim.Gpio(27)
+	gobottest.Assert(t, g.Level, 1)
+
+	gobottest.Assert(t, a.PwmWrite("7", 255), nil)
+	gobottest.Assert(t, sim.Contents("/dev/pi-blaster"), "4=1\n")
+
+	gobottest.Assert(t, a.I2cStart(0x52), nil)
+	gobottest.Assert(t, a.I2cWrite(0x52, []byte{0x40, 0x00}), nil)
+	gobottest.Assert(t, sim.I2cWrites(1, 0x52), [][]byte{{0x40, 0x00}})
+	sim.SetI2cNak(1, 0x52, true)
+	_, err = a.I2cRead(0x52, 6)
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.ENXIO)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(4)
+	gobottest.Assert(t, g.Exported, false)
+}
+
+func TestRaspiAdaptorGpioChip(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+
+	sim.AddGpioChip(0, 0, 54)
+	a := initTestRaspiAdaptor()
+	gobottest.Assert(t, a.Description().GpioChip, "/dev/gpiochip0")
+
+	// a button connecting GPIO17 to ground
+	config := gpio.PinConfig{Pull: gpio.PullUp, ActiveLow: true, Debounce: 5 * time.Millisecond}
+	gobottest.Assert(t, a.ConfigurePin("11", config), nil)
+	i, err := a.DigitalRead("11")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 0)
+	sim.SetGpioLevel(17, 0)
+	i, _ = a.DigitalRead("GPIO17")
+	gobottest.Assert(t, i, 1)
+	g, _ := sim.Gpio(17)
+	gobottest.Assert(t, g.Requested, true)
+	gobottest.Assert(t, g.Pull, sysfs.PULLUP)
+
+	gobottest.Assert(t, a.DigitalWrite("7", 1), nil)
+	g, _ = sim.Gpio(4)
+	gobottest.Assert(t, g.Level, 1)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	g, _ = sim.Gpio(17)
+	gobottest.Assert(t, g.Requested, false)
+}
+
+func TestRaspiAdaptorHardwarePwm(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+
+	sim.AddGpio(4)
+	sim.AddGpio(18)
+	sim.AddPwmChip(0, 2)
+	a := initTestRaspiAdaptor()
+	a.SetPiBlaster(false)
+
+	gobottest.Assert(t, a.PwmWrite("12", 255), nil)
+	p, _ := sim.Pwm(0, 0)
+	gobottest.Assert(t, p.Enabled, true)
+	gobottest.Assert(t, p.DutyCycle, board.DefaultPwmPeriod)
+
+	gobottest.Assert(t, a.ServoWrite("GPIO19", 0), nil)
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.Period, board.ServoPeriod)
+	gobottest.Assert(t, p.DutyCycle, 500000)
+
+	gobottest.Assert(t, a.PwmPulseWrite("GPIO19", 20*time.Millisecond, 2500*time.Microsecond), nil)
+	p, _ = sim.Pwm(0, 1)
+	gobottest.Assert(t, p.DutyCycle, 2500000)
+
+	// without pi-blaster only the hardware pwm pins are pwm pins
+	gobottest.Assert(t, a.PwmWrite("7", 255), errors.New("Not a PWM pin"))
+	gobottest.Assert(t, a.DigitalWrite("GPIO4", 1), nil)
+	g, _ := sim.Gpio(4)
+	gobottest.Assert(t, g.Level, 1)
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+	p, _ = sim.Pwm(0, 0)
+	gobottest.Assert(t, p.Exported, false)
+}
+
+func TestRaspiAdaptorSpi(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+
+	sim.AddSpiDevice(0, 1, nil)
+	sim.AddSpiDevice(1, 0, func(tx []byte) []byte { return []byte{0x00, 0x03, 0xff} })
+	a := initTestRaspiAdaptor()
+
+	gobottest.Assert(t, a.SpiStart(1, 0, 1000000), nil)
+	rx, err := a.SpiTransfer(1, []byte{0x9f})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, rx, []byte{0x9f})
+
+	// spi1 on pins 35, 38 and 40, with chip select 0 on pin 12
+	a.SetSpiBus(0, 1)
+	gobottest.Assert(t, a.SpiStart(0, 0, 1000000), nil)
+	rx, _ = a.SpiTransfer(0, []byte{0x01, 0x80, 0x00})
+	gobottest.Assert(t, rx, []byte{0x00, 0x03, 0xff})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestRaspiAdaptorConcurrency(t *testing.T) {
+	sim := sysfs.NewSimulator()
+	sysfs.SetFilesystem(sim)
+	sysfs.SetSyscall(sim)
+	defer sysfs.SetSyscall(&sysfs.NativeSyscall{})
+
+	sim.AddGpio(4)
+	sim.AddGpio(17)
+	sim.AddFile("/dev/pi-blaster", "")
+	a := initTestRaspiAdaptor()
+
+	gobottest.Concurrently(t, 16, func(i int) (err error) {
+		for j := 0; j < 20 && err == nil; j++ {
+			switch (i + j) % 4 {
+			case 0:
+				err = a.DigitalWrite("7", byte(j%2))
+			case 1:
+				_, err = a.DigitalRead("GPIO17")
+			case 2:
+				err = a.PwmWrite("GPIO17", byte(j))
+			case 3:
+				err = a.ServoWrite("11", byte(j))
+			}
+		}
+		return
+	})
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
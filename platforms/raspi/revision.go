@@ -0,0 +1,131 @@
+package raspi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RaspiInfo describes a Raspberry Pi, as decoded from the revision code in /proc/cpuinfo
+type RaspiInfo struct {
+	// Revision is the revision code, eg. "a02082"
+	Revision string
+	// Model is the model, eg. "B", "3B+" or "Zero W"
+	Model string
+	// BoardRevision is the revision of the board, eg. "1.2"
+	BoardRevision string
+	// Memory is the amount of memory in MB
+	Memory int
+	// Manufacturer is the manufacturer of the board, eg. "Sony UK"
+	Manufacturer string
+	// Processor is the SoC of the board, eg. "BCM2837"
+	Processor string
+}
+
+// newStyle is set in new-style revision codes, which encode the board
+// revision, model, processor, manufacturer and memory as bit fields
+const newStyle = 0x800000
+
+var models = map[int64]string{
+	0x00: "A",
+	0x01: "B",
+	0x02: "A+",
+	0x03: "B+",
+	0x04: "2B",
+	0x05: "Alpha",
+	0x06: "CM1",
+	0x08: "3B",
+	0x09: "Zero",
+	0x0a: "CM3",
+	0x0c: "Zero W",
+	0x0d: "3B+",
+	0x0e: "3A+",
+	0x10: "CM3+",
+	0x11: "4B",
+	0x12: "Zero 2 W",
+	0x13: "400",
+	0x14: "CM4",
+	0x15: "CM4S",
+	0x17: "5",
+	0x18: "CM5",
+	0x19: "500",
+	0x1a: "CM5 Lite",
+}
+
+var processors = []string{"BCM2835", "BCM2836", "BCM2837", "BCM2711", "BCM2712"}
+
+var manufacturers = []string{"Sony UK", "Egoman", "Embest", "Sony Japan", "Embest", "Stadium"}
+
+// oldStyle holds the boards with old-style revision codes
+var oldStyle = map[int64]RaspiInfo{
+	0x0002: {Model: "B", BoardRevision: "1.0", Memory: 256, Manufacturer: "Egoman"},
+	0x0003: {Model: "B", BoardRevision: "1.0", Memory: 256, Manufacturer: "Egoman"},
+	0x0004: {Model: "B", BoardRevision: "2.0", Memory: 256, Manufacturer: "Sony UK"},
+	0x0005: {Model: "B", BoardRevision: "2.0", Memory: 256, Manufacturer: "Qisda"},
+	0x0006: {Model: "B", BoardRevision: "2.0", Memory: 256, Manufacturer: "Egoman"},
+	0x0007: {Model: "A", BoardRevision: "2.0", Memory: 256, Manufacturer: "Egoman"},
+	0x0008: {Model: "A", BoardRevision: "2.0", Memory: 256, Manufacturer: "Sony UK"},
+	0x0009: {Model: "A", BoardRevision: "2.0", Memory: 256, Manufacturer: "Qisda"},
+	0x000d: {Model: "B", BoardRevision: "2.0", Memory: 512, Manufacturer: "Egoman"},
+	0x000e: {Model: "B", BoardRevision: "2.0", Memory: 512, Manufacturer: "Sony UK"},
+	0x000f: {Model: "B", BoardRevision: "2.0", Memory: 512, Manufacturer: "Egoman"},
+	0x0010: {Model: "B+", BoardRevision: "1.2", Memory: 512, Manufacturer: "Sony UK"},
+	0x0011: {Model: "CM1", BoardRevision: "1.0", Memory: 512, Manufacturer: "Sony UK"},
+	0x0012: {Model: "A+", BoardRevision: "1.1", Memory: 256, Manufacturer: "Sony UK"},
+	0x0013: {Model: "B+", BoardRevision: "1.2", Memory: 512, Manufacturer: "Embest"},
+	0x0014: {Model: "CM1", BoardRevision: "1.0", Memory: 512, Manufacturer: "Embest"},
+	0x0015: {Model: "A+", BoardRevision: "1.1", Memory: 256, Manufacturer: "Embest"},
+}
+
+// parseRevision returns the revision code of the Revision line of /proc/cpuinfo
+func parseRevision(cpuinfo string) string {
+	for _, v := range strings.Split(cpuinfo, "\n") {
+		if strings.HasPrefix(v, "Revision") {
+			s := strings.Split(v, ":")
+			return strings.TrimSpace(s[len(s)-1])
+		}
+	}
+	return ""
+}
+
+// decodeRevision decodes a revision code, returning the board information
+// and the header layout of the board: "1" for the 26 pin header of revision
+// 1 boards, "2" for the 26 pin header of revision 2 boards and "3" for the
+// 40 pin header, which is assumed when the revision is unknown
+func decodeRevision(revision string) (info RaspiInfo, layout string) {
+	code, _ := strconv.ParseInt("0x"+revision, 0, 64)
+	// the upper bits flag overvoltage and otp settings
+	code &= 0xffffff
+
+	if revision == "" {
+		// not a Raspberry Pi, or a kernel which does not report the revision
+		layout = "3"
+	} else if code&newStyle == 0 {
+		info = oldStyle[code]
+		info.Processor = processors[0]
+		if code <= 3 {
+			layout = "1"
+		} else if code <= 15 {
+			layout = "2"
+		} else {
+			layout = "3"
+		}
+	} else {
+		model := (code >> 4) & 0xff
+		info.Model = models[model]
+		info.BoardRevision = fmt.Sprintf("1.%v", code&0xf)
+		info.Memory = 256 << uint((code>>20)&0x7)
+		if p := (code >> 12) & 0xf; p < int64(len(processors)) {
+			info.Processor = processors[p]
+		}
+		if m := (code >> 16) & 0xf; m < int64(len(manufacturers)) {
+			info.Manufacturer = manufacturers[m]
+		}
+		layout = "3"
+		if model <= 0x01 {
+			layout = "2"
+		}
+	}
+	info.Revision = revision
+	return
+}
@@ -0,0 +1,61 @@
+package raspi
+
+import (
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestParseRevision(t *testing.T) {
+	gobottest.Assert(t, parseRevision(`
+Hardware        : BCM2835
+Revision        : a02082
+Serial          : 000000003bc748ea
+`), "a02082")
+	gobottest.Assert(t, parseRevision("Hardware        : BCM2835\n"), "")
+}
+
+func TestDecodeRevision(t *testing.T) {
+	info, layout := decodeRevision("a02082")
+	gobottest.Assert(t, info, RaspiInfo{
+		Revision:      "a02082",
+		Model:         "3B",
+		BoardRevision: "1.2",
+		Memory:        1024,
+		Manufacturer:  "Sony UK",
+		Processor:     "BCM2837",
+	})
+	gobottest.Assert(t, layout, "3")
+
+	info, _ = decodeRevision("c03111")
+	gobottest.Assert(t, info.Model, "4B")
+	gobottest.Assert(t, info.Memory, 4096)
+	gobottest.Assert(t, info.Processor, "BCM2711")
+
+	info, _ = decodeRevision("9000c1")
+	gobottest.Assert(t, info.Model, "Zero W")
+	gobottest.Assert(t, info.Memory, 512)
+
+	// new-style codes of the original model B have the 26 pin header
+	_, layout = decodeRevision("900032")
+	gobottest.Assert(t, layout, "3")
+	info, layout = decodeRevision("800012")
+	gobottest.Assert(t, info.Model, "B")
+	gobottest.Assert(t, layout, "2")
+
+	// the overvoltage bit is ignored
+	info, layout = decodeRevision("1000002")
+	gobottest.Assert(t, info.Model, "B")
+	gobottest.Assert(t, info.BoardRevision, "1.0")
+	gobottest.Assert(t, info.Manufacturer, "Egoman")
+	gobottest.Assert(t, layout, "1")
+
+	info, layout = decodeRevision("000e")
+	gobottest.Assert(t, info.Memory, 512)
+	gobottest.Assert(t, info.Processor, "BCM2835")
+	gobottest.Assert(t, layout, "2")
+
+	info, layout = decodeRevision("")
+	gobottest.Assert(t, info.Model, "")
+	gobottest.Assert(t, layout, "3")
+}
@@ -4,7 +4,7 @@ import (
 	"io"
 
 	"github.com/potix/gobot"
-	"github.com/tarm/goserial"
+	"github.com/potix/gobot/transport"
 )
 
 var _ gobot.Adaptor = (*SpheroAdaptor)(nil)
@@ -18,13 +18,15 @@ type SpheroAdaptor struct {
 	connect   func(string) (io.ReadWriteCloser, error)
 }
 
-// NewSpheroAdaptor returns a new SpheroAdaptor given a name and port
+// NewSpheroAdaptor returns a new SpheroAdaptor given a name and port. The port
+// is a serial port name, or a transport spec such as "tcp://192.168.1.10:3333".
+// A serial port is opened with a baud rate of 115200 unless the spec sets one.
 func NewSpheroAdaptor(name string, port string) *SpheroAdaptor {
 	return &SpheroAdaptor{
 		name: name,
 		port: port,
 		connect: func(port string) (io.ReadWriteCloser, error) {
-			return serial.OpenPort(&serial.Config{Name: port, Baud: 115200})
+			return transport.Open(port, 115200)
 		},
 	}
 }
@@ -0,0 +1,13 @@
+/*
+Package spi provides the Gobot interfaces for spi devices, which adaptors
+supporting spi implement.
+
+A device is selected by its chip select line on the spi bus of the adaptor,
+started with a spi mode and a clock speed in Hz, and each transfer writes
+bytes to the device while reading as many bytes from it.
+
+Installing:
+
+	go get github.com/potix/gobot/platforms/spi
+*/
+package spi
@@ -0,0 +1,32 @@
+package spi
+
+import "github.com/potix/gobot"
+
+const (
+	// Mode0 samples on the rising edge of a clock idling low
+	Mode0 = 0
+	// Mode1 samples on the falling edge of a clock idling low
+	Mode1 = 1
+	// Mode2 samples on the falling edge of a clock idling high
+	Mode2 = 2
+	// Mode3 samples on the rising edge of a clock idling high
+	Mode3 = 3
+)
+
+type SpiStarter interface {
+	SpiStart(chip int, mode int, speed int) (err error)
+}
+
+type SpiTransferer interface {
+	SpiTransfer(chip int, data []byte) (rx []byte, err error)
+}
+
+type Spi interface {
+	gobot.Adaptor
+	SpiStarter
+	SpiTransferer
+}
+
+func init() {
+	gobot.RegisterInterface("Spi", func(a gobot.Adaptor) bool { _, ok := a.(Spi); return ok })
+}
@@ -0,0 +1,222 @@
+package sysfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// AnalogBuffer reads triggered, buffered samples of industrial i/o voltage
+// channels from /dev/iio:deviceN, which allows much higher sample rates than
+// reading in_voltageN_raw for every sample.
+type AnalogBuffer struct {
+	// Trigger is the name of the trigger written to trigger/current_trigger
+	// on Enable. The device trigger is left untouched when Trigger is empty.
+	Trigger string
+
+	device   int
+	path     string
+	channels []int
+	length   int
+	elements []*scanElement
+	frame    int
+	file     File
+}
+
+type scanElement struct {
+	channel   int
+	index     int
+	signed    bool
+	bigEndian bool
+	bits      uint
+	storage   int
+	shift     uint
+	offset    int
+}
+
+var scanTypeRegexp = regexp.MustCompile(`^(le|be):(s|u)(\d+)/(\d+)(?:>>(\d+))?$`)
+
+// NewAnalogBuffer returns an AnalogBuffer given an industrial i/o device number,
+// the voltage channels to capture and the buffer length in scans.
+func NewAnalogBuffer(device int, channels []int, length int) *AnalogBuffer {
+	return &AnalogBuffer{
+		device:   device,
+		path:     fmt.Sprintf("%v/iio:device%v", IIOPATH, device),
+		channels: channels,
+		length:   length,
+	}
+}
+
+// Enable enables the scan elements for the buffer channels, sets the buffer
+// length and trigger, enables the buffer and opens the character device.
+func (b *AnalogBuffer) Enable() (err error) {
+	b.elements = []*scanElement{}
+	for _, channel := range b.channels {
+		prefix := fmt.Sprintf("%v/scan_elements/in_voltage%v", b.path, channel)
+		if err = writeAttribute(prefix+"_en", "1"); err != nil {
+			return
+		}
+		e := &scanElement{channel: channel}
+		if e.index, err = readIntAttribute(prefix + "_index"); err != nil {
+			return
+		}
+		var scanType string
+		if scanType, err = ReadAttribute(prefix + "_type"); err != nil {
+			return
+		}
+		if err = e.parseType(scanType); err != nil {
+			return
+		}
+		b.elements = append(b.elements, e)
+	}
+	b.layout()
+
+	if b.Trigger != "" {
+		if err = writeAttribute(b.path+"/trigger/current_trigger", b.Trigger); err != nil {
+			return
+		}
+	}
+	if err = writeAttribute(b.path+"/buffer/length", strconv.Itoa(b.length)); err != nil {
+		return
+	}
+	if err = writeAttribute(b.path+"/buffer/enable", "1"); err != nil {
+		return
+	}
+
+	b.file, err = fs.OpenFile(fmt.Sprintf("/dev/iio:device%v", b.device), os.O_RDONLY, 0644)
+	return
+}
+
+// Read reads the scans currently available in the buffer. Each scan holds one
+// value per channel, in the order the channels were given to NewAnalogBuffer.
+func (b *AnalogBuffer) Read() (scans [][]int, err error) {
+	if b.file == nil {
+		return nil, errors.New("analog buffer has not been enabled")
+	}
+
+	buf := make([]byte, b.frame*b.length)
+	n, err := b.file.Read(buf)
+	if err != nil {
+		return
+	}
+
+	for i := 0; i+b.frame <= n; i += b.frame {
+		scan := make([]int, len(b.channels))
+		for j, e := range b.elements {
+			scan[j] = e.value(buf[i+e.offset : i+e.offset+e.storage])
+		}
+		scans = append(scans, scan)
+	}
+	return
+}
+
+// Disable disables the buffer and its scan elements and closes the character device.
+func (b *AnalogBuffer) Disable() (err error) {
+	if b.file != nil {
+		b.file.Close()
+		b.file = nil
+	}
+	if err = writeAttribute(b.path+"/buffer/enable", "0"); err != nil {
+		return
+	}
+	for _, channel := range b.channels {
+		if err = writeAttribute(fmt.Sprintf("%v/scan_elements/in_voltage%v_en", b.path, channel), "0"); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// layout computes the offset of every element within a scan. The kernel orders
+// elements by scan index and aligns each one to its storage size, and pads the
+// scan to the largest storage size.
+func (b *AnalogBuffer) layout() {
+	ordered := make([]*scanElement, len(b.elements))
+	copy(ordered, b.elements)
+	sort.Sort(byScanIndex(ordered))
+
+	offset, largest := 0, 1
+	for _, e := range ordered {
+		if offset%e.storage != 0 {
+			offset += e.storage - offset%e.storage
+		}
+		e.offset = offset
+		offset += e.storage
+		if e.storage > largest {
+			largest = e.storage
+		}
+	}
+	if offset%largest != 0 {
+		offset += largest - offset%largest
+	}
+	b.frame = offset
+}
+
+// parseType parses a scan element type such as "le:s12/16>>4"
+func (e *scanElement) parseType(t string) (err error) {
+	m := scanTypeRegexp.FindStringSubmatch(t)
+	if m == nil {
+		return fmt.Errorf("unsupported scan element type %q", t)
+	}
+	e.bigEndian = m[1] == "be"
+	e.signed = m[2] == "s"
+	bits, _ := strconv.Atoi(m[3])
+	storage, _ := strconv.Atoi(m[4])
+	if storage%8 != 0 || storage > 64 || bits > storage {
+		return fmt.Errorf("unsupported scan element type %q", t)
+	}
+	e.bits = uint(bits)
+	e.storage = storage / 8
+	if m[5] != "" {
+		shift, _ := strconv.Atoi(m[5])
+		e.shift = uint(shift)
+	}
+	return
+}
+
+// value decodes a single sample from b
+func (e *scanElement) value(b []byte) int {
+	var v uint64
+	for i := range b {
+		if e.bigEndian {
+			v = v<<8 | uint64(b[i])
+		} else {
+			v = v<<8 | uint64(b[len(b)-1-i])
+		}
+	}
+	v = (v >> e.shift) & (1<<e.bits - 1)
+	if e.signed && v&(1<<(e.bits-1)) != 0 {
+		return int(int64(v) - 1<<e.bits)
+	}
+	return int(v)
+}
+
+type byScanIndex []*scanElement
+
+func (s byScanIndex) Len() int           { return len(s) }
+func (s byScanIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byScanIndex) Less(i, j int) bool { return s[i].index < s[j].index }
+
+// readIntAttribute returns the contents of the sysfs attribute at path as an int
+func readIntAttribute(path string) (int, error) {
+	buf, err := ReadAttribute(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(buf)
+}
+
+// writeAttribute writes value to the sysfs attribute at path
+func writeAttribute(path string, value string) error {
+	f, err := fs.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(value)
+	return err
+}
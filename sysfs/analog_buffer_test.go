@@ -0,0 +1,76 @@
+package sysfs
+
+import (
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestAnalogBuffer() (*AnalogBuffer, *MockFilesystem) {
+	fs := NewMockFilesystem([]string{
+		"/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage0_en",
+		"/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage0_index",
+		"/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage0_type",
+		"/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_en",
+		"/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_index",
+		"/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_type",
+		"/sys/bus/iio/devices/iio:device0/trigger/current_trigger",
+		"/sys/bus/iio/devices/iio:device0/buffer/length",
+		"/sys/bus/iio/devices/iio:device0/buffer/enable",
+		"/dev/iio:device0",
+	})
+	SetFilesystem(fs)
+
+	fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage0_index"].Contents = "0\n"
+	fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage0_type"].Contents = "le:u12/16>>0\n"
+	fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_index"].Contents = "2\n"
+	fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_type"].Contents = "be:s12/16>>4\n"
+
+	return NewAnalogBuffer(0, []int{2, 0}, 2), fs
+}
+
+func TestAnalogBuffer(t *testing.T) {
+	b, fs := initTestAnalogBuffer()
+
+	_, err := b.Read()
+	gobottest.Refute(t, err, nil)
+
+	b.Trigger = "sysfstrig0"
+	gobottest.Assert(t, b.Enable(), nil)
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage0_en"].Contents, "1")
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_en"].Contents, "1")
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/trigger/current_trigger"].Contents, "sysfstrig0")
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/buffer/length"].Contents, "2")
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/buffer/enable"].Contents, "1")
+	gobottest.Assert(t, b.frame, 4)
+
+	// channel 0 is 0x0abc little endian, channel 2 is -2 shifted left by 4 big endian
+	fs.Files["/dev/iio:device0"].Contents = string([]byte{
+		0xbc, 0x0a, 0xff, 0xe0,
+		0x01, 0x00, 0x00, 0x10,
+	})
+	scans, err := b.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, scans, [][]int{{-2, 0xabc}, {1, 1}})
+
+	gobottest.Assert(t, b.Disable(), nil)
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/buffer/enable"].Contents, "0")
+	gobottest.Assert(t, fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_en"].Contents, "0")
+}
+
+func TestAnalogBufferUnsupportedType(t *testing.T) {
+	b, fs := initTestAnalogBuffer()
+	fs.Files["/sys/bus/iio/devices/iio:device0/scan_elements/in_voltage2_type"].Contents = "le:s12/16X2>>0\n"
+	gobottest.Assert(t, b.Enable().Error(), `unsupported scan element type "le:s12/16X2>>0"`)
+}
+
+func TestAnalogBufferLayout(t *testing.T) {
+	b := NewAnalogBuffer(0, []int{0, 1}, 1)
+	b.elements = []*scanElement{
+		&scanElement{channel: 0, index: 0, storage: 2},
+		&scanElement{channel: 1, index: 1, storage: 4},
+	}
+	b.layout()
+	gobottest.Assert(t, b.elements[1].offset, 4)
+	gobottest.Assert(t, b.frame, 8)
+}
@@ -0,0 +1,78 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// IIOPATH default linux industrial i/o path
+const IIOPATH = "/sys/bus/iio/devices"
+
+// AnalogPin is the interface for sysfs industrial i/o voltage channel interactions
+type AnalogPin interface {
+	// Read reads the raw value of the channel
+	Read() (int, error)
+	// ReadMillivolts reads the value of the channel with the channel offset and
+	// scale applied, which the kernel defines as millivolts for voltage channels
+	ReadMillivolts() (float64, error)
+}
+
+type analogPin struct {
+	device  string
+	channel string
+}
+
+// NewAnalogPin returns an AnalogPin given an industrial i/o device number and
+// a voltage channel number, eg. a device of 1 and a channel of 3 reads from
+// /sys/bus/iio/devices/iio:device1/in_voltage3_raw
+func NewAnalogPin(device int, channel int) AnalogPin {
+	return &analogPin{
+		device:  fmt.Sprintf("%v/iio:device%v", IIOPATH, device),
+		channel: strconv.Itoa(channel),
+	}
+}
+
+func (a *analogPin) Read() (int, error) {
+	buf, err := ReadAttribute(a.device + "/in_voltage" + a.channel + "_raw")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(buf)
+}
+
+func (a *analogPin) ReadMillivolts() (mv float64, err error) {
+	raw, err := a.Read()
+	if err != nil {
+		return
+	}
+	offset, err := a.attribute("offset", 0)
+	if err != nil {
+		return
+	}
+	scale, err := a.attribute("scale", 1)
+	if err != nil {
+		return
+	}
+	return (float64(raw) + offset) * scale, nil
+}
+
+// attribute reads a channel attribute such as scale or offset. The channel
+// specific attribute takes precedence over the one shared by all voltage
+// channels, and def is returned when the device has neither.
+func (a *analogPin) attribute(name string, def float64) (float64, error) {
+	for _, path := range []string{
+		a.device + "/in_voltage" + a.channel + "_" + name,
+		a.device + "/in_voltage_" + name,
+	} {
+		buf, err := ReadAttribute(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		return strconv.ParseFloat(buf, 64)
+	}
+	return def, nil
+}
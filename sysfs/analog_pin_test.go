@@ -0,0 +1,55 @@
+package sysfs
+
+import (
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestAnalogPin(t *testing.T) {
+	fs := NewMockFilesystem([]string{
+		"/sys/bus/iio/devices/iio:device1/in_voltage0_raw",
+		"/sys/bus/iio/devices/iio:device1/in_voltage3_raw",
+		"/sys/bus/iio/devices/iio:device1/in_voltage3_offset",
+		"/sys/bus/iio/devices/iio:device1/in_voltage_scale",
+	})
+	SetFilesystem(fs)
+
+	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage0_raw"].Contents = "1000\n"
+	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage3_raw"].Contents = "2048\n"
+	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage3_offset"].Contents = "-48\n"
+	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage_scale"].Contents = "1.220703125\n"
+
+	pin := NewAnalogPin(1, 0)
+	val, err := pin.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 1000)
+
+	mv, err := pin.ReadMillivolts()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, mv, 1220.703125)
+
+	pin = NewAnalogPin(1, 3)
+	mv, err = pin.ReadMillivolts()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, mv, 2441.40625)
+
+	fs.Files["/sys/bus/iio/devices/iio:device1/in_voltage3_offset"].Contents = "invalid\n"
+	_, err = pin.ReadMillivolts()
+	gobottest.Refute(t, err, nil)
+
+	_, err = NewAnalogPin(0, 0).Read()
+	gobottest.Refute(t, err, nil)
+}
+
+func TestAnalogPinWithoutScale(t *testing.T) {
+	fs := NewMockFilesystem([]string{
+		"/sys/bus/iio/devices/iio:device0/in_voltage1_raw",
+	})
+	SetFilesystem(fs)
+	fs.Files["/sys/bus/iio/devices/iio:device0/in_voltage1_raw"].Contents = "1800\n"
+
+	mv, err := NewAnalogPin(0, 1).ReadMillivolts()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, mv, 1800.0)
+}
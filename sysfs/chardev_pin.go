@@ -0,0 +1,220 @@
+package sysfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// PULLNONE disconnects the bias resistor of a gpio line
+	PULLNONE = "none"
+	// PULLUP pulls a gpio line up
+	PULLUP = "up"
+	// PULLDOWN pulls a gpio line down
+	PULLDOWN = "down"
+)
+
+const (
+	GPIO_V2_GET_LINE_IOCTL        = 0xc250b407
+	GPIO_V2_LINE_SET_CONFIG_IOCTL = 0xc110b40d
+	GPIO_V2_LINE_GET_VALUES_IOCTL = 0xc010b40e
+	GPIO_V2_LINE_SET_VALUES_IOCTL = 0xc010b40f
+
+	GPIO_V2_LINE_FLAG_ACTIVE_LOW     = 1 << 1
+	GPIO_V2_LINE_FLAG_INPUT          = 1 << 2
+	GPIO_V2_LINE_FLAG_OUTPUT         = 1 << 3
+	GPIO_V2_LINE_FLAG_OPEN_DRAIN     = 1 << 6
+	GPIO_V2_LINE_FLAG_BIAS_PULL_UP   = 1 << 8
+	GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN = 1 << 9
+	GPIO_V2_LINE_FLAG_BIAS_DISABLED  = 1 << 10
+
+	GPIO_V2_LINE_ATTR_ID_DEBOUNCE = 3
+)
+
+// gpioV2LineAttribute is struct gpio_v2_line_attribute of linux/gpio.h, whose
+// union holds the flags, the output values or the debounce period in
+// microseconds
+type gpioV2LineAttribute struct {
+	id      uint32
+	padding uint32
+	value   uint64
+}
+
+// gpioV2LineConfigAttribute is struct gpio_v2_line_config_attribute of linux/gpio.h
+type gpioV2LineConfigAttribute struct {
+	attr gpioV2LineAttribute
+	mask uint64
+}
+
+// gpioV2LineConfig is struct gpio_v2_line_config of linux/gpio.h
+type gpioV2LineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [10]gpioV2LineConfigAttribute
+}
+
+// gpioV2LineRequest is struct gpio_v2_line_request of linux/gpio.h
+type gpioV2LineRequest struct {
+	offsets         [64]uint32
+	consumer        [32]byte
+	config          gpioV2LineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+// gpioV2LineValues is struct gpio_v2_line_values of linux/gpio.h
+type gpioV2LineValues struct {
+	bits uint64
+	mask uint64
+}
+
+// LineConfig describes the electrical configuration of a gpio line
+type LineConfig struct {
+	// Pull is PULLNONE, PULLUP or PULLDOWN, or empty to keep the bias of the line
+	Pull string
+	// ActiveLow inverts the values read from and written to the line
+	ActiveLow bool
+	// OpenDrain drives the line low only while it is an output
+	OpenDrain bool
+	// Debounce is the period an input has to be stable for, rounded down to microseconds
+	Debounce time.Duration
+}
+
+// ConfigurablePin is a DigitalPin whose bias, active level, drive and
+// debounce period can be configured
+type ConfigurablePin interface {
+	DigitalPin
+	// Configure applies config to the pin
+	Configure(config LineConfig) error
+}
+
+type chardevPin struct {
+	chip      string
+	line      int
+	fd        uintptr
+	requested bool
+	direction string
+	config    LineConfig
+}
+
+// NewChardevPin returns a ConfigurablePin for line offset line of the gpio
+// character device chip, eg. /dev/gpiochip0. Export requests the line, as an
+// input until Direction is called, and Unexport releases it.
+func NewChardevPin(chip string, line int) ConfigurablePin {
+	return &chardevPin{chip: chip, line: line, direction: IN}
+}
+
+var notRequestedError = errors.New("gpio line has not been requested")
+
+func (d *chardevPin) Export() error {
+	if d.requested {
+		d.Unexport()
+	}
+	f, err := OpenFile(d.chip, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	req := gpioV2LineRequest{numLines: 1, config: d.lineConfig()}
+	req.offsets[0] = uint32(d.line)
+	copy(req.consumer[:], "gobot")
+	if _, _, errno := Syscall(syscall.SYS_IOCTL, f.Fd(), GPIO_V2_GET_LINE_IOCTL, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return fmt.Errorf("Requesting line %v of %v failed with syscall.Errno %v", d.line, d.chip, errno)
+	}
+	d.fd = uintptr(req.fd)
+	d.requested = true
+	return nil
+}
+
+func (d *chardevPin) Unexport() error {
+	if !d.requested {
+		return nil
+	}
+	d.requested = false
+	if _, _, errno := Syscall(syscall.SYS_CLOSE, d.fd, 0, 0); errno != 0 {
+		return fmt.Errorf("Releasing line %v of %v failed with syscall.Errno %v", d.line, d.chip, errno)
+	}
+	return nil
+}
+
+func (d *chardevPin) Direction(dir string) error {
+	d.direction = dir
+	return d.setConfig()
+}
+
+func (d *chardevPin) Configure(config LineConfig) error {
+	d.config = config
+	return d.setConfig()
+}
+
+func (d *chardevPin) Read() (int, error) {
+	values := gpioV2LineValues{mask: 1}
+	if err := d.ioctl(GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&values), "Reading"); err != nil {
+		return 0, err
+	}
+	return int(values.bits & 1), nil
+}
+
+func (d *chardevPin) Write(b int) error {
+	values := gpioV2LineValues{bits: uint64(b & 1), mask: 1}
+	return d.ioctl(GPIO_V2_LINE_SET_VALUES_IOCTL, unsafe.Pointer(&values), "Writing")
+}
+
+// setConfig reconfigures the requested line with its direction and config
+func (d *chardevPin) setConfig() error {
+	if !d.requested {
+		return nil
+	}
+	config := d.lineConfig()
+	return d.ioctl(GPIO_V2_LINE_SET_CONFIG_IOCTL, unsafe.Pointer(&config), "Configuring")
+}
+
+func (d *chardevPin) ioctl(request uintptr, arg unsafe.Pointer, op string) error {
+	if !d.requested {
+		return notRequestedError
+	}
+	if _, _, errno := Syscall(syscall.SYS_IOCTL, d.fd, request, uintptr(arg)); errno != 0 {
+		return fmt.Errorf("%v line %v of %v failed with syscall.Errno %v", op, d.line, d.chip, errno)
+	}
+	return nil
+}
+
+// lineConfig returns the line config of the direction and config of the pin.
+// The kernel only accepts open drain for outputs and debouncing for inputs.
+func (d *chardevPin) lineConfig() (c gpioV2LineConfig) {
+	if d.direction == OUT {
+		c.flags |= GPIO_V2_LINE_FLAG_OUTPUT
+		if d.config.OpenDrain {
+			c.flags |= GPIO_V2_LINE_FLAG_OPEN_DRAIN
+		}
+	} else {
+		c.flags |= GPIO_V2_LINE_FLAG_INPUT
+		if d.config.Debounce > 0 {
+			c.attrs[0] = gpioV2LineConfigAttribute{
+				attr: gpioV2LineAttribute{id: GPIO_V2_LINE_ATTR_ID_DEBOUNCE, value: uint64(d.config.Debounce / time.Microsecond)},
+				mask: 1,
+			}
+			c.numAttrs = 1
+		}
+	}
+	if d.config.ActiveLow {
+		c.flags |= GPIO_V2_LINE_FLAG_ACTIVE_LOW
+	}
+	switch d.config.Pull {
+	case PULLNONE:
+		c.flags |= GPIO_V2_LINE_FLAG_BIAS_DISABLED
+	case PULLUP:
+		c.flags |= GPIO_V2_LINE_FLAG_BIAS_PULL_UP
+	case PULLDOWN:
+		c.flags |= GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN
+	}
+	return
+}
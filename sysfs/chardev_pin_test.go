@@ -0,0 +1,66 @@
+package sysfs
+
+import (
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestChardevPinStructSizes(t *testing.T) {
+	gobottest.Assert(t, unsafe.Sizeof(gpioV2LineRequest{}), uintptr(592))
+	gobottest.Assert(t, unsafe.Sizeof(gpioV2LineConfig{}), uintptr(272))
+	gobottest.Assert(t, unsafe.Sizeof(gpioV2LineValues{}), uintptr(16))
+}
+
+func TestChardevPin(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddGpioChip(0, 0, 54)
+
+	pin := NewChardevPin("/dev/gpiochip0", 17)
+	_, err := pin.Read()
+	gobottest.Assert(t, err, notRequestedError)
+	gobottest.Assert(t, pin.Configure(LineConfig{Pull: PULLUP, ActiveLow: true, Debounce: 5 * time.Millisecond}), nil)
+	gobottest.Assert(t, pin.Export(), nil)
+
+	g, _ := sim.Gpio(17)
+	gobottest.Assert(t, g.Requested, true)
+	gobottest.Assert(t, g.Pull, PULLUP)
+	gobottest.Assert(t, g.Debounce, 5*time.Millisecond)
+	// the pull up holds the active low line inactive
+	val, err := pin.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 0)
+	sim.SetGpioLevel(17, 0)
+	val, _ = pin.Read()
+	gobottest.Assert(t, val, 1)
+
+	// a requested line can not be exported through sysfs
+	gobottest.Refute(t, NewDigitalPin(17).Export(), nil)
+	gobottest.Refute(t, NewChardevPin("/dev/gpiochip0", 17).Export(), nil)
+
+	// inputs can not be written
+	gobottest.Assert(t, pin.Write(1).Error(), "Writing line 17 of /dev/gpiochip0 failed with syscall.Errno operation not permitted")
+	gobottest.Assert(t, pin.Direction(OUT), nil)
+	gobottest.Assert(t, pin.Write(1), nil)
+	g, _ = sim.Gpio(17)
+	gobottest.Assert(t, g.Direction, OUT)
+	gobottest.Assert(t, g.Level, 0)
+	gobottest.Assert(t, g.Debounce, time.Duration(0))
+
+	gobottest.Assert(t, pin.Configure(LineConfig{OpenDrain: true}), nil)
+	g, _ = sim.Gpio(17)
+	gobottest.Assert(t, g.OpenDrain, true)
+	gobottest.Assert(t, g.ActiveLow, false)
+	gobottest.Assert(t, g.Pull, PULLUP)
+
+	gobottest.Assert(t, pin.Unexport(), nil)
+	g, _ = sim.Gpio(17)
+	gobottest.Assert(t, g.Requested, false)
+	gobottest.Assert(t, pin.Unexport(), nil)
+
+	gobottest.Refute(t, NewChardevPin("/dev/gpiochip0", 54).Export(), nil)
+	gobottest.Refute(t, NewChardevPin("/dev/gpiochip1", 0).Export(), nil)
+}
@@ -10,6 +10,8 @@ import (
 )
 
 func TestDigitalPin(t *testing.T) {
+	defer func(f func(File, []byte) (int, error)) { writeFile = f }(writeFile)
+
 	fs := NewMockFilesystem([]string{
 		"/sys/class/gpio/export",
 		"/sys/class/gpio/unexport",
@@ -2,6 +2,7 @@ package sysfs
 
 import (
 	"os"
+	"strings"
 )
 
 // A File represents basic IO interactions with the underlying file system
@@ -41,3 +42,20 @@ func (fs *NativeFilesystem) OpenFile(name string, flag int, perm os.FileMode) (f
 func OpenFile(name string, flag int, perm os.FileMode) (file File, err error) {
 	return fs.OpenFile(name, flag, perm)
 }
+
+// ReadAttribute returns the trimmed contents of the sysfs attribute at path.
+// The kernel limits an attribute to a page, so a single read returns all of it
+func ReadAttribute(path string) (string, error) {
+	f, err := fs.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 4096)
+	n, err := f.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(buf[:n])), nil
+}
@@ -1,8 +1,8 @@
 package sysfs
 
 import (
-	"errors"
 	"os"
+	"syscall"
 	"time"
 )
 
@@ -89,6 +89,7 @@ func NewMockFilesystem(files []string) *MockFilesystem {
 }
 
 // OpenFile opens file name from fs.Files, if the file does not exist it returns an os.PathError
+// satisfying os.IsNotExist
 func (fs *MockFilesystem) OpenFile(name string, flag int, perm os.FileMode) (file File, err error) {
 	f, ok := fs.Files[name]
 	if ok {
@@ -96,7 +97,7 @@ func (fs *MockFilesystem) OpenFile(name string, flag int, perm os.FileMode) (fil
 		f.Closed = false
 		return f, nil
 	}
-	return (*MockFile)(nil), &os.PathError{Err: errors.New(name + ": No such file.")}
+	return (*MockFile)(nil), &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
 }
 
 // Add adds a new file to fs.Files given a name, and returns the newly created file
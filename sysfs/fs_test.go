@@ -13,3 +13,16 @@ func TestFilesystemOpen(t *testing.T) {
 	gobottest.Assert(t, err, nil)
 	var _ File = file
 }
+
+func TestReadAttribute(t *testing.T) {
+	fs := NewMockFilesystem([]string{"/sys/class/leds/led0/brightness"})
+	SetFilesystem(fs)
+
+	fs.Files["/sys/class/leds/led0/brightness"].Contents = "255\n"
+	buf, err := ReadAttribute("/sys/class/leds/led0/brightness")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, buf, "255")
+
+	_, err = ReadAttribute("/sys/class/leds/led1/brightness")
+	gobottest.Refute(t, err, nil)
+}
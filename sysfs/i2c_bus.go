@@ -0,0 +1,86 @@
+package sysfs
+
+import (
+	"errors"
+	"sync"
+)
+
+// I2cBus represents a linux i2c bus shared by several devices. Every
+// transaction sets the slave address and is serialized with the bus mutex, so
+// devices at different addresses can be used from several goroutines without
+// talking to the wrong chip.
+type I2cBus struct {
+	location string
+	device   I2cDevice
+	mutex    sync.Mutex
+}
+
+// NewI2cBus returns an I2cBus given an i2c bus location such as "/dev/i2c-1".
+// The bus is opened by the first call to Start.
+func NewI2cBus(location string) *I2cBus {
+	return &I2cBus{location: location}
+}
+
+// Location returns the I2cBus location
+func (b *I2cBus) Location() string { return b.location }
+
+// Start opens the bus if it has not been opened yet
+func (b *I2cBus) Start(address int) (err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.device != nil {
+		return
+	}
+	d, err := NewI2cDevice(b.location, address)
+	if err != nil {
+		return
+	}
+	b.device = d
+	return
+}
+
+// Write writes data to the device at address
+func (b *I2cBus) Write(address int, data []byte) (err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err = b.setAddress(address); err != nil {
+		return
+	}
+	_, err = b.device.Write(data)
+	return
+}
+
+// Read returns size bytes from the device at address
+func (b *I2cBus) Read(address int, size int) (data []byte, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err = b.setAddress(address); err != nil {
+		return
+	}
+	data = make([]byte, size)
+	_, err = b.device.Read(data)
+	return
+}
+
+// Close closes the bus
+func (b *I2cBus) Close() (err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.device == nil {
+		return
+	}
+	err = b.device.Close()
+	b.device = nil
+	return
+}
+
+func (b *I2cBus) setAddress(address int) error {
+	if b.device == nil {
+		return errors.New("i2c bus " + b.location + " has not been started")
+	}
+	return b.device.SetAddress(address)
+}
@@ -0,0 +1,91 @@
+package sysfs
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+type addressRecorder struct {
+	sync.Mutex
+	addresses []uintptr
+}
+
+func (a *addressRecorder) Syscall(trap, a1, a2, a3 uintptr) (r1, r2 uintptr, err syscall.Errno) {
+	if a2 == I2C_SLAVE {
+		a.Lock()
+		a.addresses = append(a.addresses, a3)
+		a.Unlock()
+	}
+	return 0, 0, 0
+}
+
+func TestI2cBus(t *testing.T) {
+	fs := NewMockFilesystem([]string{
+		"/dev/i2c-1",
+	})
+	SetFilesystem(fs)
+	recorder := &addressRecorder{}
+	SetSyscall(recorder)
+	defer SetSyscall(&NativeSyscall{})
+
+	b := NewI2cBus("/dev/i2c-1")
+	gobottest.Assert(t, b.Location(), "/dev/i2c-1")
+
+	_, err := b.Read(0x09, 1)
+	gobottest.Assert(t, err.Error(), "i2c bus /dev/i2c-1 has not been started")
+
+	gobottest.Assert(t, b.Start(0x09), nil)
+	gobottest.Assert(t, b.Start(0x68), nil)
+
+	gobottest.Assert(t, b.Write(0x09, []byte{0x00, 0x01}), nil)
+	gobottest.Assert(t, fs.Files["/dev/i2c-1"].Contents, string([]byte{0x00, 0x01}))
+
+	data, err := b.Read(0x68, 2)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, data, []byte{0x00, 0x01})
+
+	// one address for Start and one for each transaction
+	gobottest.Assert(t, recorder.addresses, []uintptr{0x09, 0x09, 0x68})
+
+	gobottest.Assert(t, b.Close(), nil)
+	gobottest.Assert(t, b.Close(), nil)
+	gobottest.Refute(t, b.Write(0x09, []byte{0x00}), nil)
+}
+
+func TestI2cBusStartError(t *testing.T) {
+	SetFilesystem(NewMockFilesystem([]string{}))
+	SetSyscall(&MockSyscall{})
+	defer SetSyscall(&NativeSyscall{})
+
+	b := NewI2cBus("/dev/i2c-1")
+	gobottest.Refute(t, b.Start(0x09), nil)
+	gobottest.Assert(t, b.device, nil)
+}
+
+func TestI2cBusConcurrentAccess(t *testing.T) {
+	SetFilesystem(NewMockFilesystem([]string{
+		"/dev/i2c-1",
+	}))
+	SetSyscall(&MockSyscall{})
+	defer SetSyscall(&NativeSyscall{})
+
+	b := NewI2cBus("/dev/i2c-1")
+	gobottest.Assert(t, b.Start(0x09), nil)
+
+	var wg sync.WaitGroup
+	for _, address := range []int{0x09, 0x68} {
+		wg.Add(1)
+		go func(address int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				b.Write(address, []byte{byte(address)})
+				b.Read(address, 1)
+			}
+		}(address)
+	}
+	wg.Wait()
+	gobottest.Assert(t, b.Close(), nil)
+}
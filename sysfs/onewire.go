@@ -0,0 +1,56 @@
+package sysfs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// W1PATH default linux 1-wire path
+const W1PATH = "/sys/bus/w1/devices"
+
+// OneWireDevice is the interface for sysfs 1-wire slave interactions
+type OneWireDevice interface {
+	// ID returns the slave id, eg. "28-0000075a6e5c"
+	ID() string
+	// Read reads the w1_slave file of the slave
+	Read() ([]byte, error)
+}
+
+type oneWireDevice struct {
+	id string
+}
+
+// NewOneWireDevice returns a OneWireDevice given a slave id
+func NewOneWireDevice(id string) OneWireDevice {
+	return &oneWireDevice{id: id}
+}
+
+func (d *oneWireDevice) ID() string { return d.id }
+
+func (d *oneWireDevice) Read() ([]byte, error) {
+	buf, err := ReadAttribute(W1PATH + "/" + d.id + "/w1_slave")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(buf), nil
+}
+
+// OneWireSlaves returns the ids of the slaves currently found by the kernel on
+// the 1-wire bus master, eg. a master of 1 reads
+// /sys/bus/w1/devices/w1_bus_master1/w1_master_slaves
+func OneWireSlaves(master int) (ids []string, err error) {
+	buf, err := ReadAttribute(fmt.Sprintf("%v/w1_bus_master%v/w1_master_slaves", W1PATH, master))
+	if err != nil {
+		return
+	}
+	ids = []string{}
+	for _, line := range strings.Split(buf, "\n") {
+		line = strings.TrimSpace(line)
+		// the kernel reports "not found." when there are no slaves
+		if line == "" || line == "not found." {
+			continue
+		}
+		ids = append(ids, line)
+	}
+	return
+}
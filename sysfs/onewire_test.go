@@ -0,0 +1,47 @@
+package sysfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestOneWireSlaves(t *testing.T) {
+	fs := NewMockFilesystem([]string{
+		"/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves",
+	})
+	SetFilesystem(fs)
+
+	fs.Files["/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves"].Contents = "28-0000075a6e5c\n28-0000075b1a2d\n"
+	ids, err := OneWireSlaves(1)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, ids, []string{"28-0000075a6e5c", "28-0000075b1a2d"})
+
+	fs.Files["/sys/bus/w1/devices/w1_bus_master1/w1_master_slaves"].Contents = "not found.\n"
+	ids, err = OneWireSlaves(1)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, ids, []string{})
+
+	_, err = OneWireSlaves(2)
+	gobottest.Refute(t, err, nil)
+}
+
+func TestOneWireDevice(t *testing.T) {
+	fs := NewMockFilesystem([]string{
+		"/sys/bus/w1/devices/28-0000075a6e5c/w1_slave",
+	})
+	SetFilesystem(fs)
+
+	contents := "72 01 4b 46 7f ff 0e 10 57 : crc=57 YES\n72 01 4b 46 7f ff 0e 10 57 t=23125\n"
+	fs.Files["/sys/bus/w1/devices/28-0000075a6e5c/w1_slave"].Contents = contents
+
+	d := NewOneWireDevice("28-0000075a6e5c")
+	gobottest.Assert(t, d.ID(), "28-0000075a6e5c")
+	buf, err := d.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, string(buf), strings.TrimSpace(contents))
+
+	_, err = NewOneWireDevice("28-0000075b1a2d").Read()
+	gobottest.Refute(t, err, nil)
+}
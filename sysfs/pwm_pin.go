@@ -0,0 +1,84 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// PWMPATH default linux pwm class path
+const PWMPATH = "/sys/class/pwm"
+
+// PwmPin is the interface for sysfs pwm interactions
+type PwmPin interface {
+	// Export exports the pwm channel for use by the operating system
+	Export() error
+	// Unexport unexports the pwm channel and releases it
+	Unexport() error
+	// Enable enables or disables the output of the pwm channel
+	Enable(bool) error
+	// Period reads the period of the pwm channel in nanoseconds
+	Period() (int, error)
+	// SetPeriod sets the period of the pwm channel in nanoseconds
+	SetPeriod(int) error
+	// SetDutyCycle sets the active time of the pwm channel in nanoseconds
+	SetDutyCycle(int) error
+}
+
+type pwmPin struct {
+	chip    string
+	channel string
+}
+
+// NewPwmPin returns a PwmPin given a pwm chip number and a channel number,
+// eg. a chip of 0 and a channel of 1 uses /sys/class/pwm/pwmchip0/pwm1
+func NewPwmPin(chip int, channel int) PwmPin {
+	return &pwmPin{
+		chip:    fmt.Sprintf("%v/pwmchip%v", PWMPATH, chip),
+		channel: strconv.Itoa(channel),
+	}
+}
+
+func (p *pwmPin) Export() error {
+	err := writeAttribute(p.chip+"/export", p.channel)
+	if err != nil {
+		// If EBUSY then the channel has already been exported
+		if e, ok := err.(*os.PathError); !ok || e.Err != syscall.EBUSY {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pwmPin) Unexport() error {
+	return writeAttribute(p.chip+"/unexport", p.channel)
+}
+
+func (p *pwmPin) Enable(enable bool) error {
+	if enable {
+		return writeAttribute(p.path("enable"), "1")
+	}
+	return writeAttribute(p.path("enable"), "0")
+}
+
+func (p *pwmPin) Period() (int, error) {
+	buf, err := ReadAttribute(p.path("period"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(buf)
+}
+
+func (p *pwmPin) SetPeriod(period int) error {
+	return writeAttribute(p.path("period"), strconv.Itoa(period))
+}
+
+func (p *pwmPin) SetDutyCycle(duty int) error {
+	return writeAttribute(p.path("duty_cycle"), strconv.Itoa(duty))
+}
+
+// path returns the path of an attribute of the channel
+func (p *pwmPin) path(attribute string) string {
+	return p.chip + "/pwm" + p.channel + "/" + attribute
+}
@@ -0,0 +1,38 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestPwmPin(t *testing.T) {
+	sim := NewSimulator()
+	SetFilesystem(sim)
+	sim.AddPwmChip(0, 2)
+
+	pin := NewPwmPin(0, 1)
+	gobottest.Assert(t, pin.Export(), nil)
+	// exporting twice is EBUSY, which the pwm pin ignores
+	gobottest.Assert(t, pin.Export(), nil)
+
+	gobottest.Assert(t, pin.SetPeriod(20000000), nil)
+	period, err := pin.Period()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, period, 20000000)
+	gobottest.Assert(t, pin.SetDutyCycle(1500000), nil)
+	gobottest.Assert(t, pin.Enable(true), nil)
+
+	p, _ := sim.Pwm(0, 1)
+	gobottest.Assert(t, p, SimPwm{Exported: true, Period: 20000000, DutyCycle: 1500000, Enabled: true, Polarity: "normal"})
+
+	gobottest.Assert(t, pin.SetDutyCycle(30000000).(*os.PathError).Err, syscall.EINVAL)
+	gobottest.Assert(t, pin.Enable(false), nil)
+	gobottest.Assert(t, pin.Unexport(), nil)
+	_, err = pin.Period()
+	gobottest.Assert(t, os.IsNotExist(err), true)
+
+	gobottest.Assert(t, NewPwmPin(0, 2).Export().(*os.PathError).Err, syscall.ENODEV)
+}
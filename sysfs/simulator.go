@@ -0,0 +1,377 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var _ File = (*simFile)(nil)
+var _ Filesystem = (*Simulator)(nil)
+var _ SystemCaller = (*Simulator)(nil)
+
+// Simulator is a behavioural model of the gpio, pwm, industrial i/o, i2c and
+// spi parts of sysfs and /dev, and of the gpio character devices. Unlike MockFilesystem it behaves like the kernel:
+// exporting a gpio creates its directory, writes are validated and fail with
+// the same errno as on a real board, and i2c and spi ioctls are answered by
+// simulated devices. It implements both Filesystem and SystemCaller, so an adaptor runs
+// end-to-end on it with
+//
+//	sim := sysfs.NewSimulator()
+//	sysfs.SetFilesystem(sim)
+//	sysfs.SetSyscall(sim)
+//
+// Every other file is a plain attribute added with AddFile, and faults are
+// injected on any path with SetFault.
+type Simulator struct {
+	mutex  sync.Mutex
+	nodes  map[string]*simNode
+	files  map[uintptr]*simFile
+	nextFd uintptr
+	faults map[simFault]syscall.Errno
+	gpios  map[int]*SimGpio
+	pwms   map[int]*simPwmChip
+	iios   map[int]*simIio
+	i2cs   map[int]*simI2cBus
+	spis   map[string]*simSpiDevice
+}
+
+// simNode is a file of the simulated filesystem. A plain attribute keeps its
+// contents, read and write give an attribute behaviour, and dev makes it a
+// character device.
+type simNode struct {
+	contents string
+	read     func() string
+	write    func(string) syscall.Errno
+	dev      simDevice
+}
+
+// simDevice is a simulated character device
+type simDevice interface {
+	open(f *simFile) syscall.Errno
+	close(f *simFile)
+	read(f *simFile, b []byte) (int, syscall.Errno)
+	write(f *simFile, b []byte) (int, syscall.Errno)
+}
+
+// simIoctler is a simulated character device which answers ioctls
+type simIoctler interface {
+	ioctl(f *simFile, request uintptr, arg uintptr) syscall.Errno
+}
+
+type simFault struct {
+	op   string
+	path string
+}
+
+// NewSimulator returns a new Simulator with empty gpio and pwm class directories
+func NewSimulator() *Simulator {
+	s := &Simulator{
+		nodes:  make(map[string]*simNode),
+		files:  make(map[uintptr]*simFile),
+		nextFd: 3,
+		faults: make(map[simFault]syscall.Errno),
+		gpios:  make(map[int]*SimGpio),
+		pwms:   make(map[int]*simPwmChip),
+		iios:   make(map[int]*simIio),
+		i2cs:   make(map[int]*simI2cBus),
+		spis:   make(map[string]*simSpiDevice),
+	}
+	s.nodes[GPIOPATH+"/export"] = &simNode{write: s.gpioExport}
+	s.nodes[GPIOPATH+"/unexport"] = &simNode{write: s.gpioUnexport}
+	return s
+}
+
+// AddFile adds a plain file with the given contents, replacing any file at path
+func (s *Simulator) AddFile(path string, contents string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nodes[path] = &simNode{contents: contents}
+}
+
+// Exists returns true if there is a file at path
+func (s *Simulator) Exists(path string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.nodes[path]
+	return ok
+}
+
+// Contents returns the contents a read of the file at path would return
+func (s *Simulator) Contents(path string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n, ok := s.nodes[path]
+	if !ok || n.dev != nil {
+		return ""
+	}
+	return n.value()
+}
+
+// Glob returns the files and directories of the simulated filesystem matching
+// pattern, with the syntax of filepath.Glob
+func (s *Simulator) Glob(pattern string) (matches []string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	found := map[string]bool{}
+	for name := range s.nodes {
+		// directories only exist implicitly as the parents of files
+		for p := name; p != "/" && p != "."; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				found[p] = true
+			}
+		}
+	}
+	for p := range found {
+		matches = append(matches, p)
+	}
+	sort.Strings(matches)
+	return
+}
+
+// SetFault makes every op on path fail with errno until the fault is cleared.
+// op is one of "open", "read", "write" or "ioctl", eg. a permission denied
+// export is injected with
+//
+//	sim.SetFault("write", "/sys/class/gpio/export", syscall.EACCES)
+func (s *Simulator) SetFault(op string, path string, errno syscall.Errno) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults[simFault{op: op, path: path}] = errno
+}
+
+// ClearFault removes a fault set with SetFault
+func (s *Simulator) ClearFault(op string, path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.faults, simFault{op: op, path: path})
+}
+
+// OpenFile opens the simulated file name. It returns an os.PathError with the
+// errno of the kernel when the file does not exist or the open fails.
+func (s *Simulator) OpenFile(name string, flag int, perm os.FileMode) (file File, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if errno := s.fault("open", name); errno != 0 {
+		return (*simFile)(nil), &os.PathError{Op: "open", Path: name, Err: errno}
+	}
+	n, ok := s.nodes[name]
+	if !ok {
+		return (*simFile)(nil), &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+
+	f := &simFile{sim: s, name: name, node: n, fd: s.nextFd}
+	if n.dev != nil {
+		if errno := n.dev.open(f); errno != 0 {
+			return (*simFile)(nil), &os.PathError{Op: "open", Path: name, Err: errno}
+		}
+	}
+	s.files[f.fd] = f
+	s.nextFd++
+	return f, nil
+}
+
+func (s *Simulator) fault(op string, path string) syscall.Errno {
+	return s.faults[simFault{op: op, path: path}]
+}
+
+// addNodes adds the attributes of a simulated device to dir
+func (s *Simulator) addNodes(dir string, nodes map[string]*simNode) {
+	for name, n := range nodes {
+		s.nodes[dir+"/"+name] = n
+	}
+}
+
+// removeNodes removes dir and every file below it
+func (s *Simulator) removeNodes(dir string) {
+	for name := range s.nodes {
+		if strings.HasPrefix(name, dir+"/") {
+			delete(s.nodes, name)
+		}
+	}
+}
+
+// value returns the contents of a plain or attribute node
+func (n *simNode) value() string {
+	if n.read != nil {
+		return n.read()
+	}
+	return n.contents
+}
+
+// simFile is an open file of the Simulator
+type simFile struct {
+	sim    *Simulator
+	name   string
+	node   *simNode
+	fd     uintptr
+	offset int64
+	closed bool
+
+	// address is the i2c slave address selected on an i2c bus device
+	address int
+	// lines are the gpios of a line request of a gpio chip
+	lines []*SimGpio
+}
+
+// check returns the errno of an op on the file, which fails once the file is
+// closed or removed, eg. by unexporting its gpio
+func (f *simFile) check(op string) syscall.Errno {
+	if f.closed {
+		return syscall.EBADF
+	}
+	if errno := f.sim.fault(op, f.name); errno != 0 {
+		return errno
+	}
+	if f.sim.nodes[f.name] != f.node {
+		return syscall.ENODEV
+	}
+	return 0
+}
+
+func (f *simFile) Write(b []byte) (n int, err error) {
+	f.sim.mutex.Lock()
+	defer f.sim.mutex.Unlock()
+
+	if errno := f.check("write"); errno != 0 {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: errno}
+	}
+	if f.node.dev != nil {
+		n, errno := f.node.dev.write(f, b)
+		if errno != 0 {
+			return n, &os.PathError{Op: "write", Path: f.name, Err: errno}
+		}
+		return n, nil
+	}
+	if f.node.write != nil {
+		// attributes ignore the trailing newline written by echo
+		if errno := f.node.write(strings.TrimSpace(string(b))); errno != 0 {
+			return 0, &os.PathError{Op: "write", Path: f.name, Err: errno}
+		}
+		return len(b), nil
+	}
+	f.node.contents = string(b)
+	return len(b), nil
+}
+
+func (f *simFile) WriteString(s string) (ret int, err error) {
+	return f.Write([]byte(s))
+}
+
+func (f *simFile) Read(b []byte) (n int, err error) {
+	f.sim.mutex.Lock()
+	defer f.sim.mutex.Unlock()
+
+	n, err = f.readAt(b, f.offset)
+	f.offset += int64(n)
+	return
+}
+
+func (f *simFile) ReadAt(b []byte, off int64) (n int, err error) {
+	f.sim.mutex.Lock()
+	defer f.sim.mutex.Unlock()
+
+	return f.readAt(b, off)
+}
+
+// readAt reads from the file at off, with the simulator locked
+func (f *simFile) readAt(b []byte, off int64) (n int, err error) {
+	if errno := f.check("read"); errno != 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: errno}
+	}
+	if f.node.dev != nil {
+		n, errno := f.node.dev.read(f, b)
+		if errno != 0 {
+			return n, &os.PathError{Op: "read", Path: f.name, Err: errno}
+		}
+		return n, nil
+	}
+	contents := f.node.value()
+	if off >= int64(len(contents)) {
+		return 0, io.EOF
+	}
+	return copy(b, contents[off:]), nil
+}
+
+func (f *simFile) Seek(offset int64, whence int) (ret int64, err error) {
+	f.sim.mutex.Lock()
+	defer f.sim.mutex.Unlock()
+
+	switch whence {
+	case os.SEEK_SET:
+		f.offset = offset
+	case os.SEEK_CUR:
+		f.offset += offset
+	default:
+		return f.offset, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	return f.offset, nil
+}
+
+func (f *simFile) Sync() (err error) {
+	return nil
+}
+
+func (f *simFile) Fd() uintptr {
+	return f.fd
+}
+
+// Close closes the file. A nil file can be closed, as adaptors defer Close
+// before checking the error of OpenFile.
+func (f *simFile) Close() error {
+	if f == nil {
+		return nil
+	}
+	f.sim.mutex.Lock()
+	defer f.sim.mutex.Unlock()
+
+	if f.closed {
+		return &os.PathError{Op: "close", Path: f.name, Err: syscall.EBADF}
+	}
+	f.closed = true
+	delete(f.sim.files, f.fd)
+	if f.node.dev != nil {
+		f.node.dev.close(f)
+	}
+	return nil
+}
+
+// Syscall answers the ioctls of the i2c, spi and gpio chip devices on their
+// open files, and closes the line requests of gpio chips. Other system calls
+// fail with ENOSYS.
+func (s *Simulator) Syscall(trap, a1, a2, a3 uintptr) (r1, r2 uintptr, err syscall.Errno) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if trap != syscall.SYS_IOCTL && trap != syscall.SYS_CLOSE {
+		return 0, 0, syscall.ENOSYS
+	}
+	f, ok := s.files[a1]
+	if !ok {
+		return 0, 0, syscall.EBADF
+	}
+	if trap == syscall.SYS_CLOSE {
+		f.closed = true
+		delete(s.files, f.fd)
+		if f.node.dev != nil {
+			f.node.dev.close(f)
+		}
+		return 0, 0, 0
+	}
+	if errno := f.check("ioctl"); errno != 0 {
+		return 0, 0, errno
+	}
+	d, ok := f.node.dev.(simIoctler)
+	if !ok {
+		return 0, 0, syscall.ENOTTY
+	}
+	return 0, 0, d.ioctl(f, a2, a3)
+}
@@ -0,0 +1,185 @@
+package sysfs
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// simGpioChip is a gpio character device, whose lines are the gpios starting
+// at base
+type simGpioChip struct {
+	sim   *Simulator
+	base  int
+	lines int
+}
+
+// AddGpioChip adds the gpio character device /dev/gpiochipN for chip, whose
+// lines are the gpios base through base+lines-1. Missing gpios are added as
+// with AddGpio, so a line can be requested from the chip or exported through
+// sysfs, but not both at once.
+func (s *Simulator) AddGpioChip(chip int, base int, lines int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for pin := base; pin < base+lines; pin++ {
+		if _, ok := s.gpios[pin]; !ok {
+			s.gpios[pin] = &SimGpio{Pin: pin, Label: fmt.Sprintf("gpio%v", pin), Direction: IN, Edge: "none"}
+		}
+	}
+	s.nodes[fmt.Sprintf("/dev/gpiochip%v", chip)] = &simNode{dev: &simGpioChip{sim: s, base: base, lines: lines}}
+}
+
+// ioctl requests lines on the chip, and answers the config and value ioctls
+// of the line requests
+func (c *simGpioChip) ioctl(f *simFile, request uintptr, arg uintptr) syscall.Errno {
+	switch request {
+	case GPIO_V2_GET_LINE_IOCTL:
+		if f.lines != nil {
+			return syscall.ENOTTY
+		}
+		return c.request(f, (*gpioV2LineRequest)(pointer(arg)))
+	case GPIO_V2_LINE_SET_CONFIG_IOCTL:
+		if f.lines == nil {
+			return syscall.ENOTTY
+		}
+		return configureLines(f.lines, (*gpioV2LineConfig)(pointer(arg)))
+	case GPIO_V2_LINE_GET_VALUES_IOCTL:
+		if f.lines == nil {
+			return syscall.ENOTTY
+		}
+		values := (*gpioV2LineValues)(pointer(arg))
+		values.bits = 0
+		for i, g := range f.lines {
+			if values.mask&(1<<uint(i)) != 0 {
+				values.bits |= uint64(g.value()) << uint(i)
+			}
+		}
+	case GPIO_V2_LINE_SET_VALUES_IOCTL:
+		if f.lines == nil {
+			return syscall.ENOTTY
+		}
+		values := (*gpioV2LineValues)(pointer(arg))
+		for i, g := range f.lines {
+			if values.mask&(1<<uint(i)) == 0 {
+				continue
+			}
+			if g.Direction != OUT {
+				return syscall.EPERM
+			}
+			g.Level = int(values.bits>>uint(i)) & 1
+			if g.ActiveLow {
+				g.Level ^= 1
+			}
+		}
+	default:
+		return syscall.ENOTTY
+	}
+	return 0
+}
+
+// request requests the lines of req, which fails with EBUSY for lines which
+// are exported or already requested, and returns the new line request in
+// req.fd
+func (c *simGpioChip) request(f *simFile, req *gpioV2LineRequest) syscall.Errno {
+	if req.numLines == 0 || req.numLines > 64 {
+		return syscall.EINVAL
+	}
+	lines := []*SimGpio{}
+	for _, offset := range req.offsets[:req.numLines] {
+		if int(offset) >= c.lines {
+			return syscall.EINVAL
+		}
+		g := c.sim.gpios[c.base+int(offset)]
+		if g.Exported || g.Requested {
+			return syscall.EBUSY
+		}
+		lines = append(lines, g)
+	}
+	if errno := configureLines(lines, &req.config); errno != 0 {
+		return errno
+	}
+	for _, g := range lines {
+		g.Requested = true
+	}
+
+	s := c.sim
+	line := &simFile{sim: s, name: f.name, node: f.node, fd: s.nextFd, lines: lines}
+	s.files[line.fd] = line
+	s.nextFd++
+	req.fd = int32(line.fd)
+	return 0
+}
+
+// configureLines applies config to lines, failing with EINVAL for the flag
+// combinations the kernel rejects
+func configureLines(lines []*SimGpio, config *gpioV2LineConfig) syscall.Errno {
+	flags := config.flags
+	input := flags&GPIO_V2_LINE_FLAG_INPUT != 0
+	output := flags&GPIO_V2_LINE_FLAG_OUTPUT != 0
+	if input == output {
+		return syscall.EINVAL
+	}
+	if input && flags&GPIO_V2_LINE_FLAG_OPEN_DRAIN != 0 {
+		return syscall.EINVAL
+	}
+	pull := ""
+	switch flags & (GPIO_V2_LINE_FLAG_BIAS_DISABLED | GPIO_V2_LINE_FLAG_BIAS_PULL_UP | GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN) {
+	case 0:
+	case GPIO_V2_LINE_FLAG_BIAS_DISABLED:
+		pull = PULLNONE
+	case GPIO_V2_LINE_FLAG_BIAS_PULL_UP:
+		pull = PULLUP
+	case GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN:
+		pull = PULLDOWN
+	default:
+		return syscall.EINVAL
+	}
+	var debounce time.Duration
+	for _, a := range config.attrs[:config.numAttrs] {
+		if a.attr.id == GPIO_V2_LINE_ATTR_ID_DEBOUNCE {
+			if !input {
+				return syscall.EINVAL
+			}
+			debounce = time.Duration(uint32(a.attr.value)) * time.Microsecond
+		}
+	}
+
+	for _, g := range lines {
+		g.Direction = IN
+		if output {
+			g.Direction = OUT
+		}
+		g.ActiveLow = flags&GPIO_V2_LINE_FLAG_ACTIVE_LOW != 0
+		g.OpenDrain = flags&GPIO_V2_LINE_FLAG_OPEN_DRAIN != 0
+		g.Debounce = debounce
+		if pull != "" {
+			g.Pull = pull
+		}
+		// a floating input reads the level it is pulled to
+		if input && g.Pull == PULLUP {
+			g.Level = 1
+		} else if input && g.Pull == PULLDOWN {
+			g.Level = 0
+		}
+	}
+	return 0
+}
+
+func (c *simGpioChip) open(f *simFile) syscall.Errno { return 0 }
+
+// close releases the lines of a line request
+func (c *simGpioChip) close(f *simFile) {
+	for _, g := range f.lines {
+		g.Requested = false
+	}
+}
+
+// read and write of line events are not simulated
+func (c *simGpioChip) read(f *simFile, b []byte) (int, syscall.Errno) {
+	return 0, syscall.EINVAL
+}
+
+func (c *simGpioChip) write(f *simFile, b []byte) (int, syscall.Errno) {
+	return 0, syscall.EINVAL
+}
@@ -0,0 +1,195 @@
+package sysfs
+
+import (
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// SimGpio is the state of a gpio line of the Simulator
+type SimGpio struct {
+	// Pin is the kernel gpio number
+	Pin int
+	// Label is the name of the gpio directory, eg. "gpio10"
+	Label string
+	// Exported is true while the gpio is exported
+	Exported bool
+	// Direction is "in" or "out"
+	Direction string
+	// Level is the physical level of the line, set by writes to value while
+	// the gpio is an output and by SetGpioLevel while it is an input
+	Level int
+	// ActiveLow inverts the value read and written through sysfs
+	ActiveLow bool
+	// Edge is the interrupt edge, "none", "rising", "falling" or "both"
+	Edge string
+	// Requested is true while the line is requested from its gpio chip
+	Requested bool
+	// Pull is the bias of a requested line, PULLNONE, PULLUP or PULLDOWN, or
+	// empty while it was never set
+	Pull string
+	// OpenDrain is true while a requested output is driven as open drain
+	OpenDrain bool
+	// Debounce is the debounce period of a requested input
+	Debounce time.Duration
+}
+
+// AddGpio adds a gpio line which can be exported, given its kernel gpio number
+// and an optional directory label which defaults to "gpio" followed by the number.
+func (s *Simulator) AddGpio(pin int, v ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	g := &SimGpio{Pin: pin, Label: "gpio" + strconv.Itoa(pin), Direction: IN, Edge: "none"}
+	if len(v) > 0 {
+		g.Label = v[0]
+	}
+	s.gpios[pin] = g
+}
+
+// Gpio returns the state of the gpio line pin, and false if the line does not exist
+func (s *Simulator) Gpio(pin int) (SimGpio, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	g, ok := s.gpios[pin]
+	if !ok {
+		return SimGpio{}, false
+	}
+	return *g, true
+}
+
+// SetGpioLevel drives the physical level of the gpio line pin, as a button or
+// sensor connected to an input does. The level of an output is left untouched.
+func (s *Simulator) SetGpioLevel(pin int, level int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if g, ok := s.gpios[pin]; ok && g.Direction == IN {
+		g.Level = level
+	}
+}
+
+// gpioExport creates the directory of a gpio. The kernel fails with EINVAL
+// for a gpio which does not exist and with EBUSY for an exported one.
+func (s *Simulator) gpioExport(value string) syscall.Errno {
+	pin, err := strconv.Atoi(value)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	g, ok := s.gpios[pin]
+	if !ok {
+		return syscall.EINVAL
+	}
+	if g.Exported || g.Requested {
+		return syscall.EBUSY
+	}
+
+	g.Exported = true
+	s.addNodes(GPIOPATH+"/"+g.Label, map[string]*simNode{
+		"value": &simNode{
+			read:  func() string { return strconv.Itoa(g.value()) + "\n" },
+			write: g.writeValue,
+		},
+		"direction": &simNode{
+			read:  func() string { return g.Direction + "\n" },
+			write: g.writeDirection,
+		},
+		"active_low": &simNode{
+			read: func() string {
+				if g.ActiveLow {
+					return "1\n"
+				}
+				return "0\n"
+			},
+			write: g.writeActiveLow,
+		},
+		"edge": &simNode{
+			read:  func() string { return g.Edge + "\n" },
+			write: g.writeEdge,
+		},
+	})
+	return 0
+}
+
+// gpioUnexport removes the directory of a gpio, and fails with EINVAL for a
+// gpio which is not exported.
+func (s *Simulator) gpioUnexport(value string) syscall.Errno {
+	pin, err := strconv.Atoi(value)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	g, ok := s.gpios[pin]
+	if !ok || !g.Exported {
+		return syscall.EINVAL
+	}
+
+	g.Exported = false
+	s.removeNodes(GPIOPATH + "/" + g.Label)
+	return 0
+}
+
+// value returns the logical value of the gpio
+func (g *SimGpio) value() int {
+	if g.ActiveLow {
+		return g.Level ^ 1
+	}
+	return g.Level
+}
+
+// writeValue sets the level of an output, the kernel fails with EPERM for an input
+func (g *SimGpio) writeValue(value string) syscall.Errno {
+	if g.Direction != OUT {
+		return syscall.EPERM
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	if v != 0 {
+		v = 1
+	}
+	if g.ActiveLow {
+		v ^= 1
+	}
+	g.Level = v
+	return 0
+}
+
+// writeDirection accepts "in", "out", and "high" or "low" which set an output
+// glitch free with an initial level
+func (g *SimGpio) writeDirection(value string) syscall.Errno {
+	switch value {
+	case IN:
+		g.Direction = IN
+	case OUT:
+		g.Direction = OUT
+	case "high":
+		g.Direction = OUT
+		g.Level = 1
+	case "low":
+		g.Direction = OUT
+		g.Level = 0
+	default:
+		return syscall.EINVAL
+	}
+	return 0
+}
+
+func (g *SimGpio) writeActiveLow(value string) syscall.Errno {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	g.ActiveLow = v != 0
+	return 0
+}
+
+func (g *SimGpio) writeEdge(value string) syscall.Errno {
+	switch value {
+	case "none", "rising", "falling", "both":
+		g.Edge = value
+		return 0
+	}
+	return syscall.EINVAL
+}
@@ -0,0 +1,193 @@
+package sysfs
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+type simI2cBus struct {
+	funcs   uint64
+	devices map[int]*simI2cDevice
+}
+
+type simI2cDevice struct {
+	registers [256]byte
+	pointer   byte
+	writes    [][]byte
+	nak       bool
+}
+
+// AddI2cBus adds the i2c bus /dev/i2c-N, given the adapter functionality
+// mask answered to I2C_FUNCS, eg. I2C_FUNC_SMBUS_READ_BLOCK_DATA
+func (s *Simulator) AddI2cBus(bus int, funcs uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b := &simI2cBus{funcs: funcs, devices: make(map[int]*simI2cDevice)}
+	s.i2cs[bus] = b
+	s.nodes[fmt.Sprintf("/dev/i2c-%v", bus)] = &simNode{dev: b}
+}
+
+// AddI2cDevice adds a device to an i2c bus. The device has 256 registers, the
+// first byte of a write selects the register and the following bytes are
+// written to it, and reads return the registers from the selected one on.
+func (s *Simulator) AddI2cDevice(bus int, address int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if b, ok := s.i2cs[bus]; ok {
+		b.devices[address] = &simI2cDevice{}
+	}
+}
+
+// SetI2cRegisters writes data to the registers of an i2c device from register on
+func (s *Simulator) SetI2cRegisters(bus int, address int, register int, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d := s.i2cDevice(bus, address); d != nil {
+		copy(d.registers[register:], data)
+	}
+}
+
+// I2cRegisters returns the registers of an i2c device
+func (s *Simulator) I2cRegisters(bus int, address int) []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d := s.i2cDevice(bus, address); d != nil {
+		return append([]byte{}, d.registers[:]...)
+	}
+	return nil
+}
+
+// I2cWrites returns every message written to an i2c device
+func (s *Simulator) I2cWrites(bus int, address int) [][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	writes := [][]byte{}
+	if d := s.i2cDevice(bus, address); d != nil {
+		for _, w := range d.writes {
+			writes = append(writes, append([]byte{}, w...))
+		}
+	}
+	return writes
+}
+
+// SetI2cNak makes an i2c device not acknowledge its address, so every
+// transfer with it fails with ENXIO as if it was unplugged
+func (s *Simulator) SetI2cNak(bus int, address int, nak bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d := s.i2cDevice(bus, address); d != nil {
+		d.nak = nak
+	}
+}
+
+func (s *Simulator) i2cDevice(bus int, address int) *simI2cDevice {
+	if b, ok := s.i2cs[bus]; ok {
+		return b.devices[address]
+	}
+	return nil
+}
+
+// ioctl answers I2C_FUNCS, I2C_SLAVE and I2C_SMBUS
+func (b *simI2cBus) ioctl(f *simFile, request uintptr, arg uintptr) syscall.Errno {
+	switch request {
+	case I2C_FUNCS:
+		*(*uint64)(pointer(arg)) = b.funcs
+	case I2C_SLAVE:
+		if arg > 0x7f {
+			return syscall.EINVAL
+		}
+		f.address = int(arg)
+	case I2C_SMBUS:
+		return b.smbus(f, (*i2cSmbusIoctlData)(pointer(arg)))
+	default:
+		return syscall.ENOTTY
+	}
+	return 0
+}
+
+// pointer converts an ioctl argument back to the pointer the caller passed
+func pointer(arg uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&arg))
+}
+
+// smbus answers I2C_SMBUS_I2C_BLOCK_DATA transfers, the only size used by i2cDevice
+func (b *simI2cBus) smbus(f *simFile, data *i2cSmbusIoctlData) syscall.Errno {
+	if data.size != I2C_SMBUS_I2C_BLOCK_DATA {
+		return syscall.EOPNOTSUPP
+	}
+	if data.readWrite == I2C_SMBUS_READ && b.funcs&I2C_FUNC_SMBUS_READ_BLOCK_DATA == 0 ||
+		data.readWrite == I2C_SMBUS_WRITE && b.funcs&I2C_FUNC_SMBUS_WRITE_BLOCK_DATA == 0 {
+		return syscall.EOPNOTSUPP
+	}
+	d := b.devices[f.address]
+	if d == nil || d.nak {
+		return syscall.ENXIO
+	}
+
+	// the first byte of the block is its length, at most 32 bytes
+	length := int(*(*byte)(pointer(data.data)))
+	if length > 32 {
+		return syscall.EINVAL
+	}
+	var block []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&block))
+	h.Data, h.Len, h.Cap = data.data, length+1, length+1
+	if data.readWrite == I2C_SMBUS_READ {
+		d.pointer = data.command
+		d.read(block[1 : 1+length])
+		return 0
+	}
+	d.write(append([]byte{data.command}, block[1:1+length]...))
+	return 0
+}
+
+func (b *simI2cBus) open(f *simFile) syscall.Errno { return 0 }
+
+func (b *simI2cBus) close(f *simFile) {}
+
+func (b *simI2cBus) read(f *simFile, buf []byte) (int, syscall.Errno) {
+	d := b.devices[f.address]
+	if d == nil || d.nak {
+		return 0, syscall.ENXIO
+	}
+	d.read(buf)
+	return len(buf), 0
+}
+
+func (b *simI2cBus) write(f *simFile, buf []byte) (int, syscall.Errno) {
+	d := b.devices[f.address]
+	if d == nil || d.nak {
+		return 0, syscall.ENXIO
+	}
+	d.write(buf)
+	return len(buf), 0
+}
+
+// read reads the registers from the register pointer on
+func (d *simI2cDevice) read(buf []byte) {
+	for i := range buf {
+		buf[i] = d.registers[d.pointer]
+		d.pointer++
+	}
+}
+
+// write selects the register pointer and writes the registers from it on
+func (d *simI2cDevice) write(buf []byte) {
+	d.writes = append(d.writes, append([]byte{}, buf...))
+	if len(buf) == 0 {
+		return
+	}
+	d.pointer = buf[0]
+	for _, v := range buf[1:] {
+		d.registers[d.pointer] = v
+		d.pointer++
+	}
+}
@@ -0,0 +1,118 @@
+package sysfs
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+)
+
+type simIio struct {
+	raw     []int
+	scans   []byte
+	enabled bool
+	opened  bool
+}
+
+// AddIioDevice adds the industrial i/o device /sys/bus/iio/devices/iio:deviceN
+// with channels voltage channels, its scan elements and buffer, and its
+// character device /dev/iio:deviceN. The scan elements are unsigned 12 bit
+// little endian samples, which is changed by writing their _type with AddFile.
+func (s *Simulator) AddIioDevice(device int, channels int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	d := &simIio{raw: make([]int, channels)}
+	s.iios[device] = d
+
+	nodes := map[string]*simNode{
+		"buffer/length":           &simNode{contents: "0\n"},
+		"trigger/current_trigger": &simNode{contents: "\n"},
+		"buffer/enable": &simNode{
+			read: func() string {
+				if d.enabled {
+					return "1\n"
+				}
+				return "0\n"
+			},
+			write: d.writeEnable,
+		},
+	}
+	for i := 0; i < channels; i++ {
+		channel := i
+		prefix := fmt.Sprintf("scan_elements/in_voltage%v", channel)
+		nodes[prefix+"_en"] = &simNode{contents: "0\n"}
+		nodes[prefix+"_index"] = &simNode{contents: strconv.Itoa(channel) + "\n"}
+		nodes[prefix+"_type"] = &simNode{contents: "le:u12/16>>0\n"}
+		nodes[fmt.Sprintf("in_voltage%v_raw", channel)] = &simNode{
+			read:  func() string { return strconv.Itoa(d.raw[channel]) + "\n" },
+			write: func(string) syscall.Errno { return syscall.EACCES },
+		}
+	}
+	s.addNodes(fmt.Sprintf("%v/iio:device%v", IIOPATH, device), nodes)
+	s.nodes[fmt.Sprintf("/dev/iio:device%v", device)] = &simNode{dev: d}
+}
+
+// SetAnalog sets the raw value read from a voltage channel of an industrial i/o device
+func (s *Simulator) SetAnalog(device int, channel int, raw int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d, ok := s.iios[device]; ok && channel >= 0 && channel < len(d.raw) {
+		d.raw[channel] = raw
+	}
+}
+
+// PushIioScans queues data, which holds scans in the layout of the enabled
+// scan elements, to be read from the character device of an industrial i/o device
+func (s *Simulator) PushIioScans(device int, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d, ok := s.iios[device]; ok {
+		d.scans = append(d.scans, data...)
+	}
+}
+
+func (d *simIio) writeEnable(value string) syscall.Errno {
+	switch value {
+	case "0":
+		d.enabled = false
+		d.scans = nil
+	case "1":
+		d.enabled = true
+	default:
+		return syscall.EINVAL
+	}
+	return 0
+}
+
+// open fails with EBUSY as the kernel only allows a single reader
+func (d *simIio) open(f *simFile) syscall.Errno {
+	if d.opened {
+		return syscall.EBUSY
+	}
+	d.opened = true
+	return 0
+}
+
+func (d *simIio) close(f *simFile) {
+	d.opened = false
+}
+
+// read fails with EINVAL while the buffer is disabled, and with EAGAIN when
+// no scans are queued as a non-blocking read does
+func (d *simIio) read(f *simFile, b []byte) (int, syscall.Errno) {
+	if !d.enabled {
+		return 0, syscall.EINVAL
+	}
+	if len(d.scans) == 0 {
+		return 0, syscall.EAGAIN
+	}
+	n := copy(b, d.scans)
+	d.scans = d.scans[n:]
+	return n, 0
+}
+
+func (d *simIio) write(f *simFile, b []byte) (int, syscall.Errno) {
+	return 0, syscall.EINVAL
+}
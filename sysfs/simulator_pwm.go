@@ -0,0 +1,170 @@
+package sysfs
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+)
+
+// SimPwm is the state of a pwm channel of the Simulator
+type SimPwm struct {
+	// Exported is true while the channel is exported
+	Exported bool
+	// Period is the period in nanoseconds
+	Period int
+	// DutyCycle is the active time in nanoseconds
+	DutyCycle int
+	// Enabled is true while the channel outputs the signal
+	Enabled bool
+	// Polarity is "normal" or "inversed"
+	Polarity string
+}
+
+type simPwmChip struct {
+	path     string
+	channels []*SimPwm
+}
+
+// AddPwmChip adds the pwm chip /sys/class/pwm/pwmchipN with npwm channels.
+// Optionally accepts the period in nanoseconds the pwm driver initializes the
+// channels with, which defaults to 0.
+func (s *Simulator) AddPwmChip(chip int, npwm int, v ...int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	period := 0
+	if len(v) > 0 {
+		period = v[0]
+	}
+	c := &simPwmChip{path: fmt.Sprintf("%v/pwmchip%v", PWMPATH, chip)}
+	for i := 0; i < npwm; i++ {
+		c.channels = append(c.channels, &SimPwm{Period: period, Polarity: "normal"})
+	}
+	s.pwms[chip] = c
+	s.addNodes(c.path, map[string]*simNode{
+		"npwm": &simNode{
+			read:  func() string { return strconv.Itoa(npwm) + "\n" },
+			write: func(string) syscall.Errno { return syscall.EACCES },
+		},
+		"export":   &simNode{write: func(v string) syscall.Errno { return s.pwmExport(c, v) }},
+		"unexport": &simNode{write: func(v string) syscall.Errno { return s.pwmUnexport(c, v) }},
+	})
+}
+
+// Pwm returns the state of a pwm channel, and false if the channel does not exist
+func (s *Simulator) Pwm(chip int, channel int) (SimPwm, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.pwms[chip]
+	if !ok || channel < 0 || channel >= len(c.channels) {
+		return SimPwm{}, false
+	}
+	return *c.channels[channel], true
+}
+
+// pwmExport creates the directory of a pwm channel. The kernel fails with
+// ENODEV for a channel which does not exist and with EBUSY for an exported one.
+func (s *Simulator) pwmExport(c *simPwmChip, value string) syscall.Errno {
+	channel, err := strconv.Atoi(value)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	if channel < 0 || channel >= len(c.channels) {
+		return syscall.ENODEV
+	}
+	p := c.channels[channel]
+	if p.Exported {
+		return syscall.EBUSY
+	}
+
+	p.Exported = true
+	s.addNodes(fmt.Sprintf("%v/pwm%v", c.path, channel), map[string]*simNode{
+		"period": &simNode{
+			read:  func() string { return strconv.Itoa(p.Period) + "\n" },
+			write: p.writePeriod,
+		},
+		"duty_cycle": &simNode{
+			read:  func() string { return strconv.Itoa(p.DutyCycle) + "\n" },
+			write: p.writeDutyCycle,
+		},
+		"enable": &simNode{
+			read: func() string {
+				if p.Enabled {
+					return "1\n"
+				}
+				return "0\n"
+			},
+			write: p.writeEnable,
+		},
+		"polarity": &simNode{
+			read:  func() string { return p.Polarity + "\n" },
+			write: p.writePolarity,
+		},
+	})
+	return 0
+}
+
+// pwmUnexport disables and removes the directory of a pwm channel
+func (s *Simulator) pwmUnexport(c *simPwmChip, value string) syscall.Errno {
+	channel, err := strconv.Atoi(value)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	if channel < 0 || channel >= len(c.channels) || !c.channels[channel].Exported {
+		return syscall.ENODEV
+	}
+
+	c.channels[channel].Exported = false
+	c.channels[channel].Enabled = false
+	s.removeNodes(fmt.Sprintf("%v/pwm%v", c.path, channel))
+	return 0
+}
+
+// writePeriod fails with EINVAL for a period shorter than the duty cycle
+func (p *SimPwm) writePeriod(value string) syscall.Errno {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 || v < p.DutyCycle {
+		return syscall.EINVAL
+	}
+	p.Period = v
+	return 0
+}
+
+// writeDutyCycle fails with EINVAL for a duty cycle longer than the period
+func (p *SimPwm) writeDutyCycle(value string) syscall.Errno {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 || v > p.Period {
+		return syscall.EINVAL
+	}
+	p.DutyCycle = v
+	return 0
+}
+
+// writeEnable fails with EINVAL when enabling a channel without a period
+func (p *SimPwm) writeEnable(value string) syscall.Errno {
+	switch value {
+	case "0":
+		p.Enabled = false
+	case "1":
+		if p.Period == 0 {
+			return syscall.EINVAL
+		}
+		p.Enabled = true
+	default:
+		return syscall.EINVAL
+	}
+	return 0
+}
+
+// writePolarity fails with EBUSY while the channel is enabled
+func (p *SimPwm) writePolarity(value string) syscall.Errno {
+	if value != "normal" && value != "inversed" {
+		return syscall.EINVAL
+	}
+	if p.Enabled {
+		return syscall.EBUSY
+	}
+	p.Polarity = value
+	return 0
+}
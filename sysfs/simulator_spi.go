@@ -0,0 +1,111 @@
+package sysfs
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+// SimSpi is the state of a spi device of the Simulator
+type SimSpi struct {
+	// Mode is the spi mode, 0 through 3
+	Mode int
+	// Bits is the number of bits per word
+	Bits int
+	// Speed is the maximum clock speed in Hz
+	Speed int
+	// Transfers holds every message written to the device
+	Transfers [][]byte
+}
+
+type simSpiDevice struct {
+	state   SimSpi
+	respond func([]byte) []byte
+}
+
+// AddSpiDevice adds the spi device /dev/spidevB.C for chip select chip of spi
+// bus bus. Each transfer reads back what respond returns given the bytes
+// written, or the bytes written themselves when respond is nil, as when MOSI
+// is wired to MISO.
+func (s *Simulator) AddSpiDevice(bus int, chip int, respond func([]byte) []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	location := fmt.Sprintf("/dev/spidev%v.%v", bus, chip)
+	d := &simSpiDevice{state: SimSpi{Bits: 8}, respond: respond}
+	s.spis[location] = d
+	s.nodes[location] = &simNode{dev: d}
+}
+
+// Spi returns the state of a spi device, and false if the device does not exist
+func (s *Simulator) Spi(bus int, chip int) (SimSpi, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	d, ok := s.spis[fmt.Sprintf("/dev/spidev%v.%v", bus, chip)]
+	if !ok {
+		return SimSpi{}, false
+	}
+	state := d.state
+	state.Transfers = [][]byte{}
+	for _, t := range d.state.Transfers {
+		state.Transfers = append(state.Transfers, append([]byte{}, t...))
+	}
+	return state, true
+}
+
+// ioctl answers the spidev mode, bits per word, speed and message ioctls
+func (d *simSpiDevice) ioctl(f *simFile, request uintptr, arg uintptr) syscall.Errno {
+	switch request {
+	case SPI_IOC_WR_MODE:
+		mode := *(*uint8)(pointer(arg))
+		if mode > 3 {
+			return syscall.EINVAL
+		}
+		d.state.Mode = int(mode)
+	case SPI_IOC_WR_BITS_PER_WORD:
+		d.state.Bits = int(*(*uint8)(pointer(arg)))
+	case SPI_IOC_WR_MAX_SPEED_HZ:
+		d.state.Speed = int(*(*uint32)(pointer(arg)))
+	case SPI_IOC_MESSAGE_1:
+		return d.transfer((*spiIocTransfer)(pointer(arg)))
+	default:
+		return syscall.ENOTTY
+	}
+	return 0
+}
+
+func (d *simSpiDevice) transfer(t *spiIocTransfer) syscall.Errno {
+	tx := buffer(uintptr(t.txBuf), int(t.length))
+	rx := buffer(uintptr(t.rxBuf), int(t.length))
+	written := append([]byte{}, tx...)
+	d.state.Transfers = append(d.state.Transfers, written)
+	if d.respond == nil {
+		copy(rx, written)
+	} else {
+		copy(rx, d.respond(append([]byte{}, written...)))
+	}
+	return 0
+}
+
+// buffer returns the length bytes at the address the caller passed in an ioctl
+func buffer(address uintptr, length int) []byte {
+	var b []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	h.Data, h.Len, h.Cap = address, length, length
+	return b
+}
+
+func (d *simSpiDevice) open(f *simFile) syscall.Errno { return 0 }
+
+func (d *simSpiDevice) close(f *simFile) {}
+
+// read and write are half duplex transfers, which are not simulated
+func (d *simSpiDevice) read(f *simFile, b []byte) (int, syscall.Errno) {
+	return 0, syscall.EINVAL
+}
+
+func (d *simSpiDevice) write(f *simFile, b []byte) (int, syscall.Errno) {
+	return 0, syscall.EINVAL
+}
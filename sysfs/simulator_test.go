@@ -0,0 +1,290 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func initTestSimulator() *Simulator {
+	sim := NewSimulator()
+	SetFilesystem(sim)
+	SetSyscall(sim)
+	return sim
+}
+
+func TestSimulatorFiles(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+
+	sim.AddFile("/sys/devices/ocp.3/helper.5/AIN1", "567\n")
+	gobottest.Assert(t, sim.Exists("/sys/devices/ocp.3/helper.5/AIN1"), true)
+
+	f, err := OpenFile("/sys/devices/ocp.3/helper.5/AIN1", os.O_RDWR, 0644)
+	gobottest.Assert(t, err, nil)
+	buf := make([]byte, 2)
+	n, _ := f.Read(buf)
+	gobottest.Assert(t, string(buf[:n]), "56")
+	n, _ = f.Read(buf)
+	gobottest.Assert(t, string(buf[:n]), "7\n")
+	f.Seek(0, os.SEEK_SET)
+	n, _ = f.Read(buf)
+	gobottest.Assert(t, string(buf[:n]), "56")
+
+	f.WriteString("1")
+	gobottest.Assert(t, sim.Contents("/sys/devices/ocp.3/helper.5/AIN1"), "1")
+	gobottest.Assert(t, f.Close(), nil)
+	_, err = f.Read(buf)
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EBADF)
+
+	_, err = OpenFile("/sys/devices/ocp.3/helper.5/AIN2", os.O_RDONLY, 0644)
+	gobottest.Assert(t, os.IsNotExist(err), true)
+
+	matches, _ := sim.Glob("/sys/devices/ocp.*/helper.*")
+	gobottest.Assert(t, matches, []string{"/sys/devices/ocp.3/helper.5"})
+}
+
+func TestSimulatorSyscallClose(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddFile("/sys/devices/ocp.3/helper.5/AIN1", "567\n")
+
+	// a plain attribute is closed like a device
+	f, err := OpenFile("/sys/devices/ocp.3/helper.5/AIN1", os.O_RDONLY, 0644)
+	gobottest.Assert(t, err, nil)
+	_, _, errno := Syscall(syscall.SYS_CLOSE, f.Fd(), 0, 0)
+	gobottest.Assert(t, errno, syscall.Errno(0))
+	_, err = f.Read(make([]byte, 1))
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EBADF)
+	_, _, errno = Syscall(syscall.SYS_CLOSE, f.Fd(), 0, 0)
+	gobottest.Assert(t, errno, syscall.EBADF)
+}
+
+func TestSimulatorFaults(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddGpio(10)
+
+	sim.SetFault("open", GPIOPATH+"/export", syscall.EACCES)
+	pin := NewDigitalPin(10)
+	err := pin.Export()
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EACCES)
+	gobottest.Assert(t, os.IsPermission(err), true)
+
+	sim.ClearFault("open", GPIOPATH+"/export")
+	gobottest.Assert(t, pin.Export(), nil)
+
+	sim.SetFault("write", GPIOPATH+"/gpio10/value", syscall.EIO)
+	pin.Direction(OUT)
+	gobottest.Assert(t, pin.Write(HIGH).(*os.PathError).Err, syscall.EIO)
+}
+
+func TestSimulatorGpio(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddGpio(10)
+	sim.AddGpio(408, "gpio408_xio")
+
+	// unknown gpio
+	gobottest.Assert(t, NewDigitalPin(11).Export().(*os.PathError).Err, syscall.EINVAL)
+
+	pin := NewDigitalPin(10)
+	gobottest.Assert(t, sim.Exists(GPIOPATH+"/gpio10/value"), false)
+	gobottest.Assert(t, pin.Export(), nil)
+	gobottest.Assert(t, sim.Exists(GPIOPATH+"/gpio10/value"), true)
+	// exporting twice is EBUSY, which the digital pin ignores
+	gobottest.Assert(t, pin.Export(), nil)
+
+	// an input can't be written
+	gobottest.Assert(t, pin.Write(HIGH).(*os.PathError).Err, syscall.EPERM)
+	sim.SetGpioLevel(10, HIGH)
+	i, _ := pin.Read()
+	gobottest.Assert(t, i, HIGH)
+
+	gobottest.Assert(t, pin.Direction("sideways").(*os.PathError).Err, syscall.EINVAL)
+	gobottest.Assert(t, pin.Direction(OUT), nil)
+	gobottest.Assert(t, pin.Write(LOW), nil)
+	g, _ := sim.Gpio(10)
+	gobottest.Assert(t, g.Direction, OUT)
+	gobottest.Assert(t, g.Level, LOW)
+
+	// the level of an output isn't driven from outside
+	sim.SetGpioLevel(10, HIGH)
+	g, _ = sim.Gpio(10)
+	gobottest.Assert(t, g.Level, LOW)
+
+	gobottest.Assert(t, pin.Unexport(), nil)
+	gobottest.Assert(t, sim.Exists(GPIOPATH+"/gpio10/value"), false)
+	g, _ = sim.Gpio(10)
+	gobottest.Assert(t, g.Exported, false)
+	// unexporting twice is EINVAL, which the digital pin ignores
+	gobottest.Assert(t, pin.Unexport(), nil)
+
+	label := NewDigitalPin(408, "gpio408_xio")
+	gobottest.Assert(t, label.Export(), nil)
+	gobottest.Assert(t, sim.Exists(GPIOPATH+"/gpio408_xio/direction"), true)
+	gobottest.Assert(t, label.Direction("high"), nil)
+	g, _ = sim.Gpio(408)
+	gobottest.Assert(t, g.Level, HIGH)
+}
+
+func TestSimulatorGpioStaleFile(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddGpio(10)
+
+	pin := NewDigitalPin(10)
+	pin.Export()
+	f, _ := OpenFile(GPIOPATH+"/gpio10/value", os.O_RDWR, 0644)
+	pin.Unexport()
+
+	_, err := f.Write([]byte("1"))
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.ENODEV)
+}
+
+func TestSimulatorGpioActiveLow(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddGpio(10)
+
+	pin := NewDigitalPin(10)
+	pin.Export()
+	f, _ := OpenFile(GPIOPATH+"/gpio10/active_low", os.O_WRONLY, 0644)
+	f.WriteString("1\n")
+
+	sim.SetGpioLevel(10, LOW)
+	i, _ := pin.Read()
+	gobottest.Assert(t, i, HIGH)
+
+	pin.Direction(OUT)
+	pin.Write(HIGH)
+	g, _ := sim.Gpio(10)
+	gobottest.Assert(t, g.Level, LOW)
+}
+
+func TestSimulatorPwm(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddPwmChip(0, 2)
+
+	write := func(path string, value string) error {
+		f, err := OpenFile(PWMPATH+"/pwmchip0/"+path, os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString(value)
+		return err
+	}
+	errno := func(err error) error { return err.(*os.PathError).Err }
+
+	gobottest.Assert(t, sim.Contents(PWMPATH+"/pwmchip0/npwm"), "2\n")
+	gobottest.Assert(t, errno(write("export", "2")), syscall.ENODEV)
+	gobottest.Assert(t, errno(write("pwm0/period", "1000")), syscall.ENOENT)
+	gobottest.Assert(t, write("export", "0"), nil)
+	gobottest.Assert(t, errno(write("export", "0")), syscall.EBUSY)
+
+	gobottest.Assert(t, errno(write("pwm0/enable", "1")), syscall.EINVAL)
+	gobottest.Assert(t, errno(write("pwm0/duty_cycle", "500")), syscall.EINVAL)
+	gobottest.Assert(t, write("pwm0/period", "1000"), nil)
+	gobottest.Assert(t, write("pwm0/duty_cycle", "500"), nil)
+	gobottest.Assert(t, errno(write("pwm0/period", "400")), syscall.EINVAL)
+	gobottest.Assert(t, write("pwm0/enable", "1"), nil)
+	gobottest.Assert(t, errno(write("pwm0/polarity", "inversed")), syscall.EBUSY)
+
+	p, _ := sim.Pwm(0, 0)
+	gobottest.Assert(t, p, SimPwm{Exported: true, Period: 1000, DutyCycle: 500, Enabled: true, Polarity: "normal"})
+	gobottest.Assert(t, sim.Contents(PWMPATH+"/pwmchip0/pwm0/duty_cycle"), "500\n")
+
+	gobottest.Assert(t, write("unexport", "0"), nil)
+	gobottest.Assert(t, errno(write("unexport", "0")), syscall.ENODEV)
+	p, _ = sim.Pwm(0, 0)
+	gobottest.Assert(t, p.Enabled, false)
+	gobottest.Assert(t, sim.Exists(PWMPATH+"/pwmchip0/pwm0/period"), false)
+}
+
+func TestSimulatorIio(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddIioDevice(0, 2)
+	sim.SetAnalog(0, 1, 1234)
+
+	i, err := NewAnalogPin(0, 1).Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, i, 1234)
+
+	b := NewAnalogBuffer(0, []int{0, 1}, 4)
+	f, _ := OpenFile("/dev/iio:device0", os.O_RDONLY, 0644)
+	_, err = f.Read(make([]byte, 4))
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.EINVAL)
+	// a second reader is EBUSY
+	gobottest.Assert(t, b.Enable().(*os.PathError).Err, syscall.EBUSY)
+	f.Close()
+
+	gobottest.Assert(t, b.Enable(), nil)
+	gobottest.Assert(t, sim.Contents(IIOPATH+"/iio:device0/scan_elements/in_voltage1_en"), "1")
+	gobottest.Assert(t, sim.Contents(IIOPATH+"/iio:device0/buffer/enable"), "1\n")
+
+	sim.PushIioScans(0, []byte{0x01, 0x00, 0xff, 0x0f})
+	scans, err := b.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, scans, [][]int{{1, 4095}})
+
+	gobottest.Assert(t, b.Disable(), nil)
+	gobottest.Assert(t, sim.Contents(IIOPATH+"/iio:device0/buffer/enable"), "0\n")
+}
+
+func TestSimulatorI2c(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddI2cBus(1, I2C_FUNC_SMBUS_READ_BLOCK_DATA|I2C_FUNC_SMBUS_WRITE_BLOCK_DATA)
+	sim.AddI2cDevice(1, 0x52)
+	sim.SetI2cRegisters(1, 0x52, 0x10, []byte{0xaa, 0xbb})
+
+	bus := NewI2cBus("/dev/i2c-1")
+	gobottest.Assert(t, bus.Start(0x52), nil)
+
+	// smbus block transfers
+	gobottest.Assert(t, bus.Write(0x52, []byte{0x10}), nil)
+	gobottest.Assert(t, bus.Write(0x52, []byte{0x20, 0x01, 0x02}), nil)
+	gobottest.Assert(t, sim.I2cRegisters(1, 0x52)[0x20:0x22], []byte{0x01, 0x02})
+	gobottest.Assert(t, sim.I2cWrites(1, 0x52), [][]byte{{0x10}, {0x20, 0x01, 0x02}})
+
+	buf, err := bus.Read(0x52, 2)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, buf, []byte{0x00, 0x00})
+
+	// the device does not acknowledge an unknown address
+	_, err = bus.Read(0x53, 2)
+	gobottest.Refute(t, err, nil)
+
+	sim.SetI2cNak(1, 0x52, true)
+	gobottest.Refute(t, bus.Write(0x52, []byte{0x10}), nil)
+	sim.SetI2cNak(1, 0x52, false)
+	gobottest.Assert(t, bus.Write(0x52, []byte{0x10}), nil)
+	gobottest.Assert(t, bus.Close(), nil)
+}
+
+func TestSimulatorI2cPlain(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddI2cBus(1, 0)
+	sim.AddI2cDevice(1, 0x52)
+	sim.SetI2cRegisters(1, 0x52, 0x10, []byte{0xaa, 0xbb})
+
+	bus := NewI2cBus("/dev/i2c-1")
+	gobottest.Assert(t, bus.Start(0x52), nil)
+	gobottest.Assert(t, bus.Write(0x52, []byte{0x10}), nil)
+	buf, err := bus.Read(0x52, 2)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, buf, []byte{0xaa, 0xbb})
+
+	sim.SetI2cNak(1, 0x52, true)
+	_, err = bus.Read(0x52, 2)
+	gobottest.Assert(t, err.(*os.PathError).Err, syscall.ENXIO)
+
+	sim.SetFault("ioctl", "/dev/i2c-1", syscall.EBUSY)
+	gobottest.Refute(t, bus.Write(0x52, []byte{0x10}), nil)
+}
@@ -0,0 +1,109 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	SPI_IOC_WR_MODE          = 0x40016b01
+	SPI_IOC_WR_BITS_PER_WORD = 0x40016b03
+	SPI_IOC_WR_MAX_SPEED_HZ  = 0x40046b04
+	SPI_IOC_MESSAGE_1        = 0x40206b00
+)
+
+// spiIocTransfer is struct spi_ioc_transfer of linux/spi/spidev.h
+type spiIocTransfer struct {
+	txBuf          uint64
+	rxBuf          uint64
+	length         uint32
+	speedHz        uint32
+	delayUsecs     uint16
+	bitsPerWord    uint8
+	csChange       uint8
+	txNbits        uint8
+	rxNbits        uint8
+	wordDelayUsecs uint8
+	pad            uint8
+}
+
+// SpiDevice is the interface for spidev interactions
+type SpiDevice interface {
+	// Transfer writes data to the device while reading as many bytes from it
+	Transfer(data []byte) ([]byte, error)
+	// Close closes the device
+	Close() error
+}
+
+type spiDevice struct {
+	file  File
+	bits  int
+	speed int
+}
+
+// NewSpiDevice returns a SpiDevice given a spidev location, eg. /dev/spidev0.1
+// for chip select 1 of spi bus 0, and the spi mode (0-3), bits per word and
+// maximum clock speed in Hz used to transfer with it
+func NewSpiDevice(location string, mode int, bits int, speed int) (SpiDevice, error) {
+	d := &spiDevice{bits: bits, speed: speed}
+	f, err := OpenFile(location, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	d.file = f
+
+	m := uint8(mode)
+	b := uint8(bits)
+	s := uint32(speed)
+	for _, c := range []struct {
+		request uintptr
+		arg     unsafe.Pointer
+		name    string
+	}{
+		{SPI_IOC_WR_MODE, unsafe.Pointer(&m), "mode"},
+		{SPI_IOC_WR_BITS_PER_WORD, unsafe.Pointer(&b), "bits per word"},
+		{SPI_IOC_WR_MAX_SPEED_HZ, unsafe.Pointer(&s), "speed"},
+	} {
+		if _, _, errno := Syscall(syscall.SYS_IOCTL, f.Fd(), c.request, uintptr(c.arg)); errno != 0 {
+			f.Close()
+			return nil, fmt.Errorf("Setting spi %v failed with syscall.Errno %v", c.name, errno)
+		}
+	}
+	return d, nil
+}
+
+func (d *spiDevice) Transfer(data []byte) ([]byte, error) {
+	rx := make([]byte, len(data))
+	if len(data) == 0 {
+		return rx, nil
+	}
+	tx := append([]byte{}, data...)
+	transfer := spiIocTransfer{
+		txBuf:       uint64(uintptr(unsafe.Pointer(&tx[0]))),
+		rxBuf:       uint64(uintptr(unsafe.Pointer(&rx[0]))),
+		length:      uint32(len(tx)),
+		speedHz:     uint32(d.speed),
+		bitsPerWord: uint8(d.bits),
+	}
+
+	_, _, errno := Syscall(
+		syscall.SYS_IOCTL,
+		d.file.Fd(),
+		SPI_IOC_MESSAGE_1,
+		uintptr(unsafe.Pointer(&transfer)),
+	)
+	runtime.KeepAlive(tx)
+	runtime.KeepAlive(rx)
+
+	if errno != 0 {
+		return nil, fmt.Errorf("Spi transfer failed with syscall.Errno %v", errno)
+	}
+	return rx, nil
+}
+
+func (d *spiDevice) Close() error {
+	return d.file.Close()
+}
@@ -0,0 +1,47 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+func TestSpiDevice(t *testing.T) {
+	sim := initTestSimulator()
+	defer SetSyscall(&NativeSyscall{})
+	sim.AddSpiDevice(0, 0, nil)
+	sim.AddSpiDevice(0, 1, func(tx []byte) []byte {
+		return []byte{0x00, tx[0] + 1}
+	})
+
+	d, err := NewSpiDevice("/dev/spidev0.0", 3, 8, 500000)
+	gobottest.Assert(t, err, nil)
+	rx, err := d.Transfer([]byte{0x01, 0x02, 0x03})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, rx, []byte{0x01, 0x02, 0x03})
+	rx, _ = d.Transfer([]byte{})
+	gobottest.Assert(t, rx, []byte{})
+
+	s, _ := sim.Spi(0, 0)
+	gobottest.Assert(t, s.Mode, 3)
+	gobottest.Assert(t, s.Bits, 8)
+	gobottest.Assert(t, s.Speed, 500000)
+	gobottest.Assert(t, s.Transfers, [][]byte{{0x01, 0x02, 0x03}})
+	gobottest.Assert(t, d.Close(), nil)
+
+	d, _ = NewSpiDevice("/dev/spidev0.1", 0, 8, 1000000)
+	rx, _ = d.Transfer([]byte{0x41, 0x00})
+	gobottest.Assert(t, rx, []byte{0x00, 0x42})
+
+	sim.SetFault("ioctl", "/dev/spidev0.1", syscall.EIO)
+	_, err = d.Transfer([]byte{0x41, 0x00})
+	gobottest.Refute(t, err, nil)
+
+	_, err = NewSpiDevice("/dev/spidev0.0", 4, 8, 500000)
+	gobottest.Refute(t, err, nil)
+
+	_, err = NewSpiDevice("/dev/spidev1.0", 0, 8, 500000)
+	gobottest.Assert(t, os.IsNotExist(err), true)
+}
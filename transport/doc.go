@@ -0,0 +1,16 @@
+/*
+Package transport opens the byte stream used by serial based platforms such as
+firmata, sphero, mavlink and neurosky from a URL-like spec.
+
+The same platform can so run over a USB serial port, a network serial bridge
+such as ser2net, or a local pty:
+
+	/dev/ttyACM0                           serial port with the adaptor default baud rate
+	serial:///dev/ttyACM0?baud=57600       serial port with an explicit baud rate
+	serial:///dev/ttyS1?parity=even&timeout=500ms
+	                                       serial port with even parity, reads time out after half a second
+	tcp://192.168.1.10:3333?timeout=5s     tcp connection, reads time out after 5 seconds
+	udp://192.168.1.10:14550               udp datagrams to and from a remote address
+	udp://:14550                           udp datagrams received on a local port, replies go to the last sender
+*/
+package transport
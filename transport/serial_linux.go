@@ -0,0 +1,52 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// configureSerial sets the parity of port, unless it is empty, and the read
+// timeout of port, unless it is 0, through its termios
+func configureSerial(port io.ReadWriteCloser, parity string, timeout time.Duration) error {
+	f, ok := port.(interface {
+		Fd() uintptr
+	})
+	if !ok {
+		return errors.New("transport: serial port does not support parity or timeout")
+	}
+
+	var t syscall.Termios
+	if err := termios(f.Fd(), syscall.TCGETS, &t); err != nil {
+		return err
+	}
+	switch parity {
+	case "none":
+		t.Cflag &^= syscall.PARENB | syscall.PARODD
+		t.Iflag &^= syscall.INPCK
+	case "odd":
+		t.Cflag |= syscall.PARENB | syscall.PARODD
+		t.Iflag |= syscall.INPCK
+	case "even":
+		t.Cflag = t.Cflag&^syscall.PARODD | syscall.PARENB
+		t.Iflag |= syscall.INPCK
+	}
+	if timeout > 0 {
+		// reads return whatever arrived, or nothing once VTIME tenths of a
+		// second passed without data
+		t.Lflag &^= syscall.ICANON
+		t.Cc[syscall.VMIN] = 0
+		t.Cc[syscall.VTIME] = uint8((timeout + 100*time.Millisecond - 1) / (100 * time.Millisecond))
+	}
+	return termios(f.Fd(), syscall.TCSETS, &t)
+}
+
+// termios gets or sets the termios of the tty fd, it is replaced in tests
+var termios = func(fd uintptr, request uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+// openPty opens the master of a new pseudo terminal, and returns it with the
+// name of its slave, which stands in for a serial port
+func openPty(t *testing.T) (*os.File, string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip("no pseudo terminals:", err)
+	}
+	var unlock, n int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		t.Skip("no pseudo terminals:", errno)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		master.Close()
+		t.Skip("no pseudo terminals:", errno)
+	}
+	return master, "/dev/pts/" + strconv.Itoa(int(n))
+}
+
+func TestOpenSerialTimeout(t *testing.T) {
+	master, name := openPty(t)
+	defer master.Close()
+
+	port, err := Open("serial://"+name+"?timeout=150ms", 57600)
+	gobottest.Assert(t, err, nil)
+	defer port.Close()
+
+	// the timeout reaches the termios of the port
+	var tio syscall.Termios
+	gobottest.Assert(t, termios(port.(*serialPort).ReadWriteCloser.(*os.File).Fd(), syscall.TCGETS, &tio), nil)
+	gobottest.Assert(t, tio.Cc[syscall.VMIN], uint8(0))
+	gobottest.Assert(t, tio.Cc[syscall.VTIME], uint8(2))
+
+	// reads time out without data
+	start := time.Now()
+	_, err = port.Read(make([]byte, 1))
+	gobottest.Assert(t, err, ErrSerialTimeout)
+	gobottest.Assert(t, time.Since(start) >= 100*time.Millisecond, true)
+
+	master.Write([]byte("a"))
+	buf := make([]byte, 1)
+	n, err := port.Read(buf)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, string(buf[:n]), "a")
+}
+
+func TestOpenSerialParity(t *testing.T) {
+	// pseudo terminals ignore the parity, so the termios set is recorded
+	defer func(f func(uintptr, uintptr, *syscall.Termios) error) { termios = f }(termios)
+	master, name := openPty(t)
+	defer master.Close()
+
+	set := syscall.Termios{}
+	get := termios
+	termios = func(fd uintptr, request uintptr, tio *syscall.Termios) error {
+		if request == syscall.TCSETS {
+			set = *tio
+		}
+		return get(fd, request, tio)
+	}
+
+	port, err := Open("serial://"+name+"?parity=odd", 57600)
+	gobottest.Assert(t, err, nil)
+	port.Close()
+	gobottest.Assert(t, set.Cflag&(syscall.PARENB|syscall.PARODD), uint32(syscall.PARENB|syscall.PARODD))
+	gobottest.Assert(t, set.Iflag&syscall.INPCK, uint32(syscall.INPCK))
+
+	port, err = Open("serial://"+name+"?parity=e", 57600)
+	gobottest.Assert(t, err, nil)
+	port.Close()
+	gobottest.Assert(t, set.Cflag&(syscall.PARENB|syscall.PARODD), uint32(syscall.PARENB))
+
+	port, err = Open("serial://"+name+"?parity=none", 57600)
+	gobottest.Assert(t, err, nil)
+	port.Close()
+	gobottest.Assert(t, set.Cflag&(syscall.PARENB|syscall.PARODD), uint32(0))
+	gobottest.Assert(t, set.Iflag&syscall.INPCK, uint32(0))
+
+	termios = func(uintptr, uintptr, *syscall.Termios) error { return syscall.ENOTTY }
+	_, err = Open("serial://"+name+"?parity=odd", 57600)
+	gobottest.Assert(t, err, syscall.ENOTTY)
+}
+
+func TestOpenSerialSettingsUnsupported(t *testing.T) {
+	defer func(f func(string, int) (io.ReadWriteCloser, error)) { openSerial = f }(openSerial)
+	openSerial = func(string, int) (io.ReadWriteCloser, error) {
+		return nullReadWriteCloser{}, nil
+	}
+	_, err := Open("serial:///dev/ttyS0?parity=odd", 9600)
+	gobottest.Assert(t, err, errors.New("transport: serial port does not support parity or timeout"))
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package transport
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// configureSerial returns an error, as the parity and timeout of serial ports
+// are only set on linux
+func configureSerial(port io.ReadWriteCloser, parity string, timeout time.Duration) error {
+	return errors.New("transport: parity and timeout of serial ports are only supported on linux")
+}
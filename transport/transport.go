@@ -0,0 +1,262 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tarm/goserial"
+)
+
+// Spec describes a transport
+type Spec struct {
+	// Scheme is one of "serial", "tcp" or "udp"
+	Scheme string
+	// Address is the serial device name, or the host:port of a network transport
+	Address string
+	// Baud is the serial port baud rate, 0 selects the adaptor default
+	Baud int
+	// Parity is the serial port parity, "none", "odd" or "even", or empty to
+	// leave the port as it is
+	Parity string
+	// Timeout is the read timeout, 0 means reads never time out. Serial ports
+	// time out in tenths of a second, up to 25.5 seconds.
+	Timeout time.Duration
+}
+
+// maxSerialTimeout is the longest read timeout of a serial port, whose
+// termios counts it in tenths of a second in a byte
+const maxSerialTimeout = 25500 * time.Millisecond
+
+// serialParities maps the parity options to the parities of a Spec
+var serialParities = map[string]string{
+	"n": "none", "none": "none",
+	"o": "odd", "odd": "odd",
+	"e": "even", "even": "even",
+}
+
+// ErrNoRemote is returned when writing to a listening udp transport which has
+// not received a datagram yet
+var ErrNoRemote = errors.New("transport: no remote address to write to")
+
+// ErrSerialTimeout is returned by a read of a serial port receiving no data
+// within its timeout
+var ErrSerialTimeout = errors.New("transport: serial read timed out")
+
+// openSerial opens a serial port, it is replaced in tests
+var openSerial = func(name string, baud int) (io.ReadWriteCloser, error) {
+	return serial.OpenPort(&serial.Config{Name: name, Baud: baud})
+}
+
+// Parse parses a transport spec. A spec without a scheme, such as "/dev/ttyACM0"
+// or "COM3", is a serial port.
+func Parse(spec string) (s *Spec, err error) {
+	if !strings.Contains(spec, "://") {
+		if spec == "" {
+			return nil, errors.New("transport: empty spec")
+		}
+		return &Spec{Scheme: "serial", Address: spec}, nil
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("transport: invalid spec %q: %v", spec, err)
+	}
+
+	s = &Spec{Scheme: u.Scheme}
+	switch u.Scheme {
+	case "serial":
+		s.Address = u.Path
+		// serial://COM3 puts a windows port name in the host part
+		if s.Address == "" {
+			s.Address = u.Host
+		}
+	case "tcp", "udp":
+		s.Address = u.Host
+	default:
+		return nil, fmt.Errorf("transport: unsupported scheme %q", u.Scheme)
+	}
+	if s.Address == "" {
+		return nil, fmt.Errorf("transport: missing address in %q", spec)
+	}
+
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "baud":
+			if s.Scheme != "serial" {
+				return nil, fmt.Errorf("transport: baud is not supported by %v", s.Scheme)
+			}
+			if s.Baud, err = strconv.Atoi(value); err != nil || s.Baud <= 0 {
+				return nil, fmt.Errorf("transport: invalid baud %q", value)
+			}
+		case "parity":
+			if s.Scheme != "serial" {
+				return nil, fmt.Errorf("transport: parity is not supported by %v", s.Scheme)
+			}
+			var ok bool
+			if s.Parity, ok = serialParities[strings.ToLower(value)]; !ok {
+				return nil, fmt.Errorf("transport: invalid parity %q", value)
+			}
+		case "timeout":
+			if s.Timeout, err = time.ParseDuration(value); err != nil || s.Timeout < 0 {
+				return nil, fmt.Errorf("transport: invalid timeout %q", value)
+			}
+			if s.Scheme == "serial" && s.Timeout > maxSerialTimeout {
+				return nil, fmt.Errorf("transport: timeout %q exceeds the %v of serial ports", value, maxSerialTimeout)
+			}
+		default:
+			return nil, fmt.Errorf("transport: unknown option %q", key)
+		}
+	}
+	return s, nil
+}
+
+// String returns the spec in its URL-like form
+func (s *Spec) String() string {
+	v := url.Values{}
+	if s.Baud != 0 {
+		v.Set("baud", strconv.Itoa(s.Baud))
+	}
+	if s.Parity != "" {
+		v.Set("parity", s.Parity)
+	}
+	if s.Timeout != 0 {
+		v.Set("timeout", s.Timeout.String())
+	}
+	str := s.Scheme + "://" + s.Address
+	if len(v) > 0 {
+		str += "?" + v.Encode()
+	}
+	return str
+}
+
+// Open parses spec and opens the transport it describes. baud is the baud rate
+// used for a serial port when the spec does not set one.
+func Open(spec string, baud int) (io.ReadWriteCloser, error) {
+	s, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if s.Baud == 0 {
+		s.Baud = baud
+	}
+	return s.Open()
+}
+
+// Open opens the transport described by s
+func (s *Spec) Open() (io.ReadWriteCloser, error) {
+	switch s.Scheme {
+	case "serial":
+		port, err := openSerial(s.Address, s.Baud)
+		if err != nil || (s.Parity == "" && s.Timeout == 0) {
+			return port, err
+		}
+		if err = configureSerial(port, s.Parity, s.Timeout); err != nil {
+			port.Close()
+			return nil, err
+		}
+		if s.Timeout > 0 {
+			return &serialPort{ReadWriteCloser: port}, nil
+		}
+		return port, nil
+	case "tcp":
+		conn, err := net.Dial("tcp", s.Address)
+		if err != nil {
+			return nil, err
+		}
+		return &netConn{Conn: conn, timeout: s.Timeout}, nil
+	case "udp":
+		addr, err := net.ResolveUDPAddr("udp", s.Address)
+		if err != nil {
+			return nil, err
+		}
+		if addr.IP == nil {
+			conn, err := net.ListenUDP("udp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return &udpListener{conn: conn, timeout: s.Timeout}, nil
+		}
+		conn, err := net.DialUDP("udp", nil, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &netConn{Conn: conn, timeout: s.Timeout}, nil
+	}
+	return nil, fmt.Errorf("transport: unsupported scheme %q", s.Scheme)
+}
+
+// serialPort reports the reads of a serial port timing out, which return no
+// data, with ErrSerialTimeout
+type serialPort struct {
+	io.ReadWriteCloser
+}
+
+func (p *serialPort) Read(b []byte) (int, error) {
+	n, err := p.ReadWriteCloser.Read(b)
+	if n == 0 && len(b) > 0 && (err == nil || err == io.EOF) {
+		return 0, ErrSerialTimeout
+	}
+	return n, err
+}
+
+// netConn applies the read timeout to a connected net.Conn
+type netConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *netConn) Read(b []byte) (int, error) {
+	if c.timeout > 0 {
+		if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(b)
+}
+
+// udpListener reads datagrams sent to a local port and writes to the
+// address of the last datagram received
+type udpListener struct {
+	conn    *net.UDPConn
+	timeout time.Duration
+	remote  *net.UDPAddr
+	mutex   sync.Mutex
+}
+
+func (l *udpListener) Read(b []byte) (int, error) {
+	if l.timeout > 0 {
+		if err := l.conn.SetReadDeadline(time.Now().Add(l.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	n, addr, err := l.conn.ReadFromUDP(b)
+	if err != nil {
+		return n, err
+	}
+	l.mutex.Lock()
+	l.remote = addr
+	l.mutex.Unlock()
+	return n, nil
+}
+
+func (l *udpListener) Write(b []byte) (int, error) {
+	l.mutex.Lock()
+	remote := l.remote
+	l.mutex.Unlock()
+	if remote == nil {
+		return 0, ErrNoRemote
+	}
+	return l.conn.WriteToUDP(b, remote)
+}
+
+func (l *udpListener) Close() error {
+	return l.conn.Close()
+}
@@ -0,0 +1,170 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/potix/gobot/gobottest"
+)
+
+type nullReadWriteCloser struct{}
+
+func (nullReadWriteCloser) Read(b []byte) (int, error)  { return len(b), nil }
+func (nullReadWriteCloser) Write(b []byte) (int, error) { return len(b), nil }
+func (nullReadWriteCloser) Close() error                { return nil }
+
+func TestParse(t *testing.T) {
+	s, err := Parse("/dev/ttyACM0")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "serial", Address: "/dev/ttyACM0"})
+
+	s, err = Parse("COM3")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "serial", Address: "COM3"})
+
+	s, err = Parse("serial:///dev/ttyACM0?baud=57600&parity=none")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "serial", Address: "/dev/ttyACM0", Baud: 57600, Parity: "none"})
+
+	s, err = Parse("serial:///dev/ttyS1?parity=E&timeout=500ms")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "serial", Address: "/dev/ttyS1", Parity: "even", Timeout: 500 * time.Millisecond})
+
+	s, err = Parse("serial://COM3?baud=9600")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "serial", Address: "COM3", Baud: 9600})
+
+	s, err = Parse("tcp://192.168.1.10:3333?timeout=5s")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "tcp", Address: "192.168.1.10:3333", Timeout: 5 * time.Second})
+
+	s, err = Parse("udp://:14550")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *s, Spec{Scheme: "udp", Address: ":14550"})
+}
+
+func TestParseErrors(t *testing.T) {
+	for spec, msg := range map[string]string{
+		"":                                  "transport: empty spec",
+		"usb:///dev/ttyACM0":                `transport: unsupported scheme "usb"`,
+		"tcp://":                            `transport: missing address in "tcp://"`,
+		"serial:///dev/ttyACM0?baud=fast":   `transport: invalid baud "fast"`,
+		"serial:///dev/ttyACM0?parity=mark": `transport: invalid parity "mark"`,
+		"serial:///dev/ttyACM0?timeout=30s": `transport: timeout "30s" exceeds the 25.5s of serial ports`,
+		"tcp://localhost:3333?parity=odd":   "transport: parity is not supported by tcp",
+		"serial:///dev/ttyACM0?speed=9600":  `transport: unknown option "speed"`,
+		"tcp://localhost:3333?baud=9600":    "transport: baud is not supported by tcp",
+		"tcp://localhost:3333?timeout=soon": `transport: invalid timeout "soon"`,
+	} {
+		_, err := Parse(spec)
+		gobottest.Assert(t, err, errors.New(msg))
+	}
+}
+
+func TestSpecString(t *testing.T) {
+	s, _ := Parse("serial:///dev/ttyACM0?baud=57600")
+	gobottest.Assert(t, s.String(), "serial:///dev/ttyACM0?baud=57600")
+	s, _ = Parse("serial:///dev/ttyS1?parity=o&timeout=1s")
+	gobottest.Assert(t, s.String(), "serial:///dev/ttyS1?parity=odd&timeout=1s")
+	s, _ = Parse("tcp://localhost:3333?timeout=5s")
+	gobottest.Assert(t, s.String(), "tcp://localhost:3333?timeout=5s")
+}
+
+func TestOpenSerial(t *testing.T) {
+	defer func(f func(string, int) (io.ReadWriteCloser, error)) { openSerial = f }(openSerial)
+
+	name, baud := "", 0
+	openSerial = func(n string, b int) (io.ReadWriteCloser, error) {
+		name, baud = n, b
+		return nullReadWriteCloser{}, nil
+	}
+
+	_, err := Open("/dev/ttyACM0", 115200)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, name, "/dev/ttyACM0")
+	gobottest.Assert(t, baud, 115200)
+
+	_, err = Open("serial:///dev/ttyUSB0?baud=9600", 115200)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, name, "/dev/ttyUSB0")
+	gobottest.Assert(t, baud, 9600)
+
+	openSerial = func(string, int) (io.ReadWriteCloser, error) {
+		return nil, errors.New("open error")
+	}
+	_, err = Open("/dev/ttyACM0", 57600)
+	gobottest.Assert(t, err, errors.New("open error"))
+}
+
+func TestOpenTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	gobottest.Assert(t, err, nil)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4)
+		n, _ := conn.Read(buf)
+		conn.Write(buf[:n])
+	}()
+
+	conn, err := Open("tcp://"+l.Addr().String()+"?timeout=1s", 57600)
+	gobottest.Assert(t, err, nil)
+	defer conn.Close()
+
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	n, err := io.ReadFull(conn, buf)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, string(buf[:n]), "ping")
+}
+
+func TestOpenTCPTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	gobottest.Assert(t, err, nil)
+	defer l.Close()
+
+	conn, err := Open("tcp://"+l.Addr().String()+"?timeout=10ms", 57600)
+	gobottest.Assert(t, err, nil)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	gobottest.Assert(t, err.(net.Error).Timeout(), true)
+}
+
+func TestOpenUDPListener(t *testing.T) {
+	conn, err := Open("udp://127.0.0.1:0", 57600)
+	gobottest.Assert(t, err, nil)
+	conn.Close()
+
+	listener, err := Open("udp://:0", 57600)
+	gobottest.Assert(t, err, nil)
+	defer listener.Close()
+
+	_, err = listener.Write([]byte("ping"))
+	gobottest.Assert(t, err, ErrNoRemote)
+
+	port := listener.(*udpListener).conn.LocalAddr().(*net.UDPAddr).Port
+	remote, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	gobottest.Assert(t, err, nil)
+	defer remote.Close()
+
+	remote.Write([]byte("ping"))
+	buf := make([]byte, 16)
+	n, err := listener.Read(buf)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, string(buf[:n]), "ping")
+
+	listener.Write([]byte("pong"))
+	remote.SetReadDeadline(time.Now().Add(time.Second))
+	n, err = remote.Read(buf)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, string(buf[:n]), "pong")
+}
@@ -54,7 +54,9 @@ func Publish(e *Event, val interface{}) (err error) {
 // does not exist.
 func On(e *Event, f func(s interface{})) (err error) {
 	if err = eventError(e); err == nil {
+		e.Lock()
 		e.Callbacks = append(e.Callbacks, callback{f, false})
+		e.Unlock()
 	}
 	return
 }
@@ -63,7 +65,9 @@ func On(e *Event, f func(s interface{})) (err error) {
 //ErrUnknownEvent if Event does not exist.
 func Once(e *Event, f func(s interface{})) (err error) {
 	if err = eventError(e); err == nil {
+		e.Lock()
 		e.Callbacks = append(e.Callbacks, callback{f, true})
+		e.Unlock()
 	}
 	return
 }